@@ -0,0 +1,504 @@
+package gobatis
+
+import (
+	"bytes"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/runner-mei/GoBatis/convert"
+)
+
+// isArrayType 判断 typ 是否应该作为数据库的数组类型来处理
+//
+// 自定义的切片类型 (如 type Tags []string) 只有字段上有 array 选项时才作为数组处理，
+// 没有时和以前一样用 json 保存， 实现了 sql.Scanner 或 driver.Valuer 的类型， 以及
+// net.IP 之类的字节切片总是除外。 元素为结构体(time.Time 除外)的切片用 json 保存， 不作为数组处理。
+func isArrayType(typ reflect.Type, options map[string]string) bool {
+	if typ.Kind() != reflect.Slice {
+		return false
+	}
+	if typ == _bytesType {
+		return true
+	}
+	if typ.PkgPath() != "" {
+		if _, ok := options["array"]; !ok {
+			return false
+		}
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return false
+		}
+		if typ.Implements(_valuerInterface) || reflect.PtrTo(typ).Implements(_scannerInterface) {
+			return false
+		}
+	}
+
+	elem := arrayLeafType(typ)
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	if elem.Kind() == reflect.Struct && elem != _timeType {
+		return reflect.PtrTo(elem).Implements(_scannerInterface)
+	}
+	return true
+}
+
+// isArrayLeaf 判断 typ 是否是数组的元素， 而不是多维数组中的一维
+func isArrayLeaf(typ reflect.Type) bool {
+	if typ.Kind() != reflect.Slice {
+		return true
+	}
+	return typ.Elem().Kind() == reflect.Uint8
+}
+
+func arrayLeafType(typ reflect.Type) reflect.Type {
+	elem := typ.Elem()
+	for !isArrayLeaf(elem) {
+		elem = elem.Elem()
+	}
+	return elem
+}
+
+func isArrayLeafSupported(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if reflect.PtrTo(typ).Implements(_scannerInterface) {
+		return true
+	}
+
+	switch typ {
+	case _timeType, _ipType, _macType:
+		return true
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool,
+		reflect.Int,
+		reflect.Int8,
+		reflect.Int16,
+		reflect.Int32,
+		reflect.Int64,
+		reflect.Uint,
+		reflect.Uint8,
+		reflect.Uint16,
+		reflect.Uint32,
+		reflect.Uint64,
+		reflect.Float32,
+		reflect.Float64,
+		reflect.String:
+		return true
+	case reflect.Slice:
+		return typ.Elem().Kind() == reflect.Uint8
+	}
+	return false
+}
+
+var (
+	_ sql.Scanner   = &pqArray{}
+	_ driver.Valuer = &pqArray{}
+)
+
+// pqArray 是 PostgreSQL 数组的通用扫描器和转换器， 它支持元素为 mapper 能转换的
+// 任意类型的切片， 包括自定义的切片类型， 元素为指针的切片(用于保存 NULL 元素)
+// 和多维数组
+type pqArray struct {
+	name  string
+	value interface{}
+}
+
+func (a *pqArray) Value() (driver.Value, error) {
+	rv := reflect.ValueOf(a.value)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("'%T' isnot a slice", a.value)
+	}
+	if rv.IsNil() {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	if err := appendPQArray(&buf, rv); err != nil {
+		return nil, err
+	}
+	return buf.String(), nil
+}
+
+func appendPQArray(buf *bytes.Buffer, rv reflect.Value) error {
+	buf.WriteByte('{')
+	for i := 0; i < rv.Len(); i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		elem := rv.Index(i)
+		if !isArrayLeaf(elem.Type()) {
+			if err := appendPQArray(buf, elem); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := appendPQArrayElement(buf, elem); err != nil {
+			return err
+		}
+	}
+	buf.WriteByte('}')
+	return nil
+}
+
+func appendPQArrayElement(buf *bytes.Buffer, elem reflect.Value) error {
+	if elem.Kind() == reflect.Ptr {
+		if elem.IsNil() {
+			buf.WriteString("NULL")
+			return nil
+		}
+		elem = elem.Elem()
+	}
+
+	var value interface{} = elem.Interface()
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return err
+		}
+		if v == nil {
+			buf.WriteString("NULL")
+			return nil
+		}
+		value = v
+	} else if elem.CanAddr() {
+		if valuer, ok := elem.Addr().Interface().(driver.Valuer); ok {
+			v, err := valuer.Value()
+			if err != nil {
+				return err
+			}
+			if v == nil {
+				buf.WriteString("NULL")
+				return nil
+			}
+			value = v
+		}
+	}
+
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = `\x` + hex.EncodeToString(v)
+	case bool:
+		if v {
+			s = "t"
+		} else {
+			s = "f"
+		}
+	case time.Time:
+		s = v.Format("2006-01-02 15:04:05.999999999Z07:00")
+	case net.IP:
+		s = v.String()
+	case net.HardwareAddr:
+		s = v.String()
+	default:
+		rv := reflect.ValueOf(value)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			s = strconv.FormatInt(rv.Int(), 10)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			s = strconv.FormatUint(rv.Uint(), 10)
+		case reflect.Float32:
+			s = strconv.FormatFloat(rv.Float(), 'g', -1, 32)
+		case reflect.Float64:
+			s = strconv.FormatFloat(rv.Float(), 'g', -1, 64)
+		case reflect.String:
+			s = rv.String()
+		case reflect.Bool:
+			if rv.Bool() {
+				s = "t"
+			} else {
+				s = "f"
+			}
+		case reflect.Slice:
+			if rv.Type().Elem().Kind() != reflect.Uint8 {
+				return fmt.Errorf("array element '%T' is unsupported", value)
+			}
+			s = `\x` + hex.EncodeToString(rv.Bytes())
+		default:
+			return fmt.Errorf("array element '%T' is unsupported", value)
+		}
+	}
+
+	buf.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		if s[i] == '"' || s[i] == '\\' {
+			buf.WriteByte('\\')
+		}
+		buf.WriteByte(s[i])
+	}
+	buf.WriteByte('"')
+	return nil
+}
+
+func (a *pqArray) Scan(src interface{}) error {
+	rv := reflect.ValueOf(a.value)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("column %s scan into '%T' fail, it isnot a pointer", a.name, a.value)
+	}
+	rv = rv.Elem()
+
+	if src == nil {
+		rv.Set(reflect.Zero(rv.Type()))
+		return nil
+	}
+
+	var str string
+	switch s := src.(type) {
+	case []byte:
+		str = string(s)
+	case string:
+		str = s
+	default:
+		return fmt.Errorf("column %s should byte array but got '%T', target type '%T'", a.name, src, a.value)
+	}
+
+	node, err := parsePQArray(str)
+	if err != nil {
+		return fmt.Errorf("column %s is invalid array, %s - '%s'", a.name, err, str)
+	}
+
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		rv = rv.Elem()
+	}
+	if err := assignPQArray(rv, node); err != nil {
+		return fmt.Errorf("column %s scan into '%T' fail, %s", a.name, a.value, err)
+	}
+	return nil
+}
+
+type pqArrayNode struct {
+	text     string
+	isNull   bool
+	isArray  bool
+	children []pqArrayNode
+}
+
+func parsePQArray(s string) (pqArrayNode, error) {
+	s = strings.TrimSpace(s)
+	// 去掉 '[1:2][1:3]=' 这样的维度描述
+	if strings.HasPrefix(s, "[") {
+		idx := strings.IndexByte(s, '=')
+		if idx < 0 {
+			return pqArrayNode{}, errors.New("dimensions is invalid")
+		}
+		s = s[idx+1:]
+	}
+
+	node, pos, err := parsePQArrayNode(s, 0)
+	if err != nil {
+		return node, err
+	}
+	if strings.TrimSpace(s[pos:]) != "" {
+		return node, errors.New("unexpected '" + s[pos:] + "'")
+	}
+	return node, nil
+}
+
+func parsePQArrayNode(s string, pos int) (pqArrayNode, int, error) {
+	if pos >= len(s) || s[pos] != '{' {
+		return pqArrayNode{}, pos, errors.New("'{' is missing")
+	}
+	pos++
+
+	node := pqArrayNode{isArray: true}
+	for {
+		for pos < len(s) && s[pos] == ' ' {
+			pos++
+		}
+		if pos >= len(s) {
+			return node, pos, errors.New("'}' is missing")
+		}
+		if s[pos] == '}' {
+			if len(node.children) > 0 {
+				return node, pos, errors.New("element is missing")
+			}
+			return node, pos + 1, nil
+		}
+
+		var child pqArrayNode
+		switch s[pos] {
+		case '{':
+			var err error
+			child, pos, err = parsePQArrayNode(s, pos)
+			if err != nil {
+				return node, pos, err
+			}
+		case '"':
+			var sb strings.Builder
+			pos++
+			for {
+				if pos >= len(s) {
+					return node, pos, errors.New("'\"' is missing")
+				}
+				c := s[pos]
+				if c == '"' {
+					pos++
+					break
+				}
+				if c == '\\' {
+					pos++
+					if pos >= len(s) {
+						return node, pos, errors.New("'\"' is missing")
+					}
+					c = s[pos]
+				}
+				sb.WriteByte(c)
+				pos++
+			}
+			child.text = sb.String()
+		default:
+			start := pos
+			for pos < len(s) && s[pos] != ',' && s[pos] != '}' {
+				pos++
+			}
+			child.text = strings.TrimSpace(s[start:pos])
+			child.isNull = strings.EqualFold(child.text, "NULL")
+		}
+		node.children = append(node.children, child)
+
+		for pos < len(s) && s[pos] == ' ' {
+			pos++
+		}
+		if pos >= len(s) {
+			return node, pos, errors.New("'}' is missing")
+		}
+		switch s[pos] {
+		case ',':
+			pos++
+		case '}':
+			return node, pos + 1, nil
+		default:
+			return node, pos, errors.New("unexpected '" + string(s[pos]) + "'")
+		}
+	}
+}
+
+func assignPQArray(rv reflect.Value, node pqArrayNode) error {
+	if !node.isArray {
+		return errors.New("dimensions of array is mismatch")
+	}
+
+	slice := reflect.MakeSlice(rv.Type(), len(node.children), len(node.children))
+	isLeaf := isArrayLeaf(rv.Type().Elem())
+	for i := range node.children {
+		elem := slice.Index(i)
+		if !isLeaf {
+			if err := assignPQArray(elem, node.children[i]); err != nil {
+				return err
+			}
+			continue
+		}
+		if node.children[i].isArray {
+			return errors.New("dimensions of array is mismatch")
+		}
+		if err := assignPQArrayElement(elem, node.children[i]); err != nil {
+			return fmt.Errorf("element %d '%s' is invalid, %s", i, node.children[i].text, err)
+		}
+	}
+	rv.Set(slice)
+	return nil
+}
+
+func assignPQArrayElement(elem reflect.Value, node pqArrayNode) error {
+	if node.isNull {
+		if scanner, ok := elem.Addr().Interface().(sql.Scanner); ok && elem.Kind() != reflect.Ptr {
+			return scanner.Scan(nil)
+		}
+		if elem.Kind() != reflect.Ptr {
+			return errors.New("NULL cannot scan into '" + elem.Type().String() + "'")
+		}
+		elem.Set(reflect.Zero(elem.Type()))
+		return nil
+	}
+
+	if elem.Kind() == reflect.Ptr {
+		elem.Set(reflect.New(elem.Type().Elem()))
+		elem = elem.Elem()
+	}
+
+	if scanner, ok := elem.Addr().Interface().(sql.Scanner); ok {
+		return scanner.Scan([]byte(node.text))
+	}
+
+	switch elem.Type() {
+	case _timeType:
+		t, err := parsePQTime(node.text)
+		if err != nil {
+			return err
+		}
+		elem.Set(reflect.ValueOf(t))
+		return nil
+	case _ipType:
+		ip := net.ParseIP(strings.TrimSuffix(node.text, "/32"))
+		if ip == nil {
+			return errors.New("invalid ip address")
+		}
+		elem.Set(reflect.ValueOf(ip))
+		return nil
+	case _macType:
+		mac, err := net.ParseMAC(node.text)
+		if err != nil {
+			return err
+		}
+		elem.Set(reflect.ValueOf(mac))
+		return nil
+	}
+
+	if elem.Kind() == reflect.Slice && elem.Type().Elem().Kind() == reflect.Uint8 {
+		bs := []byte(node.text)
+		if strings.HasPrefix(node.text, `\x`) {
+			var err error
+			bs, err = hex.DecodeString(node.text[2:])
+			if err != nil {
+				return err
+			}
+		}
+		elem.SetBytes(bs)
+		return nil
+	}
+	return convert.ConvertAssign(elem.Addr().Interface(), node.text)
+}
+
+var pqTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999Z07:00:00",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999Z07",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999Z07:00",
+	"2006-01-02",
+}
+
+func parsePQTime(s string) (time.Time, error) {
+	for _, layout := range pqTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			if _, offset := t.Zone(); offset == 0 {
+				t = t.UTC()
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("invalid time")
+}
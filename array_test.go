@@ -1,8 +1,13 @@
 package gobatis_test
 
 import (
+	"database/sql"
+	"database/sql/driver"
+	"net"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	gobatis "github.com/runner-mei/GoBatis"
 	"github.com/runner-mei/GoBatis/tests"
@@ -72,3 +77,191 @@ func TestArray(t *testing.T) {
 		}
 	})
 }
+
+type tags []string
+
+func TestPQArray(t *testing.T) {
+	one, three := 1, 3
+	for idx, test := range []struct {
+		value interface{}
+		text  string
+	}{
+		{value: []int{1, 2, 3}, text: `{"1","2","3"}`},
+		{value: []int32{-1, 2}, text: `{"-1","2"}`},
+		{value: []uint16{7}, text: `{"7"}`},
+		{value: []float32{1.5}, text: `{"1.5"}`},
+		{value: tags{"a,b", `c"d`, `e\f`, "NULL"}, text: `{"a,b","c\"d","e\\f","NULL"}`},
+		{value: []*int{&one, nil, &three}, text: `{"1",NULL,"3"}`},
+		{value: [][]int64{{1, 2}, {3, 4}}, text: `{{"1","2"},{"3","4"}}`},
+		{value: [][][]string{{{"a"}, {"b"}}}, text: `{{{"a"},{"b"}}}`},
+		{value: []time.Time{time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}, text: `{"2020-01-02 03:04:05Z"}`},
+		{value: []net.IP{net.ParseIP("192.168.1.2")}, text: `{"192.168.1.2"}`},
+		{value: [][]byte{[]byte("ab")}, text: `{"\\x6162"}`},
+		{value: []sql.NullString{{String: "a", Valid: true}, {}}, text: `{"a",NULL}`},
+		{value: []int{}, text: `{}`},
+	} {
+		valuer, err := gobatis.DbTypePostgres.MakeArrayValuer(test.value)
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		text, err := valuer.(driver.Valuer).Value()
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		if _, ok := text.([]byte); ok {
+			text = string(text.([]byte))
+		}
+		if text != test.text {
+			t.Error("[", idx, "] excepted is", test.text)
+			t.Error("[", idx, "] actual   is", text)
+		}
+
+		actual := reflect.New(reflect.TypeOf(test.value))
+		scanner, err := gobatis.DbTypePostgres.MakeArrayScanner("field", actual.Interface())
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		if err := scanner.(sql.Scanner).Scan([]byte(test.text)); err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		if !reflect.DeepEqual(actual.Elem().Interface(), test.value) {
+			t.Error("[", idx, "] excepted is", test.value)
+			t.Error("[", idx, "] actual   is", actual.Elem().Interface())
+		}
+	}
+
+	for idx, test := range []struct {
+		text  string
+		value interface{}
+	}{
+		{text: `{1,NULL,3}`, value: []*int{&one, nil, &three}},
+		{text: `[0:1]={1,3}`, value: []int{1, 3}},
+		{text: `{t,f}`, value: []bool{true, false}},
+		{text: `{"2020-01-02 03:04:05+00"}`, value: []time.Time{time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}},
+	} {
+		actual := reflect.New(reflect.TypeOf(test.value))
+		scanner, err := gobatis.DbTypePostgres.MakeArrayScanner("field", actual.Interface())
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		if err := scanner.(sql.Scanner).Scan(test.text); err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		if !reflect.DeepEqual(actual.Elem().Interface(), test.value) {
+			t.Error("[", idx, "] excepted is", test.value)
+			t.Error("[", idx, "] actual   is", actual.Elem().Interface())
+		}
+	}
+
+	for idx, test := range []struct {
+		text  string
+		value interface{}
+	}{
+		{text: `{1,NULL}`, value: []int{}},
+		{text: `{{1},{2}}`, value: []int{}},
+		{text: `{1,2`, value: []int{}},
+		{text: `{a}`, value: []int{}},
+	} {
+		actual := reflect.New(reflect.TypeOf(test.value))
+		scanner, err := gobatis.DbTypePostgres.MakeArrayScanner("field", actual.Interface())
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		if err := scanner.(sql.Scanner).Scan(test.text); err == nil {
+			t.Error("[", idx, "] excepted error, but got", actual.Elem().Interface())
+		}
+	}
+
+	if _, err := gobatis.DbTypePostgres.MakeArrayScanner("field", &[]map[string]int{}); err == nil {
+		t.Error("excepted error")
+	}
+}
+
+type ArrayRecord struct {
+	Tags      tags  `db:"tags"`
+	ArrayTags tags  `db:"array_tags,array"`
+	TagsPtr   *tags `db:"tags_ptr"`
+}
+
+func TestNamedSliceField(t *testing.T) {
+	mapper := gobatis.CreateMapper("", nil, nil)
+	tm := mapper.TypeMap(reflect.TypeOf(ArrayRecord{}))
+
+	// 没有 array 选项的自定义切片类型和以前一样用 json 保存
+	record := &ArrayRecord{Tags: tags{"a", "b"}, ArrayTags: tags{"a", "b"}, TagsPtr: &tags{"c"}}
+	rv := reflect.ValueOf(record).Elem()
+	for idx, test := range []struct {
+		column   string
+		excepted interface{}
+	}{
+		{column: "tags", excepted: `["a","b"]`},
+		{column: "tags_ptr", excepted: `["c"]`},
+		{column: "array_tags", excepted: `{"a","b"}`},
+	} {
+		value, err := tm.Names[test.column].RValue(gobatis.DbTypePostgres, &gobatis.Param{Name: test.column}, rv)
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		if valuer, ok := value.(driver.Valuer); ok {
+			value, err = valuer.Value()
+			if err != nil {
+				t.Error("[", idx, "]", err)
+				continue
+			}
+		}
+		if bs, ok := value.([]byte); ok {
+			value = string(bs)
+		}
+		if !reflect.DeepEqual(value, test.excepted) {
+			t.Error("[", idx, "] excepted is", test.excepted)
+			t.Error("[", idx, "] actual   is", value)
+		}
+	}
+
+	var scanned ArrayRecord
+	rv = reflect.ValueOf(&scanned).Elem()
+	for idx, test := range []struct {
+		column string
+		src    interface{}
+	}{
+		{column: "tags", src: []byte(`["a","b"]`)},
+		{column: "tags_ptr", src: []byte(`["c"]`)},
+		{column: "array_tags", src: []byte(`{"a","b"}`)},
+	} {
+		scanner, err := tm.Names[test.column].LValue(gobatis.DbTypePostgres, test.column, rv)
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		if err := scanner.(sql.Scanner).Scan(test.src); err != nil {
+			t.Error("[", idx, "]", err)
+		}
+	}
+	if !reflect.DeepEqual(scanned, *record) {
+		t.Error("excepted is", *record)
+		t.Error("actual   is", scanned)
+	}
+
+	sqlStr, err := gobatis.GenerateCreateTableSQL(gobatis.DbTypePostgres, mapper, reflect.TypeOf(&struct {
+		TableName struct{} `db:"array_records"`
+		ArrayRecord
+	}{}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, s := range []string{"tags jsonb", "array_tags text[]", "tags_ptr jsonb"} {
+		if !strings.Contains(sqlStr, s) {
+			t.Error("excepted contains", s)
+			t.Error("actual   is", sqlStr)
+		}
+	}
+}
@@ -0,0 +1,457 @@
+package gobatis
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// 历史记录
+//
+// 用 "@option audit <history_table>" 注释的 update 和 delete 方法， 或者记录类型的
+// TableName 字段上有 audited 标签(如 `db:"users,audited"` 或 `db:"users,audited=users_history"`)
+// 时， 生成的代码会调用 EnableAudit。 执行这些语句时会在同一个事务中先读取将被修改的记录，
+// 执行后再按主键读取修改后的记录， 然后将操作类型，语句的 id，当前用户(见 Config.CurrentUser)
+// 和变化的字段(json 格式)写到历史表中， 历史表的 DDL 可以用 GenerateAuditTableDDL 生成。
+//
+// 读取将被修改的记录时用的是语句自己的 where 子句， 修改后的记录则按主键分批读取。
+
+type auditStatement struct {
+	table  string
+	keys   []string
+	before *MappedStatement
+	after  *MappedStatement
+	insert *MappedStatement
+}
+
+// auditKeysPerQuery 是读取修改后的记录时每条语句中最多的记录数
+const auditKeysPerQuery = 500
+
+// EnableAudit 为 update 或 delete 语句开启历史记录， historyTable 为空时使用
+// 记录类型的 TableName 字段上的 audited 标签， 没有这个标签时什么也不做
+func EnableAudit(ctx *InitContext, stmt *MappedStatement, rType reflect.Type, historyTable string) error {
+	if stmt.sqlType != StatementTypeUpdate && stmt.sqlType != StatementTypeDelete {
+		return errors.New("sql '" + stmt.id + "' is " + stmt.sqlType.String() + ", audit is unsupported")
+	}
+	if len(stmt.dynamicSQLs) != 1 {
+		return errors.New("sql '" + stmt.id + "' is multiple statements, audit is unsupported")
+	}
+
+	tableName, err := ReadTableName(ctx.Mapper, rType)
+	if err != nil {
+		return err
+	}
+	structType := ctx.Mapper.TypeMap(rType)
+	if historyTable == "" {
+		audited := false
+		for _, field := range structType.Index {
+			if field.Field.Name == "TableName" {
+				historyTable, audited = field.Options["audited"]
+				break
+			}
+		}
+		if !audited {
+			return nil
+		}
+		if historyTable == "" {
+			historyTable = tableName + "_history"
+		}
+	}
+
+	var keys []*FieldInfo
+	for _, field := range structType.Index {
+		if _, ok := field.Options["pk"]; ok {
+			keys = append(keys, field)
+		}
+	}
+	if len(keys) == 0 {
+		for _, field := range structType.Index {
+			if _, ok := field.Options["autoincr"]; ok {
+				keys = append(keys, field)
+			}
+		}
+	}
+	if len(keys) == 0 {
+		return errors.New("sql '" + stmt.id + "' enable audit fail, primary key of '" + tableName + "' isnot found")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("SELECT * FROM ")
+	sb.WriteString(quoteTableName(ctx.Dialect, tableName))
+	if where := readWhereClause(stmt.rawSQL); where != "" {
+		sb.WriteString(" ")
+		sb.WriteString(where)
+	}
+	before, err := NewMapppedStatement(ctx, stmt.id, StatementTypeSelect, ResultMap, sb.String())
+	if err != nil {
+		return err
+	}
+
+	audit := &auditStatement{table: tableName, before: before}
+
+	// 按主键读取修改后的记录， 如 SELECT * FROM t WHERE id IN (...)，
+	// 联合主键时为 SELECT * FROM t WHERE (k1=? AND k2=?) OR (k1=? AND k2=?)
+	sb.Reset()
+	sb.WriteString("SELECT * FROM ")
+	sb.WriteString(quoteTableName(ctx.Dialect, tableName))
+	sb.WriteString(" WHERE ")
+	if len(keys) == 1 {
+		sb.WriteString(quoteColumn(ctx.Dialect, keys[0]))
+		sb.WriteString(" IN (<foreach collection=\"keys\" item=\"key\" separator=\",\" >#{key.")
+		sb.WriteString(keys[0].Name)
+		sb.WriteString("}</foreach>)")
+	} else {
+		sb.WriteString("<foreach collection=\"keys\" item=\"key\" separator=\" OR \" >(")
+		for idx, field := range keys {
+			if idx != 0 {
+				sb.WriteString(" AND ")
+			}
+			sb.WriteString(quoteColumn(ctx.Dialect, field))
+			sb.WriteString("=#{key.")
+			sb.WriteString(field.Name)
+			sb.WriteString("}")
+		}
+		sb.WriteString(")</foreach>")
+	}
+	for _, field := range keys {
+		audit.keys = append(audit.keys, field.Name)
+	}
+	audit.after, err = NewMapppedStatement(ctx, stmt.id, StatementTypeSelect, ResultMap, sb.String())
+	if err != nil {
+		return err
+	}
+
+	audit.insert, err = NewMapppedStatement(ctx, stmt.id, StatementTypeInsert, ResultUnknown,
+		"INSERT INTO "+quoteTableName(ctx.Dialect, historyTable)+
+			"(table_name, record_key, operation, statement_id, actor, changes, created_at)"+
+			" VALUES(#{table_name}, #{record_key}, #{operation}, #{statement_id}, #{actor}, #{changes}, #{created_at})")
+	if err != nil {
+		return err
+	}
+	stmt.audit = audit
+	return nil
+}
+
+// readWhereClause 读取语句自己的 where 子句， 即从最外层的 where 或 <where> 开始到语句结束
+// (不包括 postgres 的 returning 子句)， 它会跳过字符串，参数，括号(如子查询)和 <set> 等
+// xml 元素中的 where， 没有 where 子句时返回空字符串
+func readWhereClause(sqlStr string) string {
+	isXML := hasXMLTag(sqlStr)
+	start := -1
+	depth, xmlDepth := 0, 0
+	for i := 0; i < len(sqlStr); i++ {
+		c := sqlStr[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := strings.IndexByte(sqlStr[i+1:], c)
+			if end < 0 {
+				return ""
+			}
+			i += end + 1
+		case c == '#' && strings.HasPrefix(sqlStr[i:], "#{"):
+			end := strings.IndexByte(sqlStr[i:], '}')
+			if end < 0 {
+				return ""
+			}
+			i += end
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == '<' && isXML && i+1 < len(sqlStr) && (sqlStr[i+1] == '/' || isIdentifierStart(sqlStr[i+1])):
+			end := indexTagEnd(sqlStr[i:])
+			if end < 0 {
+				return ""
+			}
+			tag := sqlStr[i+1 : i+end]
+			switch {
+			case strings.HasPrefix(tag, "/"):
+				xmlDepth--
+			case strings.TrimSpace(tag) == "where":
+				if start < 0 && depth == 0 && xmlDepth == 0 {
+					start = i
+				}
+				xmlDepth++
+			case !strings.HasSuffix(tag, "/"):
+				xmlDepth++
+			}
+			i += end
+		case isIdentifierStart(c) && (i == 0 || !isIdentifierPart(sqlStr[i-1])):
+			end := i + 1
+			for end < len(sqlStr) && isIdentifierPart(sqlStr[end]) {
+				end++
+			}
+			if depth == 0 && xmlDepth == 0 {
+				word := sqlStr[i:end]
+				if start < 0 && strings.EqualFold(word, "where") {
+					start = i
+				} else if start >= 0 && strings.EqualFold(word, "returning") {
+					return strings.TrimSpace(sqlStr[start:i])
+				}
+			}
+			i = end - 1
+		}
+	}
+	if start < 0 {
+		return ""
+	}
+	return strings.TrimSpace(sqlStr[start:])
+}
+
+// indexTagEnd 返回 xml 标签的结束符 '>' 的位置， 属性值中的 '>' 会被跳过
+func indexTagEnd(s string) int {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '"' || s[i] == '\'':
+			quote = s[i]
+		case s[i] == '>':
+			return i
+		}
+	}
+	return -1
+}
+
+func isIdentifierStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentifierPart(c byte) bool {
+	return isIdentifierStart(c) || (c >= '0' && c <= '9')
+}
+
+// GenerateAuditTableDDL 生成历史表的 DDL
+func GenerateAuditTableDDL(dbType Dialect, historyTable string) (string, error) {
+	var id, text, timestamp string
+	switch rootDialect(dbType) {
+	case DbTypePostgres:
+		id, text, timestamp = "bigserial PRIMARY KEY", "text", "timestamp with time zone"
+	case DbTypeMysql:
+		id, text, timestamp = "bigint AUTO_INCREMENT PRIMARY KEY", "longtext", "datetime"
+	case DbTypeMSSql:
+		id, text, timestamp = "bigint IDENTITY(1,1) PRIMARY KEY", "nvarchar(max)", "datetime"
+	case DbTypeSqlite:
+		id, text, timestamp = "INTEGER PRIMARY KEY AUTOINCREMENT", "text", "datetime"
+	default:
+		return "", errors.New("audit table is unimplemented for db type - " + dbType.Name())
+	}
+	return "CREATE TABLE " + quoteTableName(dbType, historyTable) + " (\r\n" +
+		"  id " + id + ",\r\n" +
+		"  table_name varchar(100) NOT NULL,\r\n" +
+		"  record_key varchar(200) NOT NULL,\r\n" +
+		"  operation varchar(20) NOT NULL,\r\n" +
+		"  statement_id varchar(200) NOT NULL,\r\n" +
+		"  actor varchar(100),\r\n" +
+		"  changes " + text + ",\r\n" +
+		"  created_at " + timestamp + " NOT NULL\r\n" +
+		")", nil
+}
+
+func (conn *Connection) executeWithAudit(ctx context.Context, id string, stmt *MappedStatement, paramNames []string, paramValues []interface{}, sqlAndParams []sqlAndParam) (int64, error) {
+	var tx *sql.Tx
+	if DbConnectionFromContext(ctx) == nil {
+		if beginner, ok := conn.db.(interface {
+			BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+		}); ok {
+			var err error
+			tx, err = beginner.BeginTx(ctx, nil)
+			if err != nil {
+				return 0, conn.dialect.HandleError(err)
+			}
+			defer func() {
+				if tx != nil {
+					tx.Rollback()
+				}
+			}()
+			ctx = WithDbConnection(ctx, tx)
+		}
+	}
+
+	audit := stmt.audit
+	befores, err := conn.auditQuery(ctx, id, audit.before, paramNames, paramValues)
+	if err != nil {
+		return 0, err
+	}
+
+	rowsAffected, err := conn.execute(ctx, id, sqlAndParams)
+	if err != nil {
+		return 0, err
+	}
+
+	var actor interface{}
+	if conn.currentUser != nil {
+		user, err := conn.currentUser(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("sql '%s' error : read current user fail, %s", id, err)
+		}
+		if user != nil {
+			actor = fmt.Sprint(user)
+		}
+	}
+
+	var afters map[string]map[string]interface{}
+	if stmt.sqlType == StatementTypeUpdate && len(befores) > 0 {
+		afters = make(map[string]map[string]interface{}, len(befores))
+		for offset := 0; offset < len(befores); offset += auditKeysPerQuery {
+			end := offset + auditKeysPerQuery
+			if end > len(befores) {
+				end = len(befores)
+			}
+			keys := make([]interface{}, 0, end-offset)
+			for _, before := range befores[offset:end] {
+				keys = append(keys, audit.keyValues(before))
+			}
+			rows, err := conn.auditQuery(ctx, id, audit.after, []string{"keys"}, []interface{}{keys})
+			if err != nil {
+				return 0, err
+			}
+			for _, row := range rows {
+				afters[audit.recordKey(row)] = row
+			}
+		}
+	}
+
+	createdAt := time.Now()
+	operation := stmt.sqlType.String()
+	for _, before := range befores {
+		recordKey := audit.recordKey(before)
+		changes := auditDiff(before, afters[recordKey], stmt.sqlType == StatementTypeDelete)
+		if len(changes) == 0 {
+			continue
+		}
+		bs, err := json.Marshal(changes)
+		if err != nil {
+			return 0, fmt.Errorf("sql '%s' error : marshal changes fail, %s", id, err)
+		}
+
+		if _, err := conn.auditExec(ctx, id, audit.insert, map[string]interface{}{
+			"table_name":   audit.table,
+			"record_key":   recordKey,
+			"operation":    operation,
+			"statement_id": id,
+			"actor":        actor,
+			"changes":      string(bs),
+			"created_at":   createdAt,
+		}); err != nil {
+			return 0, err
+		}
+	}
+
+	if tx != nil {
+		err = tx.Commit()
+		tx = nil
+		if err != nil {
+			return 0, conn.dialect.HandleError(err)
+		}
+	}
+	return rowsAffected, nil
+}
+
+// keyValues 返回记录的主键， 它是读取修改后的记录时的参数
+func (audit *auditStatement) keyValues(row map[string]interface{}) map[string]interface{} {
+	values := make(map[string]interface{}, len(audit.keys))
+	for _, key := range audit.keys {
+		values[key] = row[strings.ToLower(key)]
+	}
+	return values
+}
+
+// recordKey 返回记录的主键的字符串形式， 联合主键时用逗号分隔
+func (audit *auditStatement) recordKey(row map[string]interface{}) string {
+	keyStrings := make([]string, len(audit.keys))
+	for idx, key := range audit.keys {
+		keyStrings[idx] = fmt.Sprint(row[strings.ToLower(key)])
+	}
+	return strings.Join(keyStrings, ",")
+}
+
+func auditDiff(before, after map[string]interface{}, isDeleted bool) map[string]interface{} {
+	changes := map[string]interface{}{}
+	for column, old := range before {
+		if isDeleted {
+			changes[column] = map[string]interface{}{"old": old}
+			continue
+		}
+
+		value := after[column]
+		if reflect.DeepEqual(old, value) {
+			continue
+		}
+		changes[column] = map[string]interface{}{"old": old, "new": value}
+	}
+	return changes
+}
+
+func (conn *Connection) auditQuery(ctx context.Context, id string, stmt *MappedStatement, paramNames []string, paramValues []interface{}) ([]map[string]interface{}, error) {
+	genCtx, err := conn.newContext(ctx, paramNames, paramValues)
+	if err != nil {
+		return nil, fmt.Errorf("sql '%s' error : %s", id, err)
+	}
+	sqlAndParams, err := stmt.GenerateSQLs(genCtx)
+	if err != nil {
+		return nil, fmt.Errorf("sql '%s' error : %s", id, err)
+	}
+
+	tx := DbConnectionFromContext(ctx)
+	if tx == nil {
+		tx = conn.db
+	}
+	rows, err := tx.QueryContext(ctx, sqlAndParams[0].SQL, sqlAndParams[0].Params...)
+	conn.tracer.Write(ctx, id, sqlAndParams[0].SQL, sqlAndParams[0].Params, err)
+	if err != nil {
+		return nil, conn.dialect.HandleError(err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, conn.dialect.HandleError(err)
+	}
+
+	var results []map[string]interface{}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for idx := range values {
+			ptrs[idx] = &values[idx]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, conn.dialect.HandleError(err)
+		}
+
+		row := make(map[string]interface{}, len(columns))
+		for idx, column := range columns {
+			if bs, ok := values[idx].([]byte); ok {
+				row[strings.ToLower(column)] = string(bs)
+			} else {
+				row[strings.ToLower(column)] = values[idx]
+			}
+		}
+		results = append(results, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, conn.dialect.HandleError(err)
+	}
+	return results, nil
+}
+
+func (conn *Connection) auditExec(ctx context.Context, id string, stmt *MappedStatement, values map[string]interface{}) (int64, error) {
+	genCtx, err := conn.newContext(ctx, nil, []interface{}{values})
+	if err != nil {
+		return 0, fmt.Errorf("sql '%s' error : %s", id, err)
+	}
+	sqlAndParams, err := stmt.GenerateSQLs(genCtx)
+	if err != nil {
+		return 0, fmt.Errorf("sql '%s' error : %s", id, err)
+	}
+	return conn.execute(ctx, id, sqlAndParams)
+}
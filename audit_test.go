@@ -0,0 +1,132 @@
+package gobatis_test
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+
+	gobatis "github.com/runner-mei/GoBatis"
+)
+
+type T24 struct {
+	TableName struct{} `db:"t24,audited"`
+	ID        int64    `db:"id,autoincr,pk"`
+	Name      string   `db:"name"`
+}
+
+func TestAudit(t *testing.T) {
+	rType := reflect.TypeOf(&T24{})
+	factory, teardown := setupFixture(t, &gobatis.Config{
+		CurrentUser: func(ctx context.Context) (interface{}, error) {
+			return "alice", nil
+		}}, rType, []fixtureStatement{
+		{id: "T24.insert", stmtType: gobatis.StatementTypeInsert, generate: func(ctx *gobatis.InitContext) (string, error) {
+			return gobatis.GenerateInsertSQL(ctx.Dialect, ctx.Mapper, rType, []string{"r"}, []reflect.Type{rType}, false)
+		}},
+		{id: "T24.update", stmtType: gobatis.StatementTypeUpdate, audited: true, generate: func(ctx *gobatis.InitContext) (string, error) {
+			return gobatis.GenerateUpdateSQL(ctx.Dialect, ctx.Mapper, "r.", rType, nil, nil)
+		}},
+		{id: "T24.deleteByName", stmtType: gobatis.StatementTypeDelete, audited: true, generate: func(ctx *gobatis.InitContext) (string, error) {
+			return gobatis.GenerateDeleteSQL(ctx.Dialect, ctx.Mapper, rType, []string{"name"}, []reflect.Type{reflect.TypeOf("")}, nil)
+		}},
+		{id: "T24.rename", stmtType: gobatis.StatementTypeUpdate, audited: true, generate: func(ctx *gobatis.InitContext) (string, error) {
+			return `UPDATE t24 SET name=#{newName} WHERE name=#{name}`, nil
+		}},
+	})
+	defer teardown()
+	if factory == nil {
+		return
+	}
+
+	historyDDL, err := gobatis.GenerateAuditTableDDL(factory.Dialect(), "t24_history")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	ctx := context.Background()
+	factory.DB().ExecContext(ctx, `DROP TABLE IF EXISTS t24_history`)
+	if _, err := factory.DB().ExecContext(ctx, historyDDL); err != nil {
+		t.Error(err)
+		return
+	}
+	defer factory.DB().ExecContext(ctx, `DROP TABLE t24_history`)
+
+	ref := factory.SessionReference()
+	record := &T24{Name: "a"}
+	record.ID, err = ref.Insert(ctx, "T24.insert", []string{"r"}, []interface{}{record})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	record.Name = "b"
+	if _, err := ref.Update(ctx, "T24.update", []string{"r"}, []interface{}{record}); err != nil {
+		t.Error(err)
+		return
+	}
+	// 没有变化的时候不会写历史记录
+	if _, err := ref.Update(ctx, "T24.update", []string{"r"}, []interface{}{record}); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := ref.Delete(ctx, "T24.deleteByName", []string{"name"}, []interface{}{"b"}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	// 更新多条记录时用一条语句读取修改后的记录
+	var renamed []string
+	for i := 0; i < 2; i++ {
+		id, err := ref.Insert(ctx, "T24.insert", []string{"r"}, []interface{}{&T24{Name: "c"}})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		renamed = append(renamed, strconv.FormatInt(id, 10))
+	}
+	if _, err := ref.Update(ctx, "T24.rename", []string{"newName", "name"}, []interface{}{"d", "c"}); err != nil {
+		t.Error(err)
+		return
+	}
+
+	rows, err := factory.DB().QueryContext(ctx, `SELECT table_name, record_key, operation, statement_id, actor, changes FROM t24_history ORDER BY id`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer rows.Close()
+
+	var actual [][6]string
+	for rows.Next() {
+		var row [6]string
+		if err := rows.Scan(&row[0], &row[1], &row[2], &row[3], &row[4], &row[5]); err != nil {
+			t.Error(err)
+			return
+		}
+		actual = append(actual, row)
+	}
+	if err := rows.Err(); err != nil {
+		t.Error(err)
+		return
+	}
+
+	sort.SliceStable(actual, func(i, j int) bool {
+		return actual[i][1] < actual[j][1]
+	})
+
+	key := strconv.FormatInt(record.ID, 10)
+	excepted := [][6]string{
+		{"t24", key, "update", "T24.update", "alice", `{"name":{"new":"b","old":"a"}}`},
+		{"t24", key, "delete", "T24.deleteByName", "alice", `{"id":{"old":` + key + `},"name":{"old":"b"}}`},
+	}
+	for _, key := range renamed {
+		excepted = append(excepted, [6]string{"t24", key, "update", "T24.rename", "alice", `{"name":{"new":"d","old":"c"}}`})
+	}
+	if !reflect.DeepEqual(actual, excepted) {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", actual)
+	}
+}
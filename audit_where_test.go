@@ -0,0 +1,28 @@
+package gobatis
+
+import "testing"
+
+func TestReadWhereClause(t *testing.T) {
+	for idx, test := range []struct {
+		sql      string
+		excepted string
+	}{
+		{sql: "UPDATE t SET name=#{name} WHERE id=#{id}", excepted: "WHERE id=#{id}"},
+		{sql: "DELETE FROM t where id=#{id} AND f1=#{f1}", excepted: "where id=#{id} AND f1=#{f1}"},
+		{sql: "DELETE FROM t", excepted: ""},
+		{sql: "UPDATE t SET somewhere=#{where} WHERE id=#{id}", excepted: "WHERE id=#{id}"},
+		{sql: "UPDATE t SET name='a where b' WHERE id=#{id}", excepted: "WHERE id=#{id}"},
+		{sql: "UPDATE t SET total=(SELECT count(*) FROM x WHERE x.t_id = t.id) WHERE id=#{id}", excepted: "WHERE id=#{id}"},
+		{sql: "UPDATE t SET name=#{name} WHERE id=#{id} RETURNING id", excepted: "WHERE id=#{id}"},
+		{sql: `UPDATE t <set><if test="name != nil">name=(SELECT name FROM x WHERE x.id=#{x})</if></set> <where><if test="id > 0">id=#{id}</if></where>`,
+			excepted: `<where><if test="id > 0">id=#{id}</if></where>`},
+		{sql: `UPDATE t SET deleted_at=now() WHERE id in (<foreach collection="id" item="item" separator="," >#{item}</foreach>) AND f1 < #{f1}`,
+			excepted: `WHERE id in (<foreach collection="id" item="item" separator="," >#{item}</foreach>) AND f1 < #{f1}`},
+	} {
+		actual := readWhereClause(test.sql)
+		if actual != test.excepted {
+			t.Error("[", idx, "] excepted is", test.excepted)
+			t.Error("[", idx, "] actual   is", actual)
+		}
+	}
+}
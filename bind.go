@@ -0,0 +1,338 @@
+package gobatis
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+var (
+	_contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	_errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// Bind 在运行时为 dao 中的函数字段生成实现， 它不需要生成代码， 如
+//
+//	type UserDao struct {
+//		_ User
+//
+//		Insert   func(ctx context.Context, u *User) (int64, error)
+//		FindByID func(ctx context.Context, id int64) (*User, error) `params:"id"`
+//		GetName  func(ctx context.Context, id int64) (string, error) `sql:"SELECT name FROM users WHERE id = #{id}"`
+//	}
+//
+//	var dao UserDao
+//	err := gobatis.Bind(factory.SessionReference(), &dao)
+//
+// 规则和生成的代码一样:
+//
+//  1. 语句的 id 为 结构体名.字段名， 已经在 xml 中定义了同名的语句时使用 xml 中的语句
+//  2. 语句的类型由字段名推断(见 StatementTypeByName)， 也可以用 type 标签指定
+//  3. 没有 sql 标签时 sql 由 GenerateXXXSQL 生成， 记录的类型来自 _ 字段， 结构体参数或返回值
+//  4. 第一个参数可以是 context.Context， 其它参数的名字由 params 标签指定， 只有一个参数时可以省略
+//  5. 最后一个返回值必须是 error
+func Bind(session SqlSession, dao interface{}) error {
+	rValue := reflect.ValueOf(dao)
+	if rValue.Kind() != reflect.Ptr || rValue.IsNil() || rValue.Elem().Kind() != reflect.Struct {
+		return errors.New("dao must be a pointer to struct")
+	}
+	conn, err := toConnection(session)
+	if err != nil {
+		return err
+	}
+
+	rValue = rValue.Elem()
+	daoType := rValue.Type()
+	daoName := daoType.Name()
+	if daoName == "" {
+		daoName = daoType.String()
+	}
+
+	var recordType reflect.Type
+	for i := 0; i < daoType.NumField(); i++ {
+		field := daoType.Field(i)
+		if field.Name == "_" && isRecordType(field.Type) {
+			recordType = field.Type
+			break
+		}
+	}
+
+	for i := 0; i < daoType.NumField(); i++ {
+		field := daoType.Field(i)
+		if field.PkgPath != "" || field.Type.Kind() != reflect.Func {
+			continue
+		}
+
+		method, err := newBoundMethod(conn, daoName+"."+field.Name, field, recordType)
+		if err != nil {
+			return err
+		}
+		rValue.Field(i).Set(reflect.MakeFunc(field.Type, method.call))
+	}
+	return nil
+}
+
+// boundMethod 是 Bind 为一个函数字段生成的实现
+type boundMethod struct {
+	conn       *Connection
+	id         string
+	fnType     reflect.Type
+	sqlType    StatementType
+	hasContext bool
+	names      []string
+}
+
+func newBoundMethod(conn *Connection, id string, field reflect.StructField, recordType reflect.Type) (*boundMethod, error) {
+	method := &boundMethod{conn: conn, id: id, fnType: field.Type}
+
+	fnType := field.Type
+	if fnType.IsVariadic() {
+		return nil, errors.New("'" + id + "' is variadic")
+	}
+	if fnType.NumOut() == 0 || fnType.NumOut() > 2 || fnType.Out(fnType.NumOut()-1) != _errorType {
+		return nil, errors.New("'" + id + "' must return (error) or (value, error)")
+	}
+
+	var argTypes []reflect.Type
+	for i := 0; i < fnType.NumIn(); i++ {
+		if i == 0 && fnType.In(i) == _contextType {
+			method.hasContext = true
+			continue
+		}
+		argTypes = append(argTypes, fnType.In(i))
+	}
+
+	if s := field.Tag.Get("params"); s != "" {
+		for _, name := range strings.Split(s, ",") {
+			method.names = append(method.names, strings.TrimSpace(name))
+		}
+		if len(method.names) != len(argTypes) {
+			return nil, errors.New("'" + id + "' params tag is mismatch with arguments")
+		}
+	} else if len(argTypes) > 1 {
+		return nil, errors.New("'" + id + "' params tag is missing")
+	} else if len(argTypes) == 1 && isRecordType(argTypes[0]) {
+		method.names = []string{"record"}
+	}
+
+	switch s := strings.ToLower(field.Tag.Get("type")); s {
+	case "":
+		method.sqlType = StatementTypeByName(field.Name)
+	case "insert", "upsert":
+		method.sqlType = StatementTypeInsert
+	case "update":
+		method.sqlType = StatementTypeUpdate
+	case "delete":
+		method.sqlType = StatementTypeDelete
+	case "select":
+		method.sqlType = StatementTypeSelect
+	default:
+		return nil, errors.New("'" + id + "' type tag '" + s + "' is unknown")
+	}
+	if method.sqlType == StatementTypeNone {
+		return nil, errors.New("'" + id + "' statement type is unknown, please set type tag")
+	}
+
+	switch method.sqlType {
+	case StatementTypeInsert, StatementTypeUpdate, StatementTypeDelete:
+		if fnType.NumOut() == 2 && !isIntType(fnType.Out(0)) {
+			return nil, errors.New("'" + id + "' must return (error) or (int64, error)")
+		}
+	case StatementTypeSelect:
+		if fnType.NumOut() != 2 {
+			return nil, errors.New("'" + id + "' must return (value, error)")
+		}
+	}
+
+	if _, ok := conn.statement(id); ok {
+		return method, nil
+	}
+
+	if sqlStr := field.Tag.Get("sql"); sqlStr != "" {
+		return method, conn.dynamicStatements.Register(id, method.sqlType, func(dialect Dialect, mapper *Mapper) (string, error) {
+			return sqlStr, nil
+		})
+	}
+
+	if recordType == nil {
+		for _, argType := range argTypes {
+			if isRecordType(argType) {
+				recordType = argType
+				break
+			}
+		}
+	}
+	if recordType == nil && fnType.NumOut() == 2 {
+		resultType := fnType.Out(0)
+		if resultType.Kind() == reflect.Slice || resultType.Kind() == reflect.Map {
+			resultType = resultType.Elem()
+		}
+		if isRecordType(resultType) {
+			recordType = resultType
+		}
+	}
+	if recordType == nil {
+		return nil, errors.New("'" + id + "' record type is unknown, please set sql tag")
+	}
+	if recordType.Kind() == reflect.Ptr {
+		recordType = recordType.Elem()
+	}
+	if len(argTypes) > 0 && method.names == nil {
+		return nil, errors.New("'" + id + "' params tag is missing")
+	}
+
+	var generate func(dialect Dialect, mapper *Mapper) (string, error)
+	switch method.sqlType {
+	case StatementTypeInsert:
+		if strings.HasPrefix(strings.ToLower(field.Name), "upsert") {
+			return nil, errors.New("'" + id + "' is upsert, please set sql tag")
+		}
+		noReturn := fnType.NumOut() == 1
+		generate = func(dialect Dialect, mapper *Mapper) (string, error) {
+			return GenerateInsertSQL(dialect, mapper, recordType, method.names, argTypes, noReturn)
+		}
+	case StatementTypeUpdate:
+		if len(argTypes) == 0 || !isRecordType(argTypes[len(argTypes)-1]) {
+			return nil, errors.New("'" + id + "' last argument isnot a record, please set sql tag")
+		}
+		last := len(argTypes) - 1
+		generate = func(dialect Dialect, mapper *Mapper) (string, error) {
+			return GenerateUpdateSQL(dialect, mapper, method.names[last]+".", recordType, method.names[:last], argTypes[:last])
+		}
+	case StatementTypeDelete:
+		generate = func(dialect Dialect, mapper *Mapper) (string, error) {
+			return GenerateDeleteSQL(dialect, mapper, recordType, method.names, argTypes, nil)
+		}
+	case StatementTypeSelect:
+		if strings.Contains(field.Name, "Count") {
+			generate = func(dialect Dialect, mapper *Mapper) (string, error) {
+				return GenerateCountSQL(dialect, mapper, recordType, method.names, argTypes, nil)
+			}
+		} else {
+			generate = func(dialect Dialect, mapper *Mapper) (string, error) {
+				return GenerateSelectSQL(dialect, mapper, recordType, method.names, argTypes, nil)
+			}
+		}
+	}
+	return method, conn.dynamicStatements.Register(id, method.sqlType, generate)
+}
+
+func (method *boundMethod) call(args []reflect.Value) []reflect.Value {
+	ctx := context.Background()
+	if method.hasContext {
+		if c, ok := args[0].Interface().(context.Context); ok && c != nil {
+			ctx = c
+		}
+		args = args[1:]
+	}
+
+	var values []interface{}
+	if len(args) > 0 {
+		values = make([]interface{}, 0, len(args))
+		for _, arg := range args {
+			values = append(values, arg.Interface())
+		}
+	}
+
+	switch method.sqlType {
+	case StatementTypeInsert:
+		if method.fnType.NumOut() == 1 {
+			_, err := method.conn.Insert(ctx, method.id, method.names, values, true)
+			return method.results(reflect.Value{}, err)
+		}
+		id, err := method.conn.Insert(ctx, method.id, method.names, values)
+		return method.results(reflect.ValueOf(id), err)
+	case StatementTypeUpdate:
+		count, err := method.conn.Update(ctx, method.id, method.names, values)
+		return method.results(reflect.ValueOf(count), err)
+	case StatementTypeDelete:
+		count, err := method.conn.Delete(ctx, method.id, method.names, values)
+		return method.results(reflect.ValueOf(count), err)
+	default:
+		value, err := method.query(ctx, values)
+		return method.results(value, err)
+	}
+}
+
+func (method *boundMethod) query(ctx context.Context, values []interface{}) (reflect.Value, error) {
+	resultType := method.fnType.Out(0)
+	switch resultType.Kind() {
+	case reflect.Slice:
+		if resultType.Elem().Kind() == reflect.Uint8 {
+			break
+		}
+		result := reflect.New(resultType)
+		err := method.conn.Select(ctx, method.id, method.names, values).ScanSlice(result.Interface())
+		return result.Elem(), err
+	case reflect.Map:
+		result := reflect.New(resultType)
+		results := method.conn.Select(ctx, method.id, method.names, values)
+		var err error
+		if isRecordType(resultType.Elem()) {
+			err = results.ScanResults(result.Interface())
+		} else {
+			err = results.ScanBasicMap(result.Interface())
+		}
+		return result.Elem(), err
+	case reflect.Ptr:
+		result := reflect.New(resultType.Elem())
+		if isRecordType(resultType) {
+			err := method.conn.SelectOne(ctx, method.id, method.names, values).Scan(result.Interface())
+			return result, err
+		}
+		nullable := Nullable{Dialect: method.conn.dialect, Value: result.Interface()}
+		err := method.conn.SelectOne(ctx, method.id, method.names, values).Scan(&nullable)
+		if err == nil && !nullable.Valid {
+			err = sql.ErrNoRows
+		}
+		return result, err
+	}
+
+	result := reflect.New(resultType)
+	if isRecordType(resultType) {
+		err := method.conn.SelectOne(ctx, method.id, method.names, values).Scan(result.Interface())
+		return result.Elem(), err
+	}
+	nullable := Nullable{Dialect: method.conn.dialect, Value: result.Interface()}
+	err := method.conn.SelectOne(ctx, method.id, method.names, values).Scan(&nullable)
+	if err == nil && !nullable.Valid {
+		err = sql.ErrNoRows
+	}
+	return result.Elem(), err
+}
+
+// results 将返回值转换为函数字段的返回值， 出错时除 error 外的返回值都是零值
+func (method *boundMethod) results(value reflect.Value, err error) []reflect.Value {
+	errValue := reflect.Zero(_errorType)
+	if err != nil {
+		errValue = reflect.ValueOf(&err).Elem()
+	}
+	if method.fnType.NumOut() == 1 {
+		return []reflect.Value{errValue}
+	}
+
+	resultType := method.fnType.Out(0)
+	if err != nil || !value.IsValid() {
+		return []reflect.Value{reflect.Zero(resultType), errValue}
+	}
+	if value.Type() != resultType {
+		value = value.Convert(resultType)
+	}
+	return []reflect.Value{value, errValue}
+}
+
+// isRecordType 判断类型是不是一个记录， 即除 time.Time 等之外的结构体或结构体指针
+func isRecordType(t reflect.Type) bool {
+	return isStructType(t) && !isIgnoreStructType(t)
+}
+
+func isIntType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
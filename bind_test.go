@@ -0,0 +1,209 @@
+package gobatis_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	gobatis "github.com/runner-mei/GoBatis"
+	"github.com/runner-mei/GoBatis/tests"
+)
+
+type BindUser struct {
+	TableName struct{} `db:"bind_users"`
+	ID        int64    `db:"id,autoincr,pk"`
+	Name      string   `db:"name"`
+	Status    int      `db:"status"`
+}
+
+type BindUserDao struct {
+	_ BindUser
+
+	Insert         func(ctx context.Context, u *BindUser) (int64, error)
+	Update         func(ctx context.Context, u *BindUser) (int64, error)
+	DeleteByID     func(ctx context.Context, id int64) (int64, error)               `params:"id"`
+	FindByID       func(ctx context.Context, id int64) (*BindUser, error)           `params:"id"`
+	FindByStatus   func(ctx context.Context, status int) ([]BindUser, error)        `params:"status"`
+	CountByStatus  func(status int) (int64, error)                                  `params:"status"`
+	GetName        func(ctx context.Context, id int64) (string, error)              `sql:"SELECT name FROM bind_users WHERE id = #{id}"`
+	GetNameByID    func(ctx context.Context, id int64) (*string, error)             `sql:"SELECT name FROM bind_users WHERE id = #{id}"`
+	ResetStatus    func(ctx context.Context, from, to int) error                    `params:"from,to" type:"update" sql:"UPDATE bind_users SET status = #{to} WHERE status = #{from}"`
+	QueryNameByIDs func(ctx context.Context, ids []int64) (map[int64]string, error) `sql:"SELECT id, name FROM bind_users WHERE id in (<foreach collection=\"ids\" item=\"id\" separator=\",\" >#{id}</foreach>)"`
+
+	unexported func() error
+}
+
+func TestBind(t *testing.T) {
+	factory, err := gobatis.New(&gobatis.Config{DriverName: tests.TestDrv,
+		DataSource: tests.TestConnURL,
+		Tracer:     gobatis.NullTracer{}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer factory.Close()
+
+	ctx := context.Background()
+	factory.DB().ExecContext(ctx, `DROP TABLE IF EXISTS bind_users`)
+	createSQL := `CREATE TABLE bind_users (id bigserial PRIMARY KEY, name varchar(50), status int)`
+	if gobatis.IsDialect(factory.Dialect(), gobatis.DbTypeSqlite) {
+		createSQL = `CREATE TABLE bind_users (id INTEGER PRIMARY KEY AUTOINCREMENT, name varchar(50), status int)`
+	}
+	if _, err := factory.DB().ExecContext(ctx, createSQL); err != nil {
+		t.Error(err)
+		return
+	}
+	defer factory.DB().ExecContext(ctx, `DROP TABLE bind_users`)
+
+	var dao BindUserDao
+	if err := gobatis.Bind(factory.SessionReference(), &dao); err != nil {
+		t.Error(err)
+		return
+	}
+	if dao.unexported != nil {
+		t.Error("unexported field is bound")
+	}
+
+	var ids []int64
+	for _, name := range []string{"abc", "abd", "xbc"} {
+		id, err := dao.Insert(ctx, &BindUser{Name: name, Status: 1})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	user, err := dao.FindByID(ctx, ids[1])
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if user.Name != "abd" {
+		t.Error("excepted is abd")
+		t.Error("actual   is", user.Name)
+	}
+
+	user.Status = 2
+	if count, err := dao.Update(ctx, user); err != nil {
+		t.Error(err)
+	} else if count != 1 {
+		t.Error("excepted is 1")
+		t.Error("actual   is", count)
+	}
+
+	for idx, test := range []struct {
+		status   int
+		excepted []string
+	}{
+		{status: 1, excepted: []string{"abc", "xbc"}},
+		{status: 2, excepted: []string{"abd"}},
+		{status: 3, excepted: nil},
+	} {
+		users, err := dao.FindByStatus(ctx, test.status)
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		var names []string
+		for _, u := range users {
+			names = append(names, u.Name)
+		}
+		if strings.Join(names, ",") != strings.Join(test.excepted, ",") {
+			t.Error("[", idx, "] excepted is", test.excepted)
+			t.Error("[", idx, "] actual   is", names)
+		}
+
+		count, err := dao.CountByStatus(test.status)
+		if err != nil {
+			t.Error("[", idx, "]", err)
+		} else if count != int64(len(test.excepted)) {
+			t.Error("[", idx, "] excepted is", len(test.excepted))
+			t.Error("[", idx, "] actual   is", count)
+		}
+	}
+
+	if name, err := dao.GetName(ctx, ids[2]); err != nil {
+		t.Error(err)
+	} else if name != "xbc" {
+		t.Error("excepted is xbc")
+		t.Error("actual   is", name)
+	}
+	if name, err := dao.GetNameByID(ctx, ids[0]); err != nil {
+		t.Error(err)
+	} else if *name != "abc" {
+		t.Error("excepted is abc")
+		t.Error("actual   is", *name)
+	}
+	if _, err := dao.GetName(ctx, 100000); err != sql.ErrNoRows {
+		t.Error("excepted is", sql.ErrNoRows)
+		t.Error("actual   is", err)
+	}
+
+	names, err := dao.QueryNameByIDs(ctx, ids[:2])
+	if err != nil {
+		t.Error(err)
+	} else if len(names) != 2 || names[ids[0]] != "abc" || names[ids[1]] != "abd" {
+		t.Error("excepted is map[", ids[0], ":abc", ids[1], ":abd]")
+		t.Error("actual   is", names)
+	}
+
+	if err := dao.ResetStatus(ctx, 1, 2); err != nil {
+		t.Error(err)
+	}
+	if count, err := dao.CountByStatus(2); err != nil {
+		t.Error(err)
+	} else if count != 3 {
+		t.Error("excepted is 3")
+		t.Error("actual   is", count)
+	}
+
+	if count, err := dao.DeleteByID(ctx, ids[0]); err != nil {
+		t.Error(err)
+	} else if count != 1 {
+		t.Error("excepted is 1")
+		t.Error("actual   is", count)
+	}
+	if _, err := dao.FindByID(ctx, ids[0]); err != sql.ErrNoRows {
+		t.Error("excepted is", sql.ErrNoRows)
+		t.Error("actual   is", err)
+	}
+
+	for idx, test := range []struct {
+		dao      interface{}
+		excepted string
+	}{
+		{dao: dao, excepted: "dao must be a pointer to struct"},
+		{dao: &struct {
+			Find func(a, b int) ([]BindUser, error)
+		}{}, excepted: "params tag is missing"},
+		{dao: &struct {
+			Find func(a int) ([]BindUser, error) `params:"a,b"`
+		}{}, excepted: "params tag is mismatch"},
+		{dao: &struct {
+			Do func(a int) error
+		}{}, excepted: "statement type is unknown"},
+		{dao: &struct {
+			Find func(id int64) (*BindUser, bool)
+		}{}, excepted: "must return"},
+		{dao: &struct {
+			GetName func(id int64) (string, error) `params:"id"`
+		}{}, excepted: "record type is unknown"},
+		{dao: &struct {
+			UpsertUser func(u *BindUser) (int64, error)
+		}{}, excepted: "please set sql tag"},
+	} {
+		err := gobatis.Bind(factory.SessionReference(), test.dao)
+		if err == nil {
+			t.Error("[", idx, "] excepted error got ok")
+		} else if !strings.Contains(err.Error(), test.excepted) {
+			t.Error("[", idx, "] excepted is", test.excepted)
+			t.Error("[", idx, "] actual   is", err)
+		}
+	}
+
+	if err := gobatis.Bind(nil, &BindUserDao{}); err == nil {
+		t.Error("excepted error got ok")
+	}
+}
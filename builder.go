@@ -133,7 +133,7 @@ func GenerateInsertSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, names
 	if err != nil {
 		return "", err
 	}
-	sb.WriteString(tableName)
+	sb.WriteString(quoteTableName(dbType, tableName))
 	sb.WriteString("(")
 
 	skip := func(field *FieldInfo) bool {
@@ -177,16 +177,17 @@ func GenerateInsertSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, names
 			isFirst = false
 		}
 
-		sb.WriteString(field.Name)
+		sb.WriteString(quoteColumn(dbType, field))
+		writeBlindIndexColumn(&sb, dbType, field)
 	}
 	sb.WriteString(")")
 
-	if dbType == DbTypeMSSql {
+	if IsDialect(dbType, DbTypeMSSql) {
 		if !noReturn {
 			for _, field := range mapper.TypeMap(rType).Index {
 				if _, ok := field.Options["autoincr"]; ok {
 					sb.WriteString(" OUTPUT inserted.")
-					sb.WriteString(field.Name)
+					sb.WriteString(quoteColumn(dbType, field))
 					break
 				}
 			}
@@ -213,7 +214,7 @@ func GenerateInsertSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, names
 		if (AutoCreatedAt && ((isCreated && isTimeType(field.Field.Type)) || (field.Name == "created_at" && !notAuto(field)))) ||
 			(AutoUpdatedAt && ((isUpdated && isTimeType(field.Field.Type)) || (field.Name == "updated_at" && !notAuto(field)))) {
 
-			if dbType == DbTypePostgres {
+			if IsDialect(dbType, DbTypePostgres) {
 				sb.WriteString("now()")
 			} else {
 				sb.WriteString("CURRENT_TIMESTAMP")
@@ -221,23 +222,36 @@ func GenerateInsertSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, names
 			continue
 		}
 
-		sb.WriteString("#{")
+		if isTenantField(mapper, field) {
+			sb.WriteString(tenantParam(field))
+			continue
+		}
+
+		if isOperatorField(field) {
+			sb.WriteString(operatorParam(field))
+			continue
+		}
+
+		prefix := ""
 		if mustPrefix {
-			sb.WriteString(names[0])
-			sb.WriteString(".")
+			prefix = names[0] + "."
 		}
+		sb.WriteString("#{")
+		sb.WriteString(prefix)
 		sb.WriteString(field.Name)
+		sb.WriteString(fieldParamOptions(field))
 		sb.WriteString("}")
+		writeBlindIndexParam(&sb, prefix+field.Name, field)
 	}
 
 	sb.WriteString(")")
 
-	if dbType == DbTypePostgres {
+	if IsDialect(dbType, DbTypePostgres) || IsDialect(dbType, DbTypeSqlite) {
 		if !noReturn {
 			for _, field := range mapper.TypeMap(rType).Index {
 				if _, ok := field.Options["autoincr"]; ok {
 					sb.WriteString(" RETURNING ")
-					sb.WriteString(field.Name)
+					sb.WriteString(quoteColumn(dbType, field))
 					break
 				}
 			}
@@ -253,7 +267,7 @@ func GenerateInsertSQL2(dbType Dialect, mapper *Mapper, rType reflect.Type, fiel
 	if err != nil {
 		return "", err
 	}
-	sb.WriteString(tableName)
+	sb.WriteString(quoteTableName(dbType, tableName))
 	sb.WriteString("(")
 
 	skip := func(field *FieldInfo) bool {
@@ -313,7 +327,8 @@ func GenerateInsertSQL2(dbType Dialect, mapper *Mapper, rType reflect.Type, fiel
 			_, isCreated := field.Options["created"]
 			_, isUpdated := field.Options["updated"]
 
-			if (isCreated && isTimeType(field.Field.Type)) || (isUpdated && isTimeType(field.Field.Type)) || "created_at" == field.Name || "updated_at" == field.Name {
+			if (isCreated && isTimeType(field.Field.Type)) || (isUpdated && isTimeType(field.Field.Type)) || "created_at" == field.Name || "updated_at" == field.Name ||
+				isTenantField(mapper, field) || isOperatorField(field) {
 
 				if !isFirst {
 					sb.WriteString(", ")
@@ -321,7 +336,7 @@ func GenerateInsertSQL2(dbType Dialect, mapper *Mapper, rType reflect.Type, fiel
 					isFirst = false
 				}
 
-				sb.WriteString(field.Name)
+				sb.WriteString(quoteColumn(dbType, field))
 				continue
 			}
 
@@ -337,16 +352,17 @@ func GenerateInsertSQL2(dbType Dialect, mapper *Mapper, rType reflect.Type, fiel
 			isFirst = false
 		}
 
-		sb.WriteString(field.Name)
+		sb.WriteString(quoteColumn(dbType, field))
+		writeBlindIndexColumn(&sb, dbType, field)
 	}
 	sb.WriteString(")")
 
-	if dbType == DbTypeMSSql {
+	if IsDialect(dbType, DbTypeMSSql) {
 		if !noReturn {
 			for _, field := range mapper.TypeMap(rType).Index {
 				if _, ok := field.Options["autoincr"]; ok {
 					sb.WriteString(" OUTPUT inserted.")
-					sb.WriteString(field.Name)
+					sb.WriteString(quoteColumn(dbType, field))
 					break
 				}
 			}
@@ -374,6 +390,25 @@ func GenerateInsertSQL2(dbType Dialect, mapper *Mapper, rType reflect.Type, fiel
 				break
 			}
 		}
+		if isTenantField(mapper, field) {
+			if !isFirst {
+				sb.WriteString(", ")
+			} else {
+				isFirst = false
+			}
+			sb.WriteString(tenantParam(field))
+			continue
+		}
+		if isOperatorField(field) {
+			if !isFirst {
+				sb.WriteString(", ")
+			} else {
+				isFirst = false
+			}
+			sb.WriteString(operatorParam(field))
+			continue
+		}
+
 		if foundIndex < 0 {
 
 			_, isCreated := field.Options["created"]
@@ -386,7 +421,7 @@ func GenerateInsertSQL2(dbType Dialect, mapper *Mapper, rType reflect.Type, fiel
 					isFirst = false
 				}
 
-				if dbType == DbTypePostgres {
+				if IsDialect(dbType, DbTypePostgres) {
 					sb.WriteString("now()")
 				} else {
 					sb.WriteString("CURRENT_TIMESTAMP")
@@ -407,7 +442,7 @@ func GenerateInsertSQL2(dbType Dialect, mapper *Mapper, rType reflect.Type, fiel
 		_, isUpdated := field.Options["updated"]
 		if (AutoCreatedAt && ((isCreated && isTimeType(field.Field.Type)) || field.Name == "created_at")) ||
 			(AutoUpdatedAt && ((isUpdated && isTimeType(field.Field.Type)) || field.Name == "updated_at")) {
-			if dbType == DbTypePostgres {
+			if IsDialect(dbType, DbTypePostgres) {
 				sb.WriteString("now()")
 			} else {
 				sb.WriteString("CURRENT_TIMESTAMP")
@@ -424,17 +459,19 @@ func GenerateInsertSQL2(dbType Dialect, mapper *Mapper, rType reflect.Type, fiel
 				sb.WriteString(",notnull=true")
 			}
 		}
+		sb.WriteString(fieldParamOptions(field))
 		sb.WriteString("}")
+		writeBlindIndexParam(&sb, fields[foundIndex], field)
 	}
 
 	sb.WriteString(")")
 
-	if dbType == DbTypePostgres {
+	if IsDialect(dbType, DbTypePostgres) || IsDialect(dbType, DbTypeSqlite) {
 		if !noReturn {
 			for _, field := range mapper.TypeMap(rType).Index {
 				if _, ok := field.Options["autoincr"]; ok {
 					sb.WriteString(" RETURNING ")
-					sb.WriteString(field.Name)
+					sb.WriteString(quoteColumn(dbType, field))
 					break
 				}
 			}
@@ -572,10 +609,15 @@ func GenerateUpsertSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, keyNa
 			updateFields = append(updateFields, field)
 		} else if _, ok := field.Options["created"]; ok || field.Name == "created_at" {
 			insertFields = append(insertFields, field)
+		} else if isUpdaterField(field) {
+			insertFields = append(insertFields, field)
+			updateFields = append(updateFields, field)
+		} else if isCreatorField(field) || isTenantField(mapper, field) {
+			insertFields = append(insertFields, field)
 		}
 	}
 
-	if dbType == DbTypeMSSql {
+	if IsDialect(dbType, DbTypeMSSql) {
 		return GenerateUpsertMSSQL(dbType, mapper, rType, tableName, "", keyNames, insertFields, updateFields, noReturn)
 	}
 
@@ -598,7 +640,7 @@ func GenerateUpsertSQLForStruct(dbType Dialect, mapper *Mapper, rType reflect.Ty
 		}
 	}
 
-	if dbType == DbTypeMSSql {
+	if IsDialect(dbType, DbTypeMSSql) {
 		return GenerateUpsertMSSQL(dbType, mapper, rType, tableName, prefix, keyNames, insertFields, updateFields, noReturn)
 	}
 
@@ -632,6 +674,11 @@ func skipFieldForUpsert(keyNames []string, field *FieldInfo, isUpdated bool) boo
 		return true
 	}
 
+	if _, ok := field.Options["tenant"]; ok {
+		// 租户是不能被修改的
+		return isUpdated
+	}
+
 	if _, ok := field.Options["updated"]; ok || field.Name == "updated_at" {
 		return false
 	}
@@ -643,6 +690,14 @@ func skipFieldForUpsert(keyNames []string, field *FieldInfo, isUpdated bool) boo
 		return false
 	}
 
+	if isUpdaterField(field) {
+		return false
+	}
+
+	if isCreatorField(field) {
+		return isUpdated
+	}
+
 	if _, ok := field.Options["-"]; ok {
 		return true
 	}
@@ -658,16 +713,20 @@ func skipFieldForUpsert(keyNames []string, field *FieldInfo, isUpdated bool) boo
 }
 
 func generateUpsertSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, tableName string, prefix string, keyNames []string, insertFields, updateFields []*FieldInfo, noReturn bool) (string, error) {
+	if err := checkUpsertEncryptFields(insertFields); err != nil {
+		return "", err
+	}
+
 	var sb strings.Builder
 	sb.WriteString("INSERT INTO ")
-	sb.WriteString(tableName)
+	sb.WriteString(quoteTableName(dbType, tableName))
 	sb.WriteString("(")
 
 	for idx, field := range insertFields {
 		if idx != 0 {
 			sb.WriteString(", ")
 		}
-		sb.WriteString(field.Name)
+		sb.WriteString(quoteColumn(dbType, field))
 	}
 	sb.WriteString(")")
 
@@ -679,7 +738,7 @@ func generateUpsertSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, table
 		}
 
 		if isTimeField(field) {
-			if dbType == DbTypePostgres {
+			if IsDialect(dbType, DbTypePostgres) {
 				sb.WriteString("now()")
 			} else {
 				sb.WriteString("CURRENT_TIMESTAMP")
@@ -687,16 +746,32 @@ func generateUpsertSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, table
 			continue
 		}
 
+		if isTenantField(mapper, field) {
+			sb.WriteString(tenantParam(field))
+			continue
+		}
+
+		if isOperatorField(field) {
+			sb.WriteString(operatorParam(field))
+			continue
+		}
+
 		sb.WriteString("#{")
 		sb.WriteString(prefix)
 		sb.WriteString(field.Name)
+		sb.WriteString(fieldParamOptions(field))
+		if isVersionField(field) {
+			// 插入和更新后的版本都是参数中的版本加 1
+			sb.WriteString(",version}+1")
+			continue
+		}
 		sb.WriteString("}")
 	}
 
 	sb.WriteString(")")
 
-	switch dbType {
-	case DbTypePostgres:
+	switch rootDialect(dbType) {
+	case DbTypePostgres, DbTypeSqlite:
 		// @postgres insert into auth_users(username, phone, address, status, birth_day, created_at, updated_at)
 		// values (?,?,?,?,?,CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 		// ON CONFLICT (id) DO UPDATE SET
@@ -708,13 +783,14 @@ func generateUpsertSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, table
 			if idx != 0 {
 				sb.WriteString(", ")
 			}
-			sb.WriteString(name)
+			sb.WriteString(quoteColumnName(dbType, mapper, rType, name))
 		}
 		sb.WriteString(") DO")
 
 		if len(updateFields) == 0 {
 			sb.WriteString(" NOTHING ")
 		} else {
+			var versionField *FieldInfo
 			for idx, field := range updateFields {
 				if idx != 0 {
 					sb.WriteString(", ")
@@ -722,9 +798,37 @@ func generateUpsertSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, table
 					sb.WriteString(" UPDATE SET ")
 				}
 
-				sb.WriteString(field.Name)
+				if isVersionField(field) {
+					versionField = field
+					writeVersionIncr(&sb, dbType, quoteTableName(dbType, tableName), field)
+					continue
+				}
+
+				sb.WriteString(quoteColumn(dbType, field))
+				sb.WriteString("=EXCLUDED.")
+				sb.WriteString(quoteColumn(dbType, field))
+			}
+
+			// 只更新版本一致且属于同一个租户的记录
+			isFirst := true
+			for _, field := range []*FieldInfo{versionField, findTenantField(mapper, rType)} {
+				if field == nil {
+					continue
+				}
+				if isFirst {
+					isFirst = false
+					sb.WriteString(" WHERE ")
+				} else {
+					sb.WriteString(" AND ")
+				}
+				sb.WriteString(quoteTableName(dbType, tableName))
+				sb.WriteString(".")
+				sb.WriteString(quoteColumn(dbType, field))
 				sb.WriteString("=EXCLUDED.")
-				sb.WriteString(field.Name)
+				sb.WriteString(quoteColumn(dbType, field))
+				if field == versionField {
+					sb.WriteString("-1")
+				}
 			}
 		}
 
@@ -732,7 +836,7 @@ func generateUpsertSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, table
 			for _, field := range mapper.TypeMap(rType).Index {
 				if _, ok := field.Options["autoincr"]; ok {
 					sb.WriteString(" RETURNING ")
-					sb.WriteString(field.Name)
+					sb.WriteString(quoteColumn(dbType, field))
 					break
 				}
 			}
@@ -759,15 +863,43 @@ func generateUpsertSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, table
 			return "", errors.New("empty update fields is unsupported")
 		}
 
+		// mysql 不支持带条件的 update， 只能在每个字段上判断版本是否一致，
+		// 因为赋值是按顺序执行的， 所以版本字段必须放在最后
+		var versionField *FieldInfo
+		for _, field := range updateFields {
+			if isVersionField(field) {
+				versionField = field
+			}
+		}
+
 		sb.WriteString(" ON DUPLICATE KEY UPDATE ")
-		for idx, field := range updateFields {
-			if idx != 0 {
+		isFirst := true
+		for _, field := range updateFields {
+			if field == versionField {
+				continue
+			}
+			if isFirst {
+				isFirst = false
+			} else {
 				sb.WriteString(", ")
 			}
-			sb.WriteString(field.Name)
-			sb.WriteString("=VALUES(")
-			sb.WriteString(field.Name)
-			sb.WriteString(")")
+			sb.WriteString(quoteColumn(dbType, field))
+			sb.WriteString("=")
+			if versionField == nil {
+				sb.WriteString("VALUES(")
+				sb.WriteString(quoteColumn(dbType, field))
+				sb.WriteString(")")
+				continue
+			}
+			writeMysqlVersionIf(&sb, dbType, versionField, "VALUES("+quoteColumn(dbType, field)+")", quoteColumn(dbType, field))
+		}
+		if versionField != nil {
+			if !isFirst {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(quoteColumn(dbType, versionField))
+			sb.WriteString("=")
+			writeMysqlVersionIf(&sb, dbType, versionField, quoteColumn(dbType, versionField)+"+1", quoteColumn(dbType, versionField))
 		}
 	default:
 		return "", errors.New("upsert is unimplemented for db type - " + dbType.Name())
@@ -776,9 +908,13 @@ func generateUpsertSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, table
 }
 
 func GenerateUpsertMSSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, tableName string, prefixName string, keyNames []string, insertFields, updateFields []*FieldInfo, noReturn bool) (string, error) {
+	if err := checkUpsertEncryptFields(insertFields); err != nil {
+		return "", err
+	}
+
 	var sb strings.Builder
 	sb.WriteString("MERGE INTO ")
-	sb.WriteString(tableName)
+	sb.WriteString(quoteTableName(dbType, tableName))
 	sb.WriteString(" AS t USING ( VALUES(")
 	for idx, field := range insertFields {
 		if idx != 0 {
@@ -790,9 +926,25 @@ func GenerateUpsertMSSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, tab
 			continue
 		}
 
+		if isTenantField(mapper, field) {
+			sb.WriteString(tenantParam(field))
+			continue
+		}
+
+		if isOperatorField(field) {
+			sb.WriteString(operatorParam(field))
+			continue
+		}
+
 		sb.WriteString("#{")
 		sb.WriteString(prefixName)
 		sb.WriteString(field.Name)
+		sb.WriteString(fieldParamOptions(field))
+		if isVersionField(field) {
+			// 插入和更新后的版本都是参数中的版本加 1
+			sb.WriteString(",version}+1")
+			continue
+		}
 		sb.WriteString("}")
 	}
 	sb.WriteString(" ) ) AS s (")
@@ -801,7 +953,7 @@ func GenerateUpsertMSSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, tab
 		if idx != 0 {
 			sb.WriteString(", ")
 		}
-		sb.WriteString(field.Name)
+		sb.WriteString(quoteColumn(dbType, field))
 	}
 	sb.WriteString(" ) ON ")
 	for idx, name := range keyNames {
@@ -809,21 +961,42 @@ func GenerateUpsertMSSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, tab
 			sb.WriteString(" AND ")
 		}
 		sb.WriteString("t.")
-		sb.WriteString(name)
+		sb.WriteString(quoteColumnName(dbType, mapper, rType, name))
 
 		sb.WriteString(" = s.")
-		sb.WriteString(name)
+		sb.WriteString(quoteColumnName(dbType, mapper, rType, name))
+	}
+	if tenantField := findTenantField(mapper, rType); tenantField != nil {
+		sb.WriteString(" AND t.")
+		sb.WriteString(quoteColumn(dbType, tenantField))
+		sb.WriteString(" = s.")
+		sb.WriteString(quoteColumn(dbType, tenantField))
 	}
 	if len(updateFields) > 0 {
-		sb.WriteString(" WHEN MATCHED THEN UPDATE SET ")
+		sb.WriteString(" WHEN MATCHED")
+		for _, field := range updateFields {
+			if isVersionField(field) {
+				sb.WriteString(" AND t.")
+				sb.WriteString(quoteColumn(dbType, field))
+				sb.WriteString(" = s.")
+				sb.WriteString(quoteColumn(dbType, field))
+				sb.WriteString(" - 1")
+				break
+			}
+		}
+		sb.WriteString(" THEN UPDATE SET ")
 
 		for idx, field := range updateFields {
 			if idx != 0 {
 				sb.WriteString(", ")
 			}
-			sb.WriteString(field.Name)
+			if isVersionField(field) {
+				writeVersionIncr(&sb, dbType, "t", field)
+				continue
+			}
+			sb.WriteString(quoteColumn(dbType, field))
 			sb.WriteString(" = s.")
-			sb.WriteString(field.Name)
+			sb.WriteString(quoteColumn(dbType, field))
 		}
 	}
 
@@ -833,7 +1006,7 @@ func GenerateUpsertMSSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, tab
 		if idx != 0 {
 			sb.WriteString(", ")
 		}
-		sb.WriteString(field.Name)
+		sb.WriteString(quoteColumn(dbType, field))
 	}
 	sb.WriteString(") VALUES(")
 	for idx, field := range insertFields {
@@ -842,7 +1015,7 @@ func GenerateUpsertMSSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, tab
 		}
 
 		sb.WriteString("s.")
-		sb.WriteString(field.Name)
+		sb.WriteString(quoteColumn(dbType, field))
 	}
 	sb.WriteString(") ")
 
@@ -850,7 +1023,7 @@ func GenerateUpsertMSSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, tab
 		for _, field := range mapper.TypeMap(rType).Index {
 			if _, ok := field.Options["autoincr"]; ok {
 				sb.WriteString(" OUTPUT inserted.")
-				sb.WriteString(field.Name)
+				sb.WriteString(quoteColumn(dbType, field))
 				break
 			}
 		}
@@ -866,7 +1039,7 @@ func GenerateUpdateSQL(dbType Dialect, mapper *Mapper, prefix string, rType refl
 	if err != nil {
 		return "", err
 	}
-	sb.WriteString(tableName)
+	sb.WriteString(quoteTableName(dbType, tableName))
 	sb.WriteString(" SET ")
 
 	structType := mapper.TypeMap(rType)
@@ -905,9 +1078,15 @@ func GenerateUpdateSQL(dbType Dialect, mapper *Mapper, prefix string, rType refl
 		if _, ok := field.Options["created"]; ok {
 			continue
 		}
+		if _, ok := field.Options["creator"]; ok {
+			continue
+		}
 		if _, ok := field.Options["deleted"]; ok {
 			continue
 		}
+		if _, ok := field.Options["tenant"]; ok {
+			continue
+		}
 
 		found := false
 		for _, name := range names {
@@ -931,30 +1110,49 @@ func GenerateUpdateSQL(dbType Dialect, mapper *Mapper, prefix string, rType refl
 			isFirst = false
 		}
 
-		sb.WriteString(field.Name)
+		if isVersionField(field) {
+			writeVersionIncr(&sb, dbType, "", field)
+			continue
+		}
+
+		sb.WriteString(quoteColumn(dbType, field))
 
 		if _, isUpdated := field.Options["updated"]; AutoUpdatedAt && ((isUpdated && isTimeType(field.Field.Type)) || field.Name == "updated_at") {
-			if dbType == DbTypePostgres {
+			if IsDialect(dbType, DbTypePostgres) {
 				sb.WriteString("=now()")
 			} else {
 				sb.WriteString("=CURRENT_TIMESTAMP")
 			}
 			continue
 		}
+		if isUpdaterField(field) {
+			sb.WriteString("=")
+			sb.WriteString(operatorParam(field))
+			continue
+		}
 		sb.WriteString("=#{")
 
 		if prefix != "" {
 			sb.WriteString(prefix)
 		}
 		sb.WriteString(field.Name)
+		sb.WriteString(fieldParamOptions(field))
 		sb.WriteString("}")
+		writeBlindIndexSet(&sb, dbType, prefix+field.Name, field)
 	}
 
+	versionField := findVersionField(mapper, rType)
 	if len(names) > 0 {
-		err := generateWhere(dbType, mapper, rType, names, argTypes, nil, StatementTypeUpdate, false, &sb)
+		var where strings.Builder
+		err := generateWhere(dbType, mapper, rType, names, argTypes, appendTenantFilter(dbType, mapper, rType, nil), StatementTypeUpdate, false, &where)
 		if err != nil {
 			return "", err
 		}
+		if versionField != nil {
+			writeWhereWithVersion(&sb, dbType, where.String(), prefix+versionField.Name, versionField)
+		} else {
+			sb.WriteString(where.String())
+		}
 	} else {
 		isFirst = true
 		for _, field := range structType.Index {
@@ -973,7 +1171,7 @@ func GenerateUpdateSQL(dbType Dialect, mapper *Mapper, prefix string, rType refl
 				sb.WriteString(" AND ")
 			}
 
-			sb.WriteString(field.Name)
+			sb.WriteString(quoteColumn(dbType, field))
 			sb.WriteString("=#{")
 			if prefix != "" {
 				sb.WriteString(prefix)
@@ -985,6 +1183,10 @@ func GenerateUpdateSQL(dbType Dialect, mapper *Mapper, prefix string, rType refl
 		if isFirst {
 			return "", errors.New("primary key isnot found")
 		}
+		writeTenantCondition(&sb, dbType, mapper, rType)
+		if versionField != nil {
+			writeWhereWithVersion(&sb, dbType, "", prefix+versionField.Name, versionField)
+		}
 	}
 	return sb.String(), nil
 }
@@ -996,11 +1198,13 @@ func GenerateUpdateSQL2(dbType Dialect, mapper *Mapper, rType, queryType reflect
 	if err != nil {
 		return "", err
 	}
-	sb.WriteString(tableName)
+	sb.WriteString(quoteTableName(dbType, tableName))
 	sb.WriteString(" SET ")
 
 	structType := mapper.TypeMap(rType)
 	deletedField := findDeletedField(mapper, rType)
+	versionField := findVersionField(mapper, rType)
+	versionName := ""
 
 	isFirst := true
 	for _, fieldName := range values {
@@ -1023,6 +1227,9 @@ func GenerateUpdateSQL2(dbType Dialect, mapper *Mapper, rType, queryType reflect
 		if deletedField != nil && deletedField.Name == field.Name {
 			continue
 		}
+		if _, ok := field.Options["tenant"]; ok {
+			continue
+		}
 
 		if !isFirst {
 			sb.WriteString(", ")
@@ -1030,16 +1237,28 @@ func GenerateUpdateSQL2(dbType Dialect, mapper *Mapper, rType, queryType reflect
 			isFirst = false
 		}
 
-		sb.WriteString(field.Name)
+		if isVersionField(field) {
+			// 参数中的版本用于乐观锁的检查
+			versionName = fieldName
+			writeVersionIncr(&sb, dbType, "", field)
+			continue
+		}
+
+		sb.WriteString(quoteColumn(dbType, field))
 
 		if _, isUpdated := field.Options["updated"]; AutoUpdatedAt && ((isUpdated && isTimeType(field.Field.Type)) || field.Name == "updated_at") {
-			if dbType == DbTypePostgres {
+			if IsDialect(dbType, DbTypePostgres) {
 				sb.WriteString("=now()")
 			} else {
 				sb.WriteString("=CURRENT_TIMESTAMP")
 			}
 			continue
 		}
+		if isUpdaterField(field) {
+			sb.WriteString("=")
+			sb.WriteString(operatorParam(field))
+			continue
+		}
 		sb.WriteString("=#{")
 		sb.WriteString(fieldName)
 		if field.Options != nil {
@@ -1049,11 +1268,13 @@ func GenerateUpdateSQL2(dbType Dialect, mapper *Mapper, rType, queryType reflect
 				sb.WriteString(",notnull=true")
 			}
 		}
+		sb.WriteString(fieldParamOptions(field))
 		sb.WriteString("}")
+		writeBlindIndexSet(&sb, dbType, fieldName, field)
 	}
 
 	for _, field := range structType.Index {
-		if field.Name != "updated_at" {
+		if field.Name != "updated_at" && !isUpdaterField(field) {
 			continue
 		}
 
@@ -1079,19 +1300,39 @@ func GenerateUpdateSQL2(dbType Dialect, mapper *Mapper, rType, queryType reflect
 			isFirst = false
 		}
 
-		sb.WriteString(field.Name)
-		if dbType == DbTypePostgres {
+		sb.WriteString(quoteColumn(dbType, field))
+		if isUpdaterField(field) {
+			sb.WriteString("=")
+			sb.WriteString(operatorParam(field))
+		} else if IsDialect(dbType, DbTypePostgres) {
 			sb.WriteString("=now()")
 		} else {
 			sb.WriteString("=CURRENT_TIMESTAMP")
 		}
 	}
 
-	err = generateWhere(dbType, mapper, rType, []string{queryName}, []reflect.Type{queryType}, nil, StatementTypeUpdate, false, &sb)
+	if versionField != nil && versionName == "" {
+		if !isFirst {
+			sb.WriteString(", ")
+		}
+		writeVersionIncr(&sb, dbType, "", versionField)
+	}
+
+	exprs := appendTenantFilter(dbType, mapper, rType, nil)
+	if versionName == "" {
+		err = generateWhere(dbType, mapper, rType, []string{queryName}, []reflect.Type{queryType}, exprs, StatementTypeUpdate, false, &sb)
+		if err != nil {
+			return "", err
+		}
+		return sb.String(), nil
+	}
+
+	var where strings.Builder
+	err = generateWhere(dbType, mapper, rType, []string{queryName}, []reflect.Type{queryType}, exprs, StatementTypeUpdate, false, &where)
 	if err != nil {
 		return "", err
 	}
-
+	writeWhereWithVersion(&sb, dbType, where.String(), versionName, versionField)
 	return sb.String(), nil
 }
 
@@ -1138,7 +1379,7 @@ type Filter struct {
 func toFilters(filters []Filter, dbType Dialect) []string {
 	results := make([]string, 0, len(filters))
 	for idx := range filters {
-		if filters[idx].Dialect != "" && ToDbType(filters[idx].Dialect) == dbType {
+		if filters[idx].Dialect != "" && IsDialect(dbType, ToDbType(filters[idx].Dialect)) {
 			continue
 		}
 
@@ -1164,9 +1405,9 @@ func GenerateDeleteSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, names
 	if err != nil {
 		return "", err
 	}
-	sb.WriteString(tableName)
+	sb.WriteString(quoteTableName(dbType, tableName))
 
-	exprs := toFilters(filters, dbType)
+	exprs := appendTenantFilter(dbType, mapper, rType, toFilters(filters, dbType))
 	if len(names) > 0 && (deletedField == nil || forceIndex < 0 || len(names) > 1) {
 		err := generateWhere(dbType, mapper, rType, names, argTypes, exprs, StatementTypeDelete, false, &sb)
 		if err != nil {
@@ -1195,14 +1436,12 @@ func GenerateDeleteSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, names
 	}
 	full.WriteString(`UPDATE `)
 
-	full.WriteString(tableName)
+	full.WriteString(quoteTableName(dbType, tableName))
 	full.WriteString(" SET ")
-	full.WriteString(deletedField.Name)
-	if dbType == DbTypePostgres {
-		full.WriteString("=now() ")
-	} else {
-		full.WriteString("=CURRENT_TIMESTAMP ")
-	}
+	full.WriteString(quoteColumn(dbType, deletedField))
+	full.WriteString("=")
+	full.WriteString(deletedValue(dbType, deletedField, true))
+	full.WriteString(" ")
 
 	if len(names) > 0 && (forceIndex < 0 || len(names) > 1) {
 		err := generateWhere(dbType, mapper, rType, names, argTypes, exprs, StatementTypeDelete, false, &full)
@@ -1238,22 +1477,33 @@ func GenerateSelectSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, names
 	if err != nil {
 		return "", err
 	}
-	sb.WriteString(tableName)
+	sb.WriteString(quoteTableName(dbType, tableName))
 
 	hasOffset, hasLimit, hasOrderBy := false, false, false
 	hasOffset, hasLimit, names, argTypes = removeOffsetAndLimit(names, argTypes)
 	hasOrderBy, names, argTypes = removeArg(names, argTypes, "sortBy")
 
-	exprs := toFilters(filters, dbType)
+	sortBy, offset, limit := "sortBy", "offset", "limit"
+	querySortBy, queryOffset, queryLimit := readQueryPagination(names, argTypes)
+	if !hasOrderBy && querySortBy != "" {
+		hasOrderBy, sortBy = true, querySortBy
+	}
+	if !hasOffset && queryOffset != "" {
+		hasOffset, offset = true, queryOffset
+	}
+	if !hasLimit && queryLimit != "" {
+		hasLimit, limit = true, queryLimit
+	}
+
+	exprs := appendTenantFilter(dbType, mapper, rType, toFilters(filters, dbType))
 	if len(names) > 0 {
 		err := generateWhere(dbType, mapper, rType, names, argTypes, exprs, StatementTypeSelect, false, &sb)
 		if err != nil {
 			return "", err
 		}
-	} else if deletedField := findDeletedField(mapper, rType); deletedField != nil {
+	} else if filter := deletedFilter(dbType, mapper, findDeletedField(mapper, rType)); filter != "" {
 		sb.WriteString(" WHERE ")
-		sb.WriteString(deletedField.Name)
-		sb.WriteString(" IS NULL")
+		sb.WriteString(filter)
 
 		for idx := range exprs {
 			s := strings.TrimSpace(exprs[idx])
@@ -1272,20 +1522,63 @@ func GenerateSelectSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, names
 	}
 
 	if hasOrderBy {
-		sb.WriteString(` <order_by by="sortBy"/>`)
+		sb.WriteString(` <order_by by="` + sortBy + `" allowed="`)
+		sb.WriteString(xmlAttrEscaper.Replace(strings.Join(sortableColumns(dbType, mapper, rType), ",")))
+		sb.WriteString(`"/>`)
+	}
+
+	if IsDialect(dbType, DbTypeSqlite) {
+		// sqlite 的 OFFSET 必须跟在 LIMIT 后面
+		if hasOffset && hasLimit {
+			sb.WriteString(` <pagination offset="` + offset + `" limit="` + limit + `" />`)
+			hasOffset, hasLimit = false, false
+		} else if hasOffset {
+			sb.WriteString(` <if test="` + offset + ` &gt; 0"> LIMIT -1 OFFSET #{` + offset + `} </if>`)
+			hasOffset = false
+		}
 	}
 
 	if hasOffset {
 		// <if test="offset &gt; 0"> OFFSET #{offset} </if>
-		sb.WriteString(` <if test="offset &gt; 0"> OFFSET #{offset} </if>`)
+		sb.WriteString(` <if test="` + offset + ` &gt; 0"> OFFSET #{` + offset + `} </if>`)
 	}
 	if hasLimit {
 		// <if test="limit &gt; 0"> LIMIT #{limit} </if>
-		sb.WriteString(` <if test="limit &gt; 0"> LIMIT #{limit} </if>`)
+		sb.WriteString(` <if test="` + limit + ` &gt; 0"> LIMIT #{` + limit + `} </if>`)
 	}
 	return sb.String(), nil
 }
 
+// SortableColumns 返回 rType 中可以用于排序的列， 列名已按引用策略引用
+func SortableColumns(dbType Dialect, mapper *Mapper, rType reflect.Type) []string {
+	return sortableColumns(dbType, mapper, rType)
+}
+
+// xmlAttrEscaper 用于将引用后的列名(如 postgres 的 "order")写到 xml 的属性中
+var xmlAttrEscaper = strings.NewReplacer(`"`, "&quot;")
+
+// sortableColumns 返回可以用于排序的列， 生成的 order_by 只允许按这些列排序，
+// 列名已用 quoteColumn 引用， 以便 toOrderBy 直接使用
+func sortableColumns(dbType Dialect, mapper *Mapper, rType reflect.Type) []string {
+	var columns []string
+	for _, field := range mapper.TypeMap(rType).Index {
+		if field.Field.Name == "TableName" || field.Field.Anonymous {
+			continue
+		}
+		if field.Parent != nil && len(field.Parent.Index) != 0 && !field.Parent.Field.Anonymous {
+			continue
+		}
+		if _, ok := field.Options["-"]; ok {
+			continue
+		}
+		if isEncryptField(field) {
+			continue
+		}
+		columns = append(columns, quoteColumn(dbType, field))
+	}
+	return columns
+}
+
 func GenerateCountSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, names []string, argTypes []reflect.Type, filters []Filter) (string, error) {
 	var sb strings.Builder
 	sb.WriteString("SELECT count(*) FROM ")
@@ -1293,18 +1586,17 @@ func GenerateCountSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, names
 	if err != nil {
 		return "", err
 	}
-	sb.WriteString(tableName)
+	sb.WriteString(quoteTableName(dbType, tableName))
 
-	exprs := toFilters(filters, dbType)
+	exprs := appendTenantFilter(dbType, mapper, rType, toFilters(filters, dbType))
 	if len(names) > 0 {
 		err := generateWhere(dbType, mapper, rType, names, argTypes, exprs, StatementTypeSelect, false, &sb)
 		if err != nil {
 			return "", err
 		}
-	} else if deletedField := findDeletedField(mapper, rType); deletedField != nil {
+	} else if filter := deletedFilter(dbType, mapper, findDeletedField(mapper, rType)); filter != "" {
 		sb.WriteString(" WHERE ")
-		sb.WriteString(deletedField.Name)
-		sb.WriteString(" IS NULL")
+		sb.WriteString(filter)
 
 		for idx := range exprs {
 			s := strings.TrimSpace(exprs[idx])
@@ -1371,27 +1663,36 @@ func generateWhere(dbType Dialect, mapper *Mapper, rType reflect.Type, names []s
 	// xxx AND <if/> AND xxx AND <if/>
 	// <if/> AND <if/>               -- 这里要将 AND 移到后一个 if 中
 
+	// 按例查询的参数放在最后处理， 见 query_example.go
+	queryNames, queryTypes, names, argTypes := splitQueryArgs(names, argTypes)
+
 	var deletedField = findDeletedField(mapper, rType)
 	var forceIndex = findForceArg(names, argTypes, stmtType)
 	var structType = mapper.TypeMap(rType)
+	if deletedField != nil && forceIndex < 0 && stmtType == StatementTypeSelect &&
+		deletedFilter(dbType, mapper, deletedField) == "" {
+		deletedField = nil
+	}
 
 	isNotNull := func(name string, argType reflect.Type) (bool, error) {
-		fi, isSlice, err := toFieldName(structType, name, argType)
+		fi, isSlice, op, err := toWhereField(structType, name, argType)
 		if err != nil {
-			fii, isSlicei, e := toFieldName(structType, strings.TrimSuffix(strings.ToLower(name), "like"), argType)
-			if e != nil {
-				return false, err
-			}
-
-			fi = fii
-			isSlice = isSlicei
+			return false, err
 		}
-		if !isSlice {
-			_, ok := fi.Options["notnull"]
-			return ok, nil
+		if isOptionalWhereOp(op) {
+			return true, nil
 		}
-		return false, nil
+		if op != whereOpEqual || isSlice {
+			return false, nil
+		}
+		_, ok := fi.Options["notnull"]
+		return ok, nil
+	}
+	isEncrypted := func(name string, argType reflect.Type) bool {
+		fi, _, err := toFieldName(structType, name, argType)
+		return err == nil && isEncryptField(fi)
 	}
+
 	needWhereTag := true
 	var needIFExprArray []bool
 	if len(argTypes) == 0 {
@@ -1401,6 +1702,11 @@ func generateWhere(dbType Dialect, mapper *Mapper, rType reflect.Type, names []s
 		for idx := range argTypes {
 			needIFExprArray[idx] = false
 
+			if isEncrypted(names[idx], argTypes[idx]) {
+				needWhereTag = false
+				continue
+			}
+
 			if ok, _, _ := isValidable(argTypes[idx]); ok {
 				needIFExprArray[idx] = true
 				continue
@@ -1414,8 +1720,6 @@ func generateWhere(dbType Dialect, mapper *Mapper, rType reflect.Type, names []s
 				return err
 			} else if notNull {
 				needIFExprArray[idx] = true
-			} else if strings.HasSuffix(strings.ToLower(names[idx]), "like") {
-				needIFExprArray[idx] = true
 			} else {
 				needWhereTag = false
 			}
@@ -1427,6 +1731,9 @@ func generateWhere(dbType Dialect, mapper *Mapper, rType reflect.Type, names []s
 			needWhereTag = false
 		}
 	}
+	if len(queryNames) > 0 {
+		needWhereTag = true
+	}
 	if needWhereTag {
 		sb.WriteString(" <where>")
 	} else {
@@ -1484,36 +1791,111 @@ func generateWhere(dbType Dialect, mapper *Mapper, rType reflect.Type, names []s
 			argType = argTypes[idx]
 		}
 
-		isLike := false
-		field, isArgSlice, err := toFieldName(structType, name, argType)
+		field, isArgSlice, op, err := toWhereField(structType, name, argType)
 		if err != nil {
-			if !strings.HasSuffix(strings.ToLower(name), "like") {
-				return err
+			return err
+		}
+		isLike := isLikeWhereOp(op)
+		if isEncryptField(field) {
+			if op != whereOpEqual || IsValueRange(argType) {
+				return errors.New("column '" + field.Name + "' is encrypted, only equality lookup is supported")
 			}
-
-			field, isArgSlice, err = toFieldName(structType, name[:len(name)-len("like")], argType)
+			column, option, err := encryptedLookup(dbType, mapper, field)
 			if err != nil {
 				return err
 			}
+
+			if !prefixANDExpr {
+				prefixANDExpr = true
+			} else {
+				sb.WriteString(` AND `)
+			}
+			sb.WriteString(column)
 			if isArgSlice {
-				return errors.New("'" + name + "' must cannot is a slice, like array is unsupported")
+				sb.WriteString(` in (<foreach collection="`)
+				sb.WriteString(name)
+				sb.WriteString(`" item="item" separator="," >#{item`)
+				sb.WriteString(encodingOption(field))
+				sb.WriteString(option)
+				sb.WriteString(`}</foreach>)`)
+			} else {
+				sb.WriteString("=#{")
+				sb.WriteString(name)
+				sb.WriteString(encodingOption(field))
+				sb.WriteString(option)
+				sb.WriteString("}")
 			}
-			if field.Field.Type.Kind() != reflect.String {
-				return errors.New("'" + name + "' must cannot is a string, like array is unsupported")
+		} else if op == whereOpNotIn {
+			sb.WriteString(`<if test="isNotEmpty(`)
+			sb.WriteString(name)
+			sb.WriteString(`)"> `)
+
+			if !prefixANDExpr {
+				prefixANDExpr = true
+			} else {
+				sb.WriteString(`AND `)
 			}
-			isLike = true
-		}
-		if isArgSlice {
+
+			column := quoteColumn(dbType, field)
+			sb.WriteString("(")
+			sb.WriteString(column)
+			sb.WriteString(` NOT IN (<foreach collection="`)
+			sb.WriteString(name)
+			sb.WriteString(`" item="item" separator="," >#{item`)
+			sb.WriteString(encodingOption(field))
+			sb.WriteString(`}</foreach>) OR `)
+			sb.WriteString(column)
+			sb.WriteString(" IS NULL) ")
+
+			if needANDExprSuffix(idx) {
+				sb.WriteString(`AND `)
+				prefixANDExpr = false
+			}
+			sb.WriteString(`</if>`)
+		} else if op == whereOpIsNull || op == whereOpIsNotNull {
+			// 两个 if 中只有一个成立， 所以它相当于一个静态的条件
+			and := ""
+			if !prefixANDExpr {
+				prefixANDExpr = true
+			} else {
+				and = "AND "
+			}
+
+			isNull := op == whereOpIsNull
+			sb.WriteString(`<if test="`)
+			sb.WriteString(name)
+			sb.WriteString(`"> `)
+			sb.WriteString(and)
+			sb.WriteString(quoteColumn(dbType, field))
+			if isNull {
+				sb.WriteString(" IS NULL ")
+			} else {
+				sb.WriteString(" IS NOT NULL ")
+			}
+			sb.WriteString(`</if><if test="!`)
+			sb.WriteString(name)
+			sb.WriteString(`"> `)
+			sb.WriteString(and)
+			sb.WriteString(quoteColumn(dbType, field))
+			if isNull {
+				sb.WriteString(" IS NOT NULL ")
+			} else {
+				sb.WriteString(" IS NULL ")
+			}
+			sb.WriteString(`</if>`)
+		} else if isArgSlice {
 			if !prefixANDExpr {
 				prefixANDExpr = true
 			} else {
 				sb.WriteString(` AND `)
 			}
 
-			sb.WriteString(field.Name)
+			sb.WriteString(quoteColumn(dbType, field))
 			sb.WriteString(` in (<foreach collection="`)
 			sb.WriteString(name)
-			sb.WriteString(`" item="item" separator="," >#{item}</foreach>)`)
+			sb.WriteString(`" item="item" separator="," >#{item`)
+			sb.WriteString(encodingOption(field))
+			sb.WriteString(`}</foreach>)`)
 		} else if ok, _, _ := isValidable(argType); ok {
 			sb.WriteString(`<if test="`)
 			sb.WriteString(name)
@@ -1525,13 +1907,11 @@ func generateWhere(dbType Dialect, mapper *Mapper, rType reflect.Type, names []s
 				sb.WriteString(`AND `)
 			}
 
-			sb.WriteString(field.Name)
-			if isLike {
-				sb.WriteString(" like ")
-				sb.WriteString("<like value=\"")
-				sb.WriteString(name)
-				sb.WriteString("\" /> ")
+			if op != whereOpEqual {
+				sb.WriteString(whereOpExpr(dbType, field, name, op))
+				sb.WriteString(" ")
 			} else {
+				sb.WriteString(quoteColumn(dbType, field))
 				sb.WriteString("=")
 				sb.WriteString("#{")
 				sb.WriteString(name)
@@ -1551,12 +1931,19 @@ func generateWhere(dbType Dialect, mapper *Mapper, rType reflect.Type, names []s
 			}
 
 			sb.WriteString(" (")
-			sb.WriteString(field.Name)
+			sb.WriteString(quoteColumn(dbType, field))
 			sb.WriteString(" BETWEEN #{")
 			sb.WriteString(name)
 			sb.WriteString(".Start} AND #{")
 			sb.WriteString(name)
 			sb.WriteString(".End}) ")
+		} else if op != whereOpEqual && !isLike {
+			if !prefixANDExpr {
+				prefixANDExpr = true
+			} else {
+				sb.WriteString(` AND `)
+			}
+			sb.WriteString(whereOpExpr(dbType, field, name, op))
 		} else if field.Field.Type.Kind() == reflect.Slice {
 			if !prefixANDExpr {
 				prefixANDExpr = true
@@ -1568,7 +1955,7 @@ func generateWhere(dbType Dialect, mapper *Mapper, rType reflect.Type, names []s
 				_, jsonExists = field.Options["jsonb"]
 			}
 			if jsonExists {
-				sb.WriteString(field.Name)
+				sb.WriteString(quoteColumn(dbType, field))
 				sb.WriteString(" @> ")
 				sb.WriteString("#{")
 				sb.WriteString(name)
@@ -1577,7 +1964,7 @@ func generateWhere(dbType Dialect, mapper *Mapper, rType reflect.Type, names []s
 				sb.WriteString("#{")
 				sb.WriteString(name)
 				sb.WriteString("} = ANY (")
-				sb.WriteString(field.Name)
+				sb.WriteString(quoteColumn(dbType, field))
 				sb.WriteString(")")
 			}
 		} else if _, ok := field.Options["notnull"]; ok {
@@ -1602,16 +1989,15 @@ func generateWhere(dbType Dialect, mapper *Mapper, rType reflect.Type, names []s
 				sb.WriteString(`AND `)
 			}
 
-			sb.WriteString(field.Name)
 			if isLike {
-				sb.WriteString(" like ")
-				sb.WriteString("<like value=\"")
-				sb.WriteString(name)
-				sb.WriteString("\" /> ")
+				sb.WriteString(whereOpExpr(dbType, field, name, op))
+				sb.WriteString(" ")
 			} else {
+				sb.WriteString(quoteColumn(dbType, field))
 				sb.WriteString("=")
 				sb.WriteString("#{")
 				sb.WriteString(name)
+				sb.WriteString(encodingOption(field))
 				sb.WriteString("} ")
 			}
 
@@ -1632,11 +2018,8 @@ func generateWhere(dbType Dialect, mapper *Mapper, rType reflect.Type, names []s
 					sb.WriteString(` AND `)
 				}
 
-				sb.WriteString(field.Name)
-				sb.WriteString(" like ")
-				sb.WriteString("<like value=\"")
-				sb.WriteString(name)
-				sb.WriteString("\" /> ")
+				sb.WriteString(whereOpExpr(dbType, field, name, op))
+				sb.WriteString(" ")
 
 				if needANDExprSuffix(idx) {
 					sb.WriteString(`AND `)
@@ -1651,10 +2034,11 @@ func generateWhere(dbType Dialect, mapper *Mapper, rType reflect.Type, names []s
 					sb.WriteString(` AND `)
 				}
 
-				sb.WriteString(field.Name)
+				sb.WriteString(quoteColumn(dbType, field))
 				sb.WriteString("=")
 				sb.WriteString("#{")
 				sb.WriteString(name)
+				sb.WriteString(encodingOption(field))
 				sb.WriteString("}")
 			}
 		}
@@ -1699,8 +2083,8 @@ func generateWhere(dbType Dialect, mapper *Mapper, rType reflect.Type, names []s
 					sb.WriteString(`AND `)
 				}
 
-				sb.WriteString(deletedField.Name)
-				sb.WriteString(` IS NOT NULL </if>`)
+				sb.WriteString(deletedCondition(dbType, deletedField, true))
+				sb.WriteString(` </if>`)
 
 				sb.WriteString(`<if test="!`)
 				sb.WriteString(names[forceIndex])
@@ -1716,9 +2100,8 @@ func generateWhere(dbType Dialect, mapper *Mapper, rType reflect.Type, names []s
 					sb.WriteString(`AND `)
 				}
 
-				sb.WriteString(deletedField.Name)
-				sb.WriteString(` IS NULL `)
-				sb.WriteString(`</if>`)
+				sb.WriteString(deletedCondition(dbType, deletedField, false))
+				sb.WriteString(` </if>`)
 
 				if validable {
 					sb.WriteString(`</if>`)
@@ -1731,12 +2114,17 @@ func generateWhere(dbType Dialect, mapper *Mapper, rType reflect.Type, names []s
 				} else {
 					sb.WriteString(` AND `)
 				}
-				sb.WriteString(deletedField.Name)
-				sb.WriteString(" IS NULL")
+				sb.WriteString(deletedFilter(dbType, mapper, deletedField))
 			}
 		}
 	}
 
+	for idx := range queryNames {
+		if err := generateQueryWhere(dbType, structType, queryNames[idx], queryTypes[idx], sb); err != nil {
+			return err
+		}
+	}
+
 	if needWhereTag {
 		sb.WriteString("</where>")
 	}
@@ -1753,6 +2141,9 @@ func searchNameIndexs(exprs, names []string) ([]int, error) {
 
 		if len(args) > 0 {
 			for _, param := range args {
+				if param.Tenant {
+					continue
+				}
 				foundIndex := -1
 				for nameidx, nm := range names {
 					if nm == param.Name {
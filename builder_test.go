@@ -2,6 +2,7 @@ package gobatis_test
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 	"reflect"
 	"strings"
@@ -202,6 +203,58 @@ type T18 struct {
 	F1        string   `db:"f1"`
 }
 
+type T19 struct {
+	TableName struct{} `db:"user"`
+	ID        int64    `db:"id,autoincr,pk"`
+	Order     int      `db:"order"`
+	Name      string   `db:"Name,quote"`
+}
+
+type T20 struct {
+	TableName struct{} `db:"t20"`
+	ID        int64    `db:"id,autoincr,pk"`
+	Phone     string   `db:"phone,encrypt,blindindex=phone_bidx"`
+	Email     *string  `db:"email,encrypt"`
+}
+
+type T21 struct {
+	TableName struct{} `db:"t21"`
+	ID        int64    `db:"id,autoincr,pk"`
+	Name      string   `db:"name,unique"`
+	Version   int      `db:"version,version"`
+}
+
+type T22 struct {
+	TableName struct{} `db:"t22"`
+	ID        int64    `db:"id,autoincr,pk"`
+	TenantID  int64    `db:"tenant_id,tenant"`
+	Name      string   `db:"name"`
+}
+
+type T23 struct {
+	TableName struct{} `db:"t23"`
+	ID        int64    `db:"id,autoincr,pk"`
+	Name      string   `db:"name,unique"`
+	CreatedBy string   `db:"created_by,creator"`
+	UpdatedBy string   `db:"updated_by,updater"`
+}
+
+type T25 struct {
+	TableName struct{} `db:"t25"`
+	ID        int64    `db:"id,autoincr,pk"`
+	Name      string   `db:"name"`
+	IsDeleted bool     `db:"is_deleted,deleted"`
+}
+
+type T26 struct {
+	TableName struct{} `db:"t26"`
+	ID        int64    `db:"id,autoincr,pk"`
+	Name      string   `db:"name"`
+	Age       int      `db:"age"`
+	Status    int      `db:"status,notnull"`
+	Secret    string   `db:"secret,encrypt"`
+}
+
 var (
 	_stringType = reflect.TypeOf(new(string)).Elem()
 	_intType    = reflect.TypeOf(new(int)).Elem()
@@ -275,6 +328,8 @@ func TestGenerateUpsertSQL(t *testing.T) {
 		{dbType: gobatis.DbTypePostgres, value: T16{}, sql: "INSERT INTO t16_table(f1, f2, f3, created_at, updated_at) VALUES(#{f1}, #{f2}, #{f3}, now(), now()) ON CONFLICT (f1) DO UPDATE SET f2=EXCLUDED.f2, f3=EXCLUDED.f3, updated_at=EXCLUDED.updated_at RETURNING id"},
 		{dbType: gobatis.DbTypeMysql, value: T16{}, sql: "INSERT INTO t16_table(f1, f2, f3, created_at, updated_at) VALUES(#{f1}, #{f2}, #{f3}, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP) ON DUPLICATE KEY UPDATE f2=VALUES(f2), f3=VALUES(f3), updated_at=VALUES(updated_at)"},
 		{dbType: gobatis.DbTypeMSSql, value: T16{}, sql: `MERGE INTO t16_table AS t USING ( VALUES(#{f1}, #{f2}, #{f3}, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP ) ) AS s (f1, f2, f3, created_at, updated_at ) ON t.f1 = s.f1 WHEN MATCHED THEN UPDATE SET f2 = s.f2, f3 = s.f3, updated_at = s.updated_at WHEN NOT MATCHED THEN INSERT (f1, f2, f3, created_at, updated_at) VALUES(s.f1, s.f2, s.f3, s.created_at, s.updated_at)  OUTPUT inserted.id;`},
+		{dbType: gobatis.DbTypeSqlite, value: T16{}, sql: "INSERT INTO t16_table(f1, f2, f3, created_at, updated_at) VALUES(#{f1}, #{f2}, #{f3}, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP) ON CONFLICT (f1) DO UPDATE SET f2=EXCLUDED.f2, f3=EXCLUDED.f3, updated_at=EXCLUDED.updated_at RETURNING id"},
+		{dbType: gobatis.DbTypeKingbase, value: T16{}, sql: "INSERT INTO t16_table(f1, f2, f3, created_at, updated_at) VALUES(#{f1}, #{f2}, #{f3}, now(), now()) ON CONFLICT (f1) DO UPDATE SET f2=EXCLUDED.f2, f3=EXCLUDED.f3, updated_at=EXCLUDED.updated_at RETURNING id"},
 		{dbType: gobatis.DbTypePostgres, value: T18{}, sql: "INSERT INTO t18_table(id, f1) VALUES(#{id}, #{f1}) ON CONFLICT (id) DO UPDATE SET f1=EXCLUDED.f1 RETURNING id", IncrField: true},
 		{dbType: gobatis.DbTypePostgres, value: T17{}, sql: "INSERT INTO t17_table(f1) VALUES(#{f1}) ON CONFLICT (f1) DO NOTHING  RETURNING id"},
 		// {dbType: gobatis.DbTypeMysql, value: T17{}, sql: "INSERT INTO t17_table(f1) VALUES(#{f1}) ON DUPLICATE KEY UPDATE "},
@@ -820,6 +875,18 @@ func TestGenerateSelectSQL(t *testing.T) {
 			argTypes: []reflect.Type{reflect.TypeOf(new(int64)).Elem(), reflect.TypeOf(new(int64)).Elem()},
 			sql:      `SELECT * FROM t1_table <if test="offset &gt; 0"> OFFSET #{offset} </if> <if test="limit &gt; 0"> LIMIT #{limit} </if>`},
 
+		{dbType: gobatis.DbTypeSqlite,
+			value:    T1ForNoDeleted{},
+			names:    []string{"offset", "limit"},
+			argTypes: []reflect.Type{reflect.TypeOf(new(int64)).Elem(), reflect.TypeOf(new(int64)).Elem()},
+			sql:      `SELECT * FROM t1_table <pagination offset="offset" limit="limit" />`},
+
+		{dbType: gobatis.DbTypeSqlite,
+			value:    T1ForNoDeleted{},
+			names:    []string{"offset"},
+			argTypes: []reflect.Type{reflect.TypeOf(new(int64)).Elem()},
+			sql:      `SELECT * FROM t1_table <if test="offset &gt; 0"> LIMIT -1 OFFSET #{offset} </if>`},
+
 		{dbType: gobatis.DbTypePostgres,
 			value:    T13{},
 			names:    []string{"f1", "f2"},
@@ -830,7 +897,7 @@ func TestGenerateSelectSQL(t *testing.T) {
 			names:    []string{"sortBy"},
 			argTypes: []reflect.Type{_stringType},
 			filters:  []gobatis.Filter{{Expression: "f1 = #{f1}"}},
-			sql:      "SELECT * FROM t1_table WHERE deleted_at IS NULL AND f1 = #{f1} <order_by by=\"sortBy\"/>"},
+			sql:      "SELECT * FROM t1_table WHERE deleted_at IS NULL AND f1 = #{f1} <order_by by=\"sortBy\" allowed=\"id,f1,f2,f3,f4,created_at,updated_at,deleted_at\"/>"},
 
 		{dbType: gobatis.DbTypePostgres, value: &T1{},
 			filters: []gobatis.Filter{{Expression: "f1 = #{f1}"}},
@@ -862,6 +929,78 @@ func TestGenerateSelectSQL(t *testing.T) {
 	}
 }
 
+func TestSortableColumns(t *testing.T) {
+	old := gobatis.IdentifierQuotePolicy
+	gobatis.IdentifierQuotePolicy = gobatis.QuoteReservedWords
+	defer func() {
+		gobatis.IdentifierQuotePolicy = old
+	}()
+
+	rType := reflect.TypeOf(&T19{})
+	columns := gobatis.SortableColumns(gobatis.DbTypePostgres, mapper, rType)
+	excepted := []string{"id", `"order"`, `"Name"`}
+	if !reflect.DeepEqual(columns, excepted) {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", columns)
+	}
+
+	for idx, test := range []struct {
+		sort     string
+		allowed  []string
+		excepted string
+	}{
+		{sort: "-order,name", allowed: columns, excepted: `"order" DESC, "Name"`},
+		{sort: "-order,name", allowed: []string{"order", "name"}, excepted: `"order" DESC, name`},
+		{sort: "user.order", excepted: `"user"."order"`},
+	} {
+		actual, err := gobatis.ToOrderBy(gobatis.DbTypePostgres, test.sort, test.allowed)
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		if actual != test.excepted {
+			t.Error("[", idx, "] excepted is", test.excepted)
+			t.Error("[", idx, "] actual   is", actual)
+		}
+	}
+
+	sqlStr, err := gobatis.GenerateSelectSQL(gobatis.DbTypePostgres, mapper, rType, []string{"sortBy"}, []reflect.Type{_stringType}, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	exceptedSQL := `SELECT * FROM "user" <order_by by="sortBy" allowed="id,&quot;order&quot;,&quot;Name&quot;"/>`
+	if sqlStr != exceptedSQL {
+		t.Error("excepted is", exceptedSQL)
+		t.Error("actual   is", sqlStr)
+	}
+
+	initCtx := &gobatis.InitContext{Config: &gobatis.Config{},
+		Dialect:    gobatis.DbTypePostgres,
+		Mapper:     mapper,
+		Statements: make(map[string]*gobatis.MappedStatement)}
+	stmt, err := gobatis.NewMapppedStatement(initCtx, "sortable", gobatis.StatementTypeSelect, gobatis.ResultStruct, sqlStr)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	ctx, err := gobatis.NewContext(nil, gobatis.DbTypePostgres, mapper, []string{"sortBy"}, []interface{}{"-order"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	sqlParams, err := stmt.GenerateSQLs(ctx)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	exceptedSQL = `SELECT * FROM "user"  ORDER BY "order" DESC`
+	if sqlParams[0].SQL != exceptedSQL {
+		t.Error("excepted is", exceptedSQL)
+		t.Error("actual   is", sqlParams[0].SQL)
+	}
+}
+
 func TestGenerateCountSQL(t *testing.T) {
 	for idx, test := range []struct {
 		id       string
@@ -1131,3 +1270,502 @@ func TestIsValueRange(t *testing.T) {
 		return
 	}
 }
+
+func TestGenerateQuotedSQL(t *testing.T) {
+	for idx, test := range []struct {
+		dbType   gobatis.Dialect
+		policy   gobatis.QuotePolicy
+		stmtType string
+		names    []string
+		argTypes []reflect.Type
+		sql      string
+	}{
+		{dbType: gobatis.DbTypePostgres, policy: gobatis.QuoteReservedWords, stmtType: "insert", sql: `INSERT INTO "user"("order", "Name") VALUES(#{order}, #{Name}) RETURNING id`},
+		{dbType: gobatis.DbTypeMysql, policy: gobatis.QuoteReservedWords, stmtType: "insert", sql: "INSERT INTO `user`(`order`, `Name`) VALUES(#{order}, #{Name})"},
+		{dbType: gobatis.DbTypeMSSql, policy: gobatis.QuoteReservedWords, stmtType: "insert", sql: `INSERT INTO [user]([order], [Name]) OUTPUT inserted.id VALUES(#{order}, #{Name})`},
+		{dbType: gobatis.DbTypeKingbase, policy: gobatis.QuoteReservedWords, stmtType: "insert", sql: `INSERT INTO "user"("order", "Name") VALUES(#{order}, #{Name}) RETURNING id`},
+		{dbType: gobatis.DbTypeOracle, policy: gobatis.QuoteReservedWords, stmtType: "insert", sql: `INSERT INTO "USER"("ORDER", "Name") VALUES(#{order}, #{Name})`},
+		{dbType: gobatis.DbTypePostgres, policy: gobatis.QuoteAlways, stmtType: "insert", sql: `INSERT INTO "user"("order", "Name") VALUES(#{order}, #{Name}) RETURNING "id"`},
+		{dbType: gobatis.DbTypePostgres, policy: gobatis.QuoteNever, stmtType: "insert", sql: `INSERT INTO user(order, "Name") VALUES(#{order}, #{Name}) RETURNING id`},
+		{dbType: gobatis.DbTypePostgres, stmtType: "insert", sql: `INSERT INTO user(order, "Name") VALUES(#{order}, #{Name}) RETURNING id`},
+		{dbType: gobatis.DbTypePostgres, policy: gobatis.QuoteReservedWords, stmtType: "update", sql: `UPDATE "user" SET "order"=#{order}, "Name"=#{Name} WHERE id=#{id}`},
+		{dbType: gobatis.DbTypeMysql, policy: gobatis.QuoteReservedWords, stmtType: "select", names: []string{"order", "name"}, argTypes: []reflect.Type{_intType, _stringType},
+			sql: "SELECT * FROM `user` WHERE `order`=#{order} AND `Name`=#{name}"},
+		{dbType: gobatis.DbTypePostgres, policy: gobatis.QuoteReservedWords, stmtType: "count", names: []string{"order"}, argTypes: []reflect.Type{_intType},
+			sql: `SELECT count(*) FROM "user" WHERE "order"=#{order}`},
+		{dbType: gobatis.DbTypeMSSql, policy: gobatis.QuoteReservedWords, stmtType: "delete", names: []string{"order"}, argTypes: []reflect.Type{_intType},
+			sql: `DELETE FROM [user] WHERE [order]=#{order}`},
+	} {
+		old := gobatis.IdentifierQuotePolicy
+		gobatis.IdentifierQuotePolicy = test.policy
+
+		var actaul string
+		var err error
+		rType := reflect.TypeOf(&T19{})
+		switch test.stmtType {
+		case "insert":
+			actaul, err = gobatis.GenerateInsertSQL(test.dbType, mapper, rType, test.names, test.argTypes, false)
+		case "update":
+			actaul, err = gobatis.GenerateUpdateSQL(test.dbType, mapper, "", rType, test.names, test.argTypes)
+		case "delete":
+			actaul, err = gobatis.GenerateDeleteSQL(test.dbType, mapper, rType, test.names, test.argTypes, nil)
+		case "count":
+			actaul, err = gobatis.GenerateCountSQL(test.dbType, mapper, rType, test.names, test.argTypes, nil)
+		default:
+			actaul, err = gobatis.GenerateSelectSQL(test.dbType, mapper, rType, test.names, test.argTypes, nil)
+		}
+		gobatis.IdentifierQuotePolicy = old
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+
+		if actaul != test.sql {
+			t.Error("[", idx, "] excepted is", test.sql, "|")
+			t.Error("[", idx, "] actual   is", actaul, "|")
+		}
+	}
+
+	for idx, test := range []struct {
+		dbType   gobatis.Dialect
+		ident    string
+		excepted string
+	}{
+		{dbType: gobatis.DbTypePostgres, ident: `a"b`, excepted: `"a""b"`},
+		{dbType: gobatis.DbTypeMysql, ident: "a`b", excepted: "`a``b`"},
+		{dbType: gobatis.DbTypeMSSql, ident: "a]b", excepted: "[a]]b]"},
+		{dbType: gobatis.DbTypeDameng, ident: "a", excepted: `"a"`},
+	} {
+		if actual := test.dbType.Quote(test.ident); actual != test.excepted {
+			t.Error("[", idx, "] excepted is", test.excepted)
+			t.Error("[", idx, "] actual   is", actual)
+		}
+	}
+}
+
+type deterministicCipher struct{}
+
+func (deterministicCipher) Encrypt(plaintext []byte) ([]byte, error)  { return plaintext, nil }
+func (deterministicCipher) Decrypt(ciphertext []byte) ([]byte, error) { return ciphertext, nil }
+func (deterministicCipher) Deterministic() bool                       { return true }
+func (deterministicCipher) BlindIndex(plaintext []byte) ([]byte, error) {
+	return nil, errors.New("unsupported")
+}
+
+func TestGenerateEncryptSQL(t *testing.T) {
+	deterministicMapper := gobatis.CreateMapper("", nil, nil)
+	deterministicMapper.SetCipher(deterministicCipher{})
+
+	for idx, test := range []struct {
+		mapper   *gobatis.Mapper
+		stmtType string
+		names    []string
+		argTypes []reflect.Type
+		sql      string
+		err      string
+	}{
+		{stmtType: "insert", sql: `INSERT INTO t20(phone, phone_bidx, email) VALUES(#{phone,encrypt}, #{phone,blindindex}, #{email,encrypt}) RETURNING id`},
+		{stmtType: "insert", names: []string{"phone", "email"}, argTypes: []reflect.Type{_stringType, _stringType},
+			sql: `INSERT INTO t20(phone, phone_bidx, email) VALUES(#{phone,encrypt}, #{phone,blindindex}, #{email,encrypt}) RETURNING id`},
+		{stmtType: "update", sql: `UPDATE t20 SET phone=#{phone,encrypt}, phone_bidx=#{phone,blindindex}, email=#{email,encrypt} WHERE id=#{id}`},
+		{stmtType: "select", names: []string{"phone"}, argTypes: []reflect.Type{_stringType},
+			sql: `SELECT * FROM t20 WHERE phone_bidx=#{phone,blindindex}`},
+		{stmtType: "select", names: []string{"phone"}, argTypes: []reflect.Type{reflect.TypeOf([]string{})},
+			sql: `SELECT * FROM t20 WHERE phone_bidx in (<foreach collection="phone" item="item" separator="," >#{item,blindindex}</foreach>)`},
+		{stmtType: "delete", names: []string{"phone"}, argTypes: []reflect.Type{_stringType},
+			sql: `DELETE FROM t20 WHERE phone_bidx=#{phone,blindindex}`},
+		{stmtType: "select", names: []string{"email"}, argTypes: []reflect.Type{_stringType},
+			err: "without a blindindex column"},
+		{mapper: deterministicMapper, stmtType: "select", names: []string{"email"}, argTypes: []reflect.Type{_stringType},
+			sql: `SELECT * FROM t20 WHERE email=#{email,encrypt}`},
+		{stmtType: "select", names: []string{"phoneLike"}, argTypes: []reflect.Type{_stringType},
+			err: "only equality lookup is supported"},
+		{stmtType: "upsert", names: []string{"id"}, err: "unsupported in upsert"},
+	} {
+		m := test.mapper
+		if m == nil {
+			m = mapper
+		}
+
+		var actaul string
+		var err error
+		rType := reflect.TypeOf(&T20{})
+		switch test.stmtType {
+		case "insert":
+			actaul, err = gobatis.GenerateInsertSQL(gobatis.DbTypePostgres, m, rType, test.names, test.argTypes, false)
+		case "update":
+			actaul, err = gobatis.GenerateUpdateSQL(gobatis.DbTypePostgres, m, "", rType, test.names, test.argTypes)
+		case "delete":
+			actaul, err = gobatis.GenerateDeleteSQL(gobatis.DbTypePostgres, m, rType, test.names, test.argTypes, nil)
+		case "upsert":
+			actaul, err = gobatis.GenerateUpsertSQL(gobatis.DbTypePostgres, m, rType, test.names, nil, nil, false)
+		default:
+			actaul, err = gobatis.GenerateSelectSQL(gobatis.DbTypePostgres, m, rType, test.names, test.argTypes, nil)
+		}
+		if test.err != "" {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Error("[", idx, "] excepted is", test.err)
+				t.Error("[", idx, "] actual   is", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+
+		if actaul != test.sql {
+			t.Error("[", idx, "] excepted is", test.sql, "|")
+			t.Error("[", idx, "] actual   is", actaul, "|")
+		}
+	}
+}
+
+func TestGenerateVersionSQL(t *testing.T) {
+	_int64Type := reflect.TypeOf(int64(0))
+	for idx, test := range []struct {
+		dbType   gobatis.Dialect
+		stmtType string
+		names    []string
+		argTypes []reflect.Type
+		keys     []string
+		sql      string
+	}{
+		{dbType: gobatis.DbTypePostgres, stmtType: "update",
+			sql: `UPDATE t21 SET name=#{r.name}, version=version+1 WHERE id=#{r.id} AND version=#{r.version,version}`},
+		{dbType: gobatis.DbTypePostgres, stmtType: "update", names: []string{"name"}, argTypes: []reflect.Type{_stringType},
+			sql: `UPDATE t21 SET version=version+1 WHERE name=#{name} AND version=#{r.version,version}`},
+		{dbType: gobatis.DbTypePostgres, stmtType: "update2", names: []string{"name", "version"},
+			sql: `UPDATE t21 SET name=#{name}, version=version+1 WHERE id=#{id} AND version=#{version,version}`},
+		{dbType: gobatis.DbTypePostgres, stmtType: "update2", names: []string{"name"},
+			sql: `UPDATE t21 SET name=#{name}, version=version+1 WHERE id=#{id}`},
+		{dbType: gobatis.DbTypePostgres, stmtType: "upsert",
+			sql: `INSERT INTO t21(name, version) VALUES(#{name}, #{version,version}+1) ON CONFLICT (name) DO UPDATE SET version=t21.version+1 WHERE t21.version=EXCLUDED.version-1 RETURNING id`},
+		{dbType: gobatis.DbTypeMysql, stmtType: "upsert",
+			sql: `INSERT INTO t21(name, version) VALUES(#{name}, #{version,version}+1) ON DUPLICATE KEY UPDATE version=IF(version=VALUES(version)-1, version+1, version)`},
+		{dbType: gobatis.DbTypeMysql, stmtType: "upsert", keys: []string{"id"},
+			sql: `INSERT INTO t21(id, name, version) VALUES(#{id}, #{name}, #{version,version}+1) ON DUPLICATE KEY UPDATE name=IF(version=VALUES(version)-1, VALUES(name), name), version=IF(version=VALUES(version)-1, version+1, version)`},
+		{dbType: gobatis.DbTypeMSSql, stmtType: "upsert",
+			sql: `MERGE INTO t21 AS t USING ( VALUES(#{name}, #{version,version}+1 ) ) AS s (name, version ) ON t.name = s.name WHEN MATCHED AND t.version = s.version - 1 THEN UPDATE SET version=t.version+1 WHEN NOT MATCHED THEN INSERT (name, version) VALUES(s.name, s.version)  OUTPUT inserted.id;`},
+	} {
+		var actaul string
+		var err error
+		rType := reflect.TypeOf(&T21{})
+		switch test.stmtType {
+		case "update":
+			actaul, err = gobatis.GenerateUpdateSQL(test.dbType, mapper, "r.", rType, test.names, test.argTypes)
+		case "update2":
+			actaul, err = gobatis.GenerateUpdateSQL2(test.dbType, mapper, rType, _int64Type, "id", test.names)
+		case "upsert":
+			actaul, err = gobatis.GenerateUpsertSQL(test.dbType, mapper, rType, test.keys, nil, nil, false)
+		}
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+
+		if actaul != test.sql {
+			t.Error("[", idx, "] excepted is", test.sql, "|")
+			t.Error("[", idx, "] actual   is", actaul, "|")
+		}
+	}
+}
+
+func TestGenerateTenantSQL(t *testing.T) {
+	_int64Type := reflect.TypeOf(int64(0))
+	for idx, test := range []struct {
+		dbType   gobatis.Dialect
+		mapper   *gobatis.Mapper
+		stmtType string
+		names    []string
+		argTypes []reflect.Type
+		sql      string
+	}{
+		{stmtType: "insert", names: []string{"r"}, argTypes: []reflect.Type{reflect.TypeOf(&T22{})},
+			sql: `INSERT INTO t22(tenant_id, name) VALUES(#{tenant_id,tenant}, #{name}) RETURNING id`},
+		{stmtType: "insert", names: []string{"name"}, argTypes: []reflect.Type{_stringType},
+			sql: `INSERT INTO t22(tenant_id, name) VALUES(#{tenant_id,tenant}, #{name}) RETURNING id`},
+		{stmtType: "update",
+			sql: `UPDATE t22 SET name=#{r.name} WHERE id=#{r.id} AND tenant_id=#{tenant_id,tenant}`},
+		{stmtType: "update2", names: []string{"name"},
+			sql: `UPDATE t22 SET name=#{name} WHERE id=#{id} AND tenant_id=#{tenant_id,tenant}`},
+		{stmtType: "select",
+			sql: `SELECT * FROM t22 WHERE tenant_id=#{tenant_id,tenant}`},
+		{stmtType: "select", names: []string{"name"}, argTypes: []reflect.Type{_stringType},
+			sql: `SELECT * FROM t22 WHERE name=#{name} AND tenant_id=#{tenant_id,tenant}`},
+		{stmtType: "select", names: []string{"id"}, argTypes: []reflect.Type{reflect.TypeOf(sql.NullInt64{})},
+			sql: `SELECT * FROM t22 <where><if test="id.Valid"> id=#{id} AND </if>tenant_id=#{tenant_id,tenant}</where>`},
+		{stmtType: "count",
+			sql: `SELECT count(*) FROM t22 WHERE tenant_id=#{tenant_id,tenant}`},
+		{stmtType: "delete", names: []string{"id"}, argTypes: []reflect.Type{_int64Type},
+			sql: `DELETE FROM t22 WHERE id=#{id} AND tenant_id=#{tenant_id,tenant}`},
+		{stmtType: "delete",
+			sql: `DELETE FROM t22 WHERE tenant_id=#{tenant_id,tenant}`},
+		{stmtType: "upsert", names: []string{"name"},
+			sql: `INSERT INTO t22(tenant_id, name) VALUES(#{tenant_id,tenant}, #{r.name}) ON CONFLICT (tenant_id, name) DO NOTHING  RETURNING id`},
+		{mapper: mapper.WithoutTenant(), stmtType: "select",
+			sql: `SELECT * FROM t22`},
+		{mapper: mapper.WithoutTenant(), stmtType: "insert", names: []string{"r"}, argTypes: []reflect.Type{reflect.TypeOf(&T22{})},
+			sql: `INSERT INTO t22(tenant_id, name) VALUES(#{tenant_id}, #{name}) RETURNING id`},
+	} {
+		m := test.mapper
+		if m == nil {
+			m = mapper
+		}
+		dbType := test.dbType
+		if dbType == nil {
+			dbType = gobatis.DbTypePostgres
+		}
+
+		var actaul string
+		var err error
+		rType := reflect.TypeOf(&T22{})
+		switch test.stmtType {
+		case "insert":
+			actaul, err = gobatis.GenerateInsertSQL(dbType, m, rType, test.names, test.argTypes, false)
+		case "update":
+			actaul, err = gobatis.GenerateUpdateSQL(dbType, m, "r.", rType, test.names, test.argTypes)
+		case "update2":
+			actaul, err = gobatis.GenerateUpdateSQL2(dbType, m, rType, _int64Type, "id", test.names)
+		case "count":
+			actaul, err = gobatis.GenerateCountSQL(dbType, m, rType, test.names, test.argTypes, nil)
+		case "delete":
+			actaul, err = gobatis.GenerateDeleteSQL(dbType, m, rType, test.names, test.argTypes, nil)
+		case "upsert":
+			actaul, err = gobatis.GenerateUpsertSQLForStruct(dbType, m, rType, []string{"tenant_id", "name"}, "r.", false)
+		default:
+			actaul, err = gobatis.GenerateSelectSQL(dbType, m, rType, test.names, test.argTypes, nil)
+		}
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+
+		if actaul != test.sql {
+			t.Error("[", idx, "] excepted is", test.sql, "|")
+			t.Error("[", idx, "] actual   is", actaul, "|")
+		}
+	}
+}
+
+func TestGenerateOperatorSQL(t *testing.T) {
+	_int64Type := reflect.TypeOf(int64(0))
+	for idx, test := range []struct {
+		dbType   gobatis.Dialect
+		stmtType string
+		names    []string
+		argTypes []reflect.Type
+		sql      string
+	}{
+		{stmtType: "insert", names: []string{"r"}, argTypes: []reflect.Type{reflect.TypeOf(&T23{})},
+			sql: `INSERT INTO t23(name, created_by, updated_by) VALUES(#{name}, #{created_by,creator}, #{updated_by,updater}) RETURNING id`},
+		{stmtType: "insert", names: []string{"name"}, argTypes: []reflect.Type{_stringType},
+			sql: `INSERT INTO t23(name, created_by, updated_by) VALUES(#{name}, #{created_by,creator}, #{updated_by,updater}) RETURNING id`},
+		{stmtType: "insert", names: []string{"name", "created_by"}, argTypes: []reflect.Type{_stringType, _stringType},
+			sql: `INSERT INTO t23(name, created_by, updated_by) VALUES(#{name}, #{created_by,creator}, #{updated_by,updater}) RETURNING id`},
+		{stmtType: "update",
+			sql: `UPDATE t23 SET name=#{r.name}, updated_by=#{updated_by,updater} WHERE id=#{r.id}`},
+		{stmtType: "update2", names: []string{"name"},
+			sql: `UPDATE t23 SET name=#{name}, updated_by=#{updated_by,updater} WHERE id=#{id}`},
+		{stmtType: "upsert",
+			sql: `INSERT INTO t23(name, created_by, updated_by) VALUES(#{r.name}, #{created_by,creator}, #{updated_by,updater}) ON CONFLICT (name) DO UPDATE SET updated_by=EXCLUDED.updated_by RETURNING id`},
+		{dbType: gobatis.DbTypeMysql, stmtType: "upsert",
+			sql: "INSERT INTO t23(name, created_by, updated_by) VALUES(#{r.name}, #{created_by,creator}, #{updated_by,updater}) ON DUPLICATE KEY UPDATE updated_by=VALUES(updated_by)"},
+		{stmtType: "upsert2", names: []string{"name"}, argTypes: []reflect.Type{_stringType},
+			sql: `INSERT INTO t23(name, created_by, updated_by) VALUES(#{name}, #{created_by,creator}, #{updated_by,updater}) ON CONFLICT (name) DO UPDATE SET updated_by=EXCLUDED.updated_by RETURNING id`},
+	} {
+		dbType := test.dbType
+		if dbType == nil {
+			dbType = gobatis.DbTypePostgres
+		}
+
+		var actaul string
+		var err error
+		rType := reflect.TypeOf(&T23{})
+		switch test.stmtType {
+		case "insert":
+			actaul, err = gobatis.GenerateInsertSQL(dbType, mapper, rType, test.names, test.argTypes, false)
+		case "update":
+			actaul, err = gobatis.GenerateUpdateSQL(dbType, mapper, "r.", rType, test.names, test.argTypes)
+		case "update2":
+			actaul, err = gobatis.GenerateUpdateSQL2(dbType, mapper, rType, _int64Type, "id", test.names)
+		case "upsert":
+			actaul, err = gobatis.GenerateUpsertSQLForStruct(dbType, mapper, rType, []string{"name"}, "r.", false)
+		case "upsert2":
+			actaul, err = gobatis.GenerateUpsertSQL(dbType, mapper, rType, []string{"name"}, append(test.names, "id"), append(test.argTypes, _int64Type), false)
+		}
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+
+		if actaul != test.sql {
+			t.Error("[", idx, "] excepted is", test.sql, "|")
+			t.Error("[", idx, "] actual   is", actaul, "|")
+		}
+	}
+}
+
+func TestGenerateSoftDeleteSQL(t *testing.T) {
+	_int64Type := reflect.TypeOf(int64(0))
+	for idx, test := range []struct {
+		dbType   gobatis.Dialect
+		mapper   *gobatis.Mapper
+		rType    reflect.Type
+		stmtType string
+		names    []string
+		argTypes []reflect.Type
+		sql      string
+	}{
+		{mapper: mapper.WithDeleted(), rType: reflect.TypeOf(&T1{}), stmtType: "select",
+			sql: `SELECT * FROM t1_table`},
+		{mapper: mapper.WithDeleted(), rType: reflect.TypeOf(&T1{}), stmtType: "select", names: []string{"f1"}, argTypes: []reflect.Type{_stringType},
+			sql: `SELECT * FROM t1_table WHERE f1=#{f1}`},
+		{mapper: mapper.OnlyDeleted(), rType: reflect.TypeOf(&T1{}), stmtType: "select",
+			sql: `SELECT * FROM t1_table WHERE deleted_at IS NOT NULL`},
+		{mapper: mapper.OnlyDeleted(), rType: reflect.TypeOf(&T1{}), stmtType: "select", names: []string{"f1"}, argTypes: []reflect.Type{_stringType},
+			sql: `SELECT * FROM t1_table WHERE f1=#{f1} AND deleted_at IS NOT NULL`},
+		{mapper: mapper.OnlyDeleted(), rType: reflect.TypeOf(&T1{}), stmtType: "count",
+			sql: `SELECT count(*) FROM t1_table WHERE deleted_at IS NOT NULL`},
+		{rType: reflect.TypeOf(&T1{}), stmtType: "restore", names: []string{"id"}, argTypes: []reflect.Type{_stringType},
+			sql: `UPDATE t1_table SET deleted_at=NULL WHERE id=#{id} AND deleted_at IS NOT NULL`},
+		{rType: reflect.TypeOf(&T1{}), stmtType: "restore",
+			sql: `UPDATE t1_table SET deleted_at=NULL WHERE deleted_at IS NOT NULL`},
+
+		{rType: reflect.TypeOf(&T25{}), stmtType: "select",
+			sql: `SELECT * FROM t25 WHERE is_deleted=false`},
+		{dbType: gobatis.DbTypeMysql, rType: reflect.TypeOf(&T25{}), stmtType: "select", names: []string{"name"}, argTypes: []reflect.Type{_stringType},
+			sql: "SELECT * FROM t25 WHERE name=#{name} AND is_deleted=0"},
+		{mapper: mapper.OnlyDeleted(), rType: reflect.TypeOf(&T25{}), stmtType: "count",
+			sql: `SELECT count(*) FROM t25 WHERE is_deleted=true`},
+		{rType: reflect.TypeOf(&T25{}), stmtType: "delete", names: []string{"id"}, argTypes: []reflect.Type{_int64Type},
+			sql: `UPDATE t25 SET is_deleted=true  WHERE id=#{id}`},
+		{rType: reflect.TypeOf(&T25{}), stmtType: "delete", names: []string{"id", "force"}, argTypes: []reflect.Type{_int64Type, reflect.TypeOf(true)},
+			sql: `<if test="!force">UPDATE t25 SET is_deleted=true  WHERE id=#{id}</if><if test="force">DELETE FROM t25 WHERE id=#{id}</if>`},
+		{rType: reflect.TypeOf(&T25{}), stmtType: "select", names: []string{"name", "isDeleted"}, argTypes: []reflect.Type{_stringType, reflect.TypeOf(true)},
+			sql: `SELECT * FROM t25 WHERE name=#{name}<if test="isDeleted"> AND is_deleted=true </if><if test="!isDeleted"> AND is_deleted=false </if>`},
+		{rType: reflect.TypeOf(&T25{}), stmtType: "restore", names: []string{"id"}, argTypes: []reflect.Type{_int64Type},
+			sql: `UPDATE t25 SET is_deleted=false WHERE id=#{id} AND is_deleted=true`},
+	} {
+		m := test.mapper
+		if m == nil {
+			m = mapper
+		}
+		dbType := test.dbType
+		if dbType == nil {
+			dbType = gobatis.DbTypePostgres
+		}
+
+		var actaul string
+		var err error
+		switch test.stmtType {
+		case "count":
+			actaul, err = gobatis.GenerateCountSQL(dbType, m, test.rType, test.names, test.argTypes, nil)
+		case "delete":
+			actaul, err = gobatis.GenerateDeleteSQL(dbType, m, test.rType, test.names, test.argTypes, nil)
+		case "restore":
+			actaul, err = gobatis.GenerateRestoreSQL(dbType, m, test.rType, test.names, test.argTypes, nil)
+		default:
+			actaul, err = gobatis.GenerateSelectSQL(dbType, m, test.rType, test.names, test.argTypes, nil)
+		}
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+
+		if actaul != test.sql {
+			t.Error("[", idx, "] excepted is", test.sql, "|")
+			t.Error("[", idx, "] actual   is", actaul, "|")
+		}
+	}
+
+	_, err := gobatis.GenerateRestoreSQL(gobatis.DbTypePostgres, mapper, reflect.TypeOf(&T21{}), nil, nil, nil)
+	if err == nil {
+		t.Error("excepted error got ok")
+	} else if !strings.Contains(err.Error(), "restore is unsupported") {
+		t.Error(err)
+	}
+}
+
+func TestGenerateWhereOperators(t *testing.T) {
+	_intType := reflect.TypeOf(int(0))
+	_boolType := reflect.TypeOf(true)
+	for idx, test := range []struct {
+		dbType   gobatis.Dialect
+		names    []string
+		argTypes []reflect.Type
+		sql      string
+	}{
+		{names: []string{"ageGt"}, argTypes: []reflect.Type{_intType},
+			sql: `SELECT * FROM t26 WHERE age > #{ageGt}`},
+		{names: []string{"ageGte", "ageLt"}, argTypes: []reflect.Type{_intType, _intType},
+			sql: `SELECT * FROM t26 WHERE age >= #{ageGte} AND #{ageLt} > age`},
+		{names: []string{"ageLte"}, argTypes: []reflect.Type{_intType},
+			sql: `SELECT * FROM t26 WHERE #{ageLte} >= age`},
+		{names: []string{"nameNe"}, argTypes: []reflect.Type{_stringType},
+			sql: `SELECT * FROM t26 WHERE (name <> #{nameNe} OR name IS NULL)`},
+		{names: []string{"ageGte", "nameStartsWith"}, argTypes: []reflect.Type{_intType, _stringType},
+			sql: `SELECT * FROM t26 WHERE age >= #{ageGte}<if test="isNotEmptyString(nameStartsWith, true)">  AND name like <like value="nameStartsWith" match="prefix" /> </if> `},
+		{names: []string{"nameEndsWith"}, argTypes: []reflect.Type{_stringType},
+			sql: `SELECT * FROM t26 <where><if test="isNotEmptyString(nameEndsWith, true)"> name like <like value="nameEndsWith" match="suffix" /> </if> </where>`},
+		{names: []string{"nameContains"}, argTypes: []reflect.Type{_stringType},
+			sql: `SELECT * FROM t26 <where><if test="isNotEmptyString(nameContains, true)"> name like <like value="nameContains" match="any" /> </if> </where>`},
+		{names: []string{"nameNotLike"}, argTypes: []reflect.Type{_stringType},
+			sql: `SELECT * FROM t26 <where><if test="isNotEmptyString(nameNotLike, true)"> (name NOT LIKE <like value="nameNotLike" /> OR name IS NULL) </if> </where>`},
+		{names: []string{"idNotIn"}, argTypes: []reflect.Type{reflect.TypeOf([]int64{})},
+			sql: `SELECT * FROM t26 <where><if test="isNotEmpty(idNotIn)"> (id NOT IN (<foreach collection="idNotIn" item="item" separator="," >#{item}</foreach>) OR id IS NULL) </if></where>`},
+		{names: []string{"nameIsNull"}, argTypes: []reflect.Type{_boolType},
+			sql: `SELECT * FROM t26 WHERE <if test="nameIsNull"> name IS NULL </if><if test="!nameIsNull"> name IS NOT NULL </if>`},
+		{names: []string{"ageGt", "nameIsNotNull"}, argTypes: []reflect.Type{_intType, _boolType},
+			sql: `SELECT * FROM t26 WHERE age > #{ageGt}<if test="nameIsNotNull"> AND name IS NOT NULL </if><if test="!nameIsNotNull"> AND name IS NULL </if>`},
+		{names: []string{"statusGt"}, argTypes: []reflect.Type{_intType},
+			sql: `SELECT * FROM t26 WHERE status > #{statusGt}`},
+		{names: []string{"ageGt"}, argTypes: []reflect.Type{reflect.TypeOf(sql.NullInt64{})},
+			sql: `SELECT * FROM t26 <where><if test="ageGt.Valid"> age > #{ageGt} </if></where>`},
+		{dbType: gobatis.DbTypeMysql, names: []string{"ageLte"}, argTypes: []reflect.Type{_intType},
+			sql: "SELECT * FROM t26 WHERE #{ageLte} >= age"},
+	} {
+		dbType := test.dbType
+		if dbType == nil {
+			dbType = gobatis.DbTypePostgres
+		}
+
+		actaul, err := gobatis.GenerateSelectSQL(dbType, mapper, reflect.TypeOf(&T26{}), test.names, test.argTypes, nil)
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+
+		if actaul != test.sql {
+			t.Error("[", idx, "] excepted is", test.sql, "|")
+			t.Error("[", idx, "] actual   is", actaul, "|")
+		}
+	}
+
+	for idx, test := range []struct {
+		names    []string
+		argTypes []reflect.Type
+		err      string
+	}{
+		{names: []string{"ageStartsWith"}, argTypes: []reflect.Type{_intType},
+			err: "like array is unsupported"},
+		{names: []string{"idNotIn"}, argTypes: []reflect.Type{_intType},
+			err: "must is a slice"},
+		{names: []string{"nameIsNull"}, argTypes: []reflect.Type{_stringType},
+			err: "must is a bool"},
+		{names: []string{"ageGt"}, argTypes: []reflect.Type{reflect.TypeOf([]int{})},
+			err: "must cannot is a slice"},
+		{names: []string{"secretNe"}, argTypes: []reflect.Type{_stringType},
+			err: "only equality lookup is supported"},
+		{names: []string{"ageGtx"}, argTypes: []reflect.Type{_intType},
+			err: "ageGtx"},
+	} {
+		_, err := gobatis.GenerateSelectSQL(gobatis.DbTypePostgres, mapper, reflect.TypeOf(&T26{}), test.names, test.argTypes, nil)
+		if err == nil {
+			t.Error("[", idx, "] excepted error got ok")
+		} else if !strings.Contains(err.Error(), test.err) {
+			t.Error("[", idx, "] excepted is", test.err)
+			t.Error("[", idx, "] actual   is", err)
+		}
+	}
+}
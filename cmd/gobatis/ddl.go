@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	gobatis "github.com/runner-mei/GoBatis"
+)
+
+const ddlUsage = `usage: gobatis ddl [flags] [dir]
+
+为 dir(缺省为当前目录)中有 TableName 字段的结构体生成建表和建索引的语句
+
+flags:
+`
+
+var ddlProgram = template.Must(template.New("ddl").Parse(`package main
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+
+	gobatis "github.com/runner-mei/GoBatis"
+	target {{printf "%q" .importPath}}
+)
+
+func main() {
+	dialect := gobatis.ToDbType({{printf "%q" .dialect}})
+	mapper := gobatis.CreateMapper("", nil, nil)
+	for _, rType := range []reflect.Type{
+	{{- range $name := .types}}
+		reflect.TypeOf((*target.{{$name}})(nil)),
+	{{- end}}
+	} {
+		ddl, err := gobatis.GenerateDDL(dialect, mapper, rType)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, rType.Elem().Name()+":", err)
+			os.Exit(1)
+		}
+		fmt.Print(ddl)
+		fmt.Print("\r\n")
+	}
+}
+`))
+
+func runDDL(args []string) error {
+	flags := flag.NewFlagSet("ddl", flag.ExitOnError)
+	var (
+		dialect = flags.String("dialect", "postgres", "数据库方言")
+		output  = flags.String("o", "", "输出的文件， 缺省为标准输出")
+		types   = flags.String("types", "", "要生成的结构体， 用逗号分隔， 缺省为所有有 TableName 字段的结构体")
+	)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), ddlUsage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if gobatis.ToDbType(*dialect) == gobatis.DbTypeNone {
+		return errors.New("dialect '" + *dialect + "' is unknown")
+	}
+
+	dir := "."
+	if flags.NArg() > 0 {
+		dir = flags.Arg(0)
+	}
+
+	var typeNames []string
+	if *types != "" {
+		for _, name := range strings.Split(*types, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				typeNames = append(typeNames, name)
+			}
+		}
+	} else {
+		var err error
+		typeNames, err = findTableStructs(dir)
+		if err != nil {
+			return err
+		}
+	}
+	if len(typeNames) == 0 {
+		return errors.New("table struct isnot found in '" + dir + "'")
+	}
+
+	var out bytes.Buffer
+	cmd := exec.Command("go", "list", "-f", "{{.ImportPath}}")
+	cmd.Dir = dir
+	cmd.Stdout = &out
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return errors.New("read import path of '" + dir + "' fail, " + err.Error())
+	}
+	importPath := strings.TrimSpace(out.String())
+
+	tmpDir, err := ioutil.TempDir("", "gobatis_ddl")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	var program bytes.Buffer
+	err = ddlProgram.Execute(&program, map[string]interface{}{
+		"importPath": importPath,
+		"dialect":    *dialect,
+		"types":      typeNames,
+	})
+	if err != nil {
+		return err
+	}
+	filename := filepath.Join(tmpDir, "main.go")
+	if err := ioutil.WriteFile(filename, program.Bytes(), 0644); err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	// 在 dir 中执行， 这样生成的程序使用的是 dir 所在的 module
+	cmd = exec.Command("go", "run", filename)
+	cmd.Dir = dir
+	cmd.Stdout = w
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return errors.New("generate ddl fail, " + err.Error())
+	}
+	return nil
+}
+
+// findTableStructs 返回目录中有 TableName 字段的结构体的名称
+func findTableStructs(dir string) ([]string, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				genDecl, ok := decl.(*ast.GenDecl)
+				if !ok || genDecl.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range genDecl.Specs {
+					typeSpec := spec.(*ast.TypeSpec)
+					structType, ok := typeSpec.Type.(*ast.StructType)
+					if !ok || !typeSpec.Name.IsExported() {
+						continue
+					}
+					for _, field := range structType.Fields.List {
+						if len(field.Names) == 1 && field.Names[0].Name == "TableName" {
+							names = append(names, typeSpec.Name.Name)
+							break
+						}
+					}
+				}
+			}
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
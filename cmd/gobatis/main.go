@@ -2,17 +2,54 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
+	"os"
 
 	"github.com/runner-mei/GoBatis/generator"
 )
 
+const usage = `usage: gobatis [flags] [file.go | dir | dir/...]...
+       gobatis migrate [flags] up|down|status|create|unlock
+       gobatis ddl [flags] [dir]
+       gobatis reverse [flags] schema.sql...
+
+为文件中的 dao 接口生成实现， 参数为目录时处理其中有 go:generate gobatis 注释或导入了 GoBatis 并定义了接口的文件，
+没有参数时处理 go generate 设置的 GOFILE
+
+flags:
+`
+
 func main() {
+	if len(os.Args) > 1 {
+		var run func([]string) error
+		switch os.Args[1] {
+		case "migrate":
+			run = runMigrate
+		case "ddl":
+			run = runDDL
+		case "reverse":
+			run = runReverse
+		}
+		if run != nil {
+			if err := run(os.Args[2:]); err != nil {
+				log.Println(err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
 	var gen = generator.Generator{}
 	gen.Flags(flag.CommandLine)
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
 	flag.Parse()
 
 	if err := gen.Run(flag.Args()); err != nil {
 		log.Println(err)
+		os.Exit(1)
 	}
 }
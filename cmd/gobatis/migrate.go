@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	_ "github.com/denisenkom/go-mssqldb"
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	gobatis "github.com/runner-mei/GoBatis"
+	"github.com/runner-mei/GoBatis/migrate"
+)
+
+const migrateUsage = `usage: gobatis migrate [flags] up|down|status|create NAME|unlock
+
+  up           执行未执行的迁移
+  down         回滚最近执行的迁移
+  status       显示迁移的状态
+  create NAME  创建一个新的迁移文件
+  unlock       强制释放迁移的锁
+
+flags:
+`
+
+func runMigrate(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	var (
+		dbDrv   = flags.String("dbDrv", "postgres", "数据库驱动")
+		dbURL   = flags.String("dbURL", "", "数据库连接")
+		dialect = flags.String("dialect", "", "数据库方言， 缺省由驱动名决定")
+		dir     = flags.String("dir", "migrations", "迁移文件的目录")
+		table   = flags.String("table", migrate.DefaultTableName, "记录已执行的迁移的表名")
+		dryRun  = flags.Bool("dry-run", false, "只输出要执行的 sql， 不执行它们")
+		to      = flags.Int64("to", 0, "up 时执行到的版本， 0 为全部")
+		steps   = flags.Int("steps", 1, "down 时回滚的迁移个数")
+
+		allowEmptyDown = flags.Bool("allow-empty-down", false, "down 时允许回滚没有 Down 语句的迁移， 这时只删除它的记录")
+	)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), migrateUsage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("command is missing")
+	}
+
+	command := flags.Arg(0)
+	if command == "create" {
+		if flags.NArg() < 2 {
+			return errors.New("migration name is missing")
+		}
+		filename, err := migrate.Create(*dir, flags.Arg(1))
+		if err != nil {
+			return err
+		}
+		fmt.Println("created", filename)
+		return nil
+	}
+
+	dialectName := *dialect
+	if dialectName == "" {
+		dialectName = *dbDrv
+	}
+	dbType := gobatis.ToDbType(dialectName)
+	if dbType == gobatis.DbTypeNone {
+		return errors.New("dialect '" + dialectName + "' is unknown")
+	}
+
+	db, err := sql.Open(*dbDrv, *dbURL)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	migrator := migrate.New(db, dbType, migrate.Dir(*dir))
+	migrator.TableName = *table
+	migrator.DryRun = *dryRun
+	migrator.AllowEmptyDown = *allowEmptyDown
+	migrator.Out = os.Stdout
+
+	ctx := context.Background()
+	switch command {
+	case "up":
+		return migrator.Up(ctx, *to)
+	case "down":
+		return migrator.Down(ctx, *steps)
+	case "unlock":
+		return migrator.Unlock(ctx)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT\tSTATE")
+		for _, status := range statuses {
+			appliedAt, state := "-", "pending"
+			if status.Applied {
+				appliedAt = status.AppliedAt.Format("2006-01-02 15:04:05")
+				state = "applied"
+			}
+			if status.Modified {
+				state = "modified"
+			} else if status.Missing {
+				state = "missing"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", status.Version, status.Name, appliedAt, state)
+		}
+		return w.Flush()
+	default:
+		flags.Usage()
+		return errors.New("command '" + command + "' is unknown")
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"github.com/runner-mei/GoBatis/generator"
+)
+
+const reverseUsage = `usage: gobatis reverse [flags] schema.sql...
+
+从 sql 文件中的 CREATE TABLE 语句生成结构体和 dao 接口
+
+flags:
+`
+
+func runReverse(args []string) error {
+	flags := flag.NewFlagSet("reverse", flag.ExitOnError)
+	var reverse = generator.Reverse{}
+	reverse.Flags(flags)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), reverseUsage)
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	return reverse.Run(flags.Args())
+}
@@ -68,6 +68,23 @@ type Config struct {
 	TagPrefix     string
 	TagMapper     func(s string, fieldName string) []string
 	TemplateFuncs template.FuncMap
+
+	// Cipher 用于加解密带有 encrypt 标签的字段， 见 NewAESGCMCipher
+	Cipher Cipher
+
+	// TenantResolver 用于读取带有 tenant 标签的字段的值， 见 tenant.go
+	TenantResolver TenantResolver
+
+	// CurrentUser 用于读取带有 creator 和 updater 标签的字段的值， 见 current_user.go
+	CurrentUser CurrentUserResolver
+
+	// RawStatementCacheSize 是 ExecRaw 和 QueryRaw 等方法缓存的 sql 的最大数量，
+	// 为 0 时使用 DefaultRawStatementCacheSize
+	RawStatementCacheSize int
+
+	// SchemaCheck 为 SchemaCheckWarn 或 SchemaCheckStrict 时， 初始化后会检查记录类型对应的表
+	// 和数据库中的是否一致， 见 schema_check.go
+	SchemaCheck SchemaCheckMode
 }
 
 type DBRunner interface {
@@ -112,7 +129,17 @@ type Connection struct {
 	mapper        *Mapper
 	db            DBRunner
 	sqlStatements map[string]*MappedStatement
+	rawStatements *rawStatementCache
 	isUnsafe      bool
+
+	// dynamicStatements 是运行时注册的语句， 见 Repository
+	dynamicStatements *statementRegistry
+
+	tenantResolver TenantResolver
+	currentUser    CurrentUserResolver
+
+	// schemaDiagnostics 是初始化时检查表结构的结果， 见 Config.SchemaCheck
+	schemaDiagnostics []SchemaDiagnostic
 }
 
 func (conn *Connection) SqlStatements() [][2]string {
@@ -154,8 +181,21 @@ func (conn *Connection) Mapper() *Mapper {
 	return conn.mapper
 }
 
+// Tenant 用 Config.TenantResolver 从 ctx 中读取当前的租户， 没有配置 TenantResolver 时返回错误
+func (conn *Connection) Tenant(ctx context.Context) (interface{}, error) {
+	if conn.tenantResolver == nil {
+		return nil, fmt.Errorf("tenant resolver isnot configured")
+	}
+	return conn.tenantResolver(ctx)
+}
+
+// SchemaDiagnostics 返回初始化时检查表结构的结果， 见 Config.SchemaCheck
+func (conn *Connection) SchemaDiagnostics() []SchemaDiagnostic {
+	return conn.schemaDiagnostics
+}
+
 func (conn *Connection) Insert(ctx context.Context, id string, paramNames []string, paramValues []interface{}, notReturn ...bool) (int64, error) {
-	sqlAndParams, _, err := conn.readSQLParams(ctx, id, StatementTypeInsert, paramNames, paramValues)
+	sqlAndParams, _, genCtx, err := conn.readSQLParams(ctx, id, StatementTypeInsert, paramNames, paramValues)
 	if err != nil {
 		return 0, err
 	}
@@ -177,9 +217,20 @@ func (conn *Connection) Insert(ctx context.Context, id string, paramNames []stri
 	sqlParams := sqlAndParams[len(sqlAndParams)-1].Params
 
 	if len(notReturn) > 0 && notReturn[0] {
-		_, err := tx.ExecContext(ctx, sqlStr, sqlParams...)
+		result, err := tx.ExecContext(ctx, sqlStr, sqlParams...)
 		conn.tracer.Write(ctx, id, sqlStr, sqlParams, err)
-		return 0, conn.dialect.HandleError(err)
+		if err != nil {
+			return 0, conn.dialect.HandleError(err)
+		}
+		if len(genCtx.versions) > 0 {
+			// upsert 中带有乐观锁时， 版本不一致的记录不会被更新
+			affected, err := result.RowsAffected()
+			if err != nil {
+				return 0, conn.dialect.HandleError(err)
+			}
+			return 0, genCtx.checkVersions(affected)
+		}
+		return 0, nil
 	}
 
 	if conn.dialect.InsertIDSupported() {
@@ -188,6 +239,17 @@ func (conn *Connection) Insert(ctx context.Context, id string, paramNames []stri
 			conn.tracer.Write(ctx, id, sqlStr, sqlParams, err)
 			return 0, conn.dialect.HandleError(err)
 		}
+		if len(genCtx.versions) > 0 {
+			affected, err := result.RowsAffected()
+			if err != nil {
+				conn.tracer.Write(ctx, id, sqlStr, sqlParams, err)
+				return 0, conn.dialect.HandleError(err)
+			}
+			if err := genCtx.checkVersions(affected); err != nil {
+				conn.tracer.Write(ctx, id, sqlStr, sqlParams, nil)
+				return 0, err
+			}
+		}
 		insertID, err := result.LastInsertId()
 		conn.tracer.Write(ctx, id, sqlStr, sqlParams, err)
 		if err != nil {
@@ -200,24 +262,39 @@ func (conn *Connection) Insert(ctx context.Context, id string, paramNames []stri
 	err = tx.QueryRowContext(ctx, sqlStr, sqlParams...).Scan(&insertID)
 	conn.tracer.Write(ctx, id, sqlStr, sqlParams, err)
 	if err != nil {
+		if err == sql.ErrNoRows && len(genCtx.versions) > 0 {
+			return 0, ErrStaleObject
+		}
 		return 0, conn.dialect.HandleError(err)
 	}
-	return insertID, nil
+	return insertID, genCtx.checkVersions(1)
 }
 
 func (conn *Connection) Update(ctx context.Context, id string, paramNames []string, paramValues []interface{}) (int64, error) {
-	sqlAndParams, _, err := conn.readSQLParams(ctx, id, StatementTypeUpdate, paramNames, paramValues)
+	sqlAndParams, _, genCtx, err := conn.readSQLParams(ctx, id, StatementTypeUpdate, paramNames, paramValues)
 	if err != nil {
 		return 0, err
 	}
-	return conn.execute(ctx, id, sqlAndParams)
+	var rowsAffected int64
+	if stmt, _ := conn.statement(id); stmt.audit != nil {
+		rowsAffected, err = conn.executeWithAudit(ctx, id, stmt, paramNames, paramValues, sqlAndParams)
+	} else {
+		rowsAffected, err = conn.execute(ctx, id, sqlAndParams)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return rowsAffected, genCtx.checkVersions(rowsAffected)
 }
 
 func (conn *Connection) Delete(ctx context.Context, id string, paramNames []string, paramValues []interface{}) (int64, error) {
-	sqlAndParams, _, err := conn.readSQLParams(ctx, id, StatementTypeDelete, paramNames, paramValues)
+	sqlAndParams, _, _, err := conn.readSQLParams(ctx, id, StatementTypeDelete, paramNames, paramValues)
 	if err != nil {
 		return 0, err
 	}
+	if stmt, _ := conn.statement(id); stmt.audit != nil {
+		return conn.executeWithAudit(ctx, id, stmt, paramNames, paramValues, sqlAndParams)
+	}
 	return conn.execute(ctx, id, sqlAndParams)
 }
 
@@ -255,7 +332,7 @@ func (conn *Connection) InsertQuery(ctx context.Context, id string, paramNames [
 }
 
 func (conn *Connection) selectOneOrInsert(ctx context.Context, id string, sqlType StatementType, paramNames []string, paramValues []interface{}) Result {
-	sqlAndParams, _, err := conn.readSQLParams(ctx, id, sqlType, paramNames, paramValues)
+	sqlAndParams, _, _, err := conn.readSQLParams(ctx, id, sqlType, paramNames, paramValues)
 	if err != nil {
 		return Result{o: conn,
 			ctx: ctx,
@@ -280,7 +357,7 @@ func (conn *Connection) selectOneOrInsert(ctx context.Context, id string, sqlTyp
 }
 
 func (conn *Connection) Select(ctx context.Context, id string, paramNames []string, paramValues []interface{}) *Results {
-	sqlAndParams, _, err := conn.readSQLParams(ctx, id, StatementTypeSelect, paramNames, paramValues)
+	sqlAndParams, _, _, err := conn.readSQLParams(ctx, id, StatementTypeSelect, paramNames, paramValues)
 	if err != nil {
 		return &Results{o: conn,
 			ctx: ctx,
@@ -304,28 +381,83 @@ func (conn *Connection) Select(ctx context.Context, id string, paramNames []stri
 	}
 }
 
-func (o *Connection) readSQLParams(ctx context.Context, id string, sqlType StatementType, paramNames []string, paramValues []interface{}) ([]sqlAndParam, ResultType, error) {
-	stmt, ok := o.sqlStatements[id]
+// ExecSQL 执行一个已生成好的 sql， sql 中的占位符必须是 Dialect().Placeholder() 的格式，
+// id 仅用于跟踪日志， 它主要用于 sqlb 等 sql 构建器
+func (conn *Connection) ExecSQL(ctx context.Context, id, sqlStr string, sqlParams []interface{}) (int64, error) {
+	return conn.execute(ctx, id, []sqlAndParam{{SQL: sqlStr, Params: sqlParams}})
+}
+
+// QueryRowSQL 和 ExecSQL 一样， 只是它执行的是查询一条记录的 sql
+func (conn *Connection) QueryRowSQL(ctx context.Context, id, sqlStr string, sqlParams []interface{}) Result {
+	return Result{o: conn,
+		ctx:       ctx,
+		id:        id,
+		sql:       sqlStr,
+		sqlParams: sqlParams,
+	}
+}
+
+// QuerySQL 和 ExecSQL 一样， 只是它执行的是查询多条记录的 sql
+func (conn *Connection) QuerySQL(ctx context.Context, id, sqlStr string, sqlParams []interface{}) *Results {
+	return &Results{o: conn,
+		ctx:       ctx,
+		id:        id,
+		sql:       sqlStr,
+		sqlParams: sqlParams,
+	}
+}
+
+// statement 查找 sql 语句， 找不到注册的语句时再查找运行时注册的语句(见 Repository)
+func (conn *Connection) statement(id string) (*MappedStatement, bool) {
+	if stmt, ok := conn.sqlStatements[id]; ok {
+		return stmt, true
+	}
+	if conn.dynamicStatements != nil {
+		return conn.dynamicStatements.Get(id)
+	}
+	return nil, false
+}
+
+func (o *Connection) readSQLParams(ctx context.Context, id string, sqlType StatementType, paramNames []string, paramValues []interface{}) ([]sqlAndParam, ResultType, *Context, error) {
+	stmt, ok := o.statement(id)
 	if !ok {
-		return nil, ResultUnknown, fmt.Errorf("sql '%s' error : statement not found ", id)
+		return nil, ResultUnknown, nil, fmt.Errorf("sql '%s' error : statement not found ", id)
 	}
 
 	if stmt.sqlType != sqlType {
-		return nil, ResultUnknown, fmt.Errorf("sql '%s' error : Select type Error, excepted is %s, actual is %s",
+		return nil, ResultUnknown, nil, fmt.Errorf("sql '%s' error : Select type Error, excepted is %s, actual is %s",
 			id, sqlType.String(), stmt.sqlType.String())
 	}
 
-	genCtx, err := NewContext(o.constants, o.dialect, o.mapper, paramNames, paramValues)
+	genCtx, err := o.newContext(ctx, paramNames, paramValues)
 	if err != nil {
-		return nil, ResultUnknown, fmt.Errorf("sql '%s' error : %s", id, err)
+		return nil, ResultUnknown, nil, fmt.Errorf("sql '%s' error : %s", id, err)
 	}
 
 	sqlAndParams, err := stmt.GenerateSQLs(genCtx)
 	if err != nil {
 		o.tracer.Write(ctx, id, stmt.rawSQL, nil, err)
-		return nil, ResultUnknown, fmt.Errorf("sql '%s' error : %s", id, err)
+		return nil, ResultUnknown, nil, fmt.Errorf("sql '%s' error : %s", id, err)
+	}
+	return sqlAndParams, stmt.result, genCtx, nil
+}
+
+func (o *Connection) newContext(ctx context.Context, paramNames []string, paramValues []interface{}) (*Context, error) {
+	genCtx, err := NewContext(o.constants, o.dialect, o.mapper, paramNames, paramValues)
+	if err != nil {
+		return nil, err
+	}
+	if o.tenantResolver != nil {
+		genCtx.tenant = func() (interface{}, error) {
+			return o.tenantResolver(ctx)
+		}
 	}
-	return sqlAndParams, stmt.result, nil
+	if o.currentUser != nil {
+		genCtx.currentUser = func() (interface{}, error) {
+			return o.currentUser(ctx)
+		}
+	}
+	return genCtx, nil
 }
 
 // New 创建一个新的Osm，这个过程会打开数据库连接。
@@ -392,12 +524,21 @@ func newConnection(cfg *Config) (*Connection, error) {
 		tagMapper = cfg.TagMapper
 	}
 	base.mapper = CreateMapper(tagPrefix, nil, tagMapper)
+	if cfg != nil {
+		base.mapper.cipher = cfg.Cipher
+		base.tenantResolver = cfg.TenantResolver
+		base.currentUser = cfg.CurrentUser
+	}
 	base.dialect = ToDbType(cfg.DriverName)
 	if base.dialect == DbTypeNone {
 		base.dialect = DbTypePostgres
 	}
 
-	dbName := strings.ToLower(base.Dialect().Name())
+	// 方言目录优先使用本方言的，没有时依次使用它所兼容的方言的目录
+	var dbNames []string
+	for d := base.Dialect(); d != nil; d = ParentDialect(d) {
+		dbNames = append(dbNames, strings.ToLower(d.Name()))
+	}
 	xmlPaths := []string{}
 	for _, xmlPath := range cfg.XMLPaths {
 		pathInfo, err := os.Stat(xmlPath)
@@ -418,6 +559,7 @@ func newConnection(cfg *Config) (*Connection, error) {
 			return nil, err
 		}
 
+		dialectDirNames := map[string]string{}
 		for _, fileInfo := range fs {
 			if !fileInfo.IsDir() {
 				if fileName := fileInfo.Name(); strings.ToLower(filepath.Ext(fileName)) == ".xml" {
@@ -425,21 +567,26 @@ func newConnection(cfg *Config) (*Connection, error) {
 				}
 				continue
 			}
+			dialectDirNames[strings.ToLower(fileInfo.Name())] = fileInfo.Name()
+		}
 
-			if dbName != strings.ToLower(fileInfo.Name()) {
+		for _, dbName := range dbNames {
+			dirName, ok := dialectDirNames[dbName]
+			if !ok {
 				continue
 			}
 
-			dialectDirs, err := ioutil.ReadDir(filepath.Join(xmlPath, fileInfo.Name()))
+			dialectDirs, err := ioutil.ReadDir(filepath.Join(xmlPath, dirName))
 			if err != nil {
 				return nil, err
 			}
 
 			for _, dialectInfo := range dialectDirs {
 				if fileName := dialectInfo.Name(); strings.ToLower(filepath.Ext(fileName)) == ".xml" {
-					xmlPaths = append(xmlPaths, filepath.Join(xmlPath, fileInfo.Name(), fileName))
+					xmlPaths = append(xmlPaths, filepath.Join(xmlPath, dirName, fileName))
 				}
 			}
+			break
 		}
 	}
 
@@ -447,6 +594,8 @@ func newConnection(cfg *Config) (*Connection, error) {
 		Dialect:    base.dialect,
 		Mapper:     base.mapper,
 		Statements: base.sqlStatements}
+	base.rawStatements = newRawStatementCache(ctx, cfg.RawStatementCacheSize)
+	base.dynamicStatements = newStatementRegistry(ctx)
 
 	for _, xmlPath := range xmlPaths {
 		log.Println("load xml -", xmlPath)
@@ -464,5 +613,19 @@ func newConnection(cfg *Config) (*Connection, error) {
 		return nil, err
 	}
 
+	if cfg.SchemaCheck != SchemaCheckNone {
+		diagnostics, err := base.CheckSchema(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		if cfg.SchemaCheck == SchemaCheckStrict && len(diagnostics) > 0 {
+			return nil, &SchemaCheckError{Diagnostics: diagnostics}
+		}
+		for _, d := range diagnostics {
+			log.Println("schema check -", d.String())
+		}
+		base.schemaDiagnostics = diagnostics
+	}
+
 	return base, nil
 }
@@ -92,6 +92,14 @@ func TestToDbType(t *testing.T) {
 		{"oracle", gobatis.DbTypeOracle},
 		{"Oracle", gobatis.DbTypeOracle},
 		{"ora", gobatis.DbTypeOracle},
+		{"kingbase", gobatis.DbTypeKingbase},
+		{"KingbaseES", gobatis.DbTypeKingbase},
+		{"opengauss", gobatis.DbTypeOpenGauss},
+		{"highgo", gobatis.DbTypeHighgo},
+		{"dameng", gobatis.DbTypeDameng},
+		{"dm", gobatis.DbTypeDameng},
+		{"sqlite", gobatis.DbTypeSqlite},
+		{"sqlite3", gobatis.DbTypeSqlite},
 		{"aara", gobatis.DbTypeNone},
 	} {
 		if test.dbType != gobatis.ToDbType(test.name) {
@@ -100,6 +108,76 @@ func TestToDbType(t *testing.T) {
 	}
 }
 
+func TestRegisterDialect(t *testing.T) {
+	oscar := gobatis.InheritDialect("oscar", gobatis.DbTypeOracle)
+	gobatis.RegisterDialect("oscar", oscar, "shentong")
+	defer gobatis.UnregisterDialect("oscar", "shentong")
+
+	for _, test := range []struct {
+		name   string
+		dbType gobatis.Dialect
+	}{
+		{"oscar", oscar},
+		{"Shentong", oscar},
+	} {
+		if test.dbType != gobatis.ToDbType(test.name) {
+			t.Error(test.name, ", excepted ", test.dbType, "got", gobatis.ToDbType(test.name))
+		}
+	}
+
+	if !gobatis.IsDialect(oscar, gobatis.DbTypeOracle) {
+		t.Error("oscar is oracle compatible")
+	}
+	if gobatis.IsDialect(gobatis.DbTypeOracle, oscar) {
+		t.Error("oracle isnot oscar compatible")
+	}
+
+	for idx, test := range []struct {
+		dbType   gobatis.Dialect
+		names    []string
+		excepted gobatis.Dialect
+	}{
+		{gobatis.DbTypeKingbase, []string{"mysql", "postgres"}, gobatis.DbTypePostgres},
+		{gobatis.DbTypeKingbase, []string{"kingbase", "postgres"}, gobatis.DbTypeKingbase},
+		{gobatis.DbTypeDameng, []string{"oracle"}, gobatis.DbTypeOracle},
+		{gobatis.DbTypeDameng, []string{"postgres"}, gobatis.DbTypeDameng},
+		{oscar, []string{"mssql", "oracle"}, gobatis.DbTypeOracle},
+		{gobatis.DbTypeMysql, []string{"mysql"}, gobatis.DbTypeMysql},
+	} {
+		actual := gobatis.MatchDialect(test.dbType, test.names...)
+		if actual != test.excepted {
+			t.Error("[", idx, "] excepted is", test.excepted.Name())
+			t.Error("[", idx, "] actual   is", actual.Name())
+		}
+	}
+
+	gobatis.UnregisterDialect("Shentong")
+	if gobatis.ToDbType("shentong") != gobatis.DbTypeNone {
+		t.Error("shentong is unregistered")
+	}
+	if gobatis.ToDbType("oscar") != oscar {
+		t.Error("oscar is registered")
+	}
+}
+
+func TestSqlitePagination(t *testing.T) {
+	for idx, test := range []struct {
+		offset, limit int64
+		sql           string
+	}{
+		{offset: 0, limit: 0, sql: ""},
+		{offset: 0, limit: 10, sql: " LIMIT 10 "},
+		{offset: 20, limit: 10, sql: " LIMIT 10 OFFSET 20 "},
+		{offset: 20, limit: 0, sql: " LIMIT -1 OFFSET 20 "},
+	} {
+		actual, _ := gobatis.DbTypeSqlite.GeneratePagination(test.offset, test.limit)
+		if actual != test.sql {
+			t.Error("[", idx, "] excepted is", test.sql)
+			t.Error("[", idx, "] actual   is", actual)
+		}
+	}
+}
+
 func TestConnection(t *testing.T) {
 	tests.Run(t, func(_ testing.TB, factory *gobatis.SessionFactory) {
 		sqlStatements := factory.SqlStatements()
@@ -17,6 +17,38 @@ type SqlSession interface {
 	Select(ctx context.Context, id string, paramNames []string, paramValues []interface{}) *Results
 }
 
+// SqlRunner 是可以直接执行已生成好的 sql 的 SqlSession， sqlb 等 sql 构建器通过它执行 sql
+type SqlRunner interface {
+	SqlSession
+
+	Mapper() *Mapper
+	Tenant(ctx context.Context) (interface{}, error)
+	ExecSQL(ctx context.Context, id, sqlStr string, sqlParams []interface{}) (int64, error)
+	QueryRowSQL(ctx context.Context, id, sqlStr string, sqlParams []interface{}) Result
+	QuerySQL(ctx context.Context, id, sqlStr string, sqlParams []interface{}) *Results
+}
+
+var _ SqlRunner = &Connection{}
+
+// ToSqlRunner 将 SqlSession 转换为 SqlRunner， 它会展开 Reference
+func ToSqlRunner(sess SqlSession) (SqlRunner, bool) {
+	for {
+		switch s := sess.(type) {
+		case SqlRunner:
+			return s, true
+		case Reference:
+			sess = s.SqlSession
+		case *Reference:
+			if s == nil {
+				return nil, false
+			}
+			sess = s.SqlSession
+		default:
+			return nil, false
+		}
+	}
+}
+
 type sessionKeyType struct{}
 
 func (*sessionKeyType) String() string {
@@ -20,6 +20,34 @@ type SQLType interface {
 }
 
 func toSQLType(dialect Dialect, param *Param, value interface{}) (interface{}, error) {
+	var handler TypeHandler
+	if param.Handler != "" {
+		var err error
+		handler, err = typeHandlerByName(param.Handler)
+		if err != nil {
+			return nil, errors.New("param '" + param.Name + "' " + err.Error())
+		}
+	} else if param.Encoding != "" && value != nil {
+		var err error
+		handler, err = makeEncodingHandler(param.Encoding, reflect.TypeOf(value))
+		if err != nil {
+			return nil, errors.New("param '" + param.Name + "' " + err.Error())
+		}
+	} else if value != nil {
+		handler = typeHandlerByType(reflect.TypeOf(value))
+	}
+	if handler != nil {
+		var err error
+		value, err = typeHandlerToDB(dialect, handler, value)
+		if err != nil {
+			return nil, fmt.Errorf("param '%s' convert fail, %s", param.Name, err)
+		}
+		if value == nil && param.NotNull.Valid && param.NotNull.Bool {
+			return nil, errors.New("param '" + param.Name + "' is zero value")
+		}
+		return value, nil
+	}
+
 	if value == nil {
 		if param.NotNull.Valid && param.NotNull.Bool {
 			return nil, errors.New("param '" + param.Name + "' is zero value")
@@ -472,9 +500,12 @@ func (s *emptyScanner) Scan(src interface{}) error {
 
 var emptyScan sql.Scanner = &emptyScanner{}
 
+// Nullable 用于读取可能为 NULL 的值， Dialect 为空时注册的 TypeHandler 拿不到数据库方言，
+// 用 ScanAny 等函数读取时会自动设置它
 type Nullable struct {
-	Name  string
-	Value interface{}
+	Dialect Dialect
+	Name    string
+	Value   interface{}
 
 	Valid bool
 }
@@ -484,6 +515,9 @@ func (s *Nullable) Scan(src interface{}) error {
 		return nil
 	}
 	s.Valid = true
+	if scanner, ok := makeTypeHandlerScanner(s.Dialect, s.Name, s.Value).(sql.Scanner); ok {
+		return scanner.Scan(src)
+	}
 	return convert.ConvertAssign(s.Value, src)
 }
 
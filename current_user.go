@@ -0,0 +1,59 @@
+package gobatis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// 操作人
+//
+// 字段上加上 creator 或 updater 标签(如 `db:"created_by,creator"` 和
+// `db:"updated_by,updater"`)后， 和 created 与 updated 标签的时间字段一样，
+// 生成的 insert 语句会用 #{created_by,creator} 和 #{updated_by,updater} 来填充它们，
+// 生成的 update 语句会用 #{updated_by,updater} 来更新 updater 字段， 它们的值是由
+// Config.CurrentUser 从 context 中读取的当前用户， 当前用户为 nil 时值为 NULL。
+
+// CurrentUserResolver 从 context 中读取当前的用户
+type CurrentUserResolver func(ctx context.Context) (interface{}, error)
+
+func isCreatorField(field *FieldInfo) bool {
+	if field == nil || field.Options == nil {
+		return false
+	}
+	_, ok := field.Options["creator"]
+	return ok
+}
+
+func isUpdaterField(field *FieldInfo) bool {
+	if field == nil || field.Options == nil {
+		return false
+	}
+	_, ok := field.Options["updater"]
+	return ok
+}
+
+func isOperatorField(field *FieldInfo) bool {
+	return isCreatorField(field) || isUpdaterField(field)
+}
+
+func operatorParam(field *FieldInfo) string {
+	if isCreatorField(field) {
+		return "#{" + field.Name + ",creator}"
+	}
+	return "#{" + field.Name + ",updater}"
+}
+
+func (bc *Context) currentUserValue(param *Param) (interface{}, error) {
+	if bc.currentUser == nil {
+		return nil, errors.New("param '" + param.Name + "' is creator or updater, but current user resolver isnot configured")
+	}
+	value, err := bc.currentUser()
+	if err != nil {
+		return nil, fmt.Errorf("param '%s' read current user fail, %s", param.Name, err)
+	}
+	if value == nil {
+		return nil, nil
+	}
+	return toSQLType(bc.Dialect, param, value)
+}
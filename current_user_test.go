@@ -0,0 +1,69 @@
+package gobatis_test
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+
+	gobatis "github.com/runner-mei/GoBatis"
+)
+
+type currentUserKey struct{}
+
+func TestCurrentUser(t *testing.T) {
+	rType := reflect.TypeOf(&T23{})
+	factory, teardown := setupFixture(t, &gobatis.Config{
+		CurrentUser: func(ctx context.Context) (interface{}, error) {
+			return ctx.Value(currentUserKey{}), nil
+		}}, rType, []fixtureStatement{
+		{id: "T23.insert", stmtType: gobatis.StatementTypeInsert, generate: func(ctx *gobatis.InitContext) (string, error) {
+			return gobatis.GenerateInsertSQL(ctx.Dialect, ctx.Mapper, rType, []string{"r"}, []reflect.Type{rType}, false)
+		}},
+		{id: "T23.update", stmtType: gobatis.StatementTypeUpdate, generate: func(ctx *gobatis.InitContext) (string, error) {
+			return gobatis.GenerateUpdateSQL(ctx.Dialect, ctx.Mapper, "r.", rType, nil, nil)
+		}},
+	})
+	defer teardown()
+	if factory == nil {
+		return
+	}
+	ctx := context.Background()
+
+	ref := factory.SessionReference()
+	record := &T23{Name: "a", CreatedBy: "ignored", UpdatedBy: "ignored"}
+	var err error
+	record.ID, err = ref.Insert(context.WithValue(ctx, currentUserKey{}, "alice"), "T23.insert", []string{"r"}, []interface{}{record})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	assert := func(excepted [2]sql.NullString) {
+		t.Helper()
+		var actual [2]sql.NullString
+		err := factory.DB().QueryRowContext(ctx, `SELECT created_by, updated_by FROM t23`).Scan(&actual[0], &actual[1])
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if actual != excepted {
+			t.Error("excepted is", excepted)
+			t.Error("actual   is", actual)
+		}
+	}
+	assert([2]sql.NullString{{String: "alice", Valid: true}, {String: "alice", Valid: true}})
+
+	record.Name = "b"
+	if _, err := ref.Update(context.WithValue(ctx, currentUserKey{}, "bob"), "T23.update", []string{"r"}, []interface{}{record}); err != nil {
+		t.Error(err)
+		return
+	}
+	assert([2]sql.NullString{{String: "alice", Valid: true}, {String: "bob", Valid: true}})
+
+	if _, err := ref.Update(ctx, "T23.update", []string{"r"}, []interface{}{record}); err != nil {
+		t.Error(err)
+		return
+	}
+	assert([2]sql.NullString{{String: "alice", Valid: true}})
+}
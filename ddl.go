@@ -0,0 +1,446 @@
+package gobatis
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+var _rawMessageType = reflect.TypeOf((*json.RawMessage)(nil)).Elem()
+
+// columnTypes 是一种数据库的列类型
+type columnTypes struct {
+	boolean, smallint, integer, bigint string
+	float, double                      string
+	varchar, text, binary              string
+	timestamp                          string
+	json, jsonb                        string
+	// array 不为空时数组用数据库的数组类型， 如 text[]， 否则用 json 保存
+	array string
+
+	// serial 和 bigserial 是自增列的类型， autoincr 是自增列的修饰
+	serial, bigserial, autoincr string
+}
+
+var ddlColumnTypes = map[Dialect]*columnTypes{
+	DbTypePostgres: {
+		boolean: "boolean", smallint: "smallint", integer: "int", bigint: "bigint",
+		float: "real", double: "double precision",
+		varchar: "varchar", text: "text", binary: "bytea",
+		timestamp: "timestamp with time zone",
+		json:      "json", jsonb: "jsonb",
+		array:  "[]",
+		serial: "serial", bigserial: "bigserial",
+	},
+	DbTypeMysql: {
+		boolean: "boolean", smallint: "smallint", integer: "int", bigint: "bigint",
+		float: "float", double: "double",
+		varchar: "varchar", text: "longtext", binary: "longblob",
+		timestamp: "datetime",
+		json:      "json", jsonb: "json",
+		autoincr: "AUTO_INCREMENT",
+	},
+	DbTypeMSSql: {
+		boolean: "bit", smallint: "smallint", integer: "int", bigint: "bigint",
+		float: "real", double: "float",
+		varchar: "nvarchar", text: "nvarchar(max)", binary: "varbinary(max)",
+		timestamp: "datetime",
+		json:      "nvarchar(max)", jsonb: "nvarchar(max)",
+		autoincr: "IDENTITY(1,1)",
+	},
+	DbTypeOracle: {
+		boolean: "NUMBER(1)", smallint: "NUMBER(5)", integer: "NUMBER(10)", bigint: "NUMBER(19)",
+		float: "BINARY_FLOAT", double: "BINARY_DOUBLE",
+		varchar: "VARCHAR2", text: "CLOB", binary: "BLOB",
+		timestamp: "TIMESTAMP",
+		json:      "CLOB", jsonb: "CLOB",
+		autoincr: "GENERATED BY DEFAULT AS IDENTITY",
+	},
+	DbTypeSqlite: {
+		boolean: "boolean", smallint: "smallint", integer: "int", bigint: "bigint",
+		float: "real", double: "double",
+		varchar: "varchar", text: "text", binary: "blob",
+		timestamp: "datetime",
+		json:      "text", jsonb: "text",
+		autoincr: "PRIMARY KEY AUTOINCREMENT",
+	},
+}
+
+// defaultVarcharSize 是没有 size 选项的字符串在不支持无长度的 varchar 的数据库中的长度
+const defaultVarcharSize = "255"
+
+// tableColumns 返回表的列， 它们按字段在结构体中声明的顺序排序
+func tableColumns(mapper *Mapper, rType reflect.Type) []*FieldInfo {
+	var fields []*FieldInfo
+	for _, field := range mapper.TypeMap(rType).Index {
+		if field.Field.Name == "TableName" || field.Name == "-" {
+			continue
+		}
+		if field.Field.Anonymous {
+			continue
+		}
+		if field.Parent != nil && len(field.Parent.Index) != 0 && !field.Parent.Field.Anonymous {
+			continue
+		}
+		if _, ok := field.Options["-"]; ok {
+			continue
+		}
+		fields = append(fields, field)
+	}
+
+	sort.SliceStable(fields, func(i, j int) bool {
+		a, b := fields[i].Index, fields[j].Index
+		for idx := 0; idx < len(a) && idx < len(b); idx++ {
+			if a[idx] != b[idx] {
+				return a[idx] < b[idx]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return fields
+}
+
+// columnType 返回字段的列类型
+func columnType(types *columnTypes, field *FieldInfo) (string, error) {
+	if _, ok := field.Options["jsonb"]; ok {
+		return types.jsonb, nil
+	}
+	if _, ok := field.Options["json"]; ok {
+		return types.json, nil
+	}
+	if isEncryptField(field) {
+		return types.text, nil
+	}
+	if isJSONSliceType(field) {
+		return types.jsonb, nil
+	}
+
+	s := typeToColumnType(types, field.Field.Type, field.Options["size"])
+	if s == "" {
+		return "", errors.New("field '" + field.Field.Name + "' type '" + field.Field.Type.String() + "' is unsupported")
+	}
+	return s, nil
+}
+
+// isJSONSliceType 判断字段是否是没有 array 选项的自定义切片类型， 它们用 json 保存， 见 isArrayType
+func isJSONSliceType(field *FieldInfo) bool {
+	fType := field.Field.Type
+	for fType.Kind() == reflect.Ptr {
+		fType = fType.Elem()
+	}
+	if fType.Kind() != reflect.Slice || fType.PkgPath() == "" || fType.Elem().Kind() == reflect.Uint8 {
+		return false
+	}
+	if fType.Implements(_valuerInterface) || reflect.PtrTo(fType).Implements(_scannerInterface) {
+		return false
+	}
+	_, ok := field.Options["array"]
+	return !ok
+}
+
+// typeToColumnType 返回 go 类型对应的列类型， 不支持时返回空字符串
+func typeToColumnType(types *columnTypes, fType reflect.Type, size string) string {
+	for fType.Kind() == reflect.Ptr {
+		fType = fType.Elem()
+	}
+
+	varchar := func() string {
+		if size == "" {
+			if types.varchar == "varchar" && types.text == "text" {
+				// postgres 和 sqlite 的 text 没有长度限制， 也可以建索引
+				return types.text
+			}
+			size = defaultVarcharSize
+		}
+		return types.varchar + "(" + size + ")"
+	}
+
+	switch fType {
+	case _timeType:
+		return types.timestamp
+	case _ipType, _macType:
+		if size == "" {
+			size = "50"
+		}
+		return types.varchar + "(" + size + ")"
+	case _rawMessageType:
+		return types.jsonb
+	}
+	if ok, kind, _ := isValidable(fType); ok {
+		switch kind {
+		case reflect.Bool:
+			return types.boolean
+		case reflect.Int64:
+			return types.bigint
+		case reflect.Float64:
+			return types.double
+		default:
+			return varchar()
+		}
+	}
+	switch fType.String() {
+	case "sql.NullInt32":
+		return types.integer
+	case "sql.NullTime":
+		return types.timestamp
+	}
+
+	switch fType.Kind() {
+	case reflect.Bool:
+		return types.boolean
+	case reflect.Int8, reflect.Int16, reflect.Uint8:
+		return types.smallint
+	case reflect.Int32, reflect.Uint16:
+		return types.integer
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint32, reflect.Uint64:
+		return types.bigint
+	case reflect.Float32:
+		return types.float
+	case reflect.Float64:
+		return types.double
+	case reflect.String:
+		return varchar()
+	case reflect.Slice, reflect.Array:
+		elemType := fType.Elem()
+		if elemType.Kind() == reflect.Uint8 {
+			return types.binary
+		}
+		if types.array != "" && isArrayLeafSupported(elemType) {
+			if s := typeToColumnType(types, elemType, ""); s != "" {
+				return s + types.array
+			}
+		}
+		return types.jsonb
+	case reflect.Map, reflect.Struct, reflect.Interface:
+		return types.jsonb
+	}
+	return ""
+}
+
+// GenerateCreateTableSQL 按结构体的定义生成建表语句， 它支持 db 标签中的下列选项
+//
+//	pk          主键， 多个字段有 pk 时为联合主键
+//	autoincr    自增列， 没有字段有 pk 时自增列为主键
+//	notnull     NOT NULL
+//	unique      唯一约束， unique=名称 时见 GenerateCreateIndexSQL
+//	size=n      字符串的长度， 如 varchar(n)
+//	default=v   缺省值， 原样写入 DEFAULT 子句中
+//	json/jsonb  用 json 保存的列
+//	array       自定义的切片类型(如 type Tags []string)作为数组， 没有时用 json 保存
+//
+// 列的类型由字段的类型和方言决定， 数组在 postgres 中为数组类型， 在其它数据库中用 json 保存
+func GenerateCreateTableSQL(dbType Dialect, mapper *Mapper, rType reflect.Type) (string, error) {
+	if rType.Kind() == reflect.Ptr {
+		rType = rType.Elem()
+	}
+	types := ddlColumnTypes[rootDialect(dbType)]
+	if types == nil {
+		return "", errors.New("ddl is unimplemented for db type - " + dbType.Name())
+	}
+	tableName, err := ReadTableName(mapper, rType)
+	if err != nil {
+		return "", err
+	}
+
+	fields := tableColumns(mapper, rType)
+	if len(fields) == 0 {
+		return "", errors.New("struct '" + rType.Name() + "' hasnot columns")
+	}
+
+	var primaryKeys []*FieldInfo
+	for _, field := range fields {
+		if _, ok := field.Options["pk"]; ok {
+			primaryKeys = append(primaryKeys, field)
+		}
+	}
+	if len(primaryKeys) == 0 {
+		// 没有 pk 字段时将自增列作为主键， 与 EnableAudit 中查找主键的方式一致
+		for _, field := range fields {
+			if _, ok := field.Options["autoincr"]; ok {
+				primaryKeys = append(primaryKeys, field)
+			}
+		}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("CREATE TABLE ")
+	sb.WriteString(quoteTableName(dbType, tableName))
+	sb.WriteString(" (")
+	for idx, field := range fields {
+		if idx > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString("\r\n  ")
+		sb.WriteString(quoteColumn(dbType, field))
+		sb.WriteString(" ")
+
+		isPK := isPrimaryKey(primaryKeys, field)
+		_, isAutoincr := field.Options["autoincr"]
+		inlinePK := false
+		if isAutoincr && types.serial != "" {
+			if field.Field.Type.Kind() == reflect.Int32 || field.Field.Type.Kind() == reflect.Uint16 {
+				sb.WriteString(types.serial)
+			} else {
+				sb.WriteString(types.bigserial)
+			}
+		} else if isAutoincr && IsDialect(dbType, DbTypeSqlite) {
+			// sqlite 的自增列必须是 INTEGER PRIMARY KEY
+			if !isPK || len(primaryKeys) != 1 {
+				return "", errors.New("field '" + field.Field.Name + "' is autoincr, it must be the only primary key in sqlite")
+			}
+			sb.WriteString("INTEGER ")
+			sb.WriteString(types.autoincr)
+			inlinePK = true
+		} else {
+			s, err := columnType(types, field)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(s)
+			if isAutoincr {
+				sb.WriteString(" ")
+				sb.WriteString(types.autoincr)
+			}
+		}
+
+		if _, ok := field.Options["notnull"]; (ok || isPK) && !inlinePK {
+			sb.WriteString(" NOT NULL")
+		}
+		if value, ok := field.Options["default"]; ok && value != "" {
+			sb.WriteString(" DEFAULT ")
+			sb.WriteString(value)
+		}
+		if value, ok := field.Options["unique"]; ok && value == "" && !isPK {
+			sb.WriteString(" UNIQUE")
+		}
+		if inlinePK {
+			primaryKeys = nil
+		}
+
+		if column := blindIndexColumn(field); column != "" {
+			sb.WriteString(",\r\n  ")
+			sb.WriteString(quoteIdentifier(dbType, column, false))
+			sb.WriteString(" ")
+			sb.WriteString(types.varchar)
+			sb.WriteString("(64)")
+		}
+	}
+
+	if len(primaryKeys) > 0 {
+		sb.WriteString(",\r\n  PRIMARY KEY (")
+		for idx, field := range primaryKeys {
+			if idx > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(quoteColumn(dbType, field))
+		}
+		sb.WriteString(")")
+	}
+	sb.WriteString("\r\n)")
+	return sb.String(), nil
+}
+
+func isPrimaryKey(primaryKeys []*FieldInfo, field *FieldInfo) bool {
+	for _, key := range primaryKeys {
+		if key == field {
+			return true
+		}
+	}
+	return false
+}
+
+type ddlIndex struct {
+	name    string
+	unique  bool
+	columns []string
+}
+
+// GenerateCreateIndexSQL 按结构体的定义生成建索引的语句， 字段有 index 选项时为它创建索引，
+// index=名称 和 unique=名称 时名称相同的字段组成一个联合索引， 没有名称时为 idx_表名_列名
+func GenerateCreateIndexSQL(dbType Dialect, mapper *Mapper, rType reflect.Type) ([]string, error) {
+	if rType.Kind() == reflect.Ptr {
+		rType = rType.Elem()
+	}
+	tableName, err := ReadTableName(mapper, rType)
+	if err != nil {
+		return nil, err
+	}
+	shortName := tableName
+	if idx := strings.LastIndex(shortName, "."); idx >= 0 {
+		shortName = shortName[idx+1:]
+	}
+
+	var indexes []*ddlIndex
+	byName := map[string]*ddlIndex{}
+	add := func(name string, unique bool, column string) error {
+		if index, ok := byName[name]; ok {
+			if index.unique != unique {
+				return errors.New("index '" + name + "' is both unique and not unique")
+			}
+			index.columns = append(index.columns, column)
+			return nil
+		}
+		index := &ddlIndex{name: name, unique: unique, columns: []string{column}}
+		byName[name] = index
+		indexes = append(indexes, index)
+		return nil
+	}
+
+	for _, field := range tableColumns(mapper, rType) {
+		column := quoteColumn(dbType, field)
+		if name, ok := field.Options["index"]; ok {
+			if name == "" {
+				name = "idx_" + shortName + "_" + field.Name
+			}
+			if err := add(name, false, column); err != nil {
+				return nil, err
+			}
+		}
+		if name := field.Options["unique"]; name != "" {
+			if err := add(name, true, column); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	sqlStrs := make([]string, 0, len(indexes))
+	for _, index := range indexes {
+		var sb strings.Builder
+		sb.WriteString("CREATE ")
+		if index.unique {
+			sb.WriteString("UNIQUE ")
+		}
+		sb.WriteString("INDEX ")
+		sb.WriteString(quoteIdentifier(dbType, index.name, false))
+		sb.WriteString(" ON ")
+		sb.WriteString(quoteTableName(dbType, tableName))
+		sb.WriteString(" (")
+		sb.WriteString(strings.Join(index.columns, ", "))
+		sb.WriteString(")")
+		sqlStrs = append(sqlStrs, sb.String())
+	}
+	return sqlStrs, nil
+}
+
+// GenerateDDL 生成建表和建索引的语句， 每个语句以分号结尾
+func GenerateDDL(dbType Dialect, mapper *Mapper, rType reflect.Type) (string, error) {
+	createTable, err := GenerateCreateTableSQL(dbType, mapper, rType)
+	if err != nil {
+		return "", err
+	}
+	createIndexes, err := GenerateCreateIndexSQL(dbType, mapper, rType)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	sb.WriteString(createTable)
+	sb.WriteString(";\r\n")
+	for _, s := range createIndexes {
+		sb.WriteString(s)
+		sb.WriteString(";\r\n")
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,214 @@
+package gobatis_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	gobatis "github.com/runner-mei/GoBatis"
+	"github.com/runner-mei/GoBatis/tests"
+)
+
+type DDLBase struct {
+	CreatedAt time.Time  `db:"created_at,created"`
+	DeletedAt *time.Time `db:"deleted_at,deleted"`
+}
+
+type DDLUser struct {
+	TableName struct{}               `db:"ddl_users"`
+	ID        int64                  `db:"id,pk,autoincr"`
+	Name      string                 `db:"name,size=50,notnull,unique"`
+	OrgID     int64                  `db:"org_id,index=idx_ddl_users_org"`
+	Email     string                 `db:"email,size=100,unique=uq_ddl_users_email"`
+	Age       int32                  `db:"age,default=0"`
+	Enabled   bool                   `db:"enabled"`
+	Score     *float64               `db:"score,index"`
+	Tags      []string               `db:"tags"`
+	Avatar    []byte                 `db:"avatar"`
+	Attrs     map[string]interface{} `db:"attrs,json"`
+	DDLBase
+	Ignored string `db:"-"`
+}
+
+type DDLDocument struct {
+	TableName struct{} `db:"ddl_documents"`
+	ID        int64    `db:"id,autoincr"`
+	Title     string   `db:"title,size=100"`
+}
+
+type DDLUserGroup struct {
+	TableName struct{} `db:"ddl_user_groups"`
+	UserID    int64    `db:"user_id,pk"`
+	GroupID   int64    `db:"group_id,pk,index"`
+}
+
+func TestGenerateCreateTableSQL(t *testing.T) {
+	for idx, test := range []struct {
+		dbType   gobatis.Dialect
+		value    interface{}
+		excepted string
+		indexes  []string
+	}{
+		{
+			dbType: gobatis.DbTypePostgres,
+			value:  &DDLUser{},
+			excepted: "CREATE TABLE ddl_users (id bigserial NOT NULL, name varchar(50) NOT NULL UNIQUE, org_id bigint, email varchar(100)," +
+				" age int DEFAULT 0, enabled boolean, score double precision, tags text[], avatar bytea, attrs json," +
+				" created_at timestamp with time zone, deleted_at timestamp with time zone, PRIMARY KEY (id))",
+			indexes: []string{
+				"CREATE INDEX idx_ddl_users_org ON ddl_users (org_id)",
+				"CREATE UNIQUE INDEX uq_ddl_users_email ON ddl_users (email)",
+				"CREATE INDEX idx_ddl_users_score ON ddl_users (score)",
+			},
+		},
+		{
+			dbType: gobatis.DbTypeMysql,
+			value:  &DDLUser{},
+			excepted: "CREATE TABLE ddl_users (id bigint AUTO_INCREMENT NOT NULL, name varchar(50) NOT NULL UNIQUE, org_id bigint, email varchar(100)," +
+				" age int DEFAULT 0, enabled boolean, score double, tags json, avatar longblob, attrs json," +
+				" created_at datetime, deleted_at datetime, PRIMARY KEY (id))",
+			indexes: []string{
+				"CREATE INDEX idx_ddl_users_org ON ddl_users (org_id)",
+				"CREATE UNIQUE INDEX uq_ddl_users_email ON ddl_users (email)",
+				"CREATE INDEX idx_ddl_users_score ON ddl_users (score)",
+			},
+		},
+		{
+			dbType: gobatis.DbTypeMSSql,
+			value:  &DDLUser{},
+			excepted: "CREATE TABLE ddl_users (id bigint IDENTITY(1,1) NOT NULL, name nvarchar(50) NOT NULL UNIQUE, org_id bigint, email nvarchar(100)," +
+				" age int DEFAULT 0, enabled bit, score float, tags nvarchar(max), avatar varbinary(max), attrs nvarchar(max)," +
+				" created_at datetime, deleted_at datetime, PRIMARY KEY (id))",
+			indexes: []string{
+				"CREATE INDEX idx_ddl_users_org ON ddl_users (org_id)",
+				"CREATE UNIQUE INDEX uq_ddl_users_email ON ddl_users (email)",
+				"CREATE INDEX idx_ddl_users_score ON ddl_users (score)",
+			},
+		},
+		{
+			dbType: gobatis.DbTypeSqlite,
+			value:  &DDLUser{},
+			excepted: "CREATE TABLE ddl_users (id INTEGER PRIMARY KEY AUTOINCREMENT, name varchar(50) NOT NULL UNIQUE, org_id bigint, email varchar(100)," +
+				" age int DEFAULT 0, enabled boolean, score double, tags text, avatar blob, attrs text," +
+				" created_at datetime, deleted_at datetime)",
+			indexes: []string{
+				"CREATE INDEX idx_ddl_users_org ON ddl_users (org_id)",
+				"CREATE UNIQUE INDEX uq_ddl_users_email ON ddl_users (email)",
+				"CREATE INDEX idx_ddl_users_score ON ddl_users (score)",
+			},
+		},
+		{
+			dbType:   gobatis.DbTypePostgres,
+			value:    &DDLDocument{},
+			excepted: "CREATE TABLE ddl_documents (id bigserial NOT NULL, title varchar(100), PRIMARY KEY (id))",
+			indexes:  []string{},
+		},
+		{
+			dbType:   gobatis.DbTypeMysql,
+			value:    &DDLDocument{},
+			excepted: "CREATE TABLE ddl_documents (id bigint AUTO_INCREMENT NOT NULL, title varchar(100), PRIMARY KEY (id))",
+			indexes:  []string{},
+		},
+		{
+			dbType:   gobatis.DbTypeSqlite,
+			value:    &DDLDocument{},
+			excepted: "CREATE TABLE ddl_documents (id INTEGER PRIMARY KEY AUTOINCREMENT, title varchar(100))",
+			indexes:  []string{},
+		},
+		{
+			dbType:   gobatis.DbTypeKingbase,
+			value:    &DDLUserGroup{},
+			excepted: "CREATE TABLE ddl_user_groups (user_id bigint NOT NULL, group_id bigint NOT NULL, PRIMARY KEY (user_id, group_id))",
+			indexes:  []string{"CREATE INDEX idx_ddl_user_groups_group_id ON ddl_user_groups (group_id)"},
+		},
+	} {
+		actaul, err := gobatis.GenerateCreateTableSQL(test.dbType, mapper, reflect.TypeOf(test.value))
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		actaul = strings.Join(strings.Fields(strings.Replace(actaul, "\r\n", " ", -1)), " ")
+		actaul = strings.Replace(strings.Replace(actaul, "( ", "(", -1), " )", ")", -1)
+		if actaul != test.excepted {
+			t.Error("[", idx, "] excepted is", test.excepted)
+			t.Error("[", idx, "] actual   is", actaul)
+		}
+
+		indexes, err := gobatis.GenerateCreateIndexSQL(test.dbType, mapper, reflect.TypeOf(test.value))
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		if !reflect.DeepEqual(indexes, test.indexes) {
+			t.Error("[", idx, "] excepted is", test.indexes)
+			t.Error("[", idx, "] actual   is", indexes)
+		}
+	}
+
+	if _, err := gobatis.GenerateCreateTableSQL(gobatis.DbTypeSqlite, mapper, reflect.TypeOf(&struct {
+		TableName struct{} `db:"ddl_error"`
+		ID        int64    `db:"id,pk,autoincr"`
+		Sub       int64    `db:"sub,pk"`
+	}{})); err == nil {
+		t.Error("excepted error got ok")
+	}
+}
+
+func TestGenerateDDL(t *testing.T) {
+	factory, err := gobatis.New(&gobatis.Config{DriverName: tests.TestDrv,
+		DataSource: tests.TestConnURL,
+		Tracer:     gobatis.NullTracer{}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer factory.Close()
+
+	ctx := context.Background()
+	factory.DB().ExecContext(ctx, `DROP TABLE IF EXISTS ddl_users`)
+	ddl, err := gobatis.GenerateDDL(factory.Dialect(), factory.Mapper(), reflect.TypeOf(&DDLUser{}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for _, s := range strings.Split(ddl, ";\r\n") {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		if _, err := factory.DB().ExecContext(ctx, s); err != nil {
+			t.Error(s)
+			t.Error(err)
+			return
+		}
+	}
+	defer factory.DB().ExecContext(ctx, `DROP TABLE ddl_users`)
+
+	// 生成的表可以保存和读取记录
+	insertSQL, err := gobatis.GenerateInsertSQL(factory.Dialect(), factory.Mapper(), reflect.TypeOf(&DDLUser{}), nil, nil, true)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	record := DDLUser{Name: "abc", OrgID: 1, Email: "abc@example.com", Tags: []string{"a", "b"},
+		Avatar: []byte{1, 2}, Attrs: map[string]interface{}{"a": "b"}}
+	if _, err := factory.ExecRaw(ctx, insertSQL, &record); err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := factory.ExecRaw(ctx, insertSQL, &record); err == nil {
+		t.Error("excepted error got ok")
+	}
+
+	var users []DDLUser
+	if err := factory.QueryRaw(ctx, `SELECT * FROM ddl_users`).ScanSlice(&users); err != nil {
+		t.Error(err)
+		return
+	}
+	if len(users) != 1 || users[0].ID == 0 || users[0].Name != "abc" ||
+		!reflect.DeepEqual(users[0].Tags, record.Tags) || users[0].Attrs["a"] != "b" {
+		t.Error("excepted is", record)
+		t.Error("actual   is", users)
+	}
+}
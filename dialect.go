@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/lib/pq"
 )
@@ -17,22 +19,34 @@ type Dialect interface {
 	MakeArrayValuer(interface{}) (interface{}, error)
 	MakeArrayScanner(string, interface{}) (interface{}, error)
 	GeneratePagination(int64, int64) (string, []interface{})
+	Quote(string) string
 }
 
 type dialect struct {
 	name            string
+	parent          Dialect
 	placeholder     PlaceholderFormat
 	hasLastInsertID bool
 	handleError     func(e error) error
 
 	makeArrayValuer  func(interface{}) (interface{}, error)
 	makeArrayScanner func(string, interface{}) (interface{}, error)
+
+	generatePagination func(int64, int64) (string, []interface{})
+
+	// quote 是引用标识符的左右字符， 如 `""`, "``" 和 "[]"
+	quote string
 }
 
 func (d *dialect) Name() string {
 	return d.name
 }
 
+// Parent 返回本方言所兼容的方言，如 KingbaseES 兼容 Postgres，没有时返回 nil
+func (d *dialect) Parent() Dialect {
+	return d.parent
+}
+
 func (d *dialect) Placeholder() PlaceholderFormat {
 	return d.placeholder
 }
@@ -57,6 +71,9 @@ func (d *dialect) MakeArrayScanner(name string, v interface{}) (interface{}, err
 }
 
 func (d *dialect) GeneratePagination(offset, limit int64) (string, []interface{}) {
+	if d.generatePagination != nil {
+		return d.generatePagination(offset, limit)
+	}
 	if offset > 0 {
 		if limit > 0 {
 			return fmt.Sprintf(" OFFSET %d LIMIT %d ", offset, limit), nil
@@ -70,6 +87,16 @@ func (d *dialect) GeneratePagination(offset, limit int64) (string, []interface{}
 	return "", nil
 }
 
+// Quote 引用一个标识符， 如 "x", `x` 和 [x]
+func (d *dialect) Quote(ident string) string {
+	quote := d.quote
+	if quote == "" {
+		quote = `""`
+	}
+	left, right := quote[:1], quote[1:]
+	return left + strings.Replace(ident, right, right+right, -1) + right
+}
+
 var (
 	makeArrayValuer = func(v interface{}) (interface{}, error) {
 		bs, err := json.Marshal(v)
@@ -81,41 +108,179 @@ var (
 	}
 
 	makePQArrayValuer = func(v interface{}) (interface{}, error) {
-		value := pq.Array(v)
-		return value, nil
+		switch v.(type) {
+		case []bool, *[]bool,
+			[]float64, *[]float64,
+			[]int64, *[]int64,
+			[]string, *[]string:
+			return pq.Array(v), nil
+		}
+		return &pqArray{value: v}, nil
+	}
+	// sqlite 没有数组类型， 数组用 json 文本保存， 以便能使用 json 函数查询
+	makeSqliteArrayValuer = func(v interface{}) (interface{}, error) {
+		bs, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return string(bs), nil
 	}
+
 	makePQArrayScanner = func(name string, v interface{}) (interface{}, error) {
 		switch v.(type) {
-		case *[]bool:
-		case *[]float64:
-		case *[]int64:
-		case *[]string:
-		default:
-			return nil, errors.New("column '" + name + "' is array, it isnot support - []bool, []float64, []int64 and []string")
+		case *[]bool, *[]float64, *[]int64, *[]string:
+			return pq.Array(v), nil
 		}
 
-		value := pq.Array(v)
-		return value, nil
+		typ := reflect.TypeOf(v)
+		for typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+		if typ.Kind() != reflect.Slice || !isArrayLeafSupported(arrayLeafType(typ)) {
+			return nil, errors.New("column '" + name + "' is array, it isnot support - " + typ.String())
+		}
+		return &pqArray{name: name, value: v}, nil
 	}
 
 	DbTypeNone     Dialect = &dialect{name: "unknown", placeholder: Question, hasLastInsertID: true, makeArrayValuer: makeArrayValuer, makeArrayScanner: makeArrayScanner}
 	DbTypePostgres Dialect = &dialect{name: "postgres", placeholder: Dollar, hasLastInsertID: false, makeArrayValuer: makePQArrayValuer, makeArrayScanner: makePQArrayScanner, handleError: handlePQError}
-	DbTypeMysql    Dialect = &dialect{name: "mysql", placeholder: Question, hasLastInsertID: true, makeArrayValuer: makeArrayValuer, makeArrayScanner: makeArrayScanner}
-	DbTypeMSSql    Dialect = &dialect{name: "mssql", placeholder: Question, hasLastInsertID: false, makeArrayValuer: makeArrayValuer, makeArrayScanner: makeArrayScanner}
+	DbTypeMysql    Dialect = &dialect{name: "mysql", placeholder: Question, hasLastInsertID: true, makeArrayValuer: makeArrayValuer, makeArrayScanner: makeArrayScanner, handleError: handleMysqlError, quote: "``"}
+	DbTypeMSSql    Dialect = &dialect{name: "mssql", placeholder: Question, hasLastInsertID: false, makeArrayValuer: makeArrayValuer, makeArrayScanner: makeArrayScanner, handleError: handleMSSqlError, quote: "[]"}
 	DbTypeOracle   Dialect = &dialect{name: "oracle", placeholder: Question, hasLastInsertID: true, makeArrayValuer: makeArrayValuer, makeArrayScanner: makeArrayScanner}
+	DbTypeSqlite   Dialect = &dialect{name: "sqlite", placeholder: Question, hasLastInsertID: false, makeArrayValuer: makeSqliteArrayValuer, makeArrayScanner: makeArrayScanner, handleError: handleSqliteError, generatePagination: generateSqlitePagination}
 )
 
+// sqlite 的 OFFSET 必须跟在 LIMIT 后面， 没有 LIMIT 时用 LIMIT -1 表示不限制
+func generateSqlitePagination(offset, limit int64) (string, []interface{}) {
+	if offset > 0 {
+		if limit > 0 {
+			return fmt.Sprintf(" LIMIT %d OFFSET %d ", limit, offset), nil
+		}
+		return fmt.Sprintf(" LIMIT -1 OFFSET %d ", offset), nil
+	}
+
+	if limit > 0 {
+		return fmt.Sprintf(" LIMIT %d ", limit), nil
+	}
+	return "", nil
+}
+
+var (
+	DbTypeKingbase  Dialect = InheritDialect("kingbase", DbTypePostgres)
+	DbTypeOpenGauss Dialect = InheritDialect("opengauss", DbTypePostgres)
+	DbTypeHighgo    Dialect = InheritDialect("highgo", DbTypePostgres)
+	DbTypeDameng    Dialect = InheritDialect("dameng", DbTypeOracle)
+)
+
+// InheritDialect 创建一个兼容 parent 的方言， 它的行为与 parent 一致，
+// 生成 SQL 时如果没有为它指定专门的 SQL 语句，那么会使用 parent 的 SQL 语句
+func InheritDialect(name string, parent Dialect) Dialect {
+	if parent == nil {
+		panic(errors.New("parent of dialect '" + name + "' is nil"))
+	}
+	return &dialect{
+		name:               name,
+		parent:             parent,
+		placeholder:        parent.Placeholder(),
+		hasLastInsertID:    parent.InsertIDSupported(),
+		handleError:        parent.HandleError,
+		makeArrayValuer:    parent.MakeArrayValuer,
+		makeArrayScanner:   parent.MakeArrayScanner,
+		generatePagination: parent.GeneratePagination,
+		quote:              parent.Quote(""),
+	}
+}
+
+// ParentDialect 返回 d 所兼容的方言，没有时返回 nil
+func ParentDialect(d Dialect) Dialect {
+	if p, ok := d.(interface {
+		Parent() Dialect
+	}); ok {
+		return p.Parent()
+	}
+	return nil
+}
+
+// rootDialect 返回 d 最终所兼容的内置方言
+func rootDialect(d Dialect) Dialect {
+	for parent := ParentDialect(d); parent != nil; parent = ParentDialect(d) {
+		d = parent
+	}
+	return d
+}
+
+// IsDialect 判断 d 是否就是 target 或兼容 target
+func IsDialect(d, target Dialect) bool {
+	for ; d != nil; d = ParentDialect(d) {
+		if d == target {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchDialect 从 names 中找出与 d 最匹配的方言，找不到时依次用 d 所兼容的方言查找，
+// 都找不到时返回 d 本身， 生成的代码用它来选择不同方言的 SQL 语句
+func MatchDialect(d Dialect, names ...string) Dialect {
+	for current := d; current != nil; current = ParentDialect(current) {
+		for _, name := range names {
+			if ToDbType(name) == current {
+				return current
+			}
+		}
+	}
+	return d
+}
+
+var (
+	dialectLock sync.RWMutex
+	dialects    = map[string]Dialect{}
+)
+
+// RegisterDialect 注册一个方言， name 和 aliases 不区分大小写，
+// 已存在的同名方言会被覆盖
+func RegisterDialect(name string, d Dialect, aliases ...string) {
+	if d == nil {
+		panic(errors.New("dialect '" + name + "' is nil"))
+	}
+
+	dialectLock.Lock()
+	defer dialectLock.Unlock()
+
+	dialects[strings.ToLower(name)] = d
+	for _, alias := range aliases {
+		dialects[strings.ToLower(alias)] = d
+	}
+}
+
+// UnregisterDialect 删除用 RegisterDialect 注册的方言名称和别名， names 不区分大小写
+func UnregisterDialect(names ...string) {
+	dialectLock.Lock()
+	defer dialectLock.Unlock()
+
+	for _, name := range names {
+		delete(dialects, strings.ToLower(name))
+	}
+}
+
+func init() {
+	RegisterDialect("postgres", DbTypePostgres, "postgresql")
+	RegisterDialect("mysql", DbTypeMysql)
+	RegisterDialect("mssql", DbTypeMSSql, "sqlserver")
+	RegisterDialect("oracle", DbTypeOracle, "ora")
+	RegisterDialect("sqlite", DbTypeSqlite, "sqlite3")
+	RegisterDialect("kingbase", DbTypeKingbase, "kingbasees", "kingbase8")
+	RegisterDialect("opengauss", DbTypeOpenGauss, "gaussdb")
+	RegisterDialect("highgo", DbTypeHighgo, "hgdb")
+	RegisterDialect("dameng", DbTypeDameng, "dm")
+}
+
 func ToDbType(driverName string) Dialect {
-	switch strings.ToLower(driverName) {
-	case "postgres":
-		return DbTypePostgres
-	case "mysql":
-		return DbTypeMysql
-	case "mssql", "sqlserver":
-		return DbTypeMSSql
-	case "oracle", "ora":
-		return DbTypeOracle
-	default:
-		return DbTypeNone
+	dialectLock.RLock()
+	d, ok := dialects[strings.ToLower(driverName)]
+	dialectLock.RUnlock()
+	if ok {
+		return d
 	}
+	return DbTypeNone
 }
@@ -0,0 +1,231 @@
+package gobatis
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var (
+	_textMarshalerInterface     = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	_textUnmarshalerInterface   = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	_binaryMarshalerInterface   = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+	_binaryUnmarshalerInterface = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+	_jsonMarshalerInterface     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// 字段的编码方式
+//
+//	`db:"status,enum=string"` 或 `db:"status,text"` 用 encoding.TextMarshaler 和 encoding.TextUnmarshaler 保存为文本
+//	`db:"status,enum=int"`                           按类型本身的值(如 int)保存， 这是默认的方式
+//	`db:"data,binary"`                              用 encoding.BinaryMarshaler 和 encoding.BinaryUnmarshaler 保存为二进制
+//	`db:"data,json"`                                类型实现了 json.Marshaler 时用 json 保存
+//
+// 在 sql 中也可以用 #{status,enum=string}, #{status,text} 和 #{data,binary} 来指定参数的编码方式
+const (
+	encodingText   = "text"
+	encodingBinary = "binary"
+	encodingJSON   = "json"
+)
+
+func toEncoding(key, value string) (string, error) {
+	switch key {
+	case "enum":
+		switch value {
+		case "", "int":
+			return "", nil
+		case "string", "text":
+			return encodingText, nil
+		}
+		return "", errors.New("enum '" + value + "' is unsupported")
+	case encodingText, encodingBinary:
+		return key, nil
+	}
+	return "", nil
+}
+
+// fieldEncoding 返回字段的编码方式， 没有指定时返回空字符串
+func fieldEncoding(field *FieldInfo) (string, error) {
+	if field.Options == nil {
+		return "", nil
+	}
+	if value, ok := field.Options["enum"]; ok {
+		return toEncoding("enum", value)
+	}
+
+	typ := field.Field.Type
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	// 注意 text 也是 xorm 中的列类型， 所以只有实现了对应的接口时才当作编码方式
+	if _, ok := field.Options[encodingText]; ok && implements(typ, _textMarshalerInterface) {
+		return encodingText, nil
+	}
+	if _, ok := field.Options[encodingBinary]; ok && implements(typ, _binaryMarshalerInterface) {
+		return encodingBinary, nil
+	}
+
+	_, jsonExists := field.Options["json"]
+	if !jsonExists {
+		_, jsonExists = field.Options["jsonb"]
+	}
+	if jsonExists {
+		switch typ.Kind() {
+		case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array, reflect.Interface:
+			// 这些类型本来就是用 json 保存的
+		default:
+			if implements(typ, _jsonMarshalerInterface) {
+				return encodingJSON, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// encodingOption 返回字段在 #{} 中所需要的编码选项
+func encodingOption(field *FieldInfo) string {
+	switch name, _ := fieldEncoding(field); name {
+	case encodingText, encodingBinary:
+		return "," + name
+	}
+	return ""
+}
+
+// fieldParamOptions 返回生成 sql 时字段在 #{} 中需要的选项
+func fieldParamOptions(field *FieldInfo) string {
+	return encodingOption(field) + encryptOption(field)
+}
+
+func makeEncodingHandler(name string, typ reflect.Type) (TypeHandler, error) {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	switch name {
+	case encodingText:
+		if !implements(typ, _textMarshalerInterface) {
+			return nil, errors.New("'" + typ.String() + "' isnot implement encoding.TextMarshaler")
+		}
+		if !reflect.PtrTo(typ).Implements(_textUnmarshalerInterface) {
+			return nil, errors.New("'" + typ.String() + "' isnot implement encoding.TextUnmarshaler")
+		}
+		return textEncoding{typ: typ}, nil
+	case encodingBinary:
+		if !implements(typ, _binaryMarshalerInterface) {
+			return nil, errors.New("'" + typ.String() + "' isnot implement encoding.BinaryMarshaler")
+		}
+		if !reflect.PtrTo(typ).Implements(_binaryUnmarshalerInterface) {
+			return nil, errors.New("'" + typ.String() + "' isnot implement encoding.BinaryUnmarshaler")
+		}
+		return binaryEncoding{typ: typ}, nil
+	case encodingJSON:
+		return jsonEncoding{typ: typ}, nil
+	}
+	return nil, errors.New("encoding '" + name + "' is unsupported")
+}
+
+func implements(typ, iface reflect.Type) bool {
+	return typ.Implements(iface) || reflect.PtrTo(typ).Implements(iface)
+}
+
+// addressable 返回 value 的指针， 以便调用定义在指针上的方法
+func addressable(value interface{}) interface{} {
+	rv := reflect.New(reflect.TypeOf(value))
+	rv.Elem().Set(reflect.ValueOf(value))
+	return rv.Interface()
+}
+
+func srcToBytes(src interface{}) ([]byte, error) {
+	switch s := src.(type) {
+	case []byte:
+		return s, nil
+	case string:
+		return []byte(s), nil
+	}
+	return nil, fmt.Errorf("want string or []byte, but got '%T'", src)
+}
+
+type textEncoding struct {
+	typ reflect.Type
+}
+
+func (h textEncoding) ToDB(dialect Dialect, value interface{}) (interface{}, error) {
+	m, ok := value.(encoding.TextMarshaler)
+	if !ok {
+		m, ok = addressable(value).(encoding.TextMarshaler)
+		if !ok {
+			return nil, fmt.Errorf("'%T' isnot implement encoding.TextMarshaler", value)
+		}
+	}
+	bs, err := m.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return string(bs), nil
+}
+
+func (h textEncoding) FromDB(dialect Dialect, src interface{}) (interface{}, error) {
+	bs, err := srcToBytes(src)
+	if err != nil {
+		return nil, err
+	}
+	rv := reflect.New(h.typ)
+	if err := rv.Interface().(encoding.TextUnmarshaler).UnmarshalText(bs); err != nil {
+		return nil, err
+	}
+	return rv.Elem().Interface(), nil
+}
+
+type binaryEncoding struct {
+	typ reflect.Type
+}
+
+func (h binaryEncoding) ToDB(dialect Dialect, value interface{}) (interface{}, error) {
+	m, ok := value.(encoding.BinaryMarshaler)
+	if !ok {
+		m, ok = addressable(value).(encoding.BinaryMarshaler)
+		if !ok {
+			return nil, fmt.Errorf("'%T' isnot implement encoding.BinaryMarshaler", value)
+		}
+	}
+	return m.MarshalBinary()
+}
+
+func (h binaryEncoding) FromDB(dialect Dialect, src interface{}) (interface{}, error) {
+	bs, err := srcToBytes(src)
+	if err != nil {
+		return nil, err
+	}
+	rv := reflect.New(h.typ)
+	if err := rv.Interface().(encoding.BinaryUnmarshaler).UnmarshalBinary(bs); err != nil {
+		return nil, err
+	}
+	return rv.Elem().Interface(), nil
+}
+
+type jsonEncoding struct {
+	typ reflect.Type
+}
+
+func (h jsonEncoding) ToDB(dialect Dialect, value interface{}) (interface{}, error) {
+	bs, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	return string(bs), nil
+}
+
+func (h jsonEncoding) FromDB(dialect Dialect, src interface{}) (interface{}, error) {
+	bs, err := srcToBytes(src)
+	if err != nil {
+		return nil, err
+	}
+	rv := reflect.New(h.typ)
+	if err := json.Unmarshal(bs, rv.Interface()); err != nil {
+		return nil, err
+	}
+	return rv.Elem().Interface(), nil
+}
@@ -0,0 +1,240 @@
+package gobatis_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	gobatis "github.com/runner-mei/GoBatis"
+)
+
+type encodingStatus int
+
+const (
+	encodingStatusUnknown encodingStatus = iota
+	encodingStatusActive
+	encodingStatusDisabled
+)
+
+var encodingStatusNames = []string{"unknown", "active", "disabled"}
+
+func (s encodingStatus) MarshalText() ([]byte, error) {
+	if int(s) < 0 || int(s) >= len(encodingStatusNames) {
+		return nil, errors.New("status is invalid")
+	}
+	return []byte(encodingStatusNames[s]), nil
+}
+
+func (s *encodingStatus) UnmarshalText(bs []byte) error {
+	for idx, name := range encodingStatusNames {
+		if name == string(bs) {
+			*s = encodingStatus(idx)
+			return nil
+		}
+	}
+	return errors.New("status '" + string(bs) + "' is invalid")
+}
+
+type encodingPoint struct {
+	X, Y byte
+}
+
+func (p encodingPoint) MarshalBinary() ([]byte, error) {
+	return []byte{p.X, p.Y}, nil
+}
+
+func (p *encodingPoint) UnmarshalBinary(bs []byte) error {
+	if len(bs) != 2 {
+		return errors.New("point is invalid")
+	}
+	p.X, p.Y = bs[0], bs[1]
+	return nil
+}
+
+type encodingLevel int
+
+func (l encodingLevel) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]int{"level": int(l)})
+}
+
+func (l *encodingLevel) UnmarshalJSON(bs []byte) error {
+	var m map[string]int
+	if err := json.Unmarshal(bs, &m); err != nil {
+		return err
+	}
+	*l = encodingLevel(m["level"])
+	return nil
+}
+
+type EncodingRecord struct {
+	TableName struct{}        `db:"encoding_records"`
+	ID        int64           `db:"id,autoincr,pk"`
+	Status    encodingStatus  `db:"status,enum=string"`
+	StatusPtr *encodingStatus `db:"status_ptr,text"`
+	Code      encodingStatus  `db:"code,enum=int"`
+	Point     encodingPoint   `db:"point,binary"`
+	Level     encodingLevel   `db:"level,json"`
+	Remark    string          `db:"remark,text"`
+	Bad       int             `db:"bad,enum=string"`
+}
+
+func TestFieldEncoding(t *testing.T) {
+	mapper := gobatis.CreateMapper("", nil, nil)
+	tm := mapper.TypeMap(reflect.TypeOf(EncodingRecord{}))
+
+	record := &EncodingRecord{Status: encodingStatusActive, Code: encodingStatusDisabled, Point: encodingPoint{X: 1, Y: 2}, Level: 3, Remark: "abc"}
+	rv := reflect.ValueOf(record).Elem()
+	for idx, test := range []struct {
+		column   string
+		excepted interface{}
+	}{
+		{column: "status", excepted: "active"},
+		{column: "status_ptr", excepted: nil},
+		{column: "code", excepted: encodingStatusDisabled},
+		{column: "point", excepted: []byte{1, 2}},
+		{column: "level", excepted: `{"level":3}`},
+		{column: "remark", excepted: "abc"},
+	} {
+		value, err := tm.Names[test.column].RValue(gobatis.DbTypePostgres, &gobatis.Param{Name: test.column}, rv)
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		if !reflect.DeepEqual(value, test.excepted) {
+			t.Error("[", idx, "] excepted is", test.excepted)
+			t.Error("[", idx, "] actual   is", value)
+		}
+	}
+
+	if _, err := tm.Names["bad"].RValue(gobatis.DbTypePostgres, &gobatis.Param{Name: "bad"}, rv); err == nil {
+		t.Error("excepted error got ok")
+	} else if !strings.Contains(err.Error(), "TextMarshaler") {
+		t.Error(err)
+	}
+
+	var scanned EncodingRecord
+	rv = reflect.ValueOf(&scanned).Elem()
+	for idx, test := range []struct {
+		column string
+		src    interface{}
+	}{
+		{column: "status", src: []byte("disabled")},
+		{column: "status_ptr", src: "active"},
+		{column: "point", src: []byte{3, 4}},
+		{column: "level", src: []byte(`{"level":5}`)},
+	} {
+		scanner, err := tm.Names[test.column].LValue(gobatis.DbTypePostgres, test.column, rv)
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		if err := scanner.(interface{ Scan(interface{}) error }).Scan(test.src); err != nil {
+			t.Error("[", idx, "]", err)
+		}
+	}
+	if scanned.Status != encodingStatusDisabled {
+		t.Error("excepted is", encodingStatusDisabled)
+		t.Error("actual   is", scanned.Status)
+	}
+	if scanned.StatusPtr == nil || *scanned.StatusPtr != encodingStatusActive {
+		t.Error("excepted is", encodingStatusActive)
+		t.Error("actual   is", scanned.StatusPtr)
+	}
+	if scanned.Point != (encodingPoint{X: 3, Y: 4}) {
+		t.Error("excepted is", encodingPoint{X: 3, Y: 4})
+		t.Error("actual   is", scanned.Point)
+	}
+	if scanned.Level != 5 {
+		t.Error("excepted is 5")
+		t.Error("actual   is", scanned.Level)
+	}
+
+	scanner, _ := tm.Names["status"].LValue(gobatis.DbTypePostgres, "status", rv)
+	if err := scanner.(interface{ Scan(interface{}) error }).Scan([]byte("notexists")); err == nil {
+		t.Error("excepted error got ok")
+	}
+
+	initCtx := &gobatis.InitContext{Config: &gobatis.Config{},
+		Dialect:    gobatis.DbTypePostgres,
+		Mapper:     mapper,
+		Statements: make(map[string]*gobatis.MappedStatement)}
+	for idx, test := range []struct {
+		sql         string
+		paramNames  []string
+		paramValues []interface{}
+		excepted    []interface{}
+	}{
+		{sql: `select * from t where status = #{status,enum=string}`,
+			paramNames:  []string{"status"},
+			paramValues: []interface{}{encodingStatusActive},
+			excepted:    []interface{}{"active"}},
+		{sql: `select * from t where point = #{point,binary}`,
+			paramNames:  []string{"point"},
+			paramValues: []interface{}{encodingPoint{X: 7, Y: 8}},
+			excepted:    []interface{}{[]byte{7, 8}}},
+		{sql: `select * from t where status = #{r.status} and code = #{r.code,text}`,
+			paramNames:  []string{"r"},
+			paramValues: []interface{}{record},
+			excepted:    []interface{}{"active", "disabled"}},
+	} {
+		stmt, err := gobatis.NewMapppedStatement(initCtx, "encoding", gobatis.StatementTypeSelect, gobatis.ResultStruct, test.sql)
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		ctx, err := gobatis.NewContext(nil, gobatis.DbTypePostgres, mapper, test.paramNames, test.paramValues)
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		sqlParams, err := stmt.GenerateSQLs(ctx)
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		if !reflect.DeepEqual(sqlParams[0].Params, test.excepted) {
+			t.Error("[", idx, "] excepted is", test.excepted)
+			t.Error("[", idx, "] actual   is", sqlParams[0].Params)
+		}
+	}
+
+	if _, err := gobatis.NewMapppedStatement(initCtx, "encoding", gobatis.StatementTypeSelect, gobatis.ResultStruct,
+		`select * from t where status = #{status,enum=bit}`); err == nil {
+		t.Error("excepted error got ok")
+	}
+
+	statusType := reflect.TypeOf(encodingStatusUnknown)
+	for idx, test := range []struct {
+		stmtType string
+		names    []string
+		argTypes []reflect.Type
+		sql      string
+	}{
+		{stmtType: "select", names: []string{"status"}, argTypes: []reflect.Type{statusType},
+			sql: `SELECT * FROM encoding_records WHERE status=#{status,text}`},
+		{stmtType: "select", names: []string{"status"}, argTypes: []reflect.Type{reflect.SliceOf(statusType)},
+			sql: `SELECT * FROM encoding_records WHERE status in (<foreach collection="status" item="item" separator="," >#{item,text}</foreach>)`},
+		{stmtType: "update", names: []string{"id", "status"}, argTypes: []reflect.Type{reflect.TypeOf(int64(0)), statusType},
+			sql: `UPDATE encoding_records SET status=#{status,text} WHERE id=#{id}`},
+	} {
+		var actaul string
+		var err error
+		rType := reflect.TypeOf(&EncodingRecord{})
+		switch test.stmtType {
+		case "update":
+			actaul, err = gobatis.GenerateUpdateSQL2(gobatis.DbTypePostgres, mapper, rType, reflect.TypeOf(int64(0)), "id", test.names[1:])
+		default:
+			actaul, err = gobatis.GenerateSelectSQL(gobatis.DbTypePostgres, mapper, rType, test.names, test.argTypes, nil)
+		}
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		if actaul != test.sql {
+			t.Error("[", idx, "] excepted is", test.sql, "|")
+			t.Error("[", idx, "] actual   is", actaul, "|")
+		}
+	}
+}
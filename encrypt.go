@@ -0,0 +1,354 @@
+package gobatis
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/runner-mei/GoBatis/reflectx"
+)
+
+// Cipher 用于对带有 encrypt 标签的字段(如 `db:"phone,encrypt"`)进行透明加解密，
+// 它通过 Config.Cipher 来配置
+type Cipher interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+
+	// Deterministic 表示相同的明文是否总是加密为相同的密文，
+	// 只有为 true 时才可以直接用密文进行等值查询
+	Deterministic() bool
+
+	// BlindIndex 计算明文的盲索引， 它用于对非确定性加密的字段进行等值查询，
+	// 字段需要用 blindindex 标签指定保存盲索引的列， 如 `db:"phone,encrypt,blindindex=phone_bidx"`
+	BlindIndex(plaintext []byte) ([]byte, error)
+}
+
+type aesGCMCipher struct {
+	keyID         string
+	aeads         map[string]cipher.AEAD
+	blindIndexKey []byte
+}
+
+// NewAESGCMCipher 创建一个 AES-GCM 的加密器， keys 为 key id 到密钥的映射，
+// 加密时用 keyID 对应的密钥并将 key id 作为密文的前缀， 解密时按密文的前缀选择密钥，
+// 这样轮换密钥时旧的数据仍然可以解密。 blindIndexKey 用于计算盲索引(HMAC-SHA256)，
+// 它不随密钥轮换而改变， 为空时不支持盲索引。
+func NewAESGCMCipher(keyID string, keys map[string][]byte, blindIndexKey []byte) (Cipher, error) {
+	if _, ok := keys[keyID]; !ok {
+		return nil, errors.New("key '" + keyID + "' isnot found")
+	}
+
+	c := &aesGCMCipher{keyID: keyID, aeads: map[string]cipher.AEAD{}, blindIndexKey: blindIndexKey}
+	for id, key := range keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, errors.New("key id '" + id + "' is invalid")
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, errors.New("key '" + id + "' is invalid, " + err.Error())
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, errors.New("key '" + id + "' is invalid, " + err.Error())
+		}
+		c.aeads[id] = aead
+	}
+	return c, nil
+}
+
+func (c *aesGCMCipher) Encrypt(plaintext []byte) ([]byte, error) {
+	aead := c.aeads[c.keyID]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, nil)
+
+	var buf bytes.Buffer
+	buf.WriteString(c.keyID)
+	buf.WriteByte(':')
+	buf.WriteString(base64.StdEncoding.EncodeToString(sealed))
+	return buf.Bytes(), nil
+}
+
+func (c *aesGCMCipher) Decrypt(ciphertext []byte) ([]byte, error) {
+	idx := bytes.IndexByte(ciphertext, ':')
+	if idx < 0 {
+		return nil, errors.New("key id of ciphertext is missing")
+	}
+	keyID := string(ciphertext[:idx])
+	aead := c.aeads[keyID]
+	if aead == nil {
+		return nil, errors.New("key '" + keyID + "' isnot found")
+	}
+	sealed, err := base64.StdEncoding.DecodeString(string(ciphertext[idx+1:]))
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("ciphertext is too short")
+	}
+	return aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], nil)
+}
+
+func (c *aesGCMCipher) Deterministic() bool {
+	return false
+}
+
+func (c *aesGCMCipher) BlindIndex(plaintext []byte) ([]byte, error) {
+	if len(c.blindIndexKey) == 0 {
+		return nil, errors.New("blind index key isnot set")
+	}
+	mac := hmac.New(sha256.New, c.blindIndexKey)
+	mac.Write(plaintext)
+	sum := mac.Sum(nil)
+	bs := make([]byte, hex.EncodedLen(len(sum)))
+	hex.Encode(bs, sum)
+	return bs, nil
+}
+
+// SetCipher 设置加密字段所使用的加密器
+func (m *Mapper) SetCipher(c Cipher) {
+	m.cipher = c
+}
+
+func (m *Mapper) getCipher() (Cipher, error) {
+	if m == nil || m.cipher == nil {
+		return nil, errors.New("cipher isnot configured")
+	}
+	return m.cipher, nil
+}
+
+func isEncryptField(field *FieldInfo) bool {
+	if field == nil || field.Options == nil {
+		return false
+	}
+	_, ok := field.Options["encrypt"]
+	return ok
+}
+
+// toCipherInput 将参数的值转换为要加密的字节， 返回 nil 表示值为 NULL
+func toCipherInput(value interface{}) ([]byte, bool, error) {
+	if valuer, ok := value.(driver.Valuer); ok {
+		v, err := valuer.Value()
+		if err != nil {
+			return nil, false, err
+		}
+		value = v
+	}
+
+	switch v := value.(type) {
+	case nil:
+		return nil, false, nil
+	case string:
+		return []byte(v), true, nil
+	case *string:
+		if v == nil {
+			return nil, false, nil
+		}
+		return []byte(*v), true, nil
+	case []byte:
+		if v == nil {
+			return nil, false, nil
+		}
+		return v, false, nil
+	default:
+		return nil, false, fmt.Errorf("'%T' cannot be encrypted, only string and []byte are supported", value)
+	}
+}
+
+func encryptValue(c Cipher, value interface{}) (interface{}, error) {
+	plaintext, isString, err := toCipherInput(value)
+	if err != nil || plaintext == nil {
+		return nil, err
+	}
+	ciphertext, err := c.Encrypt(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	if isString {
+		return string(ciphertext), nil
+	}
+	return ciphertext, nil
+}
+
+func blindIndexValue(c Cipher, value interface{}) (interface{}, error) {
+	plaintext, _, err := toCipherInput(value)
+	if err != nil || plaintext == nil {
+		return nil, err
+	}
+	idx, err := c.BlindIndex(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	return string(idx), nil
+}
+
+func (m *Mapper) encryptParam(param *Param, value interface{}) (interface{}, error) {
+	c, err := m.getCipher()
+	if err != nil {
+		return nil, errors.New("param '" + param.Name + "' " + err.Error())
+	}
+	if param.BlindIndex {
+		value, err = blindIndexValue(c, value)
+	} else {
+		value, err = encryptValue(c, value)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("param '%s' encrypt fail, %s", param.Name, err)
+	}
+	return value, nil
+}
+
+var _ sql.Scanner = &decryptScanner{}
+
+type decryptScanner struct {
+	mapper *Mapper
+	name   string
+	field  reflect.Value
+
+	Valid bool
+}
+
+func (s *decryptScanner) Scan(src interface{}) error {
+	if src == nil {
+		s.field.Set(reflect.Zero(s.field.Type()))
+		return nil
+	}
+
+	var ciphertext []byte
+	switch v := src.(type) {
+	case []byte:
+		ciphertext = v
+	case string:
+		ciphertext = []byte(v)
+	default:
+		return fmt.Errorf("column %s should byte array but got '%T', target type '%s'", s.name, src, s.field.Type())
+	}
+
+	c, err := s.mapper.getCipher()
+	if err != nil {
+		return errors.New("column " + s.name + " " + err.Error())
+	}
+	plaintext, err := c.Decrypt(ciphertext)
+	if err != nil {
+		return errors.New("column " + s.name + " decrypt fail, " + err.Error())
+	}
+
+	field := s.field
+	if field.Kind() == reflect.Ptr {
+		field.Set(reflect.New(field.Type().Elem()))
+		field = field.Elem()
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(string(plaintext))
+	case reflect.Slice:
+		field.SetBytes(plaintext)
+	default:
+		return fmt.Errorf("column %s cannot decrypt into '%s'", s.name, s.field.Type())
+	}
+	s.Valid = true
+	return nil
+}
+
+// encryptOption 返回字段在 #{} 中所需要的加密选项
+func encryptOption(field *FieldInfo) string {
+	if isEncryptField(field) {
+		return ",encrypt"
+	}
+	return ""
+}
+
+// blindIndexColumn 返回加密字段的盲索引列名， 没有时返回空字符串
+func blindIndexColumn(field *FieldInfo) string {
+	if !isEncryptField(field) {
+		return ""
+	}
+	return field.Options["blindindex"]
+}
+
+// encryptedLookup 返回对加密字段进行等值查询时使用的列和参数选项，
+// 非确定性加密的字段必须声明了盲索引列才能用于查询
+func encryptedLookup(dbType Dialect, mapper *Mapper, field *FieldInfo) (string, string, error) {
+	if column := blindIndexColumn(field); column != "" {
+		return quoteIdentifier(dbType, column, false), ",blindindex", nil
+	}
+	if mapper.cipher != nil && mapper.cipher.Deterministic() {
+		return quoteColumn(dbType, field), ",encrypt", nil
+	}
+	return "", "", errors.New("column '" + field.Name + "' is encrypted, it cannot be used in where without a blindindex column")
+}
+
+func (fi *FieldInfo) makeEncryptRValue(mapper *Mapper) func(dialect Dialect, param *Param, v reflect.Value) (interface{}, error) {
+	return func(dialect Dialect, param *Param, v reflect.Value) (interface{}, error) {
+		field := reflectx.FieldByIndexesReadOnly(v, fi.Index)
+		value, err := toSQLType(dialect, param, field.Interface())
+		if err != nil {
+			return nil, err
+		}
+		c, err := mapper.getCipher()
+		if err != nil {
+			return nil, errors.New("field '" + fi.Field.Name + "' " + err.Error())
+		}
+		value, err = encryptValue(c, value)
+		if err != nil {
+			return nil, fmt.Errorf("field '%s' encrypt fail, %s", fi.Field.Name, err)
+		}
+		return value, nil
+	}
+}
+
+func (fi *FieldInfo) makeDecryptLValue(mapper *Mapper) func(dialect Dialect, column string, v reflect.Value) (interface{}, error) {
+	return func(dialect Dialect, column string, v reflect.Value) (interface{}, error) {
+		field := reflectx.FieldByIndexes(v, fi.Index)
+		return &decryptScanner{mapper: mapper, name: column, field: field}, nil
+	}
+}
+
+func writeBlindIndexColumn(sb *strings.Builder, dbType Dialect, field *FieldInfo) {
+	if column := blindIndexColumn(field); column != "" {
+		sb.WriteString(", ")
+		sb.WriteString(quoteIdentifier(dbType, column, false))
+	}
+}
+
+func writeBlindIndexParam(sb *strings.Builder, name string, field *FieldInfo) {
+	if column := blindIndexColumn(field); column != "" {
+		sb.WriteString(", #{")
+		sb.WriteString(name)
+		sb.WriteString(",blindindex}")
+	}
+}
+
+func writeBlindIndexSet(sb *strings.Builder, dbType Dialect, name string, field *FieldInfo) {
+	if column := blindIndexColumn(field); column != "" {
+		sb.WriteString(", ")
+		sb.WriteString(quoteIdentifier(dbType, column, false))
+		sb.WriteString("=#{")
+		sb.WriteString(name)
+		sb.WriteString(",blindindex}")
+	}
+}
+
+// checkUpsertEncryptFields 检查 upsert 语句中的加密字段， 盲索引列暂时不支持 upsert
+func checkUpsertEncryptFields(fields []*FieldInfo) error {
+	for _, field := range fields {
+		if blindIndexColumn(field) != "" {
+			return errors.New("column '" + field.Name + "' has a blindindex column, it is unsupported in upsert")
+		}
+	}
+	return nil
+}
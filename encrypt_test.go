@@ -0,0 +1,194 @@
+package gobatis_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	gobatis "github.com/runner-mei/GoBatis"
+)
+
+func TestAESGCMCipher(t *testing.T) {
+	k1 := []byte("0123456789abcdef")
+	k2 := []byte("fedcba9876543210fedcba9876543210")
+
+	old, err := gobatis.NewAESGCMCipher("k1", map[string][]byte{"k1": k1}, []byte("bidx"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	ciphertext, err := old.Encrypt([]byte("13800138000"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !strings.HasPrefix(string(ciphertext), "k1:") {
+		t.Error("excepted is k1:...")
+		t.Error("actual   is", string(ciphertext))
+	}
+	again, _ := old.Encrypt([]byte("13800138000"))
+	if string(again) == string(ciphertext) {
+		t.Error("aes-gcm should not be deterministic")
+	}
+
+	rotated, err := gobatis.NewAESGCMCipher("k2", map[string][]byte{"k1": k1, "k2": k2}, []byte("bidx"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	plaintext, err := rotated.Decrypt(ciphertext)
+	if err != nil {
+		t.Error(err)
+	} else if string(plaintext) != "13800138000" {
+		t.Error("excepted is 13800138000")
+		t.Error("actual   is", string(plaintext))
+	}
+	ciphertext, _ = rotated.Encrypt([]byte("13800138000"))
+	if !strings.HasPrefix(string(ciphertext), "k2:") {
+		t.Error("excepted is k2:...")
+		t.Error("actual   is", string(ciphertext))
+	}
+	if _, err := old.Decrypt(ciphertext); err == nil {
+		t.Error("excepted error got ok")
+	}
+
+	idx1, _ := old.BlindIndex([]byte("13800138000"))
+	idx2, _ := rotated.BlindIndex([]byte("13800138000"))
+	if string(idx1) != string(idx2) {
+		t.Error("blind index should not changed after rotated")
+	}
+
+	if _, err := gobatis.NewAESGCMCipher("k3", map[string][]byte{"k1": k1}, nil); err == nil {
+		t.Error("excepted error got ok")
+	}
+	if _, err := gobatis.NewAESGCMCipher("k1", map[string][]byte{"k1": []byte("short")}, nil); err == nil {
+		t.Error("excepted error got ok")
+	}
+}
+
+func TestEncryptField(t *testing.T) {
+	c, err := gobatis.NewAESGCMCipher("k1", map[string][]byte{"k1": []byte("0123456789abcdef")}, []byte("bidx"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	mapper := gobatis.CreateMapper("", nil, nil)
+	mapper.SetCipher(c)
+
+	decrypt := func(value interface{}) string {
+		s, ok := value.(string)
+		if !ok {
+			t.Errorf("want string got %T", value)
+			return ""
+		}
+		bs, err := c.Decrypt([]byte(s))
+		if err != nil {
+			t.Error(err)
+			return ""
+		}
+		return string(bs)
+	}
+	bidx, _ := c.BlindIndex([]byte("13800138000"))
+
+	tm := mapper.TypeMap(reflect.TypeOf(T20{}))
+	email := "a@b.c"
+	record := &T20{Phone: "13800138000", Email: &email}
+	rv := reflect.ValueOf(record).Elem()
+
+	value, err := tm.Names["phone"].RValue(gobatis.DbTypePostgres, &gobatis.Param{Name: "phone"}, rv)
+	if err != nil {
+		t.Error(err)
+	} else if s := decrypt(value); s != "13800138000" {
+		t.Error("excepted is 13800138000")
+		t.Error("actual   is", s)
+	}
+	value, err = tm.Names["email"].RValue(gobatis.DbTypePostgres, &gobatis.Param{Name: "email"}, reflect.ValueOf(&T20{}).Elem())
+	if err != nil {
+		t.Error(err)
+	} else if value != nil {
+		t.Error("excepted is nil")
+		t.Error("actual   is", value)
+	}
+
+	var scanned T20
+	srv := reflect.ValueOf(&scanned).Elem()
+	for _, column := range []string{"phone", "email"} {
+		ciphertext, _ := c.Encrypt([]byte(column + "-value"))
+		scanner, err := tm.Names[column].LValue(gobatis.DbTypePostgres, column, srv)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if err := scanner.(interface{ Scan(interface{}) error }).Scan(ciphertext); err != nil {
+			t.Error(err)
+		}
+	}
+	if scanned.Phone != "phone-value" {
+		t.Error("excepted is phone-value")
+		t.Error("actual   is", scanned.Phone)
+	}
+	if scanned.Email == nil || *scanned.Email != "email-value" {
+		t.Error("excepted is email-value")
+		t.Error("actual   is", scanned.Email)
+	}
+
+	initCtx := &gobatis.InitContext{Config: &gobatis.Config{},
+		Dialect:    gobatis.DbTypePostgres,
+		Mapper:     mapper,
+		Statements: make(map[string]*gobatis.MappedStatement)}
+	for idx, test := range []struct {
+		sql         string
+		paramNames  []string
+		paramValues []interface{}
+		check       func(params []interface{}) bool
+	}{
+		{sql: `select * from t20 where phone_bidx = #{phone,blindindex}`,
+			paramNames:  []string{"phone"},
+			paramValues: []interface{}{"13800138000"},
+			check: func(params []interface{}) bool {
+				return params[0] == string(bidx)
+			}},
+		{sql: `insert into t20(phone) values(#{phone,encrypt})`,
+			paramNames:  []string{"phone"},
+			paramValues: []interface{}{"13800138000"},
+			check: func(params []interface{}) bool {
+				return decrypt(params[0]) == "13800138000"
+			}},
+		{sql: `insert into t20(phone, phone_bidx) values(#{r.phone}, #{r.phone,blindindex})`,
+			paramNames:  []string{"r"},
+			paramValues: []interface{}{record},
+			check: func(params []interface{}) bool {
+				return decrypt(params[0]) == "13800138000" && params[1] == string(bidx)
+			}},
+		{sql: `insert into t20(phone) values(#{phone,encrypt})`,
+			paramValues: []interface{}{record},
+			check: func(params []interface{}) bool {
+				return decrypt(params[0]) == "13800138000"
+			}},
+	} {
+		stmt, err := gobatis.NewMapppedStatement(initCtx, "encrypt", gobatis.StatementTypeSelect, gobatis.ResultStruct, test.sql)
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		ctx, err := gobatis.NewContext(nil, gobatis.DbTypePostgres, mapper, test.paramNames, test.paramValues)
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		sqlParams, err := stmt.GenerateSQLs(ctx)
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		if !test.check(sqlParams[0].Params) {
+			t.Error("[", idx, "] params is unexcepted -", sqlParams[0].Params)
+		}
+	}
+
+	ctx, _ := gobatis.NewContext(nil, gobatis.DbTypePostgres, gobatis.CreateMapper("", nil, nil), []string{"phone"}, []interface{}{"a"})
+	if _, err := ctx.RValue(&gobatis.Param{Name: "phone", Encrypt: true}); err == nil || !strings.Contains(err.Error(), "cipher isnot configured") {
+		t.Error("excepted is cipher isnot configured")
+		t.Error("actual   is", err)
+	}
+}
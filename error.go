@@ -4,50 +4,290 @@ import (
 	"errors"
 	"strings"
 
+	"github.com/go-sql-driver/mysql"
 	"github.com/lib/pq"
 )
 
 var ErrMultSQL = errors.New("mult sql is unsupported")
 
+// ErrStaleObject 表示带有乐观锁的更新没有更新到任何记录， 即记录已经被其它人修改或删除了
+var ErrStaleObject = errors.New("object is stale, it has been modified or deleted by others")
+
+// 数据库错误的分类，各个方言的错误转换后可以用 errors.Is(err, ErrUniqueViolation) 来判断，
+// 而不用关心具体的驱动
+var (
+	ErrUniqueViolation     = errors.New("unique value already exists")
+	ErrForeignKeyViolation = errors.New("foreign key violation")
+	ErrNotNullViolation    = errors.New("not null violation")
+	ErrCheckViolation      = errors.New("check violation")
+	ErrDeadlock            = errors.New("deadlock detected")
+	ErrSerialization       = errors.New("could not serialize access")
+)
+
 // ValidationError store the Message & Key of a validation error
+//
+// Columns 是出错的字段名， Constraint 是出错的约束或索引名（如 mysql 的唯一索引名 name 或 PRIMARY），
+// 有的数据库（如 mysql 的 1062 错误）只返回索引名而不返回字段名， 这时 Columns 为空
 type ValidationError struct {
 	Code, Message string
 	Columns       []string
+	Constraint    string
 }
 
 // Error store a error with validation errors
 type Error struct {
 	Validations []ValidationError
 	e           error
+	kind        error
 }
 
 func (err *Error) Error() string {
 	return err.e.Error()
 }
 
+// Unwrap 返回驱动的原始错误，以便 errors.As(err, &pqErr) 能用
+func (err *Error) Unwrap() error {
+	return err.e
+}
+
+// Is 判断错误是否属于 target 这一类，如 ErrUniqueViolation
+func (err *Error) Is(target error) bool {
+	return err.kind != nil && err.kind == target
+}
+
+var pqErrorKinds = map[pq.ErrorCode]error{
+	"23505": ErrUniqueViolation,
+	"23503": ErrForeignKeyViolation,
+	"23502": ErrNotNullViolation,
+	"23514": ErrCheckViolation,
+	"40P01": ErrDeadlock,
+	"40001": ErrSerialization,
+}
+
 func handlePQError(e error) error {
 	if e == nil {
 		return nil
 	}
 
 	if pe, ok := e.(*pq.Error); ok {
+		kind := pqErrorKinds[pe.Code]
 		switch pe.Code {
 		case "23505":
-			detail := strings.TrimPrefix(strings.TrimPrefix(pe.Detail, "Key ("), "键值\"(")
-			if pidx := strings.Index(detail, ")"); pidx > 0 {
+			if columns := readPQDetailColumns(pe.Detail); len(columns) > 0 {
 				return &Error{Validations: []ValidationError{
-					{Code: "unique_value_already_exists", Message: pe.Detail, Columns: strings.Split(detail[:pidx], ",")},
-				}, e: e}
+					{Code: "unique_value_already_exists", Message: pe.Detail, Columns: columns, Constraint: pe.Constraint},
+				}, e: e, kind: kind}
+			}
+		case "23503":
+			if columns := readPQDetailColumns(pe.Detail); len(columns) > 0 {
+				return &Error{Validations: []ValidationError{
+					{Code: "PG." + pe.Code.Name(), Message: pe.Detail, Columns: columns, Constraint: pe.Constraint},
+				}, e: e, kind: kind}
+			}
+		}
+		return &Error{Validations: []ValidationError{
+			{Code: "PG." + pe.Code.Name(), Message: pe.Message, Columns: []string{pe.Column}, Constraint: pe.Constraint},
+		}, e: e, kind: kind}
+	}
+	return e
+}
+
+// readPQDetailColumns 从 Detail 中读取字段名，Detail 的格式如： Key (name)=(abc) already exists.
+func readPQDetailColumns(detail string) []string {
+	detail = strings.TrimPrefix(strings.TrimPrefix(detail, "Key ("), "键值\"(")
+	if pidx := strings.Index(detail, ")"); pidx > 0 {
+		columns := strings.Split(detail[:pidx], ",")
+		for idx := range columns {
+			columns[idx] = strings.TrimSpace(columns[idx])
+		}
+		return columns
+	}
+	return nil
+}
+
+var mysqlErrorKinds = map[uint16]struct {
+	code string
+	kind error
+}{
+	1062: {code: "unique_value_already_exists", kind: ErrUniqueViolation},
+	1216: {code: "MYSQL.foreign_key_violation", kind: ErrForeignKeyViolation},
+	1217: {code: "MYSQL.foreign_key_violation", kind: ErrForeignKeyViolation},
+	1451: {code: "MYSQL.foreign_key_violation", kind: ErrForeignKeyViolation},
+	1452: {code: "MYSQL.foreign_key_violation", kind: ErrForeignKeyViolation},
+	1048: {code: "MYSQL.not_null_violation", kind: ErrNotNullViolation},
+	1364: {code: "MYSQL.not_null_violation", kind: ErrNotNullViolation},
+	3819: {code: "MYSQL.check_violation", kind: ErrCheckViolation},
+	1213: {code: "MYSQL.deadlock_detected", kind: ErrDeadlock},
+}
+
+func handleMysqlError(e error) error {
+	if e == nil {
+		return nil
+	}
+
+	me, ok := e.(*mysql.MySQLError)
+	if !ok {
+		return e
+	}
+	c, ok := mysqlErrorKinds[me.Number]
+	if !ok {
+		return e
+	}
+
+	var columns []string
+	var constraint string
+	switch me.Number {
+	case 1062:
+		// Duplicate entry 'abc' for key 'name' 或 Duplicate entry 'abc' for key 'users.name'，
+		// 这里的 name 是唯一索引的名称（主键为 PRIMARY）， 不是字段名
+		constraint = readQuotedName(me.Message, "for key ", '\'')
+	case 1216, 1217, 1451, 1452:
+		// ... CONSTRAINT `fk_user` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`))
+		columns = readQuotedAfter(me.Message, "FOREIGN KEY (", '`')
+		constraint = readQuotedName(me.Message, "CONSTRAINT ", '`')
+	case 1048:
+		// Column 'name' cannot be null
+		columns = readQuotedAfter(me.Message, "Column ", '\'')
+	case 1364:
+		// Field 'name' doesn't have a default value
+		columns = readQuotedAfter(me.Message, "Field ", '\'')
+	case 3819:
+		// Check constraint 'age_chk' is violated.
+		constraint = readQuotedName(me.Message, "constraint ", '\'')
+	}
+	return &Error{Validations: []ValidationError{
+		{Code: c.code, Message: me.Message, Columns: columns, Constraint: constraint},
+	}, e: e, kind: c.kind}
+}
+
+var mssqlErrorKinds = map[int32]struct {
+	code string
+	kind error
+}{
+	2627: {code: "unique_value_already_exists", kind: ErrUniqueViolation},
+	2601: {code: "unique_value_already_exists", kind: ErrUniqueViolation},
+	515:  {code: "MSSQL.not_null_violation", kind: ErrNotNullViolation},
+	1205: {code: "MSSQL.deadlock_detected", kind: ErrDeadlock},
+	3960: {code: "MSSQL.serialization_failure", kind: ErrSerialization},
+}
+
+// handleMSSqlError 通过 SQLErrorNumber() 来识别 mssql 的错误，这样就不用依赖 mssql 的驱动了
+func handleMSSqlError(e error) error {
+	if e == nil {
+		return nil
+	}
+
+	me, ok := e.(interface {
+		SQLErrorNumber() int32
+		SQLErrorMessage() string
+	})
+	if !ok {
+		return e
+	}
+
+	number, msg := me.SQLErrorNumber(), me.SQLErrorMessage()
+	c, ok := mssqlErrorKinds[number]
+	if !ok {
+		if number != 547 {
+			return e
+		}
+
+		// 547 即可能是外键约束， 也可能是 check 约束
+		if strings.Contains(msg, "CHECK constraint") {
+			c.code, c.kind = "MSSQL.check_violation", ErrCheckViolation
+		} else {
+			c.code, c.kind = "MSSQL.foreign_key_violation", ErrForeignKeyViolation
+		}
+	}
+
+	var columns []string
+	var constraint string
+	switch number {
+	case 2627:
+		// Violation of UNIQUE KEY constraint 'UQ_name'. Cannot insert duplicate key in object 'dbo.users'. ...
+		constraint = readQuotedName(msg, "constraint ", '\'')
+	case 2601:
+		// Cannot insert duplicate key row in object 'dbo.users' with unique index 'IX_name'. ...
+		constraint = readQuotedName(msg, "unique index ", '\'')
+	case 515:
+		// Cannot insert the value NULL into column 'name', table 'db.dbo.users'; column does not allow nulls. INSERT fails.
+		columns = readQuotedAfter(msg, "column ", '\'')
+	case 547:
+		// The INSERT statement conflicted with the FOREIGN KEY constraint "FK_x". The conflict occurred in database "db", table "dbo.users", column 'id'.
+		columns = readQuotedAfter(msg, ", column ", '\'')
+		constraint = readQuotedName(msg, "constraint ", '"')
+	}
+	return &Error{Validations: []ValidationError{
+		{Code: c.code, Message: msg, Columns: columns, Constraint: constraint},
+	}, e: e, kind: c.kind}
+}
+
+// readQuotedAfter 读取 msg 中 prefix 后面用 quote 括起来的字段名， 名字前面的表名会被去掉
+func readQuotedAfter(msg, prefix string, quote byte) []string {
+	name := readQuotedName(msg, prefix, quote)
+	if name == "" {
+		return nil
+	}
+	return []string{name}
+}
+
+// readQuotedName 读取 msg 中 prefix 后面用 quote 括起来的名字， 名字前面的表名会被去掉
+func readQuotedName(msg, prefix string, quote byte) string {
+	idx := strings.Index(msg, prefix)
+	if idx < 0 {
+		return ""
+	}
+	s := msg[idx+len(prefix):]
+	if len(s) == 0 || s[0] != quote {
+		return ""
+	}
+	s = s[1:]
+	end := strings.IndexByte(s, quote)
+	if end <= 0 {
+		return ""
+	}
+	name := s[:end]
+	if dot := strings.LastIndex(name, "."); dot >= 0 {
+		name = name[dot+1:]
+	}
+	return name
+}
+
+// handleSqliteError 根据错误消息来转换 sqlite 的约束错误，这样就不用依赖 sqlite 的驱动了
+// 错误消息的格式如： UNIQUE constraint failed: users.name, users.email
+func handleSqliteError(e error) error {
+	if e == nil {
+		return nil
+	}
+
+	msg := e.Error()
+	for _, c := range []struct {
+		prefix string
+		code   string
+		kind   error
+	}{
+		{prefix: "UNIQUE constraint failed", code: "unique_value_already_exists", kind: ErrUniqueViolation},
+		{prefix: "NOT NULL constraint failed", code: "SQLITE.not_null_violation", kind: ErrNotNullViolation},
+		{prefix: "FOREIGN KEY constraint failed", code: "SQLITE.foreign_key_violation", kind: ErrForeignKeyViolation},
+		{prefix: "CHECK constraint failed", code: "SQLITE.check_violation", kind: ErrCheckViolation},
+	} {
+		if !strings.HasPrefix(msg, c.prefix) {
+			continue
+		}
+
+		var columns []string
+		if s := strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(msg, c.prefix), ":")); s != "" {
+			for _, column := range strings.Split(s, ",") {
+				column = strings.TrimSpace(column)
+				if idx := strings.LastIndex(column, "."); idx >= 0 {
+					column = column[idx+1:]
+				}
+				columns = append(columns, column)
 			}
-		// case "23503":
-		//  return &Error{Validations: []ValidationError{
-		//    {Code: "PG.foreign_key_constraint", Message: pe.Message},
-		//  }, e: e}
-		default:
-			return &Error{Validations: []ValidationError{
-				{Code: "PG." + pe.Code.Name(), Message: pe.Message, Columns: []string{pe.Column}},
-			}, e: e}
 		}
+		return &Error{Validations: []ValidationError{
+			{Code: c.code, Message: msg, Columns: columns},
+		}, e: e, kind: c.kind}
 	}
 	return e
 }
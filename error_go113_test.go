@@ -0,0 +1,178 @@
+// +build go1.13
+
+package gobatis_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	gobatis "github.com/runner-mei/GoBatis"
+)
+
+type mssqlError struct {
+	Number  int32
+	Message string
+}
+
+func (e mssqlError) Error() string           { return "mssql: " + e.Message }
+func (e mssqlError) SQLErrorNumber() int32   { return e.Number }
+func (e mssqlError) SQLErrorMessage() string { return e.Message }
+
+func TestErrorIs(t *testing.T) {
+	for idx, test := range []struct {
+		dialect    gobatis.Dialect
+		err        error
+		kind       error
+		columns    []string
+		constraint string
+	}{
+		{dialect: gobatis.DbTypePostgres,
+			err:        &pq.Error{Code: "23505", Message: "duplicate key value violates unique constraint", Detail: "Key (name)=(abc) already exists.", Constraint: "users_name_key"},
+			kind:       gobatis.ErrUniqueViolation,
+			columns:    []string{"name"},
+			constraint: "users_name_key"},
+		{dialect: gobatis.DbTypePostgres,
+			err:     &pq.Error{Code: "23503", Message: "insert or update violates foreign key constraint", Detail: "Key (user_id)=(3) is not present in table \"users\"."},
+			kind:    gobatis.ErrForeignKeyViolation,
+			columns: []string{"user_id"}},
+		{dialect: gobatis.DbTypePostgres,
+			err:     &pq.Error{Code: "23502", Message: "null value in column \"name\" violates not-null constraint", Column: "name"},
+			kind:    gobatis.ErrNotNullViolation,
+			columns: []string{"name"}},
+		{dialect: gobatis.DbTypePostgres,
+			err:     &pq.Error{Code: "40P01", Message: "deadlock detected"},
+			kind:    gobatis.ErrDeadlock,
+			columns: []string{""}},
+		{dialect: gobatis.DbTypeKingbase,
+			err:     &pq.Error{Code: "40001", Message: "could not serialize access due to concurrent update"},
+			kind:    gobatis.ErrSerialization,
+			columns: []string{""}},
+
+		{dialect: gobatis.DbTypeMysql,
+			err:        &mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'abc' for key 'gobatis_usergroups.uq_name'"},
+			kind:       gobatis.ErrUniqueViolation,
+			constraint: "uq_name"},
+		{dialect: gobatis.DbTypeMysql,
+			err:        &mysql.MySQLError{Number: 1062, Message: "Duplicate entry '1' for key 'PRIMARY'"},
+			kind:       gobatis.ErrUniqueViolation,
+			constraint: "PRIMARY"},
+		{dialect: gobatis.DbTypeMysql,
+			err:        &mysql.MySQLError{Number: 1452, Message: "Cannot add or update a child row: a foreign key constraint fails (`db`.`t`, CONSTRAINT `fk` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`))"},
+			kind:       gobatis.ErrForeignKeyViolation,
+			columns:    []string{"user_id"},
+			constraint: "fk"},
+		{dialect: gobatis.DbTypeMysql,
+			err:     &mysql.MySQLError{Number: 1048, Message: "Column 'name' cannot be null"},
+			kind:    gobatis.ErrNotNullViolation,
+			columns: []string{"name"}},
+		{dialect: gobatis.DbTypeMysql,
+			err:        &mysql.MySQLError{Number: 3819, Message: "Check constraint 'age_chk' is violated."},
+			kind:       gobatis.ErrCheckViolation,
+			constraint: "age_chk"},
+		{dialect: gobatis.DbTypeMysql,
+			err:  &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock; try restarting transaction"},
+			kind: gobatis.ErrDeadlock},
+
+		{dialect: gobatis.DbTypeMSSql,
+			err:        mssqlError{Number: 2627, Message: "Violation of UNIQUE KEY constraint 'UQ_name'. Cannot insert duplicate key in object 'dbo.users'. The duplicate key value is (abc)."},
+			kind:       gobatis.ErrUniqueViolation,
+			constraint: "UQ_name"},
+		{dialect: gobatis.DbTypeMSSql,
+			err:        mssqlError{Number: 2601, Message: "Cannot insert duplicate key row in object 'dbo.users' with unique index 'IX_name'. The duplicate key value is (abc)."},
+			kind:       gobatis.ErrUniqueViolation,
+			constraint: "IX_name"},
+		{dialect: gobatis.DbTypeMSSql,
+			err:     mssqlError{Number: 515, Message: "Cannot insert the value NULL into column 'name', table 'db.dbo.users'; column does not allow nulls. INSERT fails."},
+			kind:    gobatis.ErrNotNullViolation,
+			columns: []string{"name"}},
+		{dialect: gobatis.DbTypeMSSql,
+			err:        mssqlError{Number: 547, Message: "The INSERT statement conflicted with the FOREIGN KEY constraint \"FK_x\". The conflict occurred in database \"db\", table \"dbo.users\", column 'id'."},
+			kind:       gobatis.ErrForeignKeyViolation,
+			columns:    []string{"id"},
+			constraint: "FK_x"},
+		{dialect: gobatis.DbTypeMSSql,
+			err:        mssqlError{Number: 547, Message: "The INSERT statement conflicted with the CHECK constraint \"CK_age\". The conflict occurred in database \"db\", table \"dbo.users\", column 'age'."},
+			kind:       gobatis.ErrCheckViolation,
+			columns:    []string{"age"},
+			constraint: "CK_age"},
+		{dialect: gobatis.DbTypeMSSql,
+			err:  mssqlError{Number: 1205, Message: "Transaction (Process ID 52) was deadlocked on lock resources with another process and has been chosen as the deadlock victim. Rerun the transaction."},
+			kind: gobatis.ErrDeadlock},
+
+		{dialect: gobatis.DbTypeSqlite,
+			err:     errors.New("UNIQUE constraint failed: gobatis_usergroups.name"),
+			kind:    gobatis.ErrUniqueViolation,
+			columns: []string{"name"}},
+	} {
+		err := test.dialect.HandleError(test.err)
+		if !errors.Is(err, test.kind) {
+			t.Error("[", idx, "] excepted is", test.kind)
+			t.Error("[", idx, "] actual   is", err)
+			continue
+		}
+
+		for _, kind := range []error{
+			gobatis.ErrUniqueViolation,
+			gobatis.ErrForeignKeyViolation,
+			gobatis.ErrNotNullViolation,
+			gobatis.ErrCheckViolation,
+			gobatis.ErrDeadlock,
+			gobatis.ErrSerialization,
+		} {
+			if kind != test.kind && errors.Is(err, kind) {
+				t.Error("[", idx, "] error shouldnot is", kind)
+			}
+		}
+
+		if err.Error() != test.err.Error() {
+			t.Error("[", idx, "] excepted is", test.err.Error())
+			t.Error("[", idx, "] actual   is", err.Error())
+		}
+
+		if !errors.Is(err, test.err) {
+			t.Error("[", idx, "] unwrap fail")
+		}
+
+		var e *gobatis.Error
+		if !errors.As(err, &e) {
+			t.Error("[", idx, "] error isnot excepted -", err)
+			continue
+		}
+		if !reflect.DeepEqual(e.Validations[0].Columns, test.columns) {
+			t.Error("[", idx, "] excepted is", test.columns)
+			t.Error("[", idx, "] actual   is", e.Validations[0].Columns)
+		}
+		if e.Validations[0].Constraint != test.constraint {
+			t.Error("[", idx, "] excepted is", test.constraint)
+			t.Error("[", idx, "] actual   is", e.Validations[0].Constraint)
+		}
+	}
+
+	var pe *pq.Error
+	err := gobatis.DbTypePostgres.HandleError(&pq.Error{Code: "23505", Detail: "Key (name)=(abc) already exists."})
+	if !errors.As(err, &pe) {
+		t.Error("errors.As(*pq.Error) fail")
+	} else if pe.Code != "23505" {
+		t.Error("excepted is 23505")
+		t.Error("actual   is", pe.Code)
+	}
+
+	var me *mysql.MySQLError
+	err = gobatis.DbTypeMysql.HandleError(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'abc' for key 'name'"})
+	if !errors.As(err, &me) {
+		t.Error("errors.As(*mysql.MySQLError) fail")
+	}
+
+	for _, err := range []error{
+		&mysql.MySQLError{Number: 1146, Message: "Table 'db.abc' doesn't exist"},
+		mssqlError{Number: 208, Message: "Invalid object name 'abc'."},
+		errors.New("abc"),
+	} {
+		if gobatis.DbTypeMysql.HandleError(err) != err || gobatis.DbTypeMSSql.HandleError(err) != err {
+			t.Error(err, "is changed")
+		}
+	}
+}
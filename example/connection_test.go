@@ -139,6 +139,46 @@ CREATE TABLE IF NOT EXISTS auth_users_and_roles (
   
   PRIMARY KEY(user_id, role_id)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8;`
+
+	sqlite = `DROP TABLE IF EXISTS auth_users_and_roles;
+DROP TABLE IF EXISTS user_profiles;
+DROP TABLE IF EXISTS auth_users;
+
+CREATE TABLE IF NOT EXISTS auth_users (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  username VARCHAR(32) NOT NULL UNIQUE,
+  phone VARCHAR(32),
+  address VARCHAR(256),
+  status INT,
+  birth_day DATE,
+  created_at TIMESTAMP default CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP default CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS user_profiles (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  user_id int NOT NULL,
+  name varchar(45) DEFAULT NULL,
+  value varchar(255) DEFAULT NULL,
+  created_at TIMESTAMP default CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP default CURRENT_TIMESTAMP
+);
+
+DROP TABLE IF EXISTS auth_roles;
+
+CREATE TABLE IF NOT EXISTS auth_roles (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  name VARCHAR(32) NOT NULL UNIQUE,
+  created_at TIMESTAMP default CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP default CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS auth_users_and_roles (
+  user_id BIGINT,
+  role_id BIGINT,
+
+  PRIMARY KEY(user_id, role_id)
+);`
 )
 
 func toString(v interface{}) string {
@@ -162,6 +202,8 @@ func TestConnection(t *testing.T) {
 			_, err = factory.DB().ExecContext(context.Background(), postgres)
 		case gobatis.DbTypeMSSql:
 			_, err = factory.DB().ExecContext(context.Background(), mssql)
+		case gobatis.DbTypeSqlite:
+			_, err = factory.DB().ExecContext(context.Background(), sqlite)
 		default:
 			_, err = factory.DB().ExecContext(context.Background(), mysql)
 		}
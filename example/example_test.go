@@ -40,6 +40,8 @@ func ExampleSimple() {
 		_, err = factory.DB().ExecContext(context.Background(), postgres)
 	case gobatis.DbTypeMSSql:
 		_, err = factory.DB().ExecContext(context.Background(), mssql)
+	case gobatis.DbTypeSqlite:
+		_, err = factory.DB().ExecContext(context.Background(), sqlite)
 	default:
 		_, err = factory.DB().ExecContext(context.Background(), mysql)
 	}
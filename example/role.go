@@ -21,6 +21,9 @@ type RoleDao interface {
 	// @postgres insert into auth_roles(name, created_at, updated_at)
 	// values (#{name}, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP) returning id
 	//
+	// @sqlite insert into auth_roles(name, created_at, updated_at)
+	// values (#{name}, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP) returning id
+	//
 	// @default insert into auth_roles(name, created_at, updated_at)
 	// values (#{name}, CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 	Insert(name string) (int64, error)
@@ -29,6 +29,9 @@ type UserDao interface {
 	// @postgres insert into auth_users(username, phone, address, status, birth_day, created_at, updated_at)
 	// values (#{username},#{phone},#{address},#{status},#{birth_day},CURRENT_TIMESTAMP, CURRENT_TIMESTAMP) returning id
 	//
+	// @sqlite insert into auth_users(username, phone, address, status, birth_day, created_at, updated_at)
+	// values (#{username},#{phone},#{address},#{status},#{birth_day},CURRENT_TIMESTAMP, CURRENT_TIMESTAMP) returning id
+	//
 	// @default insert into auth_users(username, phone, address, status, birth_day, created_at, updated_at)
 	// values (#{username},#{phone},#{address},#{status},#{birth_day},CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
 	Insert(u *User) (int64, error)
@@ -54,6 +57,13 @@ type UserDao interface {
 	// on duplicate key update
 	//   username=values(username), phone=values(phone), address=values(address),
 	//   status=values(status), birth_day=values(birth_day), updated_at=CURRENT_TIMESTAMP
+	//
+	// @sqlite insert into auth_users(username, phone, address, status, birth_day, created_at, updated_at)
+	// values (#{username},#{phone},#{address},#{status},#{birth_day},CURRENT_TIMESTAMP, CURRENT_TIMESTAMP)
+	// on conflict (username) do update set
+	//   phone=excluded.phone, address=excluded.address,
+	//   status=excluded.status, birth_day=excluded.birth_day, updated_at=CURRENT_TIMESTAMP
+	// returning id
 	Upsert(u *User) (int64, error)
 
 	// @default UPDATE auth_users
@@ -103,6 +113,7 @@ type UserDao interface {
 
 	// @mssql select * from auth_users ORDER BY username OFFSET #{offset} ROWS FETCH NEXT #{size}  ROWS ONLY
 	// @mysql select * from auth_users limit #{offset}, #{size}
+	// @sqlite select * from auth_users limit #{size} offset #{offset}
 	// @default select * from auth_users offset #{offset} limit  #{size}
 	List(offset, size int) (users []*User, err error)
 
@@ -40,6 +40,8 @@ func ExampleSimple() {
 		_, err = factory.DB().ExecContext(context.Background(), tests.PostgresqlScript)
 	case gobatis.DbTypeMSSql:
 		_, err = factory.DB().ExecContext(context.Background(), tests.MssqlScript)
+	case gobatis.DbTypeSqlite:
+		_, err = factory.DB().ExecContext(context.Background(), tests.SqliteScript)
 	default:
 		_, err = factory.DB().ExecContext(context.Background(), tests.MysqlScript)
 	}
@@ -0,0 +1,89 @@
+package gobatis_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	gobatis "github.com/runner-mei/GoBatis"
+	"github.com/runner-mei/GoBatis/tests"
+)
+
+// fixtureStatement 是测试中注册的语句， generate 生成它的 sql， audited 为 true 时会调用 EnableAudit
+type fixtureStatement struct {
+	id       string
+	stmtType gobatis.StatementType
+	generate func(ctx *gobatis.InitContext) (string, error)
+	audited  bool
+}
+
+// setupFixture 注册 statements， 用 config 连接测试数据库， 并按 rType 的定义重建它的表，
+// 建表语句由 GenerateCreateTableSQL 生成， 这样每种数据库都有它自己的 DDL。
+// 返回的 teardown 会删除表， 关闭连接并恢复 Init 的回调， 出错时返回的 factory 为 nil
+func setupFixture(t *testing.T, config *gobatis.Config, rType reflect.Type, statements []fixtureStatement) (*gobatis.SessionFactory, func()) {
+	var closers []func()
+	teardown := func() {
+		for i := len(closers) - 1; i >= 0; i-- {
+			closers[i]()
+		}
+	}
+
+	callbacks := gobatis.ClearInit()
+	closers = append(closers, func() { gobatis.SetInit(callbacks) })
+
+	gobatis.Init(func(ctx *gobatis.InitContext) error {
+		for _, test := range statements {
+			sqlStr, err := test.generate(ctx)
+			if err != nil {
+				return err
+			}
+			stmt, err := gobatis.NewMapppedStatement(ctx, test.id, test.stmtType, gobatis.ResultStruct, sqlStr)
+			if err != nil {
+				return err
+			}
+			if test.audited {
+				if err := gobatis.EnableAudit(ctx, stmt, rType, ""); err != nil {
+					return err
+				}
+			}
+			ctx.Statements[test.id] = stmt
+		}
+		return nil
+	})
+
+	if config == nil {
+		config = &gobatis.Config{}
+	}
+	config.DriverName = tests.TestDrv
+	config.DataSource = tests.TestConnURL
+	if config.Tracer == nil {
+		config.Tracer = gobatis.NullTracer{}
+	}
+	factory, err := gobatis.New(config)
+	if err != nil {
+		t.Error(err)
+		return nil, teardown
+	}
+	closers = append(closers, func() { factory.Close() })
+
+	tableName, err := gobatis.ReadTableName(factory.Mapper(), rType)
+	if err != nil {
+		t.Error(err)
+		return nil, teardown
+	}
+	ddl, err := gobatis.GenerateCreateTableSQL(factory.Dialect(), factory.Mapper(), rType)
+	if err != nil {
+		t.Error(err)
+		return nil, teardown
+	}
+
+	ctx := context.Background()
+	factory.DB().ExecContext(ctx, `DROP TABLE IF EXISTS `+tableName)
+	if _, err := factory.DB().ExecContext(ctx, ddl); err != nil {
+		t.Error(ddl)
+		t.Error(err)
+		return nil, teardown
+	}
+	closers = append(closers, func() { factory.DB().ExecContext(ctx, `DROP TABLE `+tableName) })
+	return factory, teardown
+}
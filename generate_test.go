@@ -29,7 +29,7 @@ func TestGenerate(t *testing.T) {
 	// 	}
 	// }
 
-	for _, name := range []string{"user", "role", "users", "interface", "upsert", "embedded"} {
+	for _, name := range []string{"user", "role", "users", "interface", "upsert", "embedded", "document"} {
 		t.Log("=====================", name)
 		os.Remove(filepath.Join(wd, "gentest", name+".gobatis.go"))
 		// fmt.Println(filepath.Join(wd, "gentest", name+".gobatis.go"))
@@ -60,11 +60,10 @@ func TestGenerate(t *testing.T) {
 		os.Remove(filepath.Join(wd, "gentest", "fail", name+".gobatis.go"))
 		// fmt.Println(filepath.Join(wd, "gentest", "fail", name+".gobatis.go"))
 
+		// 生成的代码是错误的， goimports 会失败
 		var gen = generator.Generator{}
-		if err := gen.Run([]string{filepath.Join(wd, "gentest", "fail", name+".go")}); err != nil {
-			fmt.Println(err)
-			t.Error(err)
-			continue
+		if err := gen.Run([]string{filepath.Join(wd, "gentest", "fail", name+".go")}); err == nil {
+			t.Error("excepted error got ok")
 		}
 
 		actual := readFile(filepath.Join(wd, "gentest", "fail", name+".gobatis.go"), true)
@@ -1,16 +1,22 @@
 package generator
 
 import (
+	"bytes"
 	"errors"
 	"flag"
 	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
 	"go/types"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
 	"text/template"
 
@@ -18,22 +24,243 @@ import (
 	"github.com/runner-mei/GoBatis/goparser"
 )
 
+// DefaultSuffix 是生成的文件的缺省后缀
+const DefaultSuffix = ".gobatis.go"
+
 type Generator struct {
+	// Output 是生成的文件， 只能在只有一个源文件时使用， 缺省为源文件名加上 Suffix
+	Output string
+	// Suffix 是生成的文件的后缀， 缺省为 DefaultSuffix
+	Suffix string
+	// Dialects 是要生成的方言的 sql， 用逗号分隔， 为空时生成所有方言的 sql
+	Dialects string
+	// Importer 是读取依赖的包的类型信息的方式(gc 或 source)， 见 goparser.ParseWithImporter，
+	// 它不是选择 goparser 或 goparser2， 生成代码时总是使用 goparser， 因为模板需要
+	// go/types 的类型信息， 而 goparser2 只解析语法树， 所以没有选择解析器的参数
+	Importer string
+	Verbose  bool
+
+	dialects []gobatis.Dialect
 }
 
 func (cmd *Generator) Flags(fs *flag.FlagSet) *flag.FlagSet {
+	fs.StringVar(&cmd.Output, "o", "", "生成的文件， 只能在只有一个源文件时使用， 缺省为源文件名加上后缀")
+	fs.StringVar(&cmd.Suffix, "suffix", DefaultSuffix, "生成的文件的后缀")
+	fs.StringVar(&cmd.Dialects, "dialects", "", "要生成的方言的 sql， 用逗号分隔， 缺省为所有方言")
+	fs.StringVar(&cmd.Importer, "importer", "", "读取依赖的包的类型信息的方式， gc 或 source， 缺省时 GO111MODULE=on 时为 source， 否则为 gc")
+	fs.BoolVar(&cmd.Verbose, "v", false, "打印详细的信息")
 	return fs
 }
 
+// Run 为参数中的文件生成代码， 参数可以是文件， 目录或以 /... 结尾的目录，
+// 目录中有 go:generate gobatis 注释或导入了 GoBatis 并定义了接口的文件会被处理，
+// 没有参数时使用 go generate 设置的 GOFILE 环境变量
 func (cmd *Generator) Run(args []string) error {
-	for _, file := range args {
+	if cmd.Suffix == "" {
+		cmd.Suffix = DefaultSuffix
+	}
+
+	if err := cmd.parseDialects(); err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		if filename := os.Getenv("GOFILE"); filename != "" {
+			args = []string{filename}
+		} else {
+			args = []string{"."}
+		}
+	}
+
+	filenames, err := cmd.findFiles(args)
+	if err != nil {
+		return err
+	}
+	if len(filenames) == 0 {
+		return errors.New("dao file isnot found in " + strings.Join(args, ","))
+	}
+	if cmd.Output != "" && len(filenames) > 1 {
+		return errors.New("output file cannot be used with multiple source files")
+	}
+
+	failed := 0
+	for _, file := range filenames {
+		if cmd.Verbose {
+			log.Println("generate", file)
+		}
 		if err := cmd.runFile(file); err != nil {
-			log.Println(err)
+			log.Println(file+":", err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return errors.New("generate fail, " + strconv.Itoa(failed) + " of " + strconv.Itoa(len(filenames)) + " files has errors")
+	}
+	return nil
+}
+
+func (cmd *Generator) parseDialects() error {
+	cmd.dialects = nil
+	for _, name := range strings.Split(cmd.Dialects, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
 		}
+		dialect := gobatis.ToDbType(name)
+		if dialect == gobatis.DbTypeNone {
+			return errors.New("dialect '" + name + "' is unknown")
+		}
+		cmd.dialects = append(cmd.dialects, dialect)
 	}
 	return nil
 }
 
+// findFiles 将参数中的目录展开为其中的 dao 文件
+func (cmd *Generator) findFiles(args []string) ([]string, error) {
+	var filenames []string
+	exists := map[string]bool{}
+	add := func(filename string) {
+		if !exists[filename] {
+			exists[filename] = true
+			filenames = append(filenames, filename)
+		}
+	}
+
+	for _, arg := range args {
+		recursive := false
+		if arg == "..." || strings.HasSuffix(arg, "/...") {
+			recursive = true
+			arg = strings.TrimSuffix(strings.TrimSuffix(arg, "..."), "/")
+			if arg == "" {
+				arg = "."
+			}
+		}
+
+		st, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !st.IsDir() {
+			add(arg)
+			continue
+		}
+
+		dirs := []string{arg}
+		if recursive {
+			dirs = nil
+			err := filepath.Walk(arg, func(pa string, info os.FileInfo, err error) error {
+				if err != nil {
+					return err
+				}
+				if !info.IsDir() {
+					return nil
+				}
+				name := info.Name()
+				if pa != arg && (name == "vendor" || name == "testdata" ||
+					strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+					return filepath.SkipDir
+				}
+				dirs = append(dirs, pa)
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, dir := range dirs {
+			files, err := cmd.findDaoFiles(dir)
+			if err != nil {
+				return nil, err
+			}
+			for _, filename := range files {
+				add(filename)
+			}
+		}
+	}
+	return filenames, nil
+}
+
+// findDaoFiles 返回目录中有 go:generate gobatis 注释或导入了 GoBatis 并定义了接口的文件
+func (cmd *Generator) findDaoFiles(dir string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	var filenames []string
+	fset := token.NewFileSet()
+	for _, filename := range matches {
+		if strings.HasSuffix(filename, "_test.go") ||
+			strings.HasSuffix(filename, cmd.Suffix) ||
+			strings.HasSuffix(filename, DefaultSuffix) {
+			continue
+		}
+
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Contains(data, []byte("//go:generate gobatis")) {
+			filenames = append(filenames, filename)
+			continue
+		}
+		if !bytes.Contains(data, []byte(`"github.com/runner-mei/GoBatis"`)) {
+			continue
+		}
+
+		f, err := parser.ParseFile(fset, filename, data, 0)
+		if err != nil {
+			return nil, err
+		}
+		if hasInterface(f) {
+			filenames = append(filenames, filename)
+		}
+	}
+	return filenames, nil
+}
+
+func hasInterface(f *ast.File) bool {
+	for _, decl := range f.Decls {
+		genDecl, ok := decl.(*ast.GenDecl)
+		if !ok || genDecl.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range genDecl.Specs {
+			if _, ok := spec.(*ast.TypeSpec).Type.(*ast.InterfaceType); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// filterDialects 删除不需要生成的方言的 sql， 选择的方言兼容的方言的 sql 会被保留
+func (cmd *Generator) filterDialects(file *goparser.File) {
+	if len(cmd.dialects) == 0 {
+		return
+	}
+	for _, itf := range file.Interfaces {
+		for _, method := range itf.Methods {
+			if method.Config == nil {
+				continue
+			}
+			for name := range method.Config.Dialects {
+				dialect := gobatis.ToDbType(name)
+				found := false
+				for _, selected := range cmd.dialects {
+					if gobatis.IsDialect(selected, dialect) {
+						found = true
+						break
+					}
+				}
+				if !found {
+					delete(method.Config.Dialects, name)
+				}
+			}
+		}
+	}
+}
+
 func (cmd *Generator) runFile(filename string) error {
 	pa, err := filepath.Abs(filename)
 	if err != nil {
@@ -41,12 +268,19 @@ func (cmd *Generator) runFile(filename string) error {
 	}
 	//dir := filepath.Dir(pa)
 
-	file, err := goparser.Parse(pa)
+	file, err := goparser.ParseWithImporter(pa, cmd.Importer, cmd.Verbose)
 	if err != nil {
 		return err
 	}
+	cmd.filterDialects(file)
 
-	targetFile := strings.TrimSuffix(pa, ".go") + ".gobatis.go"
+	targetFile := strings.TrimSuffix(pa, ".go") + cmd.Suffix
+	if cmd.Output != "" {
+		targetFile, err = filepath.Abs(cmd.Output)
+		if err != nil {
+			return err
+		}
+	}
 
 	if len(file.Interfaces) == 0 {
 		err = os.Remove(targetFile)
@@ -65,11 +299,15 @@ func (cmd *Generator) runFile(filename string) error {
 	}()
 
 	if err = cmd.generateHeader(out, file); err != nil {
+		out.Close()
+		os.Remove(targetFile + ".tmp")
 		return err
 	}
 
 	for _, itf := range file.Interfaces {
 		if err := cmd.generateInterface(out, file, itf); err != nil {
+			out.Close()
+			os.Remove(targetFile + ".tmp")
 			return err
 		}
 	}
@@ -85,10 +323,10 @@ func (cmd *Generator) runFile(filename string) error {
 
 	// 不知为什么，有时运行两次 goimports 才起效
 	exec.Command("goimports", "-w", targetFile).Run()
-	return goImports(targetFile)
+	return goImports(targetFile, cmd.Verbose)
 }
 
-func goImports(src string) error {
+func goImports(src string, verbose bool) error {
 	cmd := exec.Command("goimports", "-w", src)
 	cmd.Dir = filepath.Dir(src)
 	out, err := cmd.CombinedOutput()
@@ -97,11 +335,12 @@ func goImports(src string) error {
 		fmt.Println(string(out))
 	}
 	if err != nil {
-		fmt.Println(err)
-	} else if len(out) == 0 {
+		return errors.New("run goimports fail, " + err.Error())
+	}
+	if verbose && len(out) == 0 {
 		fmt.Println("run `" + cmd.Path + " -w " + src + "` ok")
 	}
-	return err
+	return nil
 }
 
 func (cmd *Generator) generateHeader(out io.Writer, file *goparser.File) error {
@@ -166,6 +405,14 @@ var funcs = template.FuncMap{
 	"detectRecordType": func(itf *goparser.Interface, method *goparser.Method) types.Type {
 		return itf.DetectRecordType(method)
 	},
+	"isAudited":            isAudited,
+	"statementRecordTypes": statementRecordTypes,
+	"auditTable": func(method *goparser.Method) string {
+		if method.Config == nil {
+			return ""
+		}
+		return method.Config.Options["audit"]
+	},
 	"isBasicMap": func(recordType, returnType types.Type) bool {
 		// keyType := getKeyType(recordType)
 
@@ -256,6 +503,18 @@ func init() {
 	}
 
 	newFunc = template.Must(template.New("NewFunc").Funcs(funcs).Parse(`
+{{- define "mapper" -}}
+	ctx.Mapper
+	{{- if and .method.Config .method.Config.Options (eq .method.Config.Options.tenant "ignore") -}}
+	.WithoutTenant()
+	{{- end -}}
+	{{- if endWith .method.Name "WithDeleted" -}}
+	.WithDeleted()
+	{{- else if endWith .method.Name "OnlyDeleted" -}}
+	.OnlyDeleted()
+	{{- end -}}
+{{- end}}
+
 {{- define "insert"}}
 	{{- set . "var_has_context" false}}
 	{{- set . "var_contains_struct" false}}
@@ -311,7 +570,7 @@ func init() {
 		sqlStr
 		{{- else}}
 		s
-		{{- end}}, err := gobatis.Generate{{if eq .var_style "upsert"}}Upsert{{else}}Insert{{end}}SQL(ctx.Dialect, ctx.Mapper, 
+		{{- end}}, err := gobatis.Generate{{if eq .var_style "upsert"}}Upsert{{else}}Insert{{end}}SQL(ctx.Dialect, {{template "mapper" .}}, 
     reflect.TypeOf(&{{.recordTypeName}}{}),
     {{- if eq .var_style "upsert"}}
     []string{
@@ -435,7 +694,7 @@ func init() {
 		{{- end}}, err := 
 
 		{{- if $var_style_1 -}}
-				gobatis.GenerateUpdateSQL(ctx.Dialect, ctx.Mapper, 
+				gobatis.GenerateUpdateSQL(ctx.Dialect, {{template "mapper" .}}, 
 					"{{$lastParam.Name}}.", reflect.TypeOf(&{{.recordTypeName}}{}), 
 					[]string{
 					{{- range $idx, $param := .method.Params.List}}
@@ -468,7 +727,7 @@ func init() {
 					{{- end}}
 				})
 		{{-  else -}}
-				gobatis.GenerateUpdateSQL2(ctx.Dialect, ctx.Mapper, 
+				gobatis.GenerateUpdateSQL2(ctx.Dialect, {{template "mapper" .}}, 
 					reflect.TypeOf(&{{.recordTypeName}}{}), 
 					{{- if .var_first_is_context -}}
 						{{- $firstParam := index .method.Params.List 1 -}}
@@ -516,7 +775,54 @@ func init() {
 	sqlStr
 	{{- else}}
 	s
-	{{- end}}, err := gobatis.GenerateDeleteSQL(ctx.Dialect, ctx.Mapper, 
+	{{- end}}, err := gobatis.GenerateDeleteSQL(ctx.Dialect, {{template "mapper" .}}, 
+	reflect.TypeOf(&{{.recordTypeName}}{}), 
+		[]string{
+	{{-     range $idx, $param := .method.Params.List}}
+	{{-       if isType $param.Type "context" | not}}
+		  {{- if eq $param.Name "_type"}}
+   		"type",
+   		{{- else}}
+			"{{$param.Name}}",
+			{{- end}}
+	{{-       end}}
+	{{-     end}}
+		},
+		[]reflect.Type{
+	{{-     range $idx, $param := .method.Params.List}}
+	{{-       if isType $param.Type "context" | not }}
+	  {{- if isType $param.Type "slice"}}
+		  reflect.TypeOf({{typePrint $.printContext $param.Type}}{}),
+	  {{- else if isType $param.Type "ptr"}}
+		  reflect.TypeOf(({{typePrint $.printContext $param.Type}})(nil)),
+	  {{- else if isType $param.Type "basic"}}
+		  reflect.TypeOf(new({{typePrint $.printContext $param.Type}})).Elem(),
+		{{- else}}
+		  reflect.TypeOf(&{{typePrint $.printContext $param.Type}}{}).Elem(),
+		{{- end}}
+	{{-       end}}
+	{{-     end}}
+		},
+		[]gobatis.Filter{ 
+		{{- range $param := .method.Config.SQL.Filters}}
+		{Expression: "{{$param.Expression}}"{{if $param.Dialect}}, Dialect: "{{$param.Dialect}}"{{end}}},
+		{{- end}}
+		})
+	if err != nil {
+		return gobatis.ErrForGenerateStmt(err, "generate {{.itf.Name}}.{{.method.Name}} error")
+	}
+	{{- if not $var_undefined }}
+	sqlStr = s
+	{{- end}}
+{{- end}}
+
+{{- define "restore"}}
+	{{-   $var_undefined := default .var_undefined false}}
+	{{-   if $var_undefined }}
+	sqlStr
+	{{- else}}
+	s
+	{{- end}}, err := gobatis.GenerateRestoreSQL(ctx.Dialect, {{template "mapper" .}}, 
 	reflect.TypeOf(&{{.recordTypeName}}{}), 
 		[]string{
 	{{-     range $idx, $param := .method.Params.List}}
@@ -563,7 +869,7 @@ func init() {
 	sqlStr
 	{{- else}}
 	s
-	{{- end}}, err := gobatis.GenerateCountSQL(ctx.Dialect, ctx.Mapper, 
+	{{- end}}, err := gobatis.GenerateCountSQL(ctx.Dialect, {{template "mapper" .}}, 
 	reflect.TypeOf(&{{.recordTypeName}}{}), 
 		[]string{
 	{{-     range $idx, $param := .method.Params.List}}
@@ -610,7 +916,7 @@ func init() {
 	sqlStr
 	{{- else}}
 	s
-	{{- end}}, err := gobatis.GenerateSelectSQL(ctx.Dialect, ctx.Mapper, 
+	{{- end}}, err := gobatis.GenerateSelectSQL(ctx.Dialect, {{template "mapper" .}}, 
 	reflect.TypeOf(&{{.recordTypeName}}{}), 
 		[]string{
 	{{-     range $idx, $param := .method.Params.List}}
@@ -660,6 +966,8 @@ func init() {
 	  {{-   template "insert" . | arg "recordTypeName" .recordTypeName}}
 	  {{- else if eq $statementType "upsert"}}
 	  {{-   template "insert" . | arg "recordTypeName" .recordTypeName | arg "var_isUpsert" true}}
+	  {{- else if and (eq $statementType "update") (startWith .method.Name "Restore")}}
+	  {{-   template "restore" . | arg "recordTypeName" .recordTypeName}}
 	  {{- else if eq $statementType "update"}}
 	  {{-   template "update" . | arg "recordTypeName" .recordTypeName}}
 	  {{- else if eq $statementType "delete"}}
@@ -696,6 +1004,11 @@ stmt, err := gobatis.NewMapppedStatement(ctx, "{{.itf.Name}}.{{.method.Name}}",
 if err != nil {
 	return err
 }
+{{- if and .recordTypeName (isAudited .itf .method)}}
+if err := gobatis.EnableAudit(ctx, stmt, reflect.TypeOf(&{{.recordTypeName}}{}), "{{auditTable .method}}"); err != nil {
+	return err
+}
+{{- end}}
 ctx.Statements["{{.itf.Name}}.{{.method.Name}}"] = stmt
 {{- end}}
 
@@ -726,7 +1039,8 @@ func init() {
 		{{-   if or $m.Config.DefaultSQL  $m.Config.Dialects}}
 		  {{preprocessingSQL "sqlStr" true $m.Config.DefaultSQL $.recordTypeName }}
 			{{-     if $m.Config.Dialects}}
-			switch ctx.Dialect {
+			switch gobatis.MatchDialect(ctx.Dialect
+				{{- range $typ, $dialect := $m.Config.Dialects}}, "{{$typ}}"{{end}}) {
 				{{-    range $typ, $dialect := $m.Config.Dialects}}
 			case gobatis.ToDbType("{{$typ}}"):
 		  	{{preprocessingSQL "sqlStr" false $dialect $.recordTypeName }}
@@ -747,6 +1061,12 @@ func init() {
 			{{- template "registerStmt" $ | arg "method" $m}}
 		{{- end}}
 		}
+		{{- $recordTypes := statementRecordTypes $.printContext $m}}
+		{{- if $recordTypes}}
+		ctx.Statements["{{$.itf.Name}}.{{$m.Name}}"].AddRecordTypes(
+			{{- range $idx, $name := $recordTypes}}{{if $idx}}, {{end}}reflect.TypeOf(&{{$name}}{}){{end -}}
+		)
+		{{- end}}
 	}
 	{{-   end}}
 	{{- end}}
@@ -1701,3 +2021,77 @@ func isExceptedType(typ types.Type, excepted string, or ...string) bool {
 
 	return false
 }
+
+// isAudited 判断 update 或 delete 方法是否要写历史记录， 方法上有 "@option audit xxx"
+// 或者记录类型的 TableName 字段上有 audited 标签时返回 true
+func isAudited(itf *goparser.Interface, method *goparser.Method) bool {
+	switch method.StatementTypeName() {
+	case "update", "delete":
+	default:
+		return false
+	}
+	if method.Config != nil && method.Config.Options["audit"] != "" {
+		return true
+	}
+
+	recordType := itf.DetectRecordType(method)
+	if recordType == nil {
+		return false
+	}
+	for {
+		ptr, ok := recordType.(*types.Pointer)
+		if !ok {
+			break
+		}
+		recordType = ptr.Elem()
+	}
+	st, ok := recordType.Underlying().(*types.Struct)
+	if !ok {
+		return false
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		if st.Field(i).Name() != "TableName" {
+			continue
+		}
+		for _, opt := range strings.Split(reflect.StructTag(st.Tag(i)).Get("db"), ",") {
+			if opt = strings.TrimSpace(opt); opt == "audited" || strings.HasPrefix(opt, "audited=") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// statementRecordTypes 返回方法的参数和返回值中的结构体类型， 生成的代码用
+// MappedStatement.AddRecordTypes 记录它们， 初始化时的表结构检查会检查这些类型
+func statementRecordTypes(ctx *goparser.PrintContext, method *goparser.Method) []string {
+	var names []string
+	add := func(typ types.Type) {
+		if !goparser.IsStructType(typ) || goparser.IsIgnoreStructTypes(typ) {
+			return
+		}
+		named, ok := goparser.GetElemType(typ).(*types.Named)
+		if !ok {
+			return
+		}
+		name := goparser.PrintType(ctx, named, false)
+		for _, s := range names {
+			if s == name {
+				return
+			}
+		}
+		names = append(names, name)
+	}
+
+	if method.Params != nil {
+		for _, param := range method.Params.List {
+			add(param.Type)
+		}
+	}
+	if method.Results != nil {
+		for _, result := range method.Results.List {
+			add(result.Type)
+		}
+	}
+	return names
+}
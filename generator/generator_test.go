@@ -0,0 +1,102 @@
+package generator
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/runner-mei/GoBatis/goparser"
+)
+
+func TestFindFiles(t *testing.T) {
+	for idx, test := range []struct {
+		args     []string
+		excepted []string
+	}{
+		{
+			args:     []string{"../example/user.go"},
+			excepted: []string{"../example/user.go"},
+		},
+		{
+			args:     []string{"../example", "../example/user.go"},
+			excepted: []string{"../example/role.go", "../example/user.go", "../example/users_with_autogensql.go"},
+		},
+		{
+			args: []string{"../gentest/..."},
+			excepted: []string{"../gentest/document.go", "../gentest/embedded.go", "../gentest/interface.go",
+				"../gentest/role.go", "../gentest/upsert.go", "../gentest/user.go", "../gentest/users.go",
+				"../gentest/fail/interface.go"},
+		},
+		{
+			// migrate 中的接口没有导入 GoBatis
+			args:     []string{"../migrate"},
+			excepted: nil,
+		},
+	} {
+		cmd := &Generator{Suffix: DefaultSuffix}
+		actual, err := cmd.findFiles(test.args)
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		var excepted []string
+		for _, name := range test.excepted {
+			excepted = append(excepted, filepath.FromSlash(name))
+		}
+		if !reflect.DeepEqual(actual, excepted) {
+			t.Error("[", idx, "] excepted is", excepted)
+			t.Error("[", idx, "] actual   is", actual)
+		}
+	}
+
+	cmd := &Generator{Suffix: DefaultSuffix}
+	if _, err := cmd.findFiles([]string{"../notexists"}); err == nil {
+		t.Error("excepted error got ok")
+	}
+}
+
+func TestFilterDialects(t *testing.T) {
+	for idx, test := range []struct {
+		dialects string
+		excepted []string
+	}{
+		{dialects: "", excepted: []string{"mssql", "mysql", "postgres"}},
+		{dialects: "mysql", excepted: []string{"mysql"}},
+		{dialects: "kingbase", excepted: []string{"postgres"}},
+		{dialects: "mssql, postgres", excepted: []string{"mssql", "postgres"}},
+	} {
+		file := &goparser.File{Interfaces: []*goparser.Interface{{
+			Methods: []*goparser.Method{
+				{Name: "Get", Config: &goparser.SQLConfig{Dialects: map[string]string{
+					"postgres": "SELECT 1",
+					"mysql":    "SELECT 2",
+					"mssql":    "SELECT 3",
+				}}},
+				{Name: "Set"},
+			},
+		}}}
+
+		cmd := &Generator{Dialects: test.dialects}
+		if err := cmd.parseDialects(); err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		cmd.filterDialects(file)
+
+		var actual []string
+		for name := range file.Interfaces[0].Methods[0].Config.Dialects {
+			actual = append(actual, name)
+		}
+		sort.Strings(actual)
+		if !reflect.DeepEqual(actual, test.excepted) {
+			t.Error("[", idx, "] excepted is", test.excepted)
+			t.Error("[", idx, "] actual   is", actual)
+		}
+	}
+
+	cmd := &Generator{Dialects: "nosuch"}
+	if err := cmd.Run([]string{"../example/user.go"}); err == nil {
+		t.Error("excepted error got ok")
+	}
+}
@@ -0,0 +1,869 @@
+package generator
+
+import (
+	"errors"
+	"flag"
+	"go/format"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	gobatis "github.com/runner-mei/GoBatis"
+)
+
+// Table 是从 CREATE TABLE 语句中解析出来的表
+type Table struct {
+	Name    string
+	Columns []*Column
+}
+
+// Column 是表中的列
+type Column struct {
+	Name       string
+	Type       string
+	NotNull    bool
+	PrimaryKey bool
+	AutoIncr   bool
+	Unique     bool
+	// UniqueName 是多个列的唯一约束的名称
+	UniqueName string
+	Indexed    bool
+	IndexName  string
+	// Quote 表示列名需要引用， 如列名是保留字， 或者是带引号的并且不能直接使用的名字(如 postgres
+	// 中有大写字母的列名)， 生成的字段上会加上 quote 选项
+	Quote bool
+}
+
+func (table *Table) column(name string) *Column {
+	for _, column := range table.Columns {
+		if strings.EqualFold(column.Name, name) {
+			return column
+		}
+	}
+	return nil
+}
+
+func (table *Table) primaryKeys() []*Column {
+	var keys []*Column
+	for _, column := range table.Columns {
+		if column.PrimaryKey {
+			keys = append(keys, column)
+		}
+	}
+	return keys
+}
+
+type ddlToken struct {
+	text   string
+	quoted bool
+}
+
+// tokenizeDDL 将 sql 拆分为单词， 注释会被忽略， 引号中的标识符会去掉引号
+func tokenizeDDL(s string) []ddlToken {
+	var tokens []ddlToken
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		case c == '-' && i+1 < len(s) && s[i+1] == '-':
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(s) && s[i+1] == '*':
+			end := strings.Index(s[i+2:], "*/")
+			if end < 0 {
+				i = len(s)
+			} else {
+				i = i + 2 + end + 2
+			}
+		case c == '[' && i+1 < len(s) && s[i+1] == ']':
+			tokens = append(tokens, ddlToken{text: "[]"})
+			i += 2
+		case c == '"' || c == '`' || c == '[':
+			quote := c
+			if quote == '[' {
+				quote = ']'
+			}
+			end := strings.IndexByte(s[i+1:], quote)
+			if end < 0 {
+				end = len(s) - i - 1
+			}
+			tokens = append(tokens, ddlToken{text: s[i+1 : i+1+end], quoted: true})
+			i = i + 1 + end + 1
+		case c == '\'':
+			j := i + 1
+			for j < len(s) {
+				if s[j] == '\'' {
+					if j+1 < len(s) && s[j+1] == '\'' {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			if j >= len(s) {
+				j = len(s) - 1
+			}
+			tokens = append(tokens, ddlToken{text: s[i : j+1]})
+			i = j + 1
+		case isWordChar(c):
+			j := i
+			for j < len(s) && isWordChar(s[j]) {
+				j++
+			}
+			tokens = append(tokens, ddlToken{text: s[i:j]})
+			i = j
+		default:
+			tokens = append(tokens, ddlToken{text: s[i : i+1]})
+			i++
+		}
+	}
+	return tokens
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c == '$' || c == '#' || c >= 0x80 ||
+		('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+type ddlParser struct {
+	dialect gobatis.Dialect
+	tokens  []ddlToken
+	pos     int
+}
+
+func (p *ddlParser) eof() bool {
+	return p.pos >= len(p.tokens)
+}
+
+// is 判断当前位置是否是指定的关键字序列
+func (p *ddlParser) is(words ...string) bool {
+	for idx, word := range words {
+		if p.pos+idx >= len(p.tokens) {
+			return false
+		}
+		token := p.tokens[p.pos+idx]
+		if token.quoted || !strings.EqualFold(token.text, word) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *ddlParser) accept(words ...string) bool {
+	if p.is(words...) {
+		p.pos += len(words)
+		return true
+	}
+	return false
+}
+
+func (p *ddlParser) next() ddlToken {
+	if p.eof() {
+		return ddlToken{}
+	}
+	token := p.tokens[p.pos]
+	p.pos++
+	return token
+}
+
+// group 读取当前位置的括号中的内容， 并按顶层的逗号拆分
+func (p *ddlParser) group() [][]ddlToken {
+	if !p.is("(") {
+		return nil
+	}
+	p.pos++
+
+	var parts [][]ddlToken
+	var part []ddlToken
+	depth := 0
+	for !p.eof() {
+		token := p.next()
+		if !token.quoted {
+			switch token.text {
+			case "(":
+				depth++
+			case ")":
+				if depth == 0 {
+					return append(parts, part)
+				}
+				depth--
+			case ",":
+				if depth == 0 {
+					parts = append(parts, part)
+					part = nil
+					continue
+				}
+			}
+		}
+		part = append(part, token)
+	}
+	return append(parts, part)
+}
+
+// identifier 读取一个标识符， 没有引号的标识符在 postgres 和 oracle 中转为小写
+func (p *ddlParser) identifier() string {
+	return p.normalize(p.next())
+}
+
+func (p *ddlParser) normalize(token ddlToken) string {
+	if !token.quoted && (gobatis.IsDialect(p.dialect, gobatis.DbTypePostgres) ||
+		gobatis.IsDialect(p.dialect, gobatis.DbTypeOracle)) {
+		return strings.ToLower(token.text)
+	}
+	return token.text
+}
+
+// needQuote 判断名字是否需要引用， 保留字总是需要引用； 带引号的名字中有特殊字符时需要引用，
+// 在 postgres 和 oracle 中大小写和没有引号时的不一样时也需要引用
+func (p *ddlParser) needQuote(token ddlToken, name string) bool {
+	if gobatis.IsReservedWord(name) {
+		return true
+	}
+	if !token.quoted {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !(c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || (i > 0 && '0' <= c && c <= '9')) {
+			return true
+		}
+	}
+	if gobatis.IsDialect(p.dialect, gobatis.DbTypePostgres) {
+		return name != strings.ToLower(name)
+	}
+	if gobatis.IsDialect(p.dialect, gobatis.DbTypeOracle) {
+		return name != strings.ToUpper(name)
+	}
+	return false
+}
+
+// qualifiedName 读取 schema.table 格式的名称
+func (p *ddlParser) qualifiedName() string {
+	name := p.identifier()
+	for p.is(".") {
+		p.pos++
+		name = name + "." + p.identifier()
+	}
+	return name
+}
+
+// columnNames 读取 (a, b) 格式的列名， 有表达式时返回 nil
+func (p *ddlParser) columnNames() []string {
+	var names []string
+	for _, part := range p.group() {
+		if len(part) == 0 {
+			return nil
+		}
+		for _, token := range part[1:] {
+			if token.quoted || !isWordChar(token.text[0]) {
+				return nil
+			}
+		}
+		names = append(names, p.normalize(part[0]))
+	}
+	return names
+}
+
+type ddlKey struct {
+	name    string
+	columns []string
+}
+
+type ddlSchema struct {
+	tables      []*Table
+	primaryKeys map[*Table][]string
+	uniques     map[*Table][]ddlKey
+	indexes     map[*Table][]ddlKey
+}
+
+func (schema *ddlSchema) table(name string) *Table {
+	for _, table := range schema.tables {
+		if strings.EqualFold(table.Name, name) {
+			return table
+		}
+	}
+	return nil
+}
+
+// ParseDDL 解析 sql 中的 CREATE TABLE， CREATE INDEX 和 ALTER TABLE ... ADD 语句，
+// 其它语句会被忽略
+func ParseDDL(dialect gobatis.Dialect, sqlText string) ([]*Table, error) {
+	schema := &ddlSchema{
+		primaryKeys: map[*Table][]string{},
+		uniques:     map[*Table][]ddlKey{},
+		indexes:     map[*Table][]ddlKey{},
+	}
+
+	// mssql 的脚本中也可以用 GO 分隔语句
+	isMSSql := gobatis.IsDialect(dialect, gobatis.DbTypeMSSql)
+
+	var statement []ddlToken
+	for _, token := range append(tokenizeDDL(sqlText), ddlToken{text: ";"}) {
+		if token.quoted || (token.text != ";" && !(isMSSql && strings.EqualFold(token.text, "GO"))) {
+			statement = append(statement, token)
+			continue
+		}
+		if len(statement) > 0 {
+			p := &ddlParser{dialect: dialect, tokens: statement}
+			if err := schema.parseStatement(p); err != nil {
+				return nil, err
+			}
+		}
+		statement = nil
+	}
+
+	for _, table := range schema.tables {
+		for _, name := range schema.primaryKeys[table] {
+			if column := table.column(name); column != nil {
+				column.PrimaryKey = true
+			}
+		}
+
+		keys := table.primaryKeys()
+		if len(keys) == 1 && gobatis.IsDialect(dialect, gobatis.DbTypeSqlite) &&
+			strings.EqualFold(keys[0].Type, "integer") {
+			// sqlite 中 INTEGER PRIMARY KEY 是 rowid 的别名， 插入时会自动生成
+			keys[0].AutoIncr = true
+		}
+
+		for _, key := range schema.uniques[table] {
+			if len(key.columns) == 1 {
+				if column := table.column(key.columns[0]); column != nil && !column.PrimaryKey {
+					column.Unique = true
+				}
+				continue
+			}
+			if key.name == "" {
+				key.name = "uq_" + table.Name + "_" + strings.Join(key.columns, "_")
+			}
+			for _, name := range key.columns {
+				if column := table.column(name); column != nil && column.UniqueName == "" {
+					column.UniqueName = key.name
+				}
+			}
+		}
+
+		for _, key := range schema.indexes[table] {
+			for _, name := range key.columns {
+				column := table.column(name)
+				if column == nil || column.Indexed {
+					continue
+				}
+				column.Indexed = true
+				if len(key.columns) > 1 || (key.name != "" && key.name != "idx_"+table.Name+"_"+column.Name) {
+					column.IndexName = key.name
+				}
+			}
+		}
+	}
+	return schema.tables, nil
+}
+
+func (schema *ddlSchema) parseStatement(p *ddlParser) error {
+	if p.accept("ALTER", "TABLE") {
+		p.accept("IF", "EXISTS")
+		p.accept("ONLY")
+		table := schema.table(p.qualifiedName())
+		if table == nil || !p.accept("ADD") {
+			return nil
+		}
+		return schema.parseElement(p, table)
+	}
+
+	if !p.accept("CREATE") {
+		return nil
+	}
+	p.accept("OR", "REPLACE")
+	for p.accept("GLOBAL") || p.accept("LOCAL") || p.accept("TEMP") ||
+		p.accept("TEMPORARY") || p.accept("UNLOGGED") {
+	}
+
+	if p.accept("TABLE") {
+		return schema.parseTable(p)
+	}
+
+	unique := p.accept("UNIQUE")
+	p.accept("CLUSTERED")
+	p.accept("NONCLUSTERED")
+	if !p.accept("INDEX") {
+		return nil
+	}
+	p.accept("CONCURRENTLY")
+	p.accept("IF", "NOT", "EXISTS")
+	name := ""
+	if !p.is("ON") {
+		name = p.qualifiedName()
+		if idx := strings.LastIndex(name, "."); idx >= 0 {
+			name = name[idx+1:]
+		}
+	}
+	if !p.accept("ON") {
+		return errors.New("index '" + name + "' is invalid")
+	}
+	p.accept("ONLY")
+	table := schema.table(p.qualifiedName())
+	if table == nil {
+		return nil
+	}
+	if p.accept("USING") {
+		p.next()
+	}
+	columns := p.columnNames()
+	if len(columns) == 0 {
+		return nil
+	}
+	if unique {
+		schema.uniques[table] = append(schema.uniques[table], ddlKey{name: name, columns: columns})
+	} else {
+		schema.indexes[table] = append(schema.indexes[table], ddlKey{name: name, columns: columns})
+	}
+	return nil
+}
+
+func (schema *ddlSchema) parseTable(p *ddlParser) error {
+	p.accept("IF", "NOT", "EXISTS")
+	table := &Table{Name: p.qualifiedName()}
+	if !p.is("(") {
+		// 如 CREATE TABLE xxx AS SELECT ...
+		return nil
+	}
+
+	for _, part := range p.group() {
+		if len(part) == 0 {
+			continue
+		}
+		if err := schema.parseElement(&ddlParser{dialect: p.dialect, tokens: part}, table); err != nil {
+			return errors.New("table '" + table.Name + "' is invalid, " + err.Error())
+		}
+	}
+	if len(table.Columns) == 0 {
+		return errors.New("table '" + table.Name + "' hasnot any column")
+	}
+	schema.tables = append(schema.tables, table)
+	return nil
+}
+
+// parseElement 解析表中的一个列或约束
+func (schema *ddlSchema) parseElement(p *ddlParser, table *Table) error {
+	name := ""
+	if p.accept("CONSTRAINT") {
+		name = p.identifier()
+	}
+
+	switch {
+	case p.accept("PRIMARY", "KEY"):
+		p.accept("CLUSTERED")
+		p.accept("NONCLUSTERED")
+		schema.primaryKeys[table] = append(schema.primaryKeys[table], p.columnNames()...)
+		return nil
+	case p.accept("UNIQUE"):
+		if p.accept("KEY") || p.accept("INDEX") {
+			if !p.is("(") {
+				name = p.identifier()
+			}
+		}
+		p.accept("CLUSTERED")
+		p.accept("NONCLUSTERED")
+		if columns := p.columnNames(); len(columns) > 0 {
+			schema.uniques[table] = append(schema.uniques[table], ddlKey{name: name, columns: columns})
+		}
+		return nil
+	case p.accept("KEY") || p.accept("INDEX"):
+		if !p.is("(") {
+			name = p.identifier()
+		}
+		if columns := p.columnNames(); len(columns) > 0 {
+			schema.indexes[table] = append(schema.indexes[table], ddlKey{name: name, columns: columns})
+		}
+		return nil
+	case p.is("FOREIGN") || p.is("CHECK") || p.is("EXCLUDE") || p.is("FULLTEXT") || p.is("SPATIAL"):
+		return nil
+	}
+	if name != "" {
+		return nil
+	}
+
+	p.accept("COLUMN")
+	token := p.next()
+	column := &Column{Name: p.normalize(token)}
+	column.Quote = p.needQuote(token, column.Name)
+	if p.eof() {
+		return errors.New("type of column '" + column.Name + "' is missing")
+	}
+
+	var typeName strings.Builder
+	for !p.eof() && !p.isColumnConstraint() {
+		token := p.tokens[p.pos]
+		switch {
+		case token.text == "(" && !token.quoted:
+			var args []string
+			for _, part := range p.group() {
+				var sb strings.Builder
+				for _, t := range part {
+					sb.WriteString(t.text)
+				}
+				args = append(args, sb.String())
+			}
+			typeName.WriteString("(" + strings.Join(args, ",") + ")")
+		case token.text == "[]" && !token.quoted:
+			p.pos++
+			typeName.WriteString("[]")
+		default:
+			p.pos++
+			if typeName.Len() > 0 {
+				typeName.WriteString(" ")
+			}
+			typeName.WriteString(token.text)
+		}
+	}
+	column.Type = typeName.String()
+
+	for !p.eof() {
+		switch {
+		case p.accept("NOT", "NULL"):
+			column.NotNull = true
+		case p.accept("PRIMARY", "KEY"):
+			column.PrimaryKey = true
+		case p.accept("UNIQUE"):
+			p.accept("KEY")
+			column.Unique = true
+		case p.accept("AUTO_INCREMENT") || p.accept("AUTOINCREMENT") || p.accept("IDENTITY"):
+			column.AutoIncr = true
+		case p.accept("DEFAULT"):
+			// postgres 中 DEFAULT nextval('xxx_seq') 相当于 serial
+			if p.accept("nextval") {
+				column.AutoIncr = true
+			} else if !p.is("(") {
+				p.next()
+			}
+			p.group()
+		case p.is("("):
+			p.group()
+		default:
+			p.next()
+		}
+	}
+
+	switch strings.ToLower(column.Type) {
+	case "serial", "bigserial", "smallserial", "serial4", "serial8", "serial2":
+		column.AutoIncr = true
+	}
+	table.Columns = append(table.Columns, column)
+	return nil
+}
+
+// isColumnConstraint 判断当前位置是否是列的类型后面的约束
+func (p *ddlParser) isColumnConstraint() bool {
+	for _, word := range []string{"NOT", "NULL", "PRIMARY", "UNIQUE", "DEFAULT", "AUTO_INCREMENT",
+		"AUTOINCREMENT", "IDENTITY", "GENERATED", "REFERENCES", "CHECK", "CONSTRAINT", "COLLATE",
+		"COMMENT", "ON", "KEY"} {
+		if p.is(word) {
+			return true
+		}
+	}
+	return p.is("CHARACTER", "SET") || p.is("CHARSET")
+}
+
+// goType 返回列对应的 go 类型和 db 标签中的选项
+func goType(dialect gobatis.Dialect, column *Column) (string, string) {
+	t := strings.ToLower(strings.TrimSpace(column.Type))
+	if strings.HasSuffix(t, "[]") {
+		elem, _ := goType(dialect, &Column{Type: strings.TrimSuffix(t, "[]"), NotNull: true})
+		return "[]" + elem, ""
+	}
+
+	var args []string
+	if start := strings.Index(t, "("); start >= 0 {
+		if end := strings.Index(t, ")"); end > start {
+			args = strings.Split(t[start+1:end], ",")
+			t = strings.TrimSpace(t[:start] + " " + t[end+1:])
+		}
+	}
+	for _, suffix := range []string{" zerofill", " unsigned", " signed"} {
+		t = strings.TrimSpace(strings.TrimSuffix(t, suffix))
+	}
+
+	switch t {
+	case "bool", "boolean", "bit":
+		return "bool", ""
+	case "tinyint":
+		if len(args) == 1 && args[0] == "1" && gobatis.IsDialect(dialect, gobatis.DbTypeMysql) {
+			return "bool", ""
+		}
+		return "int8", ""
+	case "smallint", "int2", "smallserial", "serial2":
+		return "int16", ""
+	case "integer":
+		if gobatis.IsDialect(dialect, gobatis.DbTypeSqlite) {
+			return "int64", ""
+		}
+		return "int", ""
+	case "int", "int4", "mediumint", "serial", "serial4":
+		return "int", ""
+	case "bigint", "int8", "bigserial", "serial8":
+		return "int64", ""
+	case "number":
+		if len(args) < 2 || strings.TrimSpace(args[1]) == "0" {
+			return "int64", ""
+		}
+		return "float64", ""
+	case "real", "float4", "binary_float":
+		return "float32", ""
+	case "float", "float8", "double", "double precision", "binary_double",
+		"numeric", "decimal", "money", "smallmoney":
+		return "float64", ""
+	case "date", "time", "datetime", "datetime2", "smalldatetime", "datetimeoffset", "timestamp",
+		"timestamptz", "timestamp with time zone", "timestamp without time zone",
+		"time with time zone", "time without time zone":
+		if !column.NotNull && !column.PrimaryKey {
+			return "*time.Time", ""
+		}
+		return "time.Time", ""
+	case "bytea", "blob", "tinyblob", "mediumblob", "longblob", "binary", "varbinary", "image",
+		"raw", "long raw":
+		return "[]byte", ""
+	case "json", "jsonb":
+		return "map[string]interface{}", t
+	}
+	return "string", ""
+}
+
+func isNilableType(typ string) bool {
+	return strings.HasPrefix(typ, "[]") || strings.HasPrefix(typ, "map[") || strings.HasPrefix(typ, "*")
+}
+
+type reverseField struct {
+	name string
+	typ  string
+	tag  string
+}
+
+func reverseFields(dialect gobatis.Dialect, table *Table) []reverseField {
+	var fields []reverseField
+	exists := map[string]bool{"TableName": true}
+	for _, column := range table.Columns {
+		name := Goify(column.Name, true)
+		if name == "" {
+			name = "Field"
+		}
+		for base, idx := name, 2; exists[name]; idx++ {
+			name = base + strconv.Itoa(idx)
+		}
+		exists[name] = true
+
+		typ, option := goType(dialect, column)
+
+		options := []string{column.Name}
+		if column.Quote {
+			options = append(options, "quote")
+		}
+		if column.PrimaryKey {
+			options = append(options, "pk")
+		}
+		if column.AutoIncr {
+			options = append(options, "autoincr")
+		}
+		if !column.NotNull && !column.PrimaryKey && !isNilableType(typ) {
+			options = append(options, "null")
+		}
+		if column.Unique {
+			options = append(options, "unique")
+		} else if column.UniqueName != "" {
+			options = append(options, "unique="+column.UniqueName)
+		}
+		if column.IndexName != "" {
+			options = append(options, "index="+column.IndexName)
+		} else if column.Indexed {
+			options = append(options, "index")
+		}
+		if option != "" {
+			options = append(options, option)
+		}
+
+		fields = append(fields, reverseField{name: name, typ: typ, tag: strings.Join(options, ",")})
+	}
+	return fields
+}
+
+func hasAutoIncr(table *Table) bool {
+	for _, column := range table.Columns {
+		if column.AutoIncr {
+			return true
+		}
+	}
+	return false
+}
+
+// typeName 返回表对应的结构体的名称， 如 user_profiles 为 UserProfile
+func typeName(table *Table) string {
+	name := table.Name
+	if idx := strings.LastIndex(name, "."); idx >= 0 {
+		name = name[idx+1:]
+	}
+	name = Goify(Typeify(strings.ToLower(name)), true)
+	if name == "" {
+		return "Record"
+	}
+	return name
+}
+
+// GenerateFromTables 为表生成结构体和 dao 接口的代码
+func GenerateFromTables(dialect gobatis.Dialect, pkgName, filename string, tables []*Table) ([]byte, error) {
+	var sb strings.Builder
+	if filename != "" {
+		sb.WriteString("//go:generate gobatis " + filename + "\n")
+	}
+	sb.WriteString("package " + pkgName + "\n\n")
+
+	type reverseType struct {
+		table  *Table
+		name   string
+		fields []reverseField
+	}
+	var types []reverseType
+	hasTime := false
+	for _, table := range tables {
+		fields := reverseFields(dialect, table)
+		for _, field := range fields {
+			if strings.HasSuffix(field.typ, "time.Time") {
+				hasTime = true
+			}
+		}
+		types = append(types, reverseType{table: table, name: typeName(table), fields: fields})
+	}
+
+	sb.WriteString("import (\n")
+	if hasTime {
+		sb.WriteString("\t\"time\"\n\n")
+	}
+	sb.WriteString("\tgobatis \"github.com/runner-mei/GoBatis\"\n)\n")
+
+	for _, rt := range types {
+		sb.WriteString("\n// " + rt.name + " 对应表 " + rt.table.Name + "\n")
+		sb.WriteString("type " + rt.name + " struct {\n")
+		sb.WriteString("\tTableName gobatis.TableName `db:\"" + rt.table.Name + "\"`\n")
+		for _, field := range rt.fields {
+			sb.WriteString("\t" + field.name + " " + field.typ + " `db:\"" + field.tag + "\"`\n")
+		}
+		sb.WriteString("}\n")
+
+		recordName := CamelizeDownFirst(rt.name)
+		var keyParams, keyNames []string
+		for idx, column := range rt.table.Columns {
+			if !column.PrimaryKey {
+				continue
+			}
+			// 参数名和字段名一致时， 生成的 sql 会按参数查询对应的列
+			paramName := Goify(column.Name, false)
+			if paramName == recordName {
+				recordName = "record"
+			}
+			keyNames = append(keyNames, paramName)
+			keyParams = append(keyParams, paramName+" "+strings.TrimPrefix(rt.fields[idx].typ, "*"))
+		}
+
+		sb.WriteString("\n// " + rt.name + "Dao 是表 " + rt.table.Name + " 的 dao， 用 go generate 生成它的实现\n")
+		sb.WriteString("type " + rt.name + "Dao interface {\n")
+		// 没有自增列时， postgres 等数据库无法返回插入的 id
+		if hasAutoIncr(rt.table) {
+			sb.WriteString("\tInsert(" + recordName + " *" + rt.name + ") (int64, error)\n")
+		} else {
+			sb.WriteString("\tInsert(" + recordName + " *" + rt.name + ") error\n")
+		}
+		if len(keyParams) > 0 {
+			keys := strings.Join(keyParams, ", ")
+			// 表中只有主键时没有可以更新的列
+			if len(keyParams) < len(rt.table.Columns) {
+				sb.WriteString("\n\tUpdate(" + keys + ", " + recordName + " *" + rt.name + ") (int64, error)\n")
+			}
+			sb.WriteString("\n\tDelete(" + keys + ") (int64, error)\n")
+			sb.WriteString("\n\tFindByID(" + keys + ") (*" + rt.name + ", error)\n")
+		}
+		sb.WriteString("\n\tList(offset, limit int64) ([]*" + rt.name + ", error)\n")
+		sb.WriteString("}\n")
+	}
+
+	return format.Source([]byte(sb.String()))
+}
+
+// Reverse 从 CREATE TABLE 语句生成结构体和 dao 接口
+type Reverse struct {
+	Dialect string
+	Package string
+	Output  string
+}
+
+func (cmd *Reverse) Flags(fs *flag.FlagSet) *flag.FlagSet {
+	fs.StringVar(&cmd.Dialect, "dialect", "postgres", "数据库方言")
+	fs.StringVar(&cmd.Package, "package", "", "生成的代码的包名， 缺省为输出文件所在目录的名称")
+	fs.StringVar(&cmd.Output, "o", "", "输出的文件， 缺省为标准输出")
+	return fs
+}
+
+func (cmd *Reverse) Run(args []string) error {
+	dialect := gobatis.ToDbType(cmd.Dialect)
+	if dialect == gobatis.DbTypeNone {
+		return errors.New("dialect '" + cmd.Dialect + "' is unknown")
+	}
+	if len(args) == 0 {
+		return errors.New("sql file is missing")
+	}
+
+	var sb strings.Builder
+	for _, filename := range args {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		sb.Write(data)
+		sb.WriteString("\n;\n")
+	}
+
+	tables, err := ParseDDL(dialect, sb.String())
+	if err != nil {
+		return err
+	}
+	if len(tables) == 0 {
+		return errors.New("CREATE TABLE isnot found in " + strings.Join(args, ","))
+	}
+
+	pkgName := cmd.Package
+	filename := ""
+	if cmd.Output != "" {
+		filename = filepath.Base(cmd.Output)
+		if pkgName == "" {
+			dir, err := filepath.Abs(filepath.Dir(cmd.Output))
+			if err != nil {
+				return err
+			}
+			pkgName = Goify(filepath.Base(dir), false)
+			pkgName = strings.ToLower(pkgName)
+		}
+	}
+	if pkgName == "" {
+		pkgName = "models"
+	}
+
+	code, err := GenerateFromTables(dialect, pkgName, filename, tables)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if cmd.Output != "" {
+		f, err := os.Create(cmd.Output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+	_, err = out.Write(code)
+	return err
+}
@@ -0,0 +1,242 @@
+package generator
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	gobatis "github.com/runner-mei/GoBatis"
+)
+
+func TestParseDDL(t *testing.T) {
+	for idx, test := range []struct {
+		dialect  gobatis.Dialect
+		sql      string
+		excepted []*Table
+	}{
+		{
+			dialect: gobatis.DbTypePostgres,
+			sql: `-- comment; with semicolon
+			CREATE TABLE IF NOT EXISTS "Users" (
+			  id bigserial PRIMARY KEY,
+			  Name varchar(50) NOT NULL UNIQUE,
+			  org_id int NOT NULL REFERENCES orgs(id) ON DELETE CASCADE,
+			  email character varying(100) DEFAULT 'a,b',
+			  tags text[],
+			  created_at timestamp(6) with time zone NOT NULL DEFAULT now(),
+			  CONSTRAINT uq_users_org_email UNIQUE (org_id, email),
+			  CHECK (org_id > 0)
+			);
+			CREATE INDEX idx_Users_org_id ON "Users" USING btree (org_id);
+			CREATE INDEX ON "Users" (lower(email));
+			INSERT INTO "Users" (name) VALUES ('CREATE TABLE abc (id int)');`,
+			excepted: []*Table{{Name: "Users", Columns: []*Column{
+				{Name: "id", Type: "bigserial", PrimaryKey: true, AutoIncr: true},
+				{Name: "name", Type: "varchar(50)", NotNull: true, Unique: true},
+				{Name: "org_id", Type: "int", NotNull: true, UniqueName: "uq_users_org_email", Indexed: true, IndexName: "idx_users_org_id"},
+				{Name: "email", Type: "character varying(100)", UniqueName: "uq_users_org_email"},
+				{Name: "tags", Type: "text[]"},
+				{Name: "created_at", Type: "timestamp(6) with time zone", NotNull: true},
+			}}},
+		},
+		{
+			dialect: gobatis.DbTypeMysql,
+			sql: "CREATE TABLE `auth_users` (\r\n" +
+				"  `id` bigint(20) unsigned NOT NULL AUTO_INCREMENT,\r\n" +
+				"  `username` varchar(64) CHARACTER SET utf8mb4 NOT NULL COMMENT 'login, name',\r\n" +
+				"  `is_admin` tinyint(1) NOT NULL DEFAULT '0',\r\n" +
+				"  PRIMARY KEY (`id`),\r\n" +
+				"  UNIQUE KEY `uq_username` (`username`),\r\n" +
+				"  KEY `idx_admin` (`is_admin`, `username`)\r\n" +
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;",
+			excepted: []*Table{{Name: "auth_users", Columns: []*Column{
+				{Name: "id", Type: "bigint(20) unsigned", NotNull: true, PrimaryKey: true, AutoIncr: true},
+				{Name: "username", Type: "varchar(64)", NotNull: true, Unique: true, Indexed: true, IndexName: "idx_admin"},
+				{Name: "is_admin", Type: "tinyint(1)", NotNull: true, Indexed: true, IndexName: "idx_admin"},
+			}}},
+		},
+		{
+			dialect: gobatis.DbTypeMSSql,
+			sql: `CREATE TABLE [dbo].[Orders] (
+			  [OrderID] [int] IDENTITY(1,1) NOT NULL,
+			  [Amount] [decimal](18, 2) NULL,
+			  CONSTRAINT [PK_Orders] PRIMARY KEY CLUSTERED ([OrderID] ASC)
+			)
+			GO
+			ALTER TABLE [dbo].[Orders] ADD CONSTRAINT [UQ_Amount] UNIQUE ([Amount])
+			GO`,
+			excepted: []*Table{{Name: "dbo.Orders", Columns: []*Column{
+				{Name: "OrderID", Type: "int", NotNull: true, PrimaryKey: true, AutoIncr: true},
+				{Name: "Amount", Type: "decimal(18,2)", Unique: true},
+			}}},
+		},
+		{
+			dialect: gobatis.DbTypeSqlite,
+			sql: `CREATE TABLE user_groups (user_id INTEGER NOT NULL, group_id INTEGER NOT NULL, PRIMARY KEY (user_id, group_id));
+			CREATE TABLE groups (id INTEGER PRIMARY KEY, name TEXT)`,
+			excepted: []*Table{
+				{Name: "user_groups", Columns: []*Column{
+					{Name: "user_id", Type: "INTEGER", NotNull: true, PrimaryKey: true},
+					{Name: "group_id", Type: "INTEGER", NotNull: true, PrimaryKey: true},
+				}},
+				{Name: "groups", Columns: []*Column{
+					{Name: "id", Type: "INTEGER", PrimaryKey: true, AutoIncr: true},
+					{Name: "name", Type: "TEXT"},
+				}},
+			},
+		},
+		{
+			dialect: gobatis.DbTypePostgres,
+			sql:     `CREATE TABLE items (id int, "DisplayName" text, "order" int, "unit price" numeric, "group_id" int, Remark text)`,
+			excepted: []*Table{{Name: "items", Columns: []*Column{
+				{Name: "id", Type: "int"},
+				{Name: "DisplayName", Type: "text", Quote: true},
+				{Name: "order", Type: "int", Quote: true},
+				{Name: "unit price", Type: "numeric", Quote: true},
+				{Name: "group_id", Type: "int"},
+				{Name: "remark", Type: "text"},
+			}}},
+		},
+		{
+			dialect: gobatis.DbTypeMysql,
+			sql:     "CREATE TABLE `items` (`id` int, `DisplayName` text, `group` int)",
+			excepted: []*Table{{Name: "items", Columns: []*Column{
+				{Name: "id", Type: "int"},
+				{Name: "DisplayName", Type: "text"},
+				{Name: "group", Type: "int", Quote: true},
+			}}},
+		},
+	} {
+		actual, err := ParseDDL(test.dialect, test.sql)
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, test.excepted) {
+			excepted, _ := json.Marshal(test.excepted)
+			bs, _ := json.Marshal(actual)
+			t.Error("[", idx, "] excepted is", string(excepted))
+			t.Error("[", idx, "] actual   is", string(bs))
+		}
+	}
+
+	if _, err := ParseDDL(gobatis.DbTypePostgres, "CREATE TABLE abc ()"); err == nil {
+		t.Error("excepted error got ok")
+	}
+}
+
+func TestGenerateFromTables(t *testing.T) {
+	tables, err := ParseDDL(gobatis.DbTypePostgres, `
+	CREATE TABLE user_profiles (
+	  id bigserial PRIMARY KEY,
+	  user_id bigint NOT NULL,
+	  email varchar(100),
+	  score numeric(10, 2),
+	  attrs jsonb,
+	  birth_day date,
+	  created_at timestamp NOT NULL,
+	  UNIQUE (user_id, email)
+	);
+	CREATE INDEX ON user_profiles (created_at);
+	CREATE TABLE user_groups (user_id bigint, group_id int, PRIMARY KEY (user_id, group_id));`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	code, err := GenerateFromTables(gobatis.DbTypePostgres, "models", "models.go", tables)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	excepted := "//go:generate gobatis models.go\n" +
+		"package models\n" +
+		"\n" +
+		"import (\n" +
+		"\t\"time\"\n" +
+		"\n" +
+		"\tgobatis \"github.com/runner-mei/GoBatis\"\n" +
+		")\n" +
+		"\n" +
+		"// UserProfile 对应表 user_profiles\n" +
+		"type UserProfile struct {\n" +
+		"\tTableName gobatis.TableName      `db:\"user_profiles\"`\n" +
+		"\tID        int64                  `db:\"id,pk,autoincr\"`\n" +
+		"\tUserID    int64                  `db:\"user_id,unique=uq_user_profiles_user_id_email\"`\n" +
+		"\tEmail     string                 `db:\"email,null,unique=uq_user_profiles_user_id_email\"`\n" +
+		"\tScore     float64                `db:\"score,null\"`\n" +
+		"\tAttrs     map[string]interface{} `db:\"attrs,jsonb\"`\n" +
+		"\tBirthDay  *time.Time             `db:\"birth_day\"`\n" +
+		"\tCreatedAt time.Time              `db:\"created_at,index\"`\n" +
+		"}\n" +
+		"\n" +
+		"// UserProfileDao 是表 user_profiles 的 dao， 用 go generate 生成它的实现\n" +
+		"type UserProfileDao interface {\n" +
+		"\tInsert(userProfile *UserProfile) (int64, error)\n" +
+		"\n" +
+		"\tUpdate(id int64, userProfile *UserProfile) (int64, error)\n" +
+		"\n" +
+		"\tDelete(id int64) (int64, error)\n" +
+		"\n" +
+		"\tFindByID(id int64) (*UserProfile, error)\n" +
+		"\n" +
+		"\tList(offset, limit int64) ([]*UserProfile, error)\n" +
+		"}\n" +
+		"\n" +
+		"// UserGroup 对应表 user_groups\n" +
+		"type UserGroup struct {\n" +
+		"\tTableName gobatis.TableName `db:\"user_groups\"`\n" +
+		"\tUserID    int64             `db:\"user_id,pk\"`\n" +
+		"\tGroupID   int               `db:\"group_id,pk\"`\n" +
+		"}\n" +
+		"\n" +
+		"// UserGroupDao 是表 user_groups 的 dao， 用 go generate 生成它的实现\n" +
+		"type UserGroupDao interface {\n" +
+		"\tInsert(userGroup *UserGroup) error\n" +
+		"\n" +
+		"\tDelete(userID int64, groupID int) (int64, error)\n" +
+		"\n" +
+		"\tFindByID(userID int64, groupID int) (*UserGroup, error)\n" +
+		"\n" +
+		"\tList(offset, limit int64) ([]*UserGroup, error)\n" +
+		"}\n"
+
+	if actual := string(code); actual != excepted {
+		actualLines := strings.Split(actual, "\n")
+		for lineNo, line := range strings.Split(excepted, "\n") {
+			if lineNo >= len(actualLines) || actualLines[lineNo] != line {
+				t.Error("excepted is", line)
+				if lineNo < len(actualLines) {
+					t.Error("actual   is", actualLines[lineNo])
+				}
+				break
+			}
+		}
+		t.Log(actual)
+	}
+}
+
+func TestReverseQuote(t *testing.T) {
+	tables, err := ParseDDL(gobatis.DbTypePostgres, `CREATE TABLE items (id int PRIMARY KEY, "DisplayName" text NOT NULL, "order" int NOT NULL, name text NOT NULL)`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var actual []string
+	for _, field := range reverseFields(gobatis.DbTypePostgres, tables[0]) {
+		actual = append(actual, field.name+" "+field.tag)
+	}
+	excepted := []string{
+		"ID id,pk",
+		"DisplayName DisplayName,quote",
+		"Order order,quote",
+		"Name name",
+	}
+	if !reflect.DeepEqual(actual, excepted) {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", actual)
+	}
+}
@@ -0,0 +1,41 @@
+//go:generate gobatis document.go
+package gentest
+
+import (
+	"time"
+)
+
+type Document struct {
+	TableName struct{}   `db:"documents"`
+	ID        int64      `db:"id,autoincr"`
+	Title     string     `db:"title"`
+	DeletedAt *time.Time `db:"deleted_at,deleted"`
+}
+
+type DocumentQuery struct {
+	Title  string  `q:"title,contains"`
+	IDs    []int64 `q:"id,in"`
+	SortBy string
+	Offset int
+	Limit  int
+}
+
+type DocumentDao interface {
+	Insert(doc *Document) (int64, error)
+
+	DeleteByID(id int64) (int64, error)
+
+	Restore(id int64) (int64, error)
+
+	ListWithDeleted() ([]Document, error)
+
+	ListOnlyDeleted() ([]Document, error)
+
+	QueryByTitleWithDeleted(title string) ([]Document, error)
+
+	CountOnlyDeleted() (int64, error)
+
+	QueryBy(q *DocumentQuery) ([]Document, error)
+
+	CountBy(q *DocumentQuery) (int64, error)
+}
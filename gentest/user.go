@@ -72,6 +72,13 @@ type UserDao interface {
 	// WHERE id=#{id}
 	UpdateName(id int64, username string) (int64, error)
 
+	// @option audit auth_users_history
+	// @default UPDATE auth_users
+	// SET status=#{status},
+	//     updated_at=CURRENT_TIMESTAMP
+	// WHERE id=#{id}
+	UpdateStatus(id int64, status Status) (int64, error)
+
 	// @postgres DELETE FROM auth_users
 	// @default DELETE FROM auth_users
 	DeleteAll() (int64, error)
@@ -102,6 +109,9 @@ type UserDao interface {
 	// @default select count(*) from auth_users
 	Count() (int64, error)
 
+	// @option tenant ignore
+	CountByUsername(username string) (int64, error)
+
 	// @mssql select * from auth_users ORDER BY username OFFSET #{offset} ROWS FETCH NEXT #{size}  ROWS ONLY
 	// @mysql select * from auth_users limit #{offset}, #{size}
 	// @default select * from auth_users offset #{offset} limit  #{size}
@@ -27,8 +27,27 @@ type File struct {
 }
 
 func Parse(filename string) (*File, error) {
+	return ParseWithImporter(filename, "", false)
+}
 
-	goBuild(filename)
+// ParseWithImporter 和 Parse 一样， importer 是读取依赖的包的类型信息的方式，
+// "gc" 为从编译后的包中读取， "source" 为从源代码中读取，
+// 为空时 GO111MODULE=on 时为 source， 否则为 gc， verbose 为 true 时打印 go build 的输出
+func ParseWithImporter(filename, importer string, verbose bool) (*File, error) {
+	if importer == "" {
+		importer = "gc"
+		if modEnable := os.Getenv("GO111MODULE"); modEnable == "on" {
+			importer = "source"
+		}
+	}
+	if importer != "gc" && importer != "source" {
+		return nil, errors.New("importer '" + importer + "' is unsupported")
+	}
+
+	// source 方式不需要编译后的包
+	if importer == "gc" {
+		goBuild(filename, verbose)
+	}
 
 	dir := filepath.Dir(filename)
 	if dir == "" {
@@ -37,9 +56,9 @@ func Parse(filename string) (*File, error) {
 
 	fset := token.NewFileSet()
 
-	importer := goimporter.Default()
-	if modEnable := os.Getenv("GO111MODULE"); modEnable == "on" {
-		importer = goimporter.ForCompiler(fset, "source", nil)
+	typesImporter := goimporter.Default()
+	if importer == "source" {
+		typesImporter = goimporter.ForCompiler(fset, "source", nil)
 	}
 	filenames, err := filepath.Glob(filepath.Join(dir, "*.go"))
 	if err != nil {
@@ -72,7 +91,7 @@ func Parse(filename string) (*File, error) {
 		return nil, errors.New("`" + filename + "` isnot found")
 	}
 
-	return parse(fset, importer, files, filename, current)
+	return parse(fset, typesImporter, files, filename, current)
 }
 
 func parse(fset *token.FileSet, importer types.Importer, files []*ast.File, filename string, f *ast.File) (*File, error) {
@@ -106,10 +125,15 @@ func parse(fset *token.FileSet, importer types.Importer, files []*ast.File, file
 	return store, nil
 }
 
-func goBuild(src string) error {
+// goBuild 编译源文件所在的包， 以便 gc 方式能读到依赖的包的类型信息， 编译失败时
+// 仍然会继续解析， 所以它的输出只在 verbose 为 true 时打印
+func goBuild(src string, verbose bool) error {
 	cmd := exec.Command("go", "build", "-i")
 	cmd.Dir = filepath.Dir(src)
 	out, err := cmd.CombinedOutput()
+	if !verbose {
+		return err
+	}
 	if len(out) > 0 {
 		fmt.Println("go build -i")
 		fmt.Println(string(out))
@@ -158,19 +158,7 @@ func (m *Method) StatementType() gobatis.StatementType {
 		}
 		return gobatis.StatementTypeNone
 	}
-	if isInsertStatement(m.Name) {
-		return gobatis.StatementTypeInsert
-	}
-	if isUpdateStatement(m.Name) {
-		return gobatis.StatementTypeUpdate
-	}
-	if isDeleteStatement(m.Name) {
-		return gobatis.StatementTypeDelete
-	}
-	if isSelectStatement(m.Name) {
-		return gobatis.StatementTypeSelect
-	}
-	return gobatis.StatementTypeNone
+	return gobatis.StatementTypeByName(m.Name)
 }
 
 //func (m *Method) UpsertKeys() []string {
@@ -26,6 +26,8 @@ func TestStatement(t *testing.T) {
 	}{
 		{"update", true},
 		{"updatea", true},
+		{"restore", true},
+		{"RestoreByID", true},
 		{"a", false},
 	} {
 		if test.excepted != isUpdateStatement(test.name) {
@@ -57,6 +59,8 @@ func TestStatement(t *testing.T) {
 		{"get", true},
 		{"query", true},
 		{"id", true},
+		{"ForEachUser", true},
+		{"UserForEach", true},
 		{"a", false},
 	} {
 		if test.excepted != isSelectStatement(test.name) {
@@ -3,6 +3,7 @@ package gobatis_test
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"net"
 	"reflect"
 	"strings"
@@ -953,3 +954,44 @@ func TestHandleError(t *testing.T) {
 		gobatis.ErrForGenerateStmt(e, "aa")
 	})
 }
+
+func TestHandleSqliteError(t *testing.T) {
+	for idx, test := range []struct {
+		err     string
+		code    string
+		columns []string
+	}{
+		{err: "UNIQUE constraint failed: gobatis_usergroups.name", code: "unique_value_already_exists", columns: []string{"name"}},
+		{err: "UNIQUE constraint failed: t1.a, t1.b", code: "unique_value_already_exists", columns: []string{"a", "b"}},
+		{err: "NOT NULL constraint failed: t1.a", code: "SQLITE.not_null_violation", columns: []string{"a"}},
+		{err: "FOREIGN KEY constraint failed", code: "SQLITE.foreign_key_violation"},
+	} {
+		err := gobatis.DbTypeSqlite.HandleError(errors.New(test.err))
+		e, ok := err.(*gobatis.Error)
+		if !ok {
+			t.Error("[", idx, "] error isnot excepted -", err)
+			continue
+		}
+		if len(e.Validations) != 1 {
+			t.Error("[", idx, "] Validations is empty")
+			continue
+		}
+		if e.Validations[0].Code != test.code {
+			t.Error("[", idx, "] excepted is", test.code)
+			t.Error("[", idx, "] actual   is", e.Validations[0].Code)
+		}
+		if !reflect.DeepEqual(e.Validations[0].Columns, test.columns) {
+			t.Error("[", idx, "] excepted is", test.columns)
+			t.Error("[", idx, "] actual   is", e.Validations[0].Columns)
+		}
+		if e.Error() != test.err {
+			t.Error("[", idx, "] excepted is", test.err)
+			t.Error("[", idx, "] actual   is", e.Error())
+		}
+	}
+
+	err := errors.New("no such table: abc")
+	if gobatis.DbTypeSqlite.HandleError(err) != err {
+		t.Error("error isnot excepted")
+	}
+}
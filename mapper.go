@@ -71,9 +71,16 @@ func (s *StructMap) primaryKey() [][]int {
 }
 
 type Mapper struct {
-	mapper *reflectx.Mapper
-	cache  atomic.Value
-	mutex  sync.Mutex
+	mapper       *reflectx.Mapper
+	cipher       Cipher
+	ignoreTenant bool
+	deletedMode  int
+	cache        atomic.Value
+	mutex        sync.Mutex
+
+	// root 是 clone() 的源， clone() 生成的 Mapper 共用 root 的缓存， 这样
+	// CheckSchema 也能检查到 WithDeleted() 等生成 sql 时用到的记录类型
+	root *Mapper
 }
 
 func (m *Mapper) getCache() map[reflect.Type]*StructMap {
@@ -109,6 +116,9 @@ func (m *Mapper) getCache() map[reflect.Type]*StructMap {
 // TypeMap returns a mapping of field strings to int slices representing
 // the traversal down the struct to reach the field.
 func (m *Mapper) TypeMap(t reflect.Type) *StructMap {
+	if m.root != nil {
+		return m.root.TypeMap(t)
+	}
 	t = reflectx.Deref(t)
 
 	var cache = m.getCache()
@@ -137,14 +147,14 @@ func (m *Mapper) TypeMap(t reflect.Type) *StructMap {
 		cache = map[reflect.Type]*StructMap{}
 	}
 
-	mapping := getMapping(m.mapper, t)
+	mapping := getMapping(m, t)
 	cache[t] = mapping
 	m.cache.Store(cache)
 	return mapping
 }
 
-func getMapping(mapper *reflectx.Mapper, t reflect.Type) *StructMap {
-	mapping := mapper.TypeMap(t)
+func getMapping(mapper *Mapper, t reflect.Type) *StructMap {
+	mapping := mapper.mapper.TypeMap(t)
 	info := &StructMap{
 		Inner:      mapping,
 		Paths:      map[string]*FieldInfo{},
@@ -152,7 +162,7 @@ func getMapping(mapper *reflectx.Mapper, t reflect.Type) *StructMap {
 		FieldNames: map[string]*FieldInfo{},
 	}
 	for idx := range mapping.Index {
-		info.Index = append(info.Index, getFeildInfo(mapping.Index[idx]))
+		info.Index = append(info.Index, getFeildInfo(mapper, mapping.Index[idx]))
 	}
 
 	find := func(field *reflectx.FieldInfo) *FieldInfo {
@@ -190,6 +200,20 @@ func (fi *FieldInfo) makeRValue() func(dialect Dialect, param *Param, v reflect.
 			return nil, nil
 		}
 	}
+	if handler, err := fi.typeHandler(); err != nil {
+		return func(dialect Dialect, param *Param, v reflect.Value) (interface{}, error) {
+			return nil, fmt.Errorf("field '%s' %s", fi.Field.Name, err)
+		}
+	} else if handler != nil {
+		return func(dialect Dialect, param *Param, v reflect.Value) (interface{}, error) {
+			field := reflectx.FieldByIndexesReadOnly(v, fi.Index)
+			value, err := typeHandlerToDB(dialect, handler, field.Interface())
+			if err != nil {
+				return nil, fmt.Errorf("field '%s' convert fail, %s", fi.Field.Name, err)
+			}
+			return value, nil
+		}
+	}
 	typ := fi.Field.Type
 	kind := typ.Kind()
 	isPtr := false
@@ -204,10 +228,7 @@ func (fi *FieldInfo) makeRValue() func(dialect Dialect, param *Param, v reflect.
 		if typeElem.Kind() == reflect.Ptr {
 			typeElem = typeElem.Elem()
 		}
-		if typeElem.PkgPath() != "" {
-			break
-		}
-		if typeElem.Elem().Kind() == reflect.Struct {
+		if !isArrayType(typeElem, fi.Options) {
 			break
 		}
 
@@ -909,6 +930,16 @@ func (fi *FieldInfo) makeLValue() func(dialect Dialect, column string, v reflect
 			return emptyScan, nil
 		}
 	}
+	if handler, err := fi.typeHandler(); err != nil {
+		return func(dialect Dialect, column string, v reflect.Value) (interface{}, error) {
+			return nil, fmt.Errorf("field '%s' %s", fi.Field.Name, err)
+		}
+	} else if handler != nil {
+		return func(dialect Dialect, column string, v reflect.Value) (interface{}, error) {
+			field := reflectx.FieldByIndexes(v, fi.Index)
+			return &typeHandlerScanner{dialect: dialect, name: column, handler: handler, field: field}, nil
+		}
+	}
 	typ := fi.Field.Type
 	kind := typ.Kind()
 	if kind == reflect.Ptr {
@@ -925,7 +956,7 @@ func (fi *FieldInfo) makeLValue() func(dialect Dialect, column string, v reflect
 			typeElem = typeElem.Elem()
 		}
 
-		if typeElem.PkgPath() != "" {
+		if !isArrayType(typeElem, fi.Options) {
 			break
 		}
 
@@ -940,11 +971,6 @@ func (fi *FieldInfo) makeLValue() func(dialect Dialect, column string, v reflect
 		if !jsonExists {
 			_, jsonExists = fi.Options["jsonb"]
 		}
-		if !jsonExists {
-			if typeElem.Elem().Kind() == reflect.Struct {
-				jsonExists = true
-			}
-		}
 		if jsonExists {
 			return func(dialect Dialect, column string, v reflect.Value) (interface{}, error) {
 				field := reflectx.FieldByIndexes(v, fi.Index)
@@ -975,7 +1001,7 @@ func (fi *FieldInfo) makeLValue() func(dialect Dialect, column string, v reflect
 		if _, ok := fi.Options["null"]; ok {
 			return func(dialect Dialect, column string, v reflect.Value) (interface{}, error) {
 				field := reflectx.FieldByIndexes(v, fi.Index)
-				fvalue := &Nullable{Name: fi.Name, Value: field.Addr().Interface()}
+				fvalue := &Nullable{Dialect: dialect, Name: fi.Name, Value: field.Addr().Interface()}
 				return fvalue, nil
 			}
 		}
@@ -1001,7 +1027,7 @@ func (fi *FieldInfo) makeLValue() func(dialect Dialect, column string, v reflect
 		if _, ok := fi.Options["null"]; ok {
 			return func(dialect Dialect, column string, v reflect.Value) (interface{}, error) {
 				field := reflectx.FieldByIndexes(v, fi.Index)
-				fvalue := &Nullable{Name: fi.Name, Value: field.Addr().Interface()}
+				fvalue := &Nullable{Dialect: dialect, Name: fi.Name, Value: field.Addr().Interface()}
 				return fvalue, nil
 			}
 		}
@@ -1055,10 +1081,15 @@ var _macType = reflect.TypeOf((*net.HardwareAddr)(nil)).Elem()
 var _macPtr = reflect.TypeOf((*net.HardwareAddr)(nil))
 var _macPtrPtr = reflect.TypeOf((**net.HardwareAddr)(nil))
 
-func getFeildInfo(field *reflectx.FieldInfo) *FieldInfo {
+func getFeildInfo(mapper *Mapper, field *reflectx.FieldInfo) *FieldInfo {
 	info := &FieldInfo{
 		FieldInfo: field,
 	}
+	if isEncryptField(info) {
+		info.LValue = info.makeDecryptLValue(mapper)
+		info.RValue = info.makeEncryptRValue(mapper)
+		return info
+	}
 	info.LValue = info.makeLValue()
 	info.RValue = info.makeRValue()
 	return info
@@ -0,0 +1,317 @@
+package migrate_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	gobatis "github.com/runner-mei/GoBatis"
+	"github.com/runner-mei/GoBatis/migrate"
+	"github.com/runner-mei/GoBatis/tests"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		filename := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filename, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	for idx, test := range []struct {
+		content       string
+		up            []string
+		down          []string
+		noTransaction bool
+	}{
+		{
+			content: "CREATE TABLE a (id int);\nCREATE TABLE b (id int);\n",
+			up:      []string{"CREATE TABLE a (id int);", "CREATE TABLE b (id int);"},
+		},
+		{
+			content: "-- +gobatis Up\n-- comment\nCREATE TABLE a (id int);\n\n-- +gobatis Down\nDROP TABLE a;\n",
+			up:      []string{"CREATE TABLE a (id int);"},
+			down:    []string{"DROP TABLE a;"},
+		},
+		{
+			content: "-- +gobatis Up\n-- +gobatis NoTransaction\n-- +gobatis StatementBegin\nCREATE FUNCTION f() AS $$ BEGIN; END; $$;\n-- +gobatis StatementEnd\n",
+			up:      []string{"CREATE FUNCTION f() AS $$ BEGIN; END; $$;"},
+
+			noTransaction: true,
+		},
+	} {
+		m, err := migrate.Parse([]byte(test.content))
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		for i := range m.Up {
+			m.Up[i] = strings.TrimSpace(m.Up[i])
+		}
+		for i := range m.Down {
+			m.Down[i] = strings.TrimSpace(m.Down[i])
+		}
+		if !reflect.DeepEqual(m.Up, test.up) {
+			t.Error("[", idx, "] excepted is", test.up)
+			t.Error("[", idx, "] actual   is", m.Up)
+		}
+		if !reflect.DeepEqual(m.Down, test.down) {
+			t.Error("[", idx, "] excepted is", test.down)
+			t.Error("[", idx, "] actual   is", m.Down)
+		}
+		if m.NoTransaction != test.noTransaction {
+			t.Error("[", idx, "] excepted is", test.noTransaction)
+			t.Error("[", idx, "] actual   is", m.NoTransaction)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gobatis_migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"0001_a.sql":          "CREATE TABLE a (id int);",
+		"0002_b.sql":          "CREATE TABLE b (id int);",
+		"postgres/0002_b.sql": "CREATE TABLE b (id bigserial);",
+		"mysql/0003_c.sql":    "CREATE TABLE c (id int);",
+		"README.md":           "not a migration",
+	})
+
+	for idx, test := range []struct {
+		dialect  gobatis.Dialect
+		excepted []string
+	}{
+		{dialect: gobatis.DbTypeMysql, excepted: []string{"0001_a.sql", "0002_b.sql", "mysql/0003_c.sql"}},
+		{dialect: gobatis.DbTypePostgres, excepted: []string{"0001_a.sql", "postgres/0002_b.sql"}},
+		{dialect: gobatis.DbTypeKingbase, excepted: []string{"0001_a.sql", "postgres/0002_b.sql"}},
+	} {
+		migrations, err := migrate.Load(migrate.Dir(dir), test.dialect)
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		var filenames []string
+		for _, m := range migrations {
+			filenames = append(filenames, m.Filename)
+		}
+		if !reflect.DeepEqual(filenames, test.excepted) {
+			t.Error("[", idx, "] excepted is", test.excepted)
+			t.Error("[", idx, "] actual   is", filenames)
+		}
+	}
+
+	writeFiles(t, dir, map[string]string{"01_a2.sql": "SELECT 1;"})
+	if _, err := migrate.Load(migrate.Dir(dir), gobatis.DbTypeMysql); err == nil {
+		t.Error("excepted error got ok")
+	} else if !strings.Contains(err.Error(), "duplicated") {
+		t.Error(err)
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gobatis_migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{
+		"0001_create_a.sql": "-- +gobatis Up\nCREATE TABLE migrate_a (id int);\n-- +gobatis Down\nDROP TABLE migrate_a;\n",
+		"0002_create_b.sql": "-- +gobatis Up\nCREATE TABLE migrate_b (id int);\nINSERT INTO migrate_b (id) VALUES (1);\n-- +gobatis Down\nDROP TABLE migrate_b;\n",
+	})
+
+	db, err := sql.Open(tests.TestDrv, tests.TestConnURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	for _, table := range []string{"migrate_c", "migrate_b", "migrate_a", "schema_migrations", "schema_migrations_lock"} {
+		db.ExecContext(ctx, "DROP TABLE "+table)
+	}
+	defer func() {
+		for _, table := range []string{"migrate_c", "migrate_b", "migrate_a", "schema_migrations", "schema_migrations_lock"} {
+			db.ExecContext(ctx, "DROP TABLE "+table)
+		}
+	}()
+
+	migrator := migrate.New(db, gobatis.ToDbType(tests.TestDrv), migrate.Dir(dir))
+
+	applied := func() []int64 {
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var versions []int64
+		for _, status := range statuses {
+			if status.Applied {
+				versions = append(versions, status.Version)
+			}
+		}
+		return versions
+	}
+	assertApplied := func(excepted ...int64) {
+		t.Helper()
+		if actual := applied(); !reflect.DeepEqual(actual, excepted) {
+			t.Error("excepted is", excepted)
+			t.Error("actual   is", actual)
+		}
+	}
+
+	// dry-run 只输出 sql
+	var out bytes.Buffer
+	migrator.DryRun = true
+	migrator.Out = &out
+	if err := migrator.Up(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	migrator.DryRun = false
+	migrator.Out = nil
+	if !strings.Contains(out.String(), "CREATE TABLE migrate_a") || !strings.Contains(out.String(), "CREATE TABLE migrate_b") {
+		t.Error(out.String())
+	}
+	assertApplied()
+
+	if err := migrator.Up(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	assertApplied(1)
+
+	if err := migrator.Up(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	assertApplied(1, 2)
+
+	var count int64
+	if err := db.QueryRowContext(ctx, "SELECT count(*) FROM migrate_b").Scan(&count); err != nil {
+		t.Error(err)
+	} else if count != 1 {
+		t.Error("excepted is 1")
+		t.Error("actual   is", count)
+	}
+
+	// 执行失败时不记录版本
+	writeFiles(t, dir, map[string]string{
+		"0003_create_c.sql": "CREATE TABLE migrate_c (id int);\nINSERT INTO not_exists_table (id) VALUES (1);\n",
+	})
+	if err := migrator.Up(ctx, 0); err == nil {
+		t.Error("excepted error got ok")
+	}
+	assertApplied(1, 2)
+	os.Remove(filepath.Join(dir, "0003_create_c.sql"))
+	db.ExecContext(ctx, "DROP TABLE migrate_c")
+
+	// 已执行的文件被修改
+	writeFiles(t, dir, map[string]string{
+		"0001_create_a.sql": "-- +gobatis Up\nCREATE TABLE migrate_a (id bigint);\n-- +gobatis Down\nDROP TABLE migrate_a;\n",
+	})
+	if err := migrator.Up(ctx, 0); err == nil {
+		t.Error("excepted error got ok")
+	} else if !strings.Contains(err.Error(), "modified") {
+		t.Error(err)
+	}
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 2 || !statuses[0].Modified || statuses[1].Modified {
+		t.Error("excepted is [modified, not modified]")
+		t.Error("actual   is", statuses)
+	}
+
+	// 被锁定时不能执行
+	if _, err := db.ExecContext(ctx, "INSERT INTO schema_migrations_lock (id, locked_by, locked_at) VALUES (1, 'other', CURRENT_TIMESTAMP)"); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrator.Down(ctx, 1); err == nil {
+		t.Error("excepted error got ok")
+	} else if _, ok := err.(*migrate.LockedError); !ok {
+		t.Error(err)
+	}
+	if err := migrator.Unlock(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// 没有 Down 语句的迁移不能回滚
+	writeFiles(t, dir, map[string]string{
+		"0001_create_a.sql": "-- +gobatis Up\nCREATE TABLE migrate_a (id int);\n-- +gobatis Down\nDROP TABLE migrate_a;\n",
+		"0003_create_c.sql": "-- +gobatis Up\nCREATE TABLE migrate_c (id int);\n",
+	})
+	if err := migrator.Up(ctx, 0); err != nil {
+		t.Fatal(err)
+	}
+	assertApplied(1, 2, 3)
+	if err := migrator.Down(ctx, 2); err == nil {
+		t.Error("excepted error got ok")
+	} else if !strings.Contains(err.Error(), "has no down statements") {
+		t.Error(err)
+	}
+	assertApplied(1, 2, 3)
+
+	migrator.AllowEmptyDown = true
+	if err := migrator.Down(ctx, 3); err != nil {
+		t.Fatal(err)
+	}
+	assertApplied()
+	if err := db.QueryRowContext(ctx, "SELECT count(*) FROM migrate_a").Scan(&count); err == nil {
+		t.Error("excepted error got ok")
+	}
+
+	// 查询失败的原因不是表不存在时返回错误
+	closed, err := sql.Open(tests.TestDrv, tests.TestConnURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+	if _, err := migrate.New(closed, gobatis.ToDbType(tests.TestDrv), migrate.Dir(dir)).Status(ctx); err == nil {
+		t.Error("excepted error got ok")
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gobatis_migrate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	writeFiles(t, dir, map[string]string{"0007_a.sql": "SELECT 1;"})
+
+	filename, err := migrate.Create(dir, "add users table")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(filename) != "0008_add_users_table.sql" {
+		t.Error("excepted is 0008_add_users_table.sql")
+		t.Error("actual   is", filepath.Base(filename))
+	}
+
+	migrations, err := migrate.Load(migrate.Dir(dir), gobatis.DbTypePostgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 || len(migrations[1].Up) != 0 || len(migrations[1].Down) != 0 {
+		t.Error("excepted is empty migration")
+		t.Error("actual   is", migrations)
+	}
+
+	if _, err := migrate.Create(dir, "  "); err == nil {
+		t.Error("excepted error got ok")
+	}
+}
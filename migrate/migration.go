@@ -0,0 +1,217 @@
+package migrate
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	gobatis "github.com/runner-mei/GoBatis"
+)
+
+const (
+	cmdPrefix        = "-- +gobatis "
+	cmdUp            = "Up"
+	cmdDown          = "Down"
+	cmdNoTransaction = "NoTransaction"
+)
+
+var filenamePattern = regexp.MustCompile(`^(\d+)_(.+)\.sql$`)
+
+// Migration 是一个迁移文件， 文件名的格式为 版本号_名称.sql， 如 0001_create_users.sql，
+// 文件中用 "-- +gobatis Up" 和 "-- +gobatis Down" 分隔升级和回滚的 sql，
+// 有 "-- +gobatis NoTransaction" 时不在事务中执行
+type Migration struct {
+	Version       int64
+	Name          string
+	Filename      string
+	Checksum      string
+	Up            []string
+	Down          []string
+	NoTransaction bool
+}
+
+// Load 读取 source 中的迁移文件并按版本号排序， 根目录下与方言同名的子目录(如 postgres)中的文件
+// 会覆盖根目录下相同版本的文件， 方言兼容的方言(见 gobatis.ParentDialect)的子目录也会被查找
+func Load(source Source, dialect gobatis.Dialect) ([]*Migration, error) {
+	byVersion := map[int64]*Migration{}
+	if err := loadDir(source, ".", byVersion, false); err != nil {
+		return nil, err
+	}
+
+	var dirs []string
+	for d := dialect; d != nil; d = gobatis.ParentDialect(d) {
+		dirs = append(dirs, d.Name())
+	}
+	// 先读父方言的目录， 这样子方言的文件可以覆盖它
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := loadDir(source, dirs[i], byVersion, true); err != nil {
+			return nil, err
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+func loadDir(source Source, dir string, byVersion map[int64]*Migration, optional bool) error {
+	infos, err := source.ReadDir(dir)
+	if err != nil {
+		if optional && os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	loaded := map[int64]string{}
+	for _, info := range infos {
+		if info.IsDir() {
+			continue
+		}
+		version, name, ok := parseFilename(info.Name())
+		if !ok {
+			continue
+		}
+		if old, exists := loaded[version]; exists {
+			return errors.New("migration version " + strconv.FormatInt(version, 10) +
+				" is duplicated in '" + old + "' and '" + info.Name() + "'")
+		}
+		loaded[version] = info.Name()
+
+		filename := path.Join(dir, info.Name())
+		data, err := source.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		m, err := Parse(data)
+		if err != nil {
+			return errors.New("parse '" + filename + "' fail, " + err.Error())
+		}
+		m.Version = version
+		m.Name = name
+		m.Filename = filename
+		byVersion[version] = m
+	}
+	return nil
+}
+
+func parseFilename(filename string) (int64, string, bool) {
+	matches := filenamePattern.FindStringSubmatch(filename)
+	if matches == nil {
+		return 0, "", false
+	}
+	version, err := strconv.ParseInt(matches[1], 10, 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return version, matches[2], true
+}
+
+// Parse 解析迁移文件的内容， 没有 "-- +gobatis Up" 时整个文件都是升级的 sql
+func Parse(data []byte) (*Migration, error) {
+	sum := sha256.Sum256(data)
+	m := &Migration{Checksum: hex.EncodeToString(sum[:])}
+
+	var up, down bytes.Buffer
+	current := &up
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		text := scanner.Text()
+		if line := strings.TrimSpace(text); strings.HasPrefix(line, cmdPrefix) {
+			switch strings.TrimSpace(line[len(cmdPrefix):]) {
+			case cmdUp:
+				current = &up
+				continue
+			case cmdDown:
+				current = &down
+				continue
+			case cmdNoTransaction:
+				m.NoTransaction = true
+				continue
+			}
+		}
+		current.WriteString(text)
+		current.WriteString("\n")
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	m.Up = splitStatements(up.Bytes())
+	m.Down = splitStatements(down.Bytes())
+	return m, nil
+}
+
+func splitStatements(data []byte) []string {
+	var stmts []string
+	for _, stmt := range gobatis.SplitSQLStatements(bytes.NewReader(data)) {
+		if !isEmptyStatement(stmt) {
+			stmts = append(stmts, stmt)
+		}
+	}
+	return stmts
+}
+
+// isEmptyStatement 判断语句是不是只有空白和注释
+func isEmptyStatement(stmt string) bool {
+	for _, line := range strings.Split(stmt, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") {
+			return false
+		}
+	}
+	return true
+}
+
+const createTemplate = `-- +gobatis Up
+-- SQL in section 'Up' is executed when this migration is applied
+
+
+-- +gobatis Down
+-- SQL in section 'Down' is executed when this migration is rolled back
+
+`
+
+// Create 在 dir 中创建一个新的迁移文件， 版本号为已有的最大版本号加一， 返回文件的路径
+func Create(dir, name string) (string, error) {
+	name = strings.Join(strings.Fields(name), "_")
+	if name == "" {
+		return "", errors.New("migration name is empty")
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	var max int64
+	for _, info := range infos {
+		if version, _, ok := parseFilename(info.Name()); ok && version > max {
+			max = version
+		}
+	}
+
+	filename := filepath.Join(dir, fmt.Sprintf("%04d_%s.sql", max+1, name))
+	if err := ioutil.WriteFile(filename, []byte(createTemplate), 0644); err != nil {
+		return "", err
+	}
+	return filename, nil
+}
@@ -0,0 +1,450 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	gobatis "github.com/runner-mei/GoBatis"
+)
+
+// DefaultTableName 是记录已执行的迁移的表名
+const DefaultTableName = "schema_migrations"
+
+// LockedError 表示另一个迁移程序正在执行
+type LockedError struct {
+	LockedBy string
+	LockedAt time.Time
+}
+
+func (e *LockedError) Error() string {
+	return "migration is locked by " + e.LockedBy + " at " + e.LockedAt.Format(time.RFC3339)
+}
+
+// Migrator 执行迁移， 已执行的迁移的版本号和校验和记录在 TableName 表中，
+// 执行期间会在 TableName_lock 表中加锁， 以免多个程序同时执行迁移
+type Migrator struct {
+	DB        *sql.DB
+	Dialect   gobatis.Dialect
+	Source    Source
+	TableName string
+
+	// DryRun 为 true 时只将要执行的 sql 输出到 Out， 不执行它们
+	DryRun bool
+	// AllowEmptyDown 为 true 时允许回滚没有 Down 语句的迁移， 这时只删除它的记录
+	AllowEmptyDown bool
+	// Out 用于输出执行的进度， 为 nil 时不输出
+	Out io.Writer
+}
+
+// New 创建一个 Migrator
+func New(db *sql.DB, dialect gobatis.Dialect, source Source) *Migrator {
+	return &Migrator{
+		DB:        db,
+		Dialect:   dialect,
+		Source:    source,
+		TableName: DefaultTableName,
+	}
+}
+
+// Status 是一个迁移的状态
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	// Modified 表示迁移文件在执行后被修改过
+	Modified bool
+	// Missing 表示迁移已执行， 但迁移文件已不存在
+	Missing bool
+}
+
+type appliedRecord struct {
+	version   int64
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+func (m *Migrator) tableName() string {
+	if m.TableName == "" {
+		return DefaultTableName
+	}
+	return m.TableName
+}
+
+func (m *Migrator) lockTableName() string {
+	return m.tableName() + "_lock"
+}
+
+func (m *Migrator) printf(format string, args ...interface{}) {
+	if m.Out != nil {
+		fmt.Fprintf(m.Out, format, args...)
+	}
+}
+
+func (m *Migrator) exec(ctx context.Context, execer interface {
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+}, sqlStr string, args ...interface{}) error {
+	if len(args) > 0 {
+		s, err := m.Dialect.Placeholder().ReplacePlaceholders(sqlStr)
+		if err != nil {
+			return err
+		}
+		sqlStr = s
+	}
+	_, err := execer.ExecContext(ctx, sqlStr, args...)
+	return err
+}
+
+// transactional 判断是否可以在事务中执行 DDL， mysql 和 oracle 执行 DDL 时会隐式地提交事务
+func (m *Migrator) transactional() bool {
+	return !gobatis.IsDialect(m.Dialect, gobatis.DbTypeMysql) &&
+		!gobatis.IsDialect(m.Dialect, gobatis.DbTypeOracle)
+}
+
+// tableExists 判断表是否存在， 查询失败的原因不是表不存在时返回这个错误
+func (m *Migrator) tableExists(ctx context.Context, tableName string) (bool, error) {
+	var count int64
+	err := m.DB.QueryRowContext(ctx, "SELECT count(*) FROM "+tableName+" WHERE 1 = 0").Scan(&count)
+	if err == nil {
+		return true, nil
+	}
+	if isTableNotFound(err) {
+		return false, nil
+	}
+	return false, errors.New("check table '" + tableName + "' fail, " + err.Error())
+}
+
+var tableNotFoundMessages = []string{
+	"no such table",       // sqlite
+	"does not exist",      // postgres, kingbase 和 oracle
+	"doesn't exist",       // mysql
+	"invalid object name", // mssql
+	"ora-00942",           // oracle
+}
+
+func isTableNotFound(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, s := range tableNotFoundMessages {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Migrator) ensureTables(ctx context.Context) error {
+	bigint, varchar, timestamp := "BIGINT", "VARCHAR", "TIMESTAMP"
+	if gobatis.IsDialect(m.Dialect, gobatis.DbTypeOracle) {
+		bigint, varchar = "NUMBER(19)", "VARCHAR2"
+	} else if gobatis.IsDialect(m.Dialect, gobatis.DbTypeMSSql) {
+		timestamp = "DATETIME"
+	}
+
+	exists, err := m.tableExists(ctx, m.tableName())
+	if err != nil {
+		return err
+	}
+	if !exists {
+		err := m.exec(ctx, m.DB, "CREATE TABLE "+m.tableName()+" (version "+bigint+" NOT NULL PRIMARY KEY, name "+
+			varchar+"(255) NOT NULL, checksum "+varchar+"(64) NOT NULL, applied_at "+timestamp+" NOT NULL)")
+		if err != nil {
+			return errors.New("create table '" + m.tableName() + "' fail, " + err.Error())
+		}
+	}
+	exists, err = m.tableExists(ctx, m.lockTableName())
+	if err != nil {
+		return err
+	}
+	if !exists {
+		err := m.exec(ctx, m.DB, "CREATE TABLE "+m.lockTableName()+" (id INT NOT NULL PRIMARY KEY, locked_by "+
+			varchar+"(255) NOT NULL, locked_at "+timestamp+" NOT NULL)")
+		if err != nil {
+			return errors.New("create table '" + m.lockTableName() + "' fail, " + err.Error())
+		}
+	}
+	return nil
+}
+
+// lock 加锁， 锁是 lock 表中 id 为 1 的记录， 插入失败表示已被其它程序锁定
+func (m *Migrator) lock(ctx context.Context) error {
+	hostname, _ := os.Hostname()
+	owner := hostname + ":" + strconv.Itoa(os.Getpid())
+	err := m.exec(ctx, m.DB, "INSERT INTO "+m.lockTableName()+" (id, locked_by, locked_at) VALUES (1, ?, ?)", owner, time.Now())
+	if err == nil {
+		return nil
+	}
+
+	var lockedBy string
+	var lockedAt interface{}
+	if e := m.DB.QueryRowContext(ctx, "SELECT locked_by, locked_at FROM "+m.lockTableName()+" WHERE id = 1").Scan(&lockedBy, &lockedAt); e != nil {
+		return err
+	}
+	return &LockedError{LockedBy: lockedBy, LockedAt: toTime(lockedAt)}
+}
+
+// Unlock 强制释放锁， 用于迁移程序异常退出后没有释放锁的情况
+func (m *Migrator) Unlock(ctx context.Context) error {
+	exists, err := m.tableExists(ctx, m.lockTableName())
+	if err != nil || !exists {
+		return err
+	}
+	return m.exec(ctx, m.DB, "DELETE FROM "+m.lockTableName()+" WHERE id = 1")
+}
+
+func (m *Migrator) readApplied(ctx context.Context) (map[int64]*appliedRecord, error) {
+	applied := map[int64]*appliedRecord{}
+	exists, err := m.tableExists(ctx, m.tableName())
+	if err != nil || !exists {
+		return applied, err
+	}
+
+	rows, err := m.DB.QueryContext(ctx, "SELECT version, name, checksum, applied_at FROM "+m.tableName())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var record appliedRecord
+		var appliedAt interface{}
+		if err := rows.Scan(&record.version, &record.name, &record.checksum, &appliedAt); err != nil {
+			return nil, err
+		}
+		record.appliedAt = toTime(appliedAt)
+		applied[record.version] = &record
+	}
+	return applied, rows.Err()
+}
+
+// prepare 读取迁移文件和已执行的迁移， 不是 DryRun 时还会创建表并加锁
+func (m *Migrator) prepare(ctx context.Context) ([]*Migration, map[int64]*appliedRecord, func(), error) {
+	migrations, err := Load(m.Source, m.Dialect)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	unlock := func() {}
+	if !m.DryRun {
+		if err := m.ensureTables(ctx); err != nil {
+			return nil, nil, nil, err
+		}
+		if err := m.lock(ctx); err != nil {
+			return nil, nil, nil, err
+		}
+		unlock = func() {
+			if err := m.Unlock(context.Background()); err != nil {
+				m.printf("unlock fail, %s\n", err)
+			}
+		}
+	}
+
+	applied, err := m.readApplied(ctx)
+	if err != nil {
+		unlock()
+		return nil, nil, nil, err
+	}
+	return migrations, applied, unlock, nil
+}
+
+// Up 执行所有版本号不大于 target 的未执行的迁移， target 为 0 时执行所有未执行的迁移，
+// 已执行的迁移文件被修改过时返回错误
+func (m *Migrator) Up(ctx context.Context, target int64) error {
+	migrations, applied, unlock, err := m.prepare(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	for _, migration := range migrations {
+		if record, ok := applied[migration.Version]; ok && record.checksum != migration.Checksum {
+			return errors.New("migration '" + migration.Filename + "' has been modified after it was applied")
+		}
+	}
+
+	for _, migration := range migrations {
+		if target > 0 && migration.Version > target {
+			break
+		}
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		err := m.run(ctx, migration, "up", migration.Up,
+			"INSERT INTO "+m.tableName()+" (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)",
+			migration.Version, migration.Name, migration.Checksum, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Down 回滚最近执行的 steps 个迁移， steps 小于 1 时回滚一个， 要回滚的迁移没有 Down 语句时
+// 返回错误， 除非 AllowEmptyDown 为 true
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	if steps < 1 {
+		steps = 1
+	}
+	migrations, applied, unlock, err := m.prepare(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	byVersion := map[int64]*Migration{}
+	for _, migration := range migrations {
+		byVersion[migration.Version] = migration
+	}
+	versions := make([]int64, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i] > versions[j]
+	})
+
+	if len(versions) > steps {
+		versions = versions[:steps]
+	}
+
+	// 先检查所有要回滚的迁移， 以免回滚到一半时才失败
+	for _, version := range versions {
+		migration, ok := byVersion[version]
+		if !ok {
+			return errors.New("migration file of version " + strconv.FormatInt(version, 10) + " is missing")
+		}
+		if len(migration.Down) == 0 && !m.AllowEmptyDown {
+			return errors.New("migration '" + migration.Filename + "' has no down statements, set AllowEmptyDown to remove its record only")
+		}
+	}
+
+	for _, version := range versions {
+		migration := byVersion[version]
+		err := m.run(ctx, migration, "down", migration.Down,
+			"DELETE FROM "+m.tableName()+" WHERE version = ?", migration.Version)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// run 执行迁移的 sql 并更新记录， 可以时在同一个事务中执行
+func (m *Migrator) run(ctx context.Context, migration *Migration, direction string, stmts []string, recordSQL string, recordArgs ...interface{}) error {
+	if m.DryRun {
+		m.printf("-- %s %s\n", direction, migration.Filename)
+		for _, stmt := range stmts {
+			m.printf("%s\n", stmt)
+		}
+		m.printf("\n")
+		return nil
+	}
+
+	wrap := func(err error) error {
+		return errors.New("migrate " + direction + " '" + migration.Filename + "' fail, " + err.Error())
+	}
+
+	if migration.NoTransaction || !m.transactional() {
+		for _, stmt := range stmts {
+			if err := m.exec(ctx, m.DB, stmt); err != nil {
+				return wrap(err)
+			}
+		}
+		if err := m.exec(ctx, m.DB, recordSQL, recordArgs...); err != nil {
+			return wrap(err)
+		}
+	} else {
+		tx, err := m.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return wrap(err)
+		}
+		for _, stmt := range stmts {
+			if err := m.exec(ctx, tx, stmt); err != nil {
+				tx.Rollback()
+				return wrap(err)
+			}
+		}
+		if err := m.exec(ctx, tx, recordSQL, recordArgs...); err != nil {
+			tx.Rollback()
+			return wrap(err)
+		}
+		if err := tx.Commit(); err != nil {
+			return wrap(err)
+		}
+	}
+	m.printf("OK   %s %s\n", direction, migration.Filename)
+	return nil
+}
+
+// Status 返回所有迁移的状态， 按版本号排序
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	migrations, err := Load(m.Source, m.Dialect)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := m.readApplied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, 0, len(migrations))
+	for _, migration := range migrations {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if record, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = record.appliedAt
+			status.Modified = record.checksum != migration.Checksum
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, record := range applied {
+		statuses = append(statuses, Status{
+			Version:   record.version,
+			Name:      record.name,
+			Applied:   true,
+			AppliedAt: record.appliedAt,
+			Missing:   true,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04:05",
+}
+
+// toTime 转换 applied_at 列的值， 有些驱动(如没有 parseTime 的 mysql)返回的是字符串
+func toTime(value interface{}) time.Time {
+	var s string
+	switch v := value.(type) {
+	case time.Time:
+		return v
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return time.Time{}
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
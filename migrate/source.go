@@ -0,0 +1,28 @@
+package migrate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Source 是迁移文件的来源， 路径都是相对于来源的根目录的， 用 "/" 分隔
+type Source interface {
+	ReadDir(dir string) ([]os.FileInfo, error)
+	ReadFile(name string) ([]byte, error)
+}
+
+// Dir 返回一个从目录中读取迁移文件的 Source
+func Dir(root string) Source {
+	return dirSource(root)
+}
+
+type dirSource string
+
+func (root dirSource) ReadDir(dir string) ([]os.FileInfo, error) {
+	return ioutil.ReadDir(filepath.Join(string(root), filepath.FromSlash(dir)))
+}
+
+func (root dirSource) ReadFile(name string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(string(root), filepath.FromSlash(name)))
+}
@@ -0,0 +1,38 @@
+//go:build go1.16
+// +build go1.16
+
+package migrate
+
+import (
+	"io/fs"
+	"os"
+)
+
+// FS 返回一个从 fs.FS 中读取迁移文件的 Source， 如用 embed 嵌入到程序中的迁移文件
+func FS(fsys fs.FS) Source {
+	return fsSource{fsys: fsys}
+}
+
+type fsSource struct {
+	fsys fs.FS
+}
+
+func (src fsSource) ReadDir(dir string) ([]os.FileInfo, error) {
+	entries, err := fs.ReadDir(src.fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]os.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func (src fsSource) ReadFile(name string) ([]byte, error) {
+	return fs.ReadFile(src.fsys, name)
+}
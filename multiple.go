@@ -45,7 +45,7 @@ func (ct *committer) commit() bool {
 	return isCommit
 }
 
-func (ct *committer) estimateWith(value interface{}) interface{} {
+func (ct *committer) estimateWith(dialect Dialect, value interface{}) interface{} {
 	if ct.commitFunc == nil {
 		return value
 	}
@@ -92,7 +92,7 @@ func (ct *committer) estimateWith(value interface{}) interface{} {
 		})
 		return value
 	default:
-		nullable := &Nullable{Value: value}
+		nullable := &Nullable{Dialect: dialect, Value: value}
 		ct.canCommits = append(ct.canCommits, func() bool {
 			return nullable.Valid
 		})
@@ -198,12 +198,12 @@ func (m *Multiple) Scan(dialect Dialect, mapper *Mapper, r colScanner, isUnsafe
 		if m.columns[idx].fi == nil {
 			vp := m.Returns[valueIndex]
 			if _, ok := vp.(sql.Scanner); ok {
-				values[idx] = m.commits[valueIndex].estimateWith(vp)
+				values[idx] = m.commits[valueIndex].estimateWith(dialect, vp)
 				continue
 			}
 
-			nullable := &Nullable{Name: m.columns[idx].columnName, Value: vp}
-			values[idx] = m.commits[valueIndex].estimateWith(nullable)
+			nullable := &Nullable{Dialect: dialect, Name: m.columns[idx].columnName, Value: vp}
+			values[idx] = m.commits[valueIndex].estimateWith(dialect, nullable)
 			continue
 		}
 
@@ -230,7 +230,7 @@ func (m *Multiple) Scan(dialect Dialect, mapper *Mapper, r colScanner, isUnsafe
 			continue
 		}
 
-		values[idx] = m.commits[valueIndex].estimateWith(fvalue)
+		values[idx] = m.commits[valueIndex].estimateWith(dialect, fvalue)
 	}
 
 	if err := r.Scan(values...); err != nil {
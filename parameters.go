@@ -137,7 +137,7 @@ func (sf structFinder) RValue(dialect Dialect, param *Param) (interface{}, error
 			return nil, ErrNotFound
 		}
 	}
-	return fi.RValue(dialect, param, sf.rValue)
+	return fieldRValue(dialect, param, fi, sf.rValue)
 }
 
 type kvFinder struct {
@@ -174,7 +174,16 @@ func (v rvalueGetter) value(value interface{}) (interface{}, error) {
 }
 
 func (v rvalueGetter) field(fi *FieldInfo, rv reflect.Value) (interface{}, error) {
-	return fi.RValue(v.dialect, v.param, rv)
+	return fieldRValue(v.dialect, v.param, fi, rv)
+}
+
+// fieldRValue 读取字段的值， 当参数中指定了 handler、编码或加密选项时用它们代替字段上的转换
+func fieldRValue(dialect Dialect, param *Param, fi *FieldInfo, rv reflect.Value) (interface{}, error) {
+	if param.Handler != "" || param.Encoding != "" || param.Encrypt || param.BlindIndex {
+		field := reflectx.FieldByIndexesReadOnly(rv, fi.Index)
+		return toSQLType(dialect, param, field.Interface())
+	}
+	return fi.RValue(dialect, param, rv)
 }
 
 var directgetter = directGetter{}
@@ -318,7 +327,11 @@ type Context struct {
 	ParamNames  []string
 	ParamValues []interface{}
 
-	finder Parameters
+	finder   Parameters
+	versions []versionParam
+	tenant   func() (interface{}, error)
+
+	currentUser func() (interface{}, error)
 }
 
 func (bc *Context) Get(name string) (interface{}, error) {
@@ -326,7 +339,22 @@ func (bc *Context) Get(name string) (interface{}, error) {
 }
 
 func (bc *Context) RValue(param *Param) (interface{}, error) {
-	return bc.finder.RValue(bc.Dialect, param)
+	if param.Tenant {
+		return bc.tenantValue(param)
+	}
+	if param.Operator {
+		return bc.currentUserValue(param)
+	}
+	value, err := bc.finder.RValue(bc.Dialect, param)
+	if err == nil && param.Version {
+		if err = bc.addVersion(param.Name); err != nil {
+			return nil, err
+		}
+	}
+	if err != nil || !(param.Encrypt || param.BlindIndex) {
+		return value, err
+	}
+	return bc.Mapper.encryptParam(param, value)
 }
 
 func NewContext(constants map[string]interface{}, dialect Dialect, mapper *Mapper, paramNames []string, paramValues []interface{}) (*Context, error) {
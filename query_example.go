@@ -0,0 +1,317 @@
+package gobatis
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// 按例查询
+//
+// 查询方法的参数可以是一个结构体， 如 ListUsers(q *UserQuery)， 它的字段用 q 标签声明对应的
+// 字段和比较操作， 格式为 `q:"字段名[,操作]"`， 如
+//
+//	type UserQuery struct {
+//		Name    string  `q:"name,like"`
+//		AgeMin  int     `q:"age,gte"`
+//		Status  []int   `q:"status,in"`
+//		Keyword struct {
+//			Name  string `q:"name,contains"`
+//			Phone string `q:"phone,contains"`
+//		} `q:",or"`
+//		SortBy string `q:",sort"`
+//		Offset int    `q:",offset"`
+//		Limit  int    `q:",limit"`
+//	}
+//
+// 为 nil 或零值的字段会被忽略， 操作有 eq(缺省)，in，ne，gt，gte，lt，lte，like，contains，
+// startswith，endswith，notlike，notin，isnull 和 isnotnull， 它们的含义和参数名后缀(见 where_op.go)
+// 一样； 类型为结构体且操作为 or 或 and 的字段是一个分组，分组中的条件用 or 或 and 连接；
+// 操作为 sort，offset 和 limit 的字段(字段名为 SortBy，Offset 和 Limit 时可以省略 q 标签)
+// 会生成 ORDER BY 和分页子句
+
+const queryTagName = "q"
+
+const (
+	queryOpSort   = "sort"
+	queryOpOffset = "offset"
+	queryOpLimit  = "limit"
+	queryOpOr     = "or"
+	queryOpAnd    = "and"
+)
+
+var queryOps = map[string]string{
+	"eq":              whereOpEqual,
+	"in":              whereOpEqual,
+	whereOpNe:         whereOpNe,
+	whereOpGt:         whereOpGt,
+	whereOpGte:        whereOpGte,
+	whereOpLt:         whereOpLt,
+	whereOpLte:        whereOpLte,
+	whereOpLike:       whereOpLike,
+	whereOpContains:   whereOpContains,
+	whereOpStartsWith: whereOpStartsWith,
+	whereOpEndsWith:   whereOpEndsWith,
+	whereOpNotLike:    whereOpNotLike,
+	whereOpNotIn:      whereOpNotIn,
+	whereOpIsNull:     whereOpIsNull,
+	whereOpIsNotNull:  whereOpIsNotNull,
+}
+
+// isQueryArg 判断参数是不是按例查询的结构体， 即结构体中至少有一个字段有 q 标签
+func isQueryArg(argType reflect.Type) bool {
+	if argType == nil {
+		return false
+	}
+	if argType.Kind() == reflect.Ptr {
+		argType = argType.Elem()
+	}
+	if argType.Kind() != reflect.Struct {
+		return false
+	}
+	for idx := 0; idx < argType.NumField(); idx++ {
+		if _, ok := argType.Field(idx).Tag.Lookup(queryTagName); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// splitQueryArgs 将按例查询的参数从参数列表中分离出来
+func splitQueryArgs(names []string, argTypes []reflect.Type) ([]string, []reflect.Type, []string, []reflect.Type) {
+	found := false
+	for idx := range argTypes {
+		if isQueryArg(argTypes[idx]) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, nil, names, argTypes
+	}
+
+	var queryNames []string
+	var queryTypes []reflect.Type
+	nameCopy := make([]string, 0, len(names))
+	argTypeCopy := make([]reflect.Type, 0, len(argTypes))
+	for idx := range names {
+		if isQueryArg(argTypes[idx]) {
+			queryNames = append(queryNames, names[idx])
+			queryTypes = append(queryTypes, argTypes[idx])
+			continue
+		}
+		nameCopy = append(nameCopy, names[idx])
+		argTypeCopy = append(argTypeCopy, argTypes[idx])
+	}
+	return queryNames, queryTypes, nameCopy, argTypeCopy
+}
+
+func parseQueryTag(field reflect.StructField) (string, string, bool) {
+	tag, ok := field.Tag.Lookup(queryTagName)
+	if !ok {
+		switch name := strings.ToLower(field.Name); name {
+		case "sortby":
+			return "", queryOpSort, true
+		case queryOpOffset, queryOpLimit:
+			return "", name, true
+		}
+		return "", "", false
+	}
+	if tag == "-" {
+		return "", "", false
+	}
+
+	ss := strings.SplitN(tag, ",", 2)
+	name := strings.TrimSpace(ss[0])
+	op := "eq"
+	if len(ss) == 2 {
+		if s := strings.ToLower(strings.TrimSpace(ss[1])); s != "" {
+			op = s
+		}
+	}
+	if name == "" {
+		name = field.Name
+	}
+	return name, op, true
+}
+
+// readQueryPagination 读取按例查询的参数中 sort， offset 和 limit 字段的路径
+func readQueryPagination(names []string, argTypes []reflect.Type) (sortBy, offset, limit string) {
+	for idx := range argTypes {
+		if !isQueryArg(argTypes[idx]) {
+			continue
+		}
+
+		argType := argTypes[idx]
+		if argType.Kind() == reflect.Ptr {
+			argType = argType.Elem()
+		}
+		for i := 0; i < argType.NumField(); i++ {
+			field := argType.Field(i)
+			if len(field.PkgPath) != 0 {
+				continue
+			}
+			_, op, ok := parseQueryTag(field)
+			if !ok {
+				continue
+			}
+			switch op {
+			case queryOpSort:
+				if sortBy == "" {
+					sortBy = names[idx] + "." + field.Name
+				}
+			case queryOpOffset:
+				if offset == "" {
+					offset = names[idx] + "." + field.Name
+				}
+			case queryOpLimit:
+				if limit == "" {
+					limit = names[idx] + "." + field.Name
+				}
+			}
+		}
+	}
+	return sortBy, offset, limit
+}
+
+// generateQueryWhere 生成按例查询的条件， 每个条件都以 AND 开头， 所以它必须在 <where> 中
+func generateQueryWhere(dbType Dialect, structType *StructMap, name string, argType reflect.Type, sb *strings.Builder) error {
+	if argType.Kind() == reflect.Ptr {
+		sb.WriteString(`<if test="isNotNull(`)
+		sb.WriteString(name)
+		sb.WriteString(`)">`)
+		if err := generateQueryConditions(dbType, structType, name, argType.Elem(), "AND", sb); err != nil {
+			return err
+		}
+		sb.WriteString(`</if>`)
+		return nil
+	}
+	return generateQueryConditions(dbType, structType, name, argType, "AND", sb)
+}
+
+func generateQueryConditions(dbType Dialect, structType *StructMap, prefix string, argType reflect.Type, joiner string, sb *strings.Builder) error {
+	for idx := 0; idx < argType.NumField(); idx++ {
+		field := argType.Field(idx)
+		if len(field.PkgPath) != 0 {
+			continue
+		}
+		column, op, ok := parseQueryTag(field)
+		if !ok {
+			continue
+		}
+		name := prefix + "." + field.Name
+
+		switch op {
+		case queryOpSort, queryOpOffset, queryOpLimit:
+			continue
+		case queryOpOr, queryOpAnd:
+			if field.Type.Kind() != reflect.Struct {
+				return errors.New("'" + name + "' must is a struct, because it is a '" + op + "' group")
+			}
+			sb.WriteString(` <trim prefix="`)
+			sb.WriteString(joiner)
+			sb.WriteString(` (" suffix=")" prefixOverrides="`)
+			sb.WriteString(op)
+			sb.WriteString(` ">`)
+			if err := generateQueryConditions(dbType, structType, name, field.Type, strings.ToUpper(op), sb); err != nil {
+				return err
+			}
+			sb.WriteString(`</trim>`)
+			continue
+		}
+
+		whereOp, ok := queryOps[op]
+		if !ok {
+			return errors.New("'" + name + "' has a invalid operator '" + op + "'")
+		}
+
+		fi, isArgSlice, err := toFieldName(structType, column, field.Type)
+		if err != nil {
+			return errors.New("'" + name + "' is invalid, " + err.Error())
+		}
+		if isEncryptField(fi) {
+			return errors.New("column '" + fi.Name + "' is encrypted, query by example is unsupported")
+		}
+		isRange := IsValueRange(field.Type)
+		if whereOp != whereOpEqual {
+			if err := checkWhereOp(name, whereOp, fi, isArgSlice, field.Type); err != nil {
+				return err
+			}
+		}
+
+		sb.WriteString(`<if test="`)
+		sb.WriteString(queryTest(name, field.Type))
+		sb.WriteString(`"> `)
+		sb.WriteString(joiner)
+		sb.WriteString(" ")
+
+		column = quoteColumn(dbType, fi)
+		switch {
+		case whereOp == whereOpNotIn:
+			sb.WriteString("(")
+			sb.WriteString(column)
+			sb.WriteString(` NOT IN (<foreach collection="`)
+			sb.WriteString(name)
+			sb.WriteString(`" item="item" separator="," >#{item`)
+			sb.WriteString(encodingOption(fi))
+			sb.WriteString(`}</foreach>) OR `)
+			sb.WriteString(column)
+			sb.WriteString(" IS NULL)")
+		case whereOp == whereOpIsNull:
+			sb.WriteString(column)
+			sb.WriteString(" IS NULL")
+		case whereOp == whereOpIsNotNull:
+			sb.WriteString(column)
+			sb.WriteString(" IS NOT NULL")
+		case whereOp != whereOpEqual:
+			sb.WriteString(whereOpExpr(dbType, fi, name, whereOp))
+		case isArgSlice:
+			sb.WriteString(column)
+			sb.WriteString(` in (<foreach collection="`)
+			sb.WriteString(name)
+			sb.WriteString(`" item="item" separator="," >#{item`)
+			sb.WriteString(encodingOption(fi))
+			sb.WriteString(`}</foreach>)`)
+		case isRange:
+			sb.WriteString("(")
+			sb.WriteString(column)
+			sb.WriteString(" BETWEEN #{")
+			sb.WriteString(name)
+			sb.WriteString(".Start} AND #{")
+			sb.WriteString(name)
+			sb.WriteString(".End})")
+		default:
+			sb.WriteString(column)
+			sb.WriteString("=#{")
+			sb.WriteString(name)
+			sb.WriteString(encodingOption(fi))
+			sb.WriteString("}")
+		}
+		sb.WriteString("</if>")
+	}
+	return nil
+}
+
+// queryTest 生成判断字段是否为 nil 或零值的表达式
+func queryTest(name string, fieldType reflect.Type) string {
+	if ok, _, _ := isValidable(fieldType); ok {
+		return name + ".Valid"
+	}
+
+	switch fieldType.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Interface:
+		return "isNotNull(" + name + ")"
+	case reflect.Slice, reflect.Array:
+		return "isNotEmpty(" + name + ")"
+	case reflect.String:
+		return "isNotEmptyString(" + name + ", true)"
+	case reflect.Bool:
+		return name
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return name + " != 0"
+	default:
+		return "isNotZero(" + name + ")"
+	}
+}
@@ -0,0 +1,209 @@
+package gobatis_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	gobatis "github.com/runner-mei/GoBatis"
+	"github.com/runner-mei/GoBatis/tests"
+)
+
+type T26Query struct {
+	Name    string  `q:"name,startswith"`
+	AgeMin  int     `q:"age,gte"`
+	AgeMax  *int    `q:"age,lte"`
+	IDs     []int64 `q:"id,in"`
+	NoName  bool    `q:"name,isnull"`
+	Keyword struct {
+		Name   string `q:"name,contains"`
+		Status int    `q:"status"`
+	} `q:",or"`
+	SortBy string
+	Offset int
+	Limit  int
+}
+
+type T26BadQuery struct {
+	Name string `q:"name,abc"`
+}
+
+func TestGenerateQueryExampleSQL(t *testing.T) {
+	conditions := `<if test="isNotEmptyString(q.Name, true)"> AND name like <like value="q.Name" match="prefix" /></if>` +
+		`<if test="q.AgeMin != 0"> AND age >= #{q.AgeMin}</if>` +
+		`<if test="isNotNull(q.AgeMax)"> AND #{q.AgeMax} >= age</if>` +
+		`<if test="isNotEmpty(q.IDs)"> AND id in (<foreach collection="q.IDs" item="item" separator="," >#{item}</foreach>)</if>` +
+		`<if test="q.NoName"> AND name IS NULL</if>` +
+		` <trim prefix="AND (" suffix=")" prefixOverrides="or ">` +
+		`<if test="isNotEmptyString(q.Keyword.Name, true)"> OR name like <like value="q.Keyword.Name" match="any" /></if>` +
+		`<if test="q.Keyword.Status != 0"> OR status=#{q.Keyword.Status}</if></trim>`
+
+	for idx, test := range []struct {
+		dbType   gobatis.Dialect
+		stmtType string
+		names    []string
+		argTypes []reflect.Type
+		sql      string
+	}{
+		{stmtType: "select", names: []string{"q"}, argTypes: []reflect.Type{reflect.TypeOf(&T26Query{})},
+			sql: `SELECT * FROM t26 <where><if test="isNotNull(q)">` + conditions + `</if></where>` +
+				` <order_by by="q.SortBy" allowed="id,name,age,status"/> <if test="q.Offset &gt; 0"> OFFSET #{q.Offset} </if> <if test="q.Limit &gt; 0"> LIMIT #{q.Limit} </if>`},
+		{dbType: gobatis.DbTypeSqlite, stmtType: "select", names: []string{"q"}, argTypes: []reflect.Type{reflect.TypeOf(T26Query{})},
+			sql: `SELECT * FROM t26 <where>` + conditions + `</where>` +
+				` <order_by by="q.SortBy" allowed="id,name,age,status"/> <pagination offset="q.Offset" limit="q.Limit" />`},
+		{stmtType: "select", names: []string{"q", "sortBy"}, argTypes: []reflect.Type{reflect.TypeOf(T26Query{}), _stringType},
+			sql: `SELECT * FROM t26 <where>` + conditions + `</where>` +
+				` <order_by by="sortBy" allowed="id,name,age,status"/> <if test="q.Offset &gt; 0"> OFFSET #{q.Offset} </if> <if test="q.Limit &gt; 0"> LIMIT #{q.Limit} </if>`},
+		{stmtType: "count", names: []string{"name", "q"}, argTypes: []reflect.Type{_stringType, reflect.TypeOf(T26Query{})},
+			sql: `SELECT count(*) FROM t26 <where>name=#{name}` + conditions + `</where>`},
+	} {
+		dbType := test.dbType
+		if dbType == nil {
+			dbType = gobatis.DbTypePostgres
+		}
+
+		var actaul string
+		var err error
+		if test.stmtType == "count" {
+			actaul, err = gobatis.GenerateCountSQL(dbType, mapper, reflect.TypeOf(&T26{}), test.names, test.argTypes, nil)
+		} else {
+			actaul, err = gobatis.GenerateSelectSQL(dbType, mapper, reflect.TypeOf(&T26{}), test.names, test.argTypes, nil)
+		}
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+
+		if actaul != test.sql {
+			t.Error("[", idx, "] excepted is", test.sql, "|")
+			t.Error("[", idx, "] actual   is", actaul, "|")
+		}
+	}
+
+	_, err := gobatis.GenerateSelectSQL(gobatis.DbTypePostgres, mapper, reflect.TypeOf(&T26{}),
+		[]string{"q"}, []reflect.Type{reflect.TypeOf(&T26BadQuery{})}, nil)
+	if err == nil {
+		t.Error("excepted error got ok")
+	} else if !strings.Contains(err.Error(), "invalid operator 'abc'") {
+		t.Error(err)
+	}
+}
+
+func TestQueryExample(t *testing.T) {
+	callbacks := gobatis.ClearInit()
+	defer gobatis.SetInit(callbacks)
+
+	rType := reflect.TypeOf(&T26{})
+	gobatis.Init(func(ctx *gobatis.InitContext) error {
+		for _, test := range []struct {
+			id       string
+			generate func() (string, error)
+		}{
+			{id: "T26.query", generate: func() (string, error) {
+				return gobatis.GenerateSelectSQL(ctx.Dialect, ctx.Mapper, rType,
+					[]string{"q"}, []reflect.Type{reflect.TypeOf(&T26Query{})}, nil)
+			}},
+			{id: "T26.count", generate: func() (string, error) {
+				return gobatis.GenerateCountSQL(ctx.Dialect, ctx.Mapper, rType,
+					[]string{"q"}, []reflect.Type{reflect.TypeOf(&T26Query{})}, nil)
+			}},
+			{id: "T26.countByStatus", generate: func() (string, error) {
+				return gobatis.GenerateCountSQL(ctx.Dialect, ctx.Mapper, rType,
+					[]string{"status", "q"}, []reflect.Type{reflect.TypeOf(int(0)), reflect.TypeOf(T26Query{})}, nil)
+			}},
+		} {
+			sqlStr, err := test.generate()
+			if err != nil {
+				return err
+			}
+			stmt, err := gobatis.NewMapppedStatement(ctx, test.id, gobatis.StatementTypeSelect, gobatis.ResultStruct, sqlStr)
+			if err != nil {
+				return err
+			}
+			ctx.Statements[test.id] = stmt
+		}
+		return nil
+	})
+
+	factory, err := gobatis.New(&gobatis.Config{DriverName: tests.TestDrv,
+		DataSource: tests.TestConnURL,
+		Tracer:     gobatis.NullTracer{}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer factory.Close()
+
+	ctx := context.Background()
+	factory.DB().ExecContext(ctx, `DROP TABLE IF EXISTS t26`)
+	if _, err := factory.DB().ExecContext(ctx, `CREATE TABLE t26 (id bigint, name varchar(50), age int, status int)`); err != nil {
+		t.Error(err)
+		return
+	}
+	defer factory.DB().ExecContext(ctx, `DROP TABLE t26`)
+
+	for _, s := range []string{
+		`INSERT INTO t26(id, name, age, status) VALUES(1, 'abc', 10, 1)`,
+		`INSERT INTO t26(id, name, age, status) VALUES(2, 'abd', 20, 2)`,
+		`INSERT INTO t26(id, name, age, status) VALUES(3, 'xbc', 30, 1)`,
+		`INSERT INTO t26(id, age, status) VALUES(4, 40, 3)`,
+	} {
+		if _, err := factory.DB().ExecContext(ctx, s); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	newQuery := func(cb func(q *T26Query)) *T26Query {
+		q := &T26Query{}
+		cb(q)
+		return q
+	}
+	ageMax := 25
+
+	ref := factory.SessionReference()
+	for idx, test := range []struct {
+		q        *T26Query
+		excepted int64
+	}{
+		{q: nil, excepted: 4},
+		{q: &T26Query{}, excepted: 4},
+		{q: &T26Query{Name: "ab"}, excepted: 2},
+		{q: &T26Query{AgeMin: 20}, excepted: 3},
+		{q: &T26Query{AgeMin: 20, AgeMax: &ageMax}, excepted: 1},
+		{q: &T26Query{IDs: []int64{1, 3}}, excepted: 2},
+		{q: &T26Query{NoName: true}, excepted: 1},
+		{q: newQuery(func(q *T26Query) { q.Keyword.Name = "bd"; q.Keyword.Status = 3 }), excepted: 2},
+		{q: newQuery(func(q *T26Query) { q.Keyword.Name = "bd"; q.Keyword.Status = 3; q.AgeMin = 30 }), excepted: 1},
+	} {
+		var count int64
+		if err := ref.SelectOne(ctx, "T26.count", []string{"q"}, []interface{}{test.q}).Scan(&count); err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		if count != test.excepted {
+			t.Error("[", idx, "] excepted is", test.excepted)
+			t.Error("[", idx, "] actual   is", count)
+		}
+	}
+
+	var count int64
+	if err := ref.SelectOne(ctx, "T26.countByStatus", []string{"status", "q"}, []interface{}{1, T26Query{Name: "x"}}).Scan(&count); err != nil {
+		t.Error(err)
+	} else if count != 1 {
+		t.Error("excepted is 1")
+		t.Error("actual   is", count)
+	}
+
+	var records []T26
+	err = ref.Select(ctx, "T26.query", []string{"q"}, []interface{}{&T26Query{SortBy: "-age", Offset: 1, Limit: 2}}).ScanSlice(&records)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(records) != 2 || records[0].ID != 3 || records[1].ID != 2 {
+		t.Error("excepted is [3, 2]")
+		t.Error("actual   is", records)
+	}
+}
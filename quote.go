@@ -0,0 +1,140 @@
+package gobatis
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// QuotePolicy 是生成 SQL 时表名和字段名的引用策略
+type QuotePolicy int
+
+const (
+	// QuoteNever 从不引用， 除非字段上有 quote 选项，如 `db:"order,quote"`
+	QuoteNever QuotePolicy = iota
+	// QuoteReservedWords 仅当名字是保留字时才引用， 如 user, order 和 group
+	QuoteReservedWords
+	// QuoteAlways 总是引用， 注意在 postgres 中引用后的名字是区分大小写的
+	QuoteAlways
+)
+
+// IdentifierQuotePolicy 是 Generate* 系列函数在生成 SQL 时使用的引用策略， 缺省为 QuoteNever，
+// 以免改变已有映射生成的 SQL。 在 oracle 中按策略引用的名字会被转为大写， 因为 oracle 中
+// 没有引用的名字是按大写保存的， 而引用后的名字是区分大小写的
+var IdentifierQuotePolicy = QuoteNever
+
+var (
+	reservedWordsLock sync.RWMutex
+	reservedWords     = map[string]struct{}{}
+)
+
+func init() {
+	for _, word := range []string{
+		"add", "all", "alter", "and", "any", "as", "asc", "authorization",
+		"between", "by", "case", "check", "column", "constraint", "create", "cross",
+		"current_date", "current_time", "current_timestamp", "current_user",
+		"default", "delete", "desc", "distinct", "drop", "else", "end", "except", "exists",
+		"fetch", "for", "foreign", "from", "full", "grant", "group", "having",
+		"in", "index", "inner", "insert", "intersect", "into", "is", "join", "key",
+		"left", "like", "limit", "natural", "not", "null", "offset", "on", "or", "order", "outer",
+		"primary", "references", "right", "rows", "select", "session_user", "set", "some",
+		"table", "then", "to", "union", "unique", "update", "user", "using",
+		"values", "view", "when", "where", "with",
+	} {
+		reservedWords[word] = struct{}{}
+	}
+}
+
+// RegisterReservedWords 注册额外的保留字， 在 QuoteReservedWords 策略下它们会被引用
+func RegisterReservedWords(words ...string) {
+	reservedWordsLock.Lock()
+	defer reservedWordsLock.Unlock()
+	for _, word := range words {
+		reservedWords[strings.ToLower(word)] = struct{}{}
+	}
+}
+
+// IsReservedWord 判断 name 是不是 sql 的保留字
+func IsReservedWord(name string) bool {
+	reservedWordsLock.RLock()
+	defer reservedWordsLock.RUnlock()
+	_, ok := reservedWords[strings.ToLower(name)]
+	return ok
+}
+
+func isQuoted(name string) bool {
+	if len(name) < 2 {
+		return false
+	}
+	switch name[0] {
+	case '"':
+		return name[len(name)-1] == '"'
+	case '`':
+		return name[len(name)-1] == '`'
+	case '[':
+		return name[len(name)-1] == ']'
+	}
+	return false
+}
+
+func quoteIdentifier(dbType Dialect, name string, force bool) string {
+	if isQuoted(name) {
+		return name
+	}
+
+	if !force {
+		switch IdentifierQuotePolicy {
+		case QuoteNever:
+			return name
+		case QuoteReservedWords:
+			if !IsReservedWord(name) {
+				return name
+			}
+		}
+		if IsDialect(dbType, DbTypeOracle) {
+			name = strings.ToUpper(name)
+		}
+	}
+	return dbType.Quote(name)
+}
+
+// quoteTableName 引用表名， 表名可能带有 schema， 如 public.user
+func quoteTableName(dbType Dialect, tableName string) string {
+	if !strings.Contains(tableName, ".") {
+		return quoteIdentifier(dbType, tableName, false)
+	}
+	ss := strings.Split(tableName, ".")
+	for idx := range ss {
+		ss[idx] = quoteIdentifier(dbType, ss[idx], false)
+	}
+	return strings.Join(ss, ".")
+}
+
+// QuoteIdentifier 按 IdentifierQuotePolicy 引用一个标识符
+func QuoteIdentifier(dbType Dialect, name string) string {
+	return quoteIdentifier(dbType, name, false)
+}
+
+// QuoteTableName 按 IdentifierQuotePolicy 引用表名， 表名可能带有 schema
+func QuoteTableName(dbType Dialect, tableName string) string {
+	return quoteTableName(dbType, tableName)
+}
+
+// QuoteColumn 按 IdentifierQuotePolicy 引用字段名， 字段上有 quote 选项时总是引用
+func QuoteColumn(dbType Dialect, field *FieldInfo) string {
+	return quoteColumn(dbType, field)
+}
+
+// quoteColumn 引用字段名， 字段上有 quote 选项时总是引用
+func quoteColumn(dbType Dialect, field *FieldInfo) string {
+	_, force := field.Options["quote"]
+	return quoteIdentifier(dbType, field.Name, force)
+}
+
+// quoteColumnName 引用字段名， 它会在 rType 中查找字段来确定是不是要强制引用
+func quoteColumnName(dbType Dialect, mapper *Mapper, rType reflect.Type, name string) string {
+	if field := mapper.TypeMap(rType).FieldByName(name); field != nil && field.Name == name {
+		return quoteColumn(dbType, field)
+	}
+	return quoteIdentifier(dbType, name, false)
+}
@@ -0,0 +1,140 @@
+package gobatis
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// DefaultRawStatementCacheSize 是 ExecRaw 和 QueryRaw 等方法缺省缓存的 sql 数量
+const DefaultRawStatementCacheSize = 256
+
+// rawStatementID 是 ExecRaw 和 QueryRaw 等方法执行的 sql 在日志中的 id
+const rawStatementID = "<raw>"
+
+// rawStatementCache 缓存 ExecRaw 和 QueryRaw 等方法编译后的 sql， 它是一个 LRU 缓存，
+// 超过 size 时淘汰最久没有使用的 sql， 以免动态拼接的 sql 将内存耗尽
+type rawStatementCache struct {
+	initCtx *InitContext
+	size    int
+
+	mu         sync.Mutex
+	lru        *list.List
+	statements map[string]*list.Element
+}
+
+type rawStatementEntry struct {
+	sqlStr string
+	stmt   *MappedStatement
+}
+
+func newRawStatementCache(initCtx *InitContext, size int) *rawStatementCache {
+	if size <= 0 {
+		size = DefaultRawStatementCacheSize
+	}
+	return &rawStatementCache{
+		initCtx:    initCtx,
+		size:       size,
+		lru:        list.New(),
+		statements: map[string]*list.Element{},
+	}
+}
+
+func (cache *rawStatementCache) Get(sqlStr string) (*MappedStatement, error) {
+	cache.mu.Lock()
+	if elem, ok := cache.statements[sqlStr]; ok {
+		cache.lru.MoveToFront(elem)
+		cache.mu.Unlock()
+		return elem.Value.(*rawStatementEntry).stmt, nil
+	}
+	cache.mu.Unlock()
+
+	// 编译时不加锁， 同一个 sql 可能会被编译多次， 但结果是一样的
+	stmt, err := NewMapppedStatement(cache.initCtx, rawStatementID, StatementTypeNone, ResultUnknown, sqlStr)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if elem, ok := cache.statements[sqlStr]; ok {
+		cache.lru.MoveToFront(elem)
+		return elem.Value.(*rawStatementEntry).stmt, nil
+	}
+	cache.statements[sqlStr] = cache.lru.PushFront(&rawStatementEntry{sqlStr: sqlStr, stmt: stmt})
+	for cache.lru.Len() > cache.size {
+		oldest := cache.lru.Back()
+		cache.lru.Remove(oldest)
+		delete(cache.statements, oldest.Value.(*rawStatementEntry).sqlStr)
+	}
+	return stmt, nil
+}
+
+func (cache *rawStatementCache) Len() int {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.lru.Len()
+}
+
+func (conn *Connection) readRawSQLParams(ctx context.Context, sqlStr string, paramNames []string, paramValues []interface{}) ([]sqlAndParam, error) {
+	var stmt *MappedStatement
+	var err error
+	if conn.rawStatements != nil {
+		stmt, err = conn.rawStatements.Get(sqlStr)
+	} else {
+		stmt, err = NewMapppedStatement(&InitContext{Config: &Config{},
+			Dialect: conn.dialect,
+			Mapper:  conn.mapper}, rawStatementID, StatementTypeNone, ResultUnknown, sqlStr)
+	}
+	if err != nil {
+		conn.tracer.Write(ctx, rawStatementID, sqlStr, nil, err)
+		return nil, err
+	}
+
+	genCtx, err := conn.newContext(ctx, paramNames, paramValues)
+	if err != nil {
+		conn.tracer.Write(ctx, rawStatementID, sqlStr, nil, err)
+		return nil, err
+	}
+
+	sqlAndParams, err := stmt.GenerateSQLs(genCtx)
+	if err != nil {
+		conn.tracer.Write(ctx, rawStatementID, sqlStr, nil, err)
+		return nil, err
+	}
+	return sqlAndParams, nil
+}
+
+// ExecRaw 执行一个没有注册的 sql， sql 的格式和 xml 中的一样， 可以使用 #{} 参数和 <if> 等动态元素，
+// 编译后的 sql 会被缓存(缓存大小见 Config.RawStatementCacheSize)
+func (conn *Connection) ExecRaw(ctx context.Context, sqlStr string, paramNames []string, paramValues []interface{}) (int64, error) {
+	sqlAndParams, err := conn.readRawSQLParams(ctx, sqlStr, paramNames, paramValues)
+	if err != nil {
+		return 0, err
+	}
+	return conn.execute(ctx, rawStatementID, sqlAndParams)
+}
+
+// QueryRowRaw 和 ExecRaw 一样， 只是它执行的是查询一条记录的 sql
+func (conn *Connection) QueryRowRaw(ctx context.Context, sqlStr string, paramNames []string, paramValues []interface{}) Result {
+	sqlAndParams, err := conn.readRawSQLParams(ctx, sqlStr, paramNames, paramValues)
+	if err != nil {
+		return Result{o: conn, ctx: ctx, id: rawStatementID, err: err}
+	}
+	if len(sqlAndParams) > 1 {
+		return Result{o: conn, ctx: ctx, id: rawStatementID, err: ErrMultSQL}
+	}
+	return conn.QueryRowSQL(ctx, rawStatementID, sqlAndParams[0].SQL, sqlAndParams[0].Params)
+}
+
+// QueryRaw 和 ExecRaw 一样， 只是它执行的是查询多条记录的 sql
+func (conn *Connection) QueryRaw(ctx context.Context, sqlStr string, paramNames []string, paramValues []interface{}) *Results {
+	sqlAndParams, err := conn.readRawSQLParams(ctx, sqlStr, paramNames, paramValues)
+	if err != nil {
+		return &Results{o: conn, ctx: ctx, id: rawStatementID, err: err}
+	}
+	if len(sqlAndParams) > 1 {
+		return &Results{o: conn, ctx: ctx, id: rawStatementID, err: ErrMultSQL}
+	}
+	return conn.QuerySQL(ctx, rawStatementID, sqlAndParams[0].SQL, sqlAndParams[0].Params)
+}
@@ -0,0 +1,40 @@
+package gobatis
+
+import "testing"
+
+func TestRawStatementCache(t *testing.T) {
+	cache := newRawStatementCache(&InitContext{Config: &Config{},
+		Dialect: DbTypePostgres,
+		Mapper:  CreateMapper("", nil, nil)}, 2)
+
+	a, err := cache.Get("SELECT * FROM a WHERE id = #{id}")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if b, _ := cache.Get("SELECT * FROM a WHERE id = #{id}"); a != b {
+		t.Error("excepted is cached")
+	}
+
+	cache.Get("SELECT * FROM b")
+	cache.Get("SELECT * FROM a WHERE id = #{id}")
+	cache.Get("SELECT * FROM c")
+	if cache.Len() != 2 {
+		t.Error("excepted is 2")
+		t.Error("actual   is", cache.Len())
+	}
+	if _, ok := cache.statements["SELECT * FROM b"]; ok {
+		t.Error("excepted 'SELECT * FROM b' is evicted")
+	}
+	if b, _ := cache.Get("SELECT * FROM a WHERE id = #{id}"); a != b {
+		t.Error("excepted is cached")
+	}
+
+	if _, err := cache.Get(`SELECT * FROM a <where>`); err == nil {
+		t.Error("excepted error got ok")
+	}
+	if cache.Len() != 2 {
+		t.Error("excepted is 2")
+		t.Error("actual   is", cache.Len())
+	}
+}
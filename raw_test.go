@@ -0,0 +1,122 @@
+package gobatis_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	gobatis "github.com/runner-mei/GoBatis"
+	"github.com/runner-mei/GoBatis/tests"
+)
+
+type rawTracer struct {
+	ids  []string
+	sqls []string
+}
+
+func (tracer *rawTracer) Write(ctx context.Context, id, sql string, args []interface{}, err error) {
+	tracer.ids = append(tracer.ids, id)
+	tracer.sqls = append(tracer.sqls, sql)
+}
+
+func TestRawSQL(t *testing.T) {
+	tracer := &rawTracer{}
+	factory, err := gobatis.New(&gobatis.Config{DriverName: tests.TestDrv,
+		DataSource:            tests.TestConnURL,
+		Tracer:                tracer,
+		RawStatementCacheSize: 2})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer factory.Close()
+
+	ctx := context.Background()
+	factory.DB().ExecContext(ctx, `DROP TABLE IF EXISTS t26`)
+	if _, err := factory.DB().ExecContext(ctx, `CREATE TABLE t26 (id bigint, name varchar(50), age int, status int)`); err != nil {
+		t.Error(err)
+		return
+	}
+	defer factory.DB().ExecContext(ctx, `DROP TABLE t26`)
+
+	for idx, record := range []T26{
+		{ID: 1, Name: "abc", Age: 10, Status: 1},
+		{ID: 2, Name: "abd", Age: 20, Status: 1},
+		{ID: 3, Name: "xbc", Age: 30, Status: 2},
+	} {
+		count, err := factory.ExecRaw(ctx, `INSERT INTO t26(id, name, age, status) VALUES(#{id}, #{name}, #{age}, #{status})`, record)
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			return
+		}
+		if count != 1 {
+			t.Error("[", idx, "] excepted is 1")
+			t.Error("[", idx, "] actual   is", count)
+		}
+	}
+
+	query := `SELECT * FROM t26 <where><if test="isNotEmptyString(name)">name like <like value="name" /></if>` +
+		`<if test="status != 0"> AND status = #{status}</if></where> ORDER BY id`
+	for idx, test := range []struct {
+		params   map[string]interface{}
+		excepted []int64
+	}{
+		{params: map[string]interface{}{"name": "ab", "status": 0}, excepted: []int64{1, 2}},
+		{params: map[string]interface{}{"name": "", "status": 2}, excepted: []int64{3}},
+		{params: map[string]interface{}{"name": "bc", "status": 1}, excepted: []int64{1}},
+	} {
+		var records []T26
+		if err := factory.QueryRaw(ctx, query, test.params).ScanSlice(&records); err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		var ids []int64
+		for _, r := range records {
+			ids = append(ids, r.ID)
+		}
+		if len(ids) != len(test.excepted) || (len(ids) > 0 && ids[0] != test.excepted[0]) {
+			t.Error("[", idx, "] excepted is", test.excepted)
+			t.Error("[", idx, "] actual   is", ids)
+		}
+	}
+
+	var name string
+	if err := factory.QueryRowRaw(ctx, `SELECT name FROM t26 WHERE id = #{id}`, 2).Scan(&name); err != nil {
+		t.Error(err)
+	} else if name != "abd" {
+		t.Error("excepted is abd")
+		t.Error("actual   is", name)
+	}
+
+	count, err := factory.ExecRaw(ctx, `DELETE FROM t26 WHERE status = #{status}`, 1)
+	if err != nil {
+		t.Error(err)
+	} else if count != 2 {
+		t.Error("excepted is 2")
+		t.Error("actual   is", count)
+	}
+
+	// 执行的 sql 和 xml 中的一样会被写入 tracer
+	for idx, id := range tracer.ids {
+		if id != "<raw>" {
+			t.Error("[", idx, "] excepted is <raw>")
+			t.Error("[", idx, "] actual   is", id, tracer.sqls[idx])
+		}
+	}
+	if len(tracer.ids) != 3+3+1+1 {
+		t.Error("excepted is", 3+3+1+1)
+		t.Error("actual   is", len(tracer.ids))
+	}
+
+	err = factory.QueryRaw(ctx, `SELECT * FROM t26 <where><if test="status != 0">status = #{status}</where>`, 1).ScanSlice(&[]T26{})
+	if err == nil {
+		t.Error("excepted error got ok")
+	}
+
+	err = factory.QueryRowRaw(ctx, "SELECT 1;\nSELECT 2;").Scan(&count)
+	if err == nil {
+		t.Error("excepted error got ok")
+	} else if !strings.Contains(err.Error(), gobatis.ErrMultSQL.Error()) {
+		t.Error(err)
+	}
+}
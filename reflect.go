@@ -23,6 +23,9 @@ func isScannable(mapper *Mapper, t reflect.Type) bool {
 	if reflect.PtrTo(t).Implements(_scannerInterface) {
 		return true
 	}
+	if typeHandlerByType(t) != nil {
+		return true
+	}
 	if t.Kind() != reflect.Struct {
 		return true
 	}
@@ -108,7 +111,10 @@ func scanAny(dialect Dialect, mapper *Mapper, r colScanner, dest interface{}, st
 	}
 
 	if scannable {
-		return r.Scan(dest)
+		if nullable, ok := dest.(*Nullable); ok && nullable.Dialect == nil {
+			nullable.Dialect = dialect
+		}
+		return r.Scan(makeTypeHandlerScanner(dialect, columns[0], dest))
 	}
 
 	// if we are not unsafe and are missing fields, return an error
@@ -244,7 +250,7 @@ func scanAll(dialect Dialect, mapper *Mapper, rows rowsi, dest interface{}, stru
 	} else {
 		for rows.Next() {
 			vp = reflect.New(base)
-			err = rows.Scan(vp.Interface())
+			err = rows.Scan(makeTypeHandlerScanner(dialect, columns[0], vp.Interface()))
 			if err != nil {
 				return err
 			}
@@ -491,7 +497,8 @@ func scanBasicMap(dialect Dialect, mapper *Mapper, rows rowsi, dest interface{})
 	for rows.Next() {
 		vkey := reflect.New(key)
 		velem := reflect.New(elem)
-		err = rows.Scan(vkey.Interface(), velem.Interface())
+		err = rows.Scan(makeTypeHandlerScanner(dialect, columns[0], vkey.Interface()),
+			makeTypeHandlerScanner(dialect, columns[1], velem.Interface()))
 		if err != nil {
 			return err
 		}
@@ -0,0 +1,131 @@
+package gobatis
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// NamedArg 是 Repository 的查询参数， 它和生成的接口方法中的参数一样， 名字可以带有比较
+// 操作的后缀(见 where_op.go)， 值也可以是按例查询的结构体(见 query_example.go)， 如
+//
+//	repo.FindAll(ctx, gobatis.Named("status", 1), gobatis.Named("nameContains", "abc"))
+type NamedArg struct {
+	Name  string
+	Value interface{}
+}
+
+// Named 创建一个 NamedArg
+func Named(name string, value interface{}) NamedArg {
+	return NamedArg{Name: name, Value: value}
+}
+
+// splitNamedArgs 返回参数的名字， 类型和值， 值为 nil 的参数会被忽略， 因为无法确定它的类型
+func splitNamedArgs(args []NamedArg) ([]string, []reflect.Type, []interface{}) {
+	names := make([]string, 0, len(args))
+	argTypes := make([]reflect.Type, 0, len(args))
+	values := make([]interface{}, 0, len(args))
+	for _, arg := range args {
+		if arg.Value == nil {
+			continue
+		}
+		names = append(names, arg.Name)
+		argTypes = append(argTypes, reflect.TypeOf(arg.Value))
+		values = append(values, arg.Value)
+	}
+	return names, argTypes, values
+}
+
+// namedArgsSignature 返回参数的签名， 它是语句 id 的一部分
+func namedArgsSignature(names []string, argTypes []reflect.Type) string {
+	var sb strings.Builder
+	sb.WriteString("(")
+	for idx := range names {
+		if idx > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(names[idx])
+		sb.WriteString(" ")
+		sb.WriteString(argTypes[idx].String())
+	}
+	sb.WriteString(")")
+	return sb.String()
+}
+
+// statementRegistry 保存运行时注册的语句， 如 Repository 在第一次使用时生成的语句，
+// 和 sqlStatements 不同， 它在运行时会被修改， 所以需要加锁
+type statementRegistry struct {
+	initCtx *InitContext
+
+	mu         sync.RWMutex
+	statements map[string]*MappedStatement
+}
+
+func newStatementRegistry(initCtx *InitContext) *statementRegistry {
+	return &statementRegistry{
+		initCtx:    initCtx,
+		statements: map[string]*MappedStatement{},
+	}
+}
+
+func (registry *statementRegistry) Get(id string) (*MappedStatement, bool) {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	stmt, ok := registry.statements[id]
+	return stmt, ok
+}
+
+// Register 注册一个语句， 语句已存在时什么也不做， 否则用 generate 生成 sql
+func (registry *statementRegistry) Register(id string, sqlType StatementType, generate func(dialect Dialect, mapper *Mapper) (string, error)) error {
+	if _, ok := registry.Get(id); ok {
+		return nil
+	}
+
+	sqlStr, err := generate(registry.initCtx.Dialect, registry.initCtx.Mapper)
+	if err != nil {
+		return ErrForGenerateStmt(err, "generate "+id+" error")
+	}
+	stmt, err := NewMapppedStatement(registry.initCtx, id, sqlType, ResultStruct, sqlStr)
+	if err != nil {
+		return err
+	}
+
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	if _, ok := registry.statements[id]; !ok {
+		registry.statements[id] = stmt
+	}
+	return nil
+}
+
+// toConnection 取出 SqlSession 中的 Connection， Repository 需要在它上面注册语句
+func toConnection(sess SqlSession) (*Connection, error) {
+	runner, ok := ToSqlRunner(sess)
+	if !ok {
+		return nil, errors.New("session isnot a gobatis connection")
+	}
+	conn, ok := runner.(*Connection)
+	if !ok || conn.dynamicStatements == nil {
+		return nil, errors.New("session isnot a gobatis connection")
+	}
+	return conn, nil
+}
+
+// primaryKeyFields 返回主键的字段
+func primaryKeyFields(mapper *Mapper, rType reflect.Type) ([]*FieldInfo, error) {
+	structType := mapper.TypeMap(rType)
+	var fields []*FieldInfo
+	for _, index := range structType.PrimaryKey {
+		for _, field := range structType.Index {
+			if reflect.DeepEqual(field.Index, index) {
+				fields = append(fields, field)
+				break
+			}
+		}
+	}
+	if len(fields) == 0 {
+		return nil, errors.New("struct '" + rType.Name() + "' hasnot primary key")
+	}
+	return fields, nil
+}
@@ -0,0 +1,235 @@
+//go:build go1.18
+// +build go1.18
+
+package gobatis
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// Repository 是一个不需要生成代码的通用 CRUD 对象， 如
+//
+//	repo := gobatis.NewRepository[User](factory.SessionReference())
+//	id, err := repo.Insert(ctx, &User{Name: "abc"})
+//	users, total, err := repo.Page(ctx, 0, 20, gobatis.Named("nameContains", "ab"), gobatis.Named("sortBy", "-id"))
+//
+// 它的 sql 和生成的代码一样是由 GenerateXXXSQL 生成的， 在第一次使用时生成并以
+// Repository[类型名].方法名 为 id 注册在连接上， 所以每种类型在每个连接(即每种方言)上只生成一次
+type Repository[T any] struct {
+	session SqlSession
+	rType   reflect.Type
+	name    string
+}
+
+// NewRepository 创建一个 Repository， T 必须是一个有表名的结构体
+func NewRepository[T any](session SqlSession) *Repository[T] {
+	rType := reflect.TypeOf((*T)(nil)).Elem()
+	return &Repository[T]{
+		session: session,
+		rType:   rType,
+		name:    "Repository[" + rType.String() + "]",
+	}
+}
+
+// prepare 注册语句并返回它的 id
+func (repo *Repository[T]) prepare(name string, sqlType StatementType, generate func(dialect Dialect, mapper *Mapper) (string, error)) (*Connection, string, error) {
+	if repo.rType.Kind() != reflect.Struct {
+		return nil, "", errors.New("'" + repo.rType.String() + "' isnot a struct")
+	}
+	conn, err := toConnection(repo.session)
+	if err != nil {
+		return nil, "", err
+	}
+
+	id := repo.name + "." + name
+	if err := conn.dynamicStatements.Register(id, sqlType, generate); err != nil {
+		return nil, "", err
+	}
+	return conn, id, nil
+}
+
+// idField 返回主键的字段， 联合主键时返回错误
+func (repo *Repository[T]) idField(mapper *Mapper) (*FieldInfo, error) {
+	fields, err := primaryKeyFields(mapper, repo.rType)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) != 1 {
+		return nil, errors.New("struct '" + repo.rType.Name() + "' has composite primary key")
+	}
+	return fields[0], nil
+}
+
+// Insert 插入一条记录， 返回记录的 id
+func (repo *Repository[T]) Insert(ctx context.Context, record *T) (int64, error) {
+	conn, id, err := repo.prepare("Insert", StatementTypeInsert, func(dialect Dialect, mapper *Mapper) (string, error) {
+		return GenerateInsertSQL(dialect, mapper, repo.rType,
+			[]string{"record"}, []reflect.Type{reflect.PtrTo(repo.rType)}, false)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return conn.Insert(ctx, id, []string{"record"}, []interface{}{record})
+}
+
+// Upsert 插入一条记录， 记录已存在时更新它， keys 是判断记录是否存在的列， 缺省为主键
+func (repo *Repository[T]) Upsert(ctx context.Context, record *T, keys ...string) (int64, error) {
+	name := "Upsert"
+	if len(keys) > 0 {
+		name = "Upsert(" + strings.Join(keys, ", ") + ")"
+	}
+	conn, id, err := repo.prepare(name, StatementTypeInsert, func(dialect Dialect, mapper *Mapper) (string, error) {
+		keyNames := keys
+		if len(keyNames) == 0 {
+			fields, err := primaryKeyFields(mapper, repo.rType)
+			if err != nil {
+				return "", err
+			}
+			for _, field := range fields {
+				keyNames = append(keyNames, field.Name)
+			}
+		}
+		return GenerateUpsertSQLForStruct(dialect, mapper, repo.rType, keyNames, "record.", false)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return conn.Insert(ctx, id, []string{"record"}, []interface{}{record})
+}
+
+// Update 按主键更新一条记录， 返回更新的记录数
+func (repo *Repository[T]) Update(ctx context.Context, record *T) (int64, error) {
+	conn, err := toConnection(repo.session)
+	if err != nil {
+		return 0, err
+	}
+	fields, err := primaryKeyFields(conn.mapper, repo.rType)
+	if err != nil {
+		return 0, err
+	}
+
+	names := make([]string, 0, len(fields)+1)
+	argTypes := make([]reflect.Type, 0, len(fields))
+	values := make([]interface{}, 0, len(fields)+1)
+	rValue := reflect.ValueOf(record).Elem()
+	for _, field := range fields {
+		names = append(names, field.Field.Name)
+		argTypes = append(argTypes, field.Field.Type)
+		values = append(values, rValue.FieldByIndex(field.Index).Interface())
+	}
+
+	conn, id, err := repo.prepare("Update", StatementTypeUpdate, func(dialect Dialect, mapper *Mapper) (string, error) {
+		return GenerateUpdateSQL(dialect, mapper, "record.", repo.rType, names, argTypes)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return conn.Update(ctx, id, append(names, "record"), append(values, record))
+}
+
+// Delete 按主键删除一条记录， 返回删除的记录数， 有 deleted 字段时是软删除
+func (repo *Repository[T]) Delete(ctx context.Context, id interface{}) (int64, error) {
+	conn, err := toConnection(repo.session)
+	if err != nil {
+		return 0, err
+	}
+	field, err := repo.idField(conn.mapper)
+	if err != nil {
+		return 0, err
+	}
+
+	conn, stmtID, err := repo.prepare("Delete", StatementTypeDelete, func(dialect Dialect, mapper *Mapper) (string, error) {
+		return GenerateDeleteSQL(dialect, mapper, repo.rType,
+			[]string{field.Field.Name}, []reflect.Type{field.Field.Type}, nil)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return conn.Delete(ctx, stmtID, []string{field.Field.Name}, []interface{}{id})
+}
+
+// FindByID 按主键查找一条记录， 找不到时返回 sql.ErrNoRows
+func (repo *Repository[T]) FindByID(ctx context.Context, id interface{}) (*T, error) {
+	conn, err := toConnection(repo.session)
+	if err != nil {
+		return nil, err
+	}
+	field, err := repo.idField(conn.mapper)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, stmtID, err := repo.prepare("FindByID", StatementTypeSelect, func(dialect Dialect, mapper *Mapper) (string, error) {
+		return GenerateSelectSQL(dialect, mapper, repo.rType,
+			[]string{field.Field.Name}, []reflect.Type{field.Field.Type}, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var record T
+	err = conn.SelectOne(ctx, stmtID, []string{field.Field.Name}, []interface{}{id}).Scan(&record)
+	if err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// FindAll 查找符合条件的记录， 参数的格式见 NamedArg， 它还可以有 sortBy， offset 和 limit 参数
+func (repo *Repository[T]) FindAll(ctx context.Context, args ...NamedArg) ([]T, error) {
+	names, argTypes, values := splitNamedArgs(args)
+	conn, id, err := repo.prepare("FindAll"+namedArgsSignature(names, argTypes), StatementTypeSelect, func(dialect Dialect, mapper *Mapper) (string, error) {
+		return GenerateSelectSQL(dialect, mapper, repo.rType, names, argTypes, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var records []T
+	err = conn.Select(ctx, id, names, values).ScanSlice(&records)
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+// Count 返回符合条件的记录数， 参数的格式见 NamedArg
+func (repo *Repository[T]) Count(ctx context.Context, args ...NamedArg) (int64, error) {
+	names, argTypes, values := splitNamedArgs(args)
+	conn, id, err := repo.prepare("Count"+namedArgsSignature(names, argTypes), StatementTypeSelect, func(dialect Dialect, mapper *Mapper) (string, error) {
+		return GenerateCountSQL(dialect, mapper, repo.rType, names, argTypes, nil)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	err = conn.SelectOne(ctx, id, names, values).Scan(&count)
+	return count, err
+}
+
+// Page 返回一页符合条件的记录和符合条件的记录总数， 参数的格式见 NamedArg， 它还可以有 sortBy 参数
+func (repo *Repository[T]) Page(ctx context.Context, offset, limit int64, args ...NamedArg) ([]T, int64, error) {
+	countArgs := make([]NamedArg, 0, len(args))
+	for _, arg := range args {
+		if !strings.EqualFold(arg.Name, "sortBy") {
+			countArgs = append(countArgs, arg)
+		}
+	}
+	total, err := repo.Count(ctx, countArgs...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	pageArgs := make([]NamedArg, 0, len(args)+2)
+	pageArgs = append(pageArgs, args...)
+	pageArgs = append(pageArgs, Named("offset", offset), Named("limit", limit))
+	records, err := repo.FindAll(ctx, pageArgs...)
+	if err != nil {
+		return nil, 0, err
+	}
+	return records, total, nil
+}
@@ -0,0 +1,150 @@
+//go:build go1.18
+// +build go1.18
+
+package gobatis_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	gobatis "github.com/runner-mei/GoBatis"
+	"github.com/runner-mei/GoBatis/tests"
+)
+
+type RepoUser struct {
+	TableName struct{}   `db:"repo_users"`
+	ID        int64      `db:"id,autoincr,pk"`
+	Name      string     `db:"name,unique"`
+	Status    int        `db:"status"`
+	DeletedAt *time.Time `db:"deleted_at,deleted"`
+}
+
+type RepoUserQuery struct {
+	Name   string `q:"name,startswith"`
+	SortBy string
+}
+
+func TestRepository(t *testing.T) {
+	factory, err := gobatis.New(&gobatis.Config{DriverName: tests.TestDrv,
+		DataSource: tests.TestConnURL,
+		Tracer:     gobatis.NullTracer{}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer factory.Close()
+
+	ctx := context.Background()
+	factory.DB().ExecContext(ctx, `DROP TABLE IF EXISTS repo_users`)
+	createSQL := `CREATE TABLE repo_users (id bigserial PRIMARY KEY, name varchar(50) UNIQUE, status int, deleted_at timestamp NULL)`
+	if gobatis.IsDialect(factory.Dialect(), gobatis.DbTypeSqlite) {
+		createSQL = `CREATE TABLE repo_users (id INTEGER PRIMARY KEY AUTOINCREMENT, name varchar(50) UNIQUE, status int, deleted_at timestamp NULL)`
+	}
+	if _, err := factory.DB().ExecContext(ctx, createSQL); err != nil {
+		t.Error(err)
+		return
+	}
+	defer factory.DB().ExecContext(ctx, `DROP TABLE repo_users`)
+
+	repo := gobatis.NewRepository[RepoUser](factory.SessionReference())
+
+	var ids []int64
+	for _, name := range []string{"abc", "abd", "xbc"} {
+		id, err := repo.Insert(ctx, &RepoUser{Name: name, Status: 1})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	user, err := repo.FindByID(ctx, ids[1])
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if user.Name != "abd" {
+		t.Error("excepted is abd")
+		t.Error("actual   is", user.Name)
+	}
+
+	user.Status = 2
+	if count, err := repo.Update(ctx, user); err != nil {
+		t.Error(err)
+	} else if count != 1 {
+		t.Error("excepted is 1")
+		t.Error("actual   is", count)
+	}
+
+	if _, err := repo.Upsert(ctx, &RepoUser{Name: "xbc", Status: 3}, "name"); err != nil {
+		t.Error(err)
+	}
+
+	for idx, test := range []struct {
+		args     []gobatis.NamedArg
+		excepted []string
+	}{
+		{excepted: []string{"abc", "abd", "xbc"}},
+		{args: []gobatis.NamedArg{gobatis.Named("status", 2)}, excepted: []string{"abd"}},
+		{args: []gobatis.NamedArg{gobatis.Named("status", 3)}, excepted: []string{"xbc"}},
+		{args: []gobatis.NamedArg{gobatis.Named("nameStartsWith", "ab"), gobatis.Named("sortBy", "-name")}, excepted: []string{"abd", "abc"}},
+		{args: []gobatis.NamedArg{gobatis.Named("q", RepoUserQuery{Name: "x"})}, excepted: []string{"xbc"}},
+	} {
+		users, err := repo.FindAll(ctx, test.args...)
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		var names []string
+		for _, u := range users {
+			names = append(names, u.Name)
+		}
+		if strings.Join(names, ",") != strings.Join(test.excepted, ",") {
+			t.Error("[", idx, "] excepted is", test.excepted)
+			t.Error("[", idx, "] actual   is", names)
+		}
+	}
+
+	users, total, err := repo.Page(ctx, 1, 1, gobatis.Named("sortBy", "name"))
+	if err != nil {
+		t.Error(err)
+	} else if total != 3 || len(users) != 1 || users[0].Name != "abd" {
+		t.Error("excepted is 3 [abd]")
+		t.Error("actual   is", total, users)
+	}
+
+	if count, err := repo.Delete(ctx, ids[0]); err != nil {
+		t.Error(err)
+	} else if count != 1 {
+		t.Error("excepted is 1")
+		t.Error("actual   is", count)
+	}
+	if _, err := repo.FindByID(ctx, ids[0]); err != sql.ErrNoRows {
+		t.Error("excepted is", sql.ErrNoRows)
+		t.Error("actual   is", err)
+	}
+	if count, err := repo.Count(ctx); err != nil {
+		t.Error(err)
+	} else if count != 2 {
+		t.Error("excepted is 2")
+		t.Error("actual   is", count)
+	}
+
+	// 语句注册在连接上， 不同的 Repository 对象共用它们
+	if count, err := gobatis.NewRepository[RepoUser](factory.SessionReference()).Count(ctx, gobatis.Named("status", 1)); err != nil {
+		t.Error(err)
+	} else if count != 0 {
+		t.Error("excepted is 0")
+		t.Error("actual   is", count)
+	}
+
+	if _, err := gobatis.NewRepository[RepoUser](nil).Count(ctx); err == nil {
+		t.Error("excepted error got ok")
+	}
+	if _, err := gobatis.NewRepository[int](factory.SessionReference()).Count(ctx); err == nil {
+		t.Error("excepted error got ok")
+	}
+}
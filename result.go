@@ -16,6 +16,11 @@ type Result struct {
 	err       error
 }
 
+// ErrorResult 返回一个 Scan 时总是返回 err 的 Result
+func ErrorResult(err error) Result {
+	return Result{err: err}
+}
+
 func (result Result) Scan(value interface{}) error {
 	return result.scan(func(r colScanner) error {
 		return scanAny(result.o.dialect, result.o.mapper, r, value, false, result.o.isUnsafe)
@@ -68,6 +73,11 @@ type Results struct {
 	err       error
 }
 
+// ErrorResults 返回一个 Scan 时总是返回 err 的 Results
+func ErrorResults(err error) *Results {
+	return &Results{err: err}
+}
+
 func (results *Results) Close() error {
 	if results.rows != nil {
 		return results.rows.Close()
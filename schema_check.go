@@ -0,0 +1,480 @@
+package gobatis
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SchemaCheckMode 是初始化后检查表结构的方式， 见 Config.SchemaCheck
+type SchemaCheckMode int
+
+const (
+	// SchemaCheckNone 不检查表结构
+	SchemaCheckNone SchemaCheckMode = iota
+	// SchemaCheckWarn 检查表结构， 结果写入日志， 并可以用 SchemaDiagnostics() 读取
+	SchemaCheckWarn
+	// SchemaCheckStrict 检查表结构， 有问题时 New() 返回 *SchemaCheckError
+	SchemaCheckStrict
+)
+
+// SchemaIssue 是表结构问题的类型
+type SchemaIssue string
+
+const (
+	// SchemaTableNotFound 表在数据库中不存在
+	SchemaTableNotFound SchemaIssue = "table_not_found"
+	// SchemaColumnNotFound 字段在表中没有对应的列
+	SchemaColumnNotFound SchemaIssue = "column_not_found"
+	// SchemaColumnNotMapped 表中的列没有对应的字段
+	SchemaColumnNotMapped SchemaIssue = "column_not_mapped"
+	// SchemaTypeMismatch 列的类型和字段的类型不匹配
+	SchemaTypeMismatch SchemaIssue = "type_mismatch"
+	// SchemaNullableMismatch 列可以为 NULL， 但字段不是指针也没有 null 选项， 读到 NULL 时会出错
+	SchemaNullableMismatch SchemaIssue = "nullable_mismatch"
+)
+
+// SchemaDiagnostic 是一个表结构问题
+type SchemaDiagnostic struct {
+	Issue   SchemaIssue
+	Type    string
+	Table   string
+	Column  string
+	Field   string
+	Message string
+}
+
+func (d SchemaDiagnostic) String() string {
+	var sb strings.Builder
+	sb.WriteString(string(d.Issue))
+	sb.WriteString(": ")
+	sb.WriteString(d.Table)
+	if d.Column != "" {
+		sb.WriteString(".")
+		sb.WriteString(d.Column)
+	}
+	if d.Field != "" {
+		sb.WriteString(" (")
+		sb.WriteString(d.Type)
+		sb.WriteString(".")
+		sb.WriteString(d.Field)
+		sb.WriteString(")")
+	}
+	if d.Message != "" {
+		sb.WriteString(" - ")
+		sb.WriteString(d.Message)
+	}
+	return sb.String()
+}
+
+// SchemaCheckError 是 SchemaCheckStrict 时 New() 返回的错误
+type SchemaCheckError struct {
+	Diagnostics []SchemaDiagnostic
+}
+
+func (e *SchemaCheckError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("schema check fail:")
+	for _, d := range e.Diagnostics {
+		sb.WriteString("\r\n  ")
+		sb.WriteString(d.String())
+	}
+	return sb.String()
+}
+
+// dbColumn 是从数据库的系统表中读到的列
+type dbColumn struct {
+	name     string
+	dataType string
+	nullable bool
+}
+
+// readTableColumns 读取表的列， 表不存在时返回 nil
+func (conn *Connection) readTableColumns(ctx context.Context, tableName string) ([]dbColumn, error) {
+	schema, name := "", tableName
+	if idx := strings.LastIndex(tableName, "."); idx >= 0 {
+		schema, name = tableName[:idx], tableName[idx+1:]
+	}
+
+	var sqlStr string
+	var args []interface{}
+	switch rootDialect(conn.dialect) {
+	case DbTypePostgres:
+		sqlStr = "SELECT column_name, data_type, is_nullable FROM information_schema.columns WHERE table_name = $1"
+		args = append(args, name)
+		if schema == "" {
+			sqlStr += " AND table_schema = current_schema()"
+		} else {
+			sqlStr += " AND table_schema = $2"
+			args = append(args, schema)
+		}
+	case DbTypeMysql:
+		sqlStr = "SELECT column_name, data_type, is_nullable FROM information_schema.columns WHERE table_name = ?"
+		args = append(args, name)
+		if schema == "" {
+			sqlStr += " AND table_schema = DATABASE()"
+		} else {
+			sqlStr += " AND table_schema = ?"
+			args = append(args, schema)
+		}
+	case DbTypeMSSql:
+		sqlStr = "SELECT c.name, t.name, CASE WHEN c.is_nullable = 1 THEN 'YES' ELSE 'NO' END" +
+			" FROM sys.columns c JOIN sys.types t ON c.user_type_id = t.user_type_id WHERE c.object_id = OBJECT_ID(?)"
+		args = append(args, tableName)
+	case DbTypeOracle:
+		sqlStr = "SELECT column_name, data_type, CASE WHEN nullable = 'Y' THEN 'YES' ELSE 'NO' END FROM all_tab_columns WHERE table_name = UPPER(?)"
+		args = append(args, name)
+		if schema == "" {
+			sqlStr += " AND owner = USER"
+		} else {
+			sqlStr += " AND owner = UPPER(?)"
+			args = append(args, schema)
+		}
+	case DbTypeSqlite:
+		return conn.readSqliteTableColumns(ctx, name)
+	default:
+		return nil, errors.New("schema check is unimplemented for db type - " + conn.dialect.Name())
+	}
+
+	rows, err := conn.db.QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []dbColumn
+	for rows.Next() {
+		var column dbColumn
+		var nullable string
+		if err := rows.Scan(&column.name, &column.dataType, &nullable); err != nil {
+			return nil, err
+		}
+		column.nullable = strings.EqualFold(nullable, "YES")
+		columns = append(columns, column)
+	}
+	return columns, rows.Err()
+}
+
+func (conn *Connection) readSqliteTableColumns(ctx context.Context, tableName string) ([]dbColumn, error) {
+	rows, err := conn.db.QueryContext(ctx, "PRAGMA table_info("+quoteIdentifier(conn.dialect, tableName, true)+")")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var columns []dbColumn
+	for rows.Next() {
+		var cid, notnull, pk int
+		var defaultValue interface{}
+		var column dbColumn
+		if err := rows.Scan(&cid, &column.name, &column.dataType, &notnull, &defaultValue, &pk); err != nil {
+			return nil, err
+		}
+		// sqlite 中 INTEGER PRIMARY KEY 列的 notnull 为 0， 但它不可能是 NULL
+		column.nullable = notnull == 0 && pk == 0
+		columns = append(columns, column)
+	}
+	return columns, rows.Err()
+}
+
+// 列和字段的类型分类， 用于检查它们是否匹配
+const (
+	kindUnknown = ""
+	kindBool    = "bool"
+	kindInt     = "int"
+	kindFloat   = "float"
+	kindDecimal = "decimal"
+	kindString  = "string"
+	kindTime    = "time"
+	kindBinary  = "binary"
+	kindJSON    = "json"
+	kindArray   = "array"
+)
+
+// columnKind 返回数据库列类型的分类， 无法识别的类型返回 kindUnknown， 它们不会被检查
+func columnKind(dataType string) string {
+	t := strings.ToLower(strings.TrimSpace(dataType))
+	if strings.HasSuffix(t, "[]") || t == "array" || strings.HasPrefix(t, "_") {
+		return kindArray
+	}
+	if idx := strings.Index(t, "("); idx >= 0 {
+		t = strings.TrimSpace(t[:idx])
+	}
+
+	switch t {
+	case "bool", "boolean", "bit":
+		return kindBool
+	case "tinyint", "smallint", "mediumint", "int", "integer", "bigint", "int2", "int4", "int8",
+		"serial", "bigserial", "smallserial":
+		return kindInt
+	case "real", "float", "float4", "float8", "double", "double precision", "binary_float", "binary_double":
+		return kindFloat
+	case "numeric", "decimal", "number", "money", "smallmoney":
+		return kindDecimal
+	case "char", "varchar", "nchar", "nvarchar", "character", "character varying", "text", "ntext",
+		"tinytext", "mediumtext", "longtext", "clob", "nclob", "varchar2", "nvarchar2", "uuid",
+		"uniqueidentifier", "enum", "set", "inet", "cidr", "macaddr", "citext", "xml", "string":
+		return kindString
+	case "date", "time", "datetime", "datetime2", "smalldatetime", "datetimeoffset", "timestamp",
+		"timestamp with time zone", "timestamp without time zone", "timestamptz", "year":
+		return kindTime
+	case "bytea", "blob", "tinyblob", "mediumblob", "longblob", "binary", "varbinary", "image", "raw", "long raw":
+		return kindBinary
+	case "json", "jsonb":
+		return kindJSON
+	}
+	if strings.HasPrefix(t, "timestamp") {
+		return kindTime
+	}
+	return kindUnknown
+}
+
+// fieldKind 返回字段类型的分类
+func fieldKind(field *FieldInfo) string {
+	if _, ok := field.Options["json"]; ok {
+		return kindJSON
+	}
+	if _, ok := field.Options["jsonb"]; ok {
+		return kindJSON
+	}
+	if isEncryptField(field) {
+		return kindString
+	}
+
+	fType := field.Field.Type
+	for fType.Kind() == reflect.Ptr {
+		fType = fType.Elem()
+	}
+	switch fType {
+	case _timeType:
+		return kindTime
+	case _ipType, _macType:
+		return kindString
+	case _rawMessageType:
+		return kindJSON
+	}
+	if ok, kind, _ := isValidable(fType); ok {
+		switch kind {
+		case reflect.Bool:
+			return kindBool
+		case reflect.Int64:
+			return kindInt
+		case reflect.Float64:
+			return kindFloat
+		default:
+			return kindString
+		}
+	}
+	switch fType.String() {
+	case "sql.NullInt32":
+		return kindInt
+	case "sql.NullTime":
+		return kindTime
+	}
+
+	switch fType.Kind() {
+	case reflect.Bool:
+		return kindBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return kindInt
+	case reflect.Float32, reflect.Float64:
+		return kindFloat
+	case reflect.String:
+		return kindString
+	case reflect.Slice, reflect.Array:
+		if fType.Elem().Kind() == reflect.Uint8 {
+			return kindBinary
+		}
+		return kindArray
+	case reflect.Map, reflect.Struct:
+		return kindJSON
+	}
+	return kindUnknown
+}
+
+// compatibleKinds 是字段类型可以对应的列类型
+var compatibleKinds = map[string][]string{
+	kindBool:   {kindBool, kindInt, kindDecimal},
+	kindInt:    {kindInt, kindDecimal, kindBool},
+	kindFloat:  {kindFloat, kindDecimal, kindInt},
+	kindString: {kindString, kindJSON},
+	kindTime:   {kindTime, kindString},
+	kindBinary: {kindBinary, kindString},
+	kindJSON:   {kindJSON, kindString, kindBinary},
+	kindArray:  {kindArray, kindJSON, kindString},
+}
+
+func isCompatibleKind(fKind, cKind string) bool {
+	if fKind == kindUnknown || cKind == kindUnknown {
+		return true
+	}
+	for _, kind := range compatibleKinds[fKind] {
+		if kind == cKind {
+			return true
+		}
+	}
+	return false
+}
+
+// isNullableField 判断字段能否保存 NULL
+func isNullableField(field *FieldInfo) bool {
+	if _, ok := field.Options["null"]; ok {
+		return true
+	}
+	if _, ok := field.Options["json"]; ok {
+		return true
+	}
+	if _, ok := field.Options["jsonb"]; ok {
+		return true
+	}
+	if _, ok := field.Options["handler"]; ok {
+		return true
+	}
+	fType := field.Field.Type
+	switch fType.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface:
+		return true
+	}
+	if ok, _, _ := isValidable(fType); ok {
+		return true
+	}
+	if strings.HasPrefix(fType.String(), "sql.Null") {
+		return true
+	}
+	return reflect.PtrTo(fType).Implements(_scannerInterface)
+}
+
+// schemaCheckTypes 返回初始化时用到的有表名的记录类型， 它们来自 Mapper 的缓存(包括
+// WithDeleted() 等生成的 Mapper)和已注册的语句的记录类型(见 MappedStatement.AddRecordTypes)
+func (conn *Connection) schemaCheckTypes() []reflect.Type {
+	seen := map[reflect.Type]bool{}
+	var types []reflect.Type
+	add := func(rType reflect.Type) {
+		if seen[rType] || rType.Kind() != reflect.Struct {
+			return
+		}
+		seen[rType] = true
+		if _, err := ReadTableName(conn.mapper, rType); err != nil {
+			return
+		}
+		types = append(types, rType)
+	}
+
+	for rType := range conn.mapper.getCache() {
+		add(rType)
+	}
+	for _, stmt := range conn.sqlStatements {
+		for _, rType := range stmt.recordTypes {
+			add(rType)
+		}
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return types[i].String() < types[j].String()
+	})
+	return types
+}
+
+// CheckSchema 检查记录类型对应的表和数据库中的是否一致， types 的元素可以是结构体，
+// 结构体指针或 reflect.Type， types 为空时检查初始化时用到的所有有表名的记录类型
+func (conn *Connection) CheckSchema(ctx context.Context, types ...interface{}) ([]SchemaDiagnostic, error) {
+	var rTypes []reflect.Type
+	if len(types) == 0 {
+		rTypes = conn.schemaCheckTypes()
+	} else {
+		for _, value := range types {
+			rType, ok := value.(reflect.Type)
+			if !ok {
+				rType = reflect.TypeOf(value)
+			}
+			for rType.Kind() == reflect.Ptr {
+				rType = rType.Elem()
+			}
+			rTypes = append(rTypes, rType)
+		}
+	}
+
+	var diagnostics []SchemaDiagnostic
+	for _, rType := range rTypes {
+		results, err := conn.checkTableSchema(ctx, rType)
+		if err != nil {
+			return nil, err
+		}
+		diagnostics = append(diagnostics, results...)
+	}
+	return diagnostics, nil
+}
+
+func (conn *Connection) checkTableSchema(ctx context.Context, rType reflect.Type) ([]SchemaDiagnostic, error) {
+	tableName, err := ReadTableName(conn.mapper, rType)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := conn.readTableColumns(ctx, tableName)
+	if err != nil {
+		return nil, errors.New("read columns of table '" + tableName + "' fail, " + err.Error())
+	}
+	if len(columns) == 0 {
+		return []SchemaDiagnostic{{Issue: SchemaTableNotFound, Type: rType.String(), Table: tableName}}, nil
+	}
+
+	byName := map[string]*dbColumn{}
+	for idx := range columns {
+		byName[strings.ToLower(columns[idx].name)] = &columns[idx]
+	}
+
+	var diagnostics []SchemaDiagnostic
+	mapped := map[string]bool{}
+	for _, field := range tableColumns(conn.mapper, rType) {
+		// 只读的字段一般是查询时计算出来的， 不一定有对应的列
+		if _, ok := field.Options["<-"]; ok {
+			continue
+		}
+
+		names := []string{field.Name}
+		if column := blindIndexColumn(field); column != "" {
+			names = append(names, column)
+		}
+		for _, name := range names[1:] {
+			mapped[strings.ToLower(name)] = true
+		}
+
+		column := byName[strings.ToLower(field.Name)]
+		if column == nil {
+			diagnostics = append(diagnostics, SchemaDiagnostic{
+				Issue: SchemaColumnNotFound, Type: rType.String(), Table: tableName,
+				Column: field.Name, Field: field.Field.Name,
+			})
+			continue
+		}
+		mapped[strings.ToLower(field.Name)] = true
+
+		fKind, cKind := fieldKind(field), columnKind(column.dataType)
+		if _, ok := field.Options["handler"]; !ok && !isCompatibleKind(fKind, cKind) {
+			diagnostics = append(diagnostics, SchemaDiagnostic{
+				Issue: SchemaTypeMismatch, Type: rType.String(), Table: tableName,
+				Column: column.name, Field: field.Field.Name,
+				Message: "column type is '" + column.dataType + "', field type is '" + field.Field.Type.String() + "'",
+			})
+		}
+
+		if _, isPK := field.Options["pk"]; column.nullable && !isPK && !isNullableField(field) {
+			diagnostics = append(diagnostics, SchemaDiagnostic{
+				Issue: SchemaNullableMismatch, Type: rType.String(), Table: tableName,
+				Column: column.name, Field: field.Field.Name,
+				Message: "column is nullable, field type is '" + field.Field.Type.String() + "' without null option",
+			})
+		}
+	}
+
+	for _, column := range columns {
+		if !mapped[strings.ToLower(column.name)] {
+			diagnostics = append(diagnostics, SchemaDiagnostic{
+				Issue: SchemaColumnNotMapped, Type: rType.String(), Table: tableName, Column: column.name,
+			})
+		}
+	}
+	return diagnostics, nil
+}
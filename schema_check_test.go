@@ -0,0 +1,176 @@
+package gobatis_test
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	gobatis "github.com/runner-mei/GoBatis"
+	"github.com/runner-mei/GoBatis/tests"
+)
+
+type SchemaCheckUser struct {
+	TableName struct{} `db:"schema_check_users"`
+	ID        int64    `db:"id,pk"`
+	Name      string   `db:"name"`
+	Nickname  string   `db:"nickname,null"`
+	Age       int      `db:"age"`
+	Score     *float64 `db:"score"`
+	Email     string   `db:"email"`
+}
+
+type SchemaCheckMissing struct {
+	TableName struct{} `db:"schema_check_missing"`
+	ID        int64    `db:"id,pk"`
+}
+
+func TestCheckSchema(t *testing.T) {
+	factory, err := gobatis.New(&gobatis.Config{DriverName: tests.TestDrv,
+		DataSource: tests.TestConnURL,
+		Tracer:     gobatis.NullTracer{}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer factory.Close()
+
+	ctx := context.Background()
+	factory.DB().ExecContext(ctx, `DROP TABLE IF EXISTS schema_check_users`)
+	_, err = factory.DB().ExecContext(ctx, `CREATE TABLE schema_check_users (
+		id int NOT NULL PRIMARY KEY,
+		name varchar(50),
+		nickname varchar(50),
+		age varchar(10) NOT NULL,
+		score float,
+		extra int
+	)`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer factory.DB().ExecContext(ctx, `DROP TABLE schema_check_users`)
+
+	diagnostics, err := factory.CheckSchema(ctx, &SchemaCheckUser{}, reflect.TypeOf(SchemaCheckMissing{}))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var actual []string
+	for _, d := range diagnostics {
+		actual = append(actual, string(d.Issue)+":"+d.Table+"."+d.Column+":"+d.Field)
+	}
+	sort.Strings(actual)
+	excepted := []string{
+		"column_not_found:schema_check_users.email:Email",
+		"column_not_mapped:schema_check_users.extra:",
+		"nullable_mismatch:schema_check_users.name:Name",
+		"table_not_found:schema_check_missing.:",
+		"type_mismatch:schema_check_users.age:Age",
+	}
+	if !reflect.DeepEqual(actual, excepted) {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", actual)
+	}
+
+	// New() 时检查的是初始化时用到的记录类型
+	warnFactory, err := gobatis.New(&gobatis.Config{DriverName: tests.TestDrv,
+		DataSource:  tests.TestConnURL,
+		Tracer:      gobatis.NullTracer{},
+		SchemaCheck: gobatis.SchemaCheckWarn})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer warnFactory.Close()
+
+	diagnostics, err = warnFactory.CheckSchema(ctx)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !reflect.DeepEqual(diagnostics, warnFactory.SchemaDiagnostics()) {
+		t.Error("excepted is", diagnostics)
+		t.Error("actual   is", warnFactory.SchemaDiagnostics())
+	}
+
+	strictFactory, err := gobatis.New(&gobatis.Config{DriverName: tests.TestDrv,
+		DataSource:  tests.TestConnURL,
+		Tracer:      gobatis.NullTracer{},
+		SchemaCheck: gobatis.SchemaCheckStrict})
+	if len(diagnostics) == 0 {
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		strictFactory.Close()
+	} else if e, ok := err.(*gobatis.SchemaCheckError); !ok {
+		t.Error("excepted SchemaCheckError got", err)
+	} else if !reflect.DeepEqual(e.Diagnostics, diagnostics) {
+		t.Error("excepted is", diagnostics)
+		t.Error("actual   is", e.Diagnostics)
+	}
+}
+
+func TestCheckSchemaRecordTypes(t *testing.T) {
+	callbacks := gobatis.ClearInit()
+	defer gobatis.SetInit(callbacks)
+
+	gobatis.Init(func(ctx *gobatis.InitContext) error {
+		// 语句的 sql 不是生成的， 记录类型来自 AddRecordTypes
+		stmt, err := gobatis.NewMapppedStatement(ctx, "SchemaCheckUser.Get", gobatis.StatementTypeSelect, gobatis.ResultStruct,
+			"SELECT * FROM schema_check_users WHERE id = #{id}")
+		if err != nil {
+			return err
+		}
+		stmt.AddRecordTypes(reflect.TypeOf([]*SchemaCheckUser{}))
+		ctx.Statements["SchemaCheckUser.Get"] = stmt
+
+		// WithDeleted() 生成的 Mapper 中用到的记录类型
+		ctx.Mapper.WithDeleted().TypeMap(reflect.TypeOf(&SchemaCheckMissing{}))
+		return nil
+	})
+
+	factory, err := gobatis.New(&gobatis.Config{DriverName: tests.TestDrv,
+		DataSource: tests.TestConnURL,
+		Tracer:     gobatis.NullTracer{}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer factory.Close()
+
+	ctx := context.Background()
+	factory.DB().ExecContext(ctx, `DROP TABLE IF EXISTS schema_check_users`)
+	_, err = factory.DB().ExecContext(ctx, `CREATE TABLE schema_check_users (
+		id int NOT NULL PRIMARY KEY,
+		name varchar(50)
+	)`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer factory.DB().ExecContext(ctx, `DROP TABLE schema_check_users`)
+
+	diagnostics, err := factory.CheckSchema(ctx)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tables := map[string]bool{}
+	for _, d := range diagnostics {
+		tables[d.Table] = true
+	}
+	var actual []string
+	for table := range tables {
+		actual = append(actual, table)
+	}
+	sort.Strings(actual)
+	excepted := []string{"schema_check_missing", "schema_check_users"}
+	if !reflect.DeepEqual(actual, excepted) {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", actual)
+	}
+}
@@ -165,6 +165,16 @@ func (sess *Session) Mapper() *Mapper {
 	return sess.base.Mapper()
 }
 
+// SchemaDiagnostics 返回初始化时检查表结构的结果， 见 Config.SchemaCheck
+func (sess *Session) SchemaDiagnostics() []SchemaDiagnostic {
+	return sess.base.SchemaDiagnostics()
+}
+
+// CheckSchema 检查记录类型对应的表和数据库中的是否一致， 见 Connection.CheckSchema
+func (sess *Session) CheckSchema(ctx context.Context, types ...interface{}) ([]SchemaDiagnostic, error) {
+	return sess.base.CheckSchema(ctx, types...)
+}
+
 // Delete 执行删除sql
 //
 //xml
@@ -225,6 +235,30 @@ func (sess *Session) Select(ctx context.Context, id string, params ...interface{
 	return sess.base.Select(ctx, id, nil, params)
 }
 
+// ExecRaw 执行一个没有注册的 sql， sql 的格式和 xml 中的一样
+//
+//代码
+//  count, err := o.ExecRaw(ctx, "UPDATE user SET email=#{Email} where id = #{Id}", user)
+func (sess *Session) ExecRaw(ctx context.Context, sqlStr string, params ...interface{}) (int64, error) {
+	return sess.base.ExecRaw(ctx, sqlStr, nil, params)
+}
+
+// QueryRowRaw 执行一个没有注册的查询 sql, 返回单行数据
+//
+//代码
+//  err := o.QueryRowRaw(ctx, "SELECT id,email FROM user WHERE id=#{id}", 3).Scan(&user)
+func (sess *Session) QueryRowRaw(ctx context.Context, sqlStr string, params ...interface{}) Result {
+	return sess.base.QueryRowRaw(ctx, sqlStr, nil, params)
+}
+
+// QueryRaw 执行一个没有注册的查询 sql, 返回多行数据
+//
+//代码
+//  err := o.QueryRaw(ctx, `SELECT id,email FROM user <where><if test="isNotEmptyString(email)">email like <like value="email" /></if></where>`, "abc").ScanSlice(&users)
+func (sess *Session) QueryRaw(ctx context.Context, sqlStr string, params ...interface{}) *Results {
+	return sess.base.QueryRaw(ctx, sqlStr, nil, params)
+}
+
 // New 创建一个新的Osm，这个过程会打开数据库连接。
 //
 // cfg 是数据连接的参数，可以是0个1个或2个数字，第一个表示MaxIdleConns，第二个表示MaxOpenConns.
@@ -0,0 +1,151 @@
+package gobatis
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// 软删除
+//
+// 带有 deleted 标签的字段可以是一个时间(如 `db:"deleted_at,deleted"`)， 删除时设为当前时间，
+// 也可以是一个 bool 值(如 `db:"is_deleted,deleted"`)， 删除时设为 true。
+// 默认生成的 select 和 count 语句会过滤掉已删除的记录， 方法名以 WithDeleted 结尾时不过滤，
+// 以 OnlyDeleted 结尾时只查询已删除的记录， 它们分别用 Mapper.WithDeleted() 和
+// Mapper.OnlyDeleted() 来生成 sql。 以 Restore 开头的方法用于恢复已删除的记录， 见 GenerateRestoreSQL
+
+const (
+	deletedExcluded = iota
+	deletedIncluded
+	deletedOnly
+)
+
+func (m *Mapper) clone() *Mapper {
+	root := m.root
+	if root == nil {
+		root = m
+	}
+	return &Mapper{mapper: m.mapper, cipher: m.cipher, ignoreTenant: m.ignoreTenant, deletedMode: m.deletedMode, root: root}
+}
+
+// WithDeleted 返回一个不过滤已删除记录的 Mapper
+func (m *Mapper) WithDeleted() *Mapper {
+	copyed := m.clone()
+	copyed.deletedMode = deletedIncluded
+	return copyed
+}
+
+// OnlyDeleted 返回一个只查询已删除记录的 Mapper
+func (m *Mapper) OnlyDeleted() *Mapper {
+	copyed := m.clone()
+	copyed.deletedMode = deletedOnly
+	return copyed
+}
+
+func isBoolDeletedField(field *FieldInfo) bool {
+	typ := field.Field.Type
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ.Kind() == reflect.Bool
+}
+
+func boolLiteral(dbType Dialect, value bool) string {
+	if IsDialect(dbType, DbTypePostgres) {
+		if value {
+			return "true"
+		}
+		return "false"
+	}
+	if value {
+		return "1"
+	}
+	return "0"
+}
+
+// deletedValue 返回删除(或恢复)时 deleted 字段的值
+func deletedValue(dbType Dialect, field *FieldInfo, deleted bool) string {
+	if isBoolDeletedField(field) {
+		return boolLiteral(dbType, deleted)
+	}
+	if !deleted {
+		return "NULL"
+	}
+	if IsDialect(dbType, DbTypePostgres) {
+		return "now()"
+	}
+	return "CURRENT_TIMESTAMP"
+}
+
+// deletedCondition 返回已删除(或未删除)的记录的条件
+func deletedCondition(dbType Dialect, field *FieldInfo, deleted bool) string {
+	if isBoolDeletedField(field) {
+		return quoteColumn(dbType, field) + "=" + boolLiteral(dbType, deleted)
+	}
+	if deleted {
+		return quoteColumn(dbType, field) + " IS NOT NULL"
+	}
+	return quoteColumn(dbType, field) + " IS NULL"
+}
+
+// deletedFilter 返回 select 语句中过滤已删除记录的条件， 不需要过滤时返回空
+func deletedFilter(dbType Dialect, mapper *Mapper, field *FieldInfo) string {
+	if field == nil {
+		return ""
+	}
+	switch mapper.deletedMode {
+	case deletedIncluded:
+		return ""
+	case deletedOnly:
+		return deletedCondition(dbType, field, true)
+	default:
+		return deletedCondition(dbType, field, false)
+	}
+}
+
+// SoftDeleteFilter 返回过滤已删除记录的条件， rType 没有 deleted 字段时返回空字符串
+func SoftDeleteFilter(dbType Dialect, mapper *Mapper, rType reflect.Type) string {
+	return deletedFilter(dbType, mapper, findDeletedField(mapper, rType))
+}
+
+// GenerateRestoreSQL 生成恢复已删除记录的 sql
+func GenerateRestoreSQL(dbType Dialect, mapper *Mapper, rType reflect.Type, names []string, argTypes []reflect.Type, filters []Filter) (string, error) {
+	deletedField := findDeletedField(mapper, rType)
+	if deletedField == nil {
+		if rType.Kind() == reflect.Ptr {
+			rType = rType.Elem()
+		}
+		return "", errors.New("struct '" + rType.Name() + "' hasnot deleted field, restore is unsupported")
+	}
+
+	var sb strings.Builder
+	sb.WriteString("UPDATE ")
+	tableName, err := ReadTableName(mapper, rType)
+	if err != nil {
+		return "", err
+	}
+	sb.WriteString(quoteTableName(dbType, tableName))
+	sb.WriteString(" SET ")
+	sb.WriteString(quoteColumn(dbType, deletedField))
+	sb.WriteString("=")
+	sb.WriteString(deletedValue(dbType, deletedField, false))
+
+	exprs := appendTenantFilter(dbType, mapper, rType, toFilters(filters, dbType))
+	exprs = append(exprs, deletedCondition(dbType, deletedField, true))
+	if len(names) > 0 {
+		err := generateWhere(dbType, mapper, rType, names, argTypes, exprs, StatementTypeDelete, false, &sb)
+		if err != nil {
+			return "", err
+		}
+		return sb.String(), nil
+	}
+
+	sb.WriteString(" WHERE ")
+	for idx := range exprs {
+		if idx > 0 {
+			sb.WriteString(" AND ")
+		}
+		sb.WriteString(strings.TrimSpace(exprs[idx]))
+	}
+	return sb.String(), nil
+}
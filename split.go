@@ -25,6 +25,11 @@ func endsWithSemicolon(line string) bool {
 	return strings.HasSuffix(prev, ";")
 }
 
+// SplitSQLStatements 将 sql 脚本拆分为单独的语句， 规则见 splitSQLStatements
+func SplitSQLStatements(r io.Reader) []string {
+	return splitSQLStatements(r)
+}
+
 // Split the given sql script into individual statements.
 //
 // The base case is to simply split on semicolons, as these
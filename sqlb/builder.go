@@ -0,0 +1,185 @@
+package sqlb
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+
+	gobatis "github.com/runner-mei/GoBatis"
+)
+
+var identifierRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// builder 用于拼接 sql， 它用 fragments 记录占位符之间的 sql 片段， 最后由
+// Dialect.Placeholder() 将它们连接起来
+type builder struct {
+	dialect    gobatis.Dialect
+	mapper     *gobatis.Mapper
+	tableName  string
+	rType      reflect.Type
+	structType *gobatis.StructMap
+
+	sb        strings.Builder
+	fragments []string
+	params    []interface{}
+}
+
+func newBuilder(dialect gobatis.Dialect, mapper *gobatis.Mapper, table interface{}) (*builder, error) {
+	if dialect == nil {
+		dialect = gobatis.DbTypeNone
+	}
+	b := &builder{dialect: dialect, mapper: mapper}
+
+	var rType reflect.Type
+	switch t := table.(type) {
+	case nil:
+		return nil, errors.New("table is missing, please invoke From()")
+	case string:
+		if !identifierRegexp.MatchString(t) {
+			return nil, errors.New("table '" + t + "' is invalid")
+		}
+		b.tableName = t
+		return b, nil
+	case reflect.Type:
+		rType = t
+	default:
+		rType = reflect.TypeOf(table)
+	}
+
+	for rType.Kind() == reflect.Ptr {
+		rType = rType.Elem()
+	}
+	if rType.Kind() != reflect.Struct {
+		return nil, errors.New("table '" + rType.String() + "' isnot a struct")
+	}
+	if mapper == nil {
+		return nil, errors.New("mapper is missing")
+	}
+
+	tableName, err := gobatis.ReadTableName(mapper, rType)
+	if err != nil {
+		return nil, err
+	}
+	b.tableName = tableName
+	b.rType = rType
+	b.structType = mapper.TypeMap(rType)
+	return b, nil
+}
+
+func (b *builder) writeString(s string) {
+	b.sb.WriteString(s)
+}
+
+func (b *builder) addParam(value interface{}) {
+	b.fragments = append(b.fragments, b.sb.String())
+	b.sb.Reset()
+	b.params = append(b.params, value)
+}
+
+// writeExpr 写入一个用 ? 作为占位符的表达式， ?? 表示一个 ?
+func (b *builder) writeExpr(expr string, args []interface{}) error {
+	count := 0
+	for {
+		p := strings.Index(expr, "?")
+		if p < 0 {
+			break
+		}
+		if strings.HasPrefix(expr[p:], "??") {
+			b.sb.WriteString(expr[:p+1])
+			expr = expr[p+2:]
+			continue
+		}
+		if count >= len(args) {
+			return errors.New("expr '" + expr + "' has too many placeholders")
+		}
+		b.sb.WriteString(expr[:p])
+		b.addParam(args[count])
+		count++
+		expr = expr[p+1:]
+	}
+	b.sb.WriteString(expr)
+	if count != len(args) {
+		return errors.New("the count of args is not equal to the count of placeholders")
+	}
+	return nil
+}
+
+func (b *builder) table() string {
+	return gobatis.QuoteTableName(b.dialect, b.tableName)
+}
+
+// column 查找列， name 可以是列名也可以是字段名， 返回引用后的列名
+func (b *builder) column(name string) (string, error) {
+	field, err := b.field(name)
+	if err != nil {
+		return "", err
+	}
+	if field == nil {
+		return gobatis.QuoteIdentifier(b.dialect, name), nil
+	}
+	return gobatis.QuoteColumn(b.dialect, field), nil
+}
+
+// whereColumn 和 column 一样， 但加密的列不能用于查询条件
+func (b *builder) whereColumn(name string) (string, error) {
+	field, err := b.field(name)
+	if err != nil {
+		return "", err
+	}
+	if field == nil {
+		return gobatis.QuoteIdentifier(b.dialect, name), nil
+	}
+	if _, ok := field.Options["encrypt"]; ok {
+		return "", errors.New("column '" + field.Name + "' is encrypted, it cannot used in where")
+	}
+	return gobatis.QuoteColumn(b.dialect, field), nil
+}
+
+func (b *builder) field(name string) (*gobatis.FieldInfo, error) {
+	if b.structType == nil {
+		if !identifierRegexp.MatchString(name) {
+			return nil, errors.New("column '" + name + "' is invalid")
+		}
+		return nil, nil
+	}
+
+	field := b.structType.FieldByName(name)
+	if field != nil && field.Field.Name != "TableName" {
+		return field, nil
+	}
+	return nil, errors.New("column '" + name + "' isnot found in '" + b.tableName + "'")
+}
+
+func (b *builder) sql() string {
+	fragments := append(b.fragments, b.sb.String())
+	return b.dialect.Placeholder().Concat(fragments, nil, 0)
+}
+
+// writeConds 用 joiner 连接多个条件， 被忽略的条件不会输出， 返回是否有输出
+func (b *builder) writeConds(conds []Cond, joiner string) (bool, error) {
+	written := false
+	for _, cond := range conds {
+		if cond == nil {
+			continue
+		}
+
+		fragments, params, s := b.fragments, b.params, b.sb.String()
+		if written {
+			b.sb.WriteString(joiner)
+		}
+		ok, err := cond.writeTo(b)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			// 回退 joiner
+			b.fragments, b.params = fragments, params
+			b.sb.Reset()
+			b.sb.WriteString(s)
+			continue
+		}
+		written = true
+	}
+	return written, nil
+}
@@ -0,0 +1,258 @@
+package sqlb
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	gobatis "github.com/runner-mei/GoBatis"
+)
+
+// Cond 是 WHERE 子句中的一个条件， 条件的值为空时(如 Like 的值为空字符串)会被忽略
+type Cond interface {
+	// writeTo 输出条件， 条件被忽略时返回 false
+	writeTo(b *builder) (bool, error)
+}
+
+type compareCond struct {
+	column string
+	op     string
+	value  interface{}
+}
+
+func (cond compareCond) writeTo(b *builder) (bool, error) {
+	column, err := b.whereColumn(cond.column)
+	if err != nil {
+		return false, err
+	}
+
+	if cond.value == nil {
+		switch cond.op {
+		case "=":
+			b.writeString(column + " IS NULL")
+			return true, nil
+		case "<>":
+			b.writeString(column + " IS NOT NULL")
+			return true, nil
+		}
+		return false, errors.New("value of '" + cond.column + " " + cond.op + "' is nil")
+	}
+
+	b.writeString(column + " " + cond.op + " ")
+	b.addParam(cond.value)
+	return true, nil
+}
+
+// Eq 生成 column = value， value 为 nil 时生成 column IS NULL
+func Eq(column string, value interface{}) Cond {
+	return compareCond{column: column, op: "=", value: value}
+}
+
+// Ne 生成 column <> value， value 为 nil 时生成 column IS NOT NULL
+func Ne(column string, value interface{}) Cond {
+	return compareCond{column: column, op: "<>", value: value}
+}
+
+// Gt 生成 column > value
+func Gt(column string, value interface{}) Cond {
+	return compareCond{column: column, op: ">", value: value}
+}
+
+// Gte 生成 column >= value
+func Gte(column string, value interface{}) Cond {
+	return compareCond{column: column, op: ">=", value: value}
+}
+
+// Lt 生成 column < value
+func Lt(column string, value interface{}) Cond {
+	return compareCond{column: column, op: "<", value: value}
+}
+
+// Lte 生成 column <= value
+func Lte(column string, value interface{}) Cond {
+	return compareCond{column: column, op: "<=", value: value}
+}
+
+type likeCond struct {
+	column string
+	value  string
+	match  string
+}
+
+func (cond likeCond) writeTo(b *builder) (bool, error) {
+	if cond.value == "" {
+		return false, nil
+	}
+	column, err := b.whereColumn(cond.column)
+	if err != nil {
+		return false, err
+	}
+
+	// 和 xml 中的 <like /> 一样， 有 match 时转义值中的通配符，
+	// 否则值以 % 开头或结尾时不再加上 %
+	value := cond.value
+	escapeClause := ""
+	if cond.match != "" {
+		value, escapeClause = gobatis.EscapeLike(b.dialect, value)
+		if cond.match == "prefix" {
+			value = value + "%"
+		} else {
+			value = "%" + value
+		}
+	} else if !strings.HasPrefix(value, "%") && !strings.HasSuffix(value, "%") {
+		value = "%" + value + "%"
+	}
+
+	b.writeString(column + " LIKE ")
+	b.addParam(value)
+	b.writeString(escapeClause)
+	return true, nil
+}
+
+// Like 生成 column LIKE '%value%'， value 为空字符串时忽略这个条件
+func Like(column string, value string) Cond {
+	return likeCond{column: column, value: value}
+}
+
+// StartsWith 生成 column LIKE 'value%'， value 中的 % 和 _ 会被转义， value 为空字符串时忽略这个条件
+func StartsWith(column string, value string) Cond {
+	return likeCond{column: column, value: value, match: "prefix"}
+}
+
+// EndsWith 生成 column LIKE '%value'， value 中的 % 和 _ 会被转义， value 为空字符串时忽略这个条件
+func EndsWith(column string, value string) Cond {
+	return likeCond{column: column, value: value, match: "suffix"}
+}
+
+type inCond struct {
+	column string
+	values interface{}
+	not    bool
+}
+
+func (cond inCond) writeTo(b *builder) (bool, error) {
+	rv := reflect.ValueOf(cond.values)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return false, errors.New("values of '" + cond.column + "' must is a slice, actual is " + fmt.Sprintf("%T", cond.values))
+	}
+
+	column, err := b.whereColumn(cond.column)
+	if err != nil {
+		return false, err
+	}
+
+	if rv.Len() == 0 {
+		if cond.not {
+			return false, nil
+		}
+		// IN () 是非法的语法， 空的列表表示没有匹配的记录
+		b.writeString("1 <> 1")
+		return true, nil
+	}
+
+	if cond.not {
+		b.writeString(column + " NOT IN (")
+	} else {
+		b.writeString(column + " IN (")
+	}
+	for idx := 0; idx < rv.Len(); idx++ {
+		if idx > 0 {
+			b.writeString(", ")
+		}
+		b.addParam(rv.Index(idx).Interface())
+	}
+	b.writeString(")")
+	return true, nil
+}
+
+// In 生成 column IN (values...)， values 必须是 slice 或 array
+func In(column string, values interface{}) Cond {
+	return inCond{column: column, values: values}
+}
+
+// NotIn 生成 column NOT IN (values...)， values 为空时忽略这个条件
+func NotIn(column string, values interface{}) Cond {
+	return inCond{column: column, values: values, not: true}
+}
+
+type nullCond struct {
+	column string
+	not    bool
+}
+
+func (cond nullCond) writeTo(b *builder) (bool, error) {
+	column, err := b.whereColumn(cond.column)
+	if err != nil {
+		return false, err
+	}
+	if cond.not {
+		b.writeString(column + " IS NOT NULL")
+	} else {
+		b.writeString(column + " IS NULL")
+	}
+	return true, nil
+}
+
+// IsNull 生成 column IS NULL
+func IsNull(column string) Cond {
+	return nullCond{column: column}
+}
+
+// IsNotNull 生成 column IS NOT NULL
+func IsNotNull(column string) Cond {
+	return nullCond{column: column, not: true}
+}
+
+type groupCond struct {
+	conds  []Cond
+	joiner string
+}
+
+func (cond groupCond) writeTo(b *builder) (bool, error) {
+	b.writeString("(")
+	ok, err := b.writeConds(cond.conds, cond.joiner)
+	if err != nil || !ok {
+		return false, err
+	}
+	b.writeString(")")
+	return true, nil
+}
+
+// And 用 AND 连接多个条件， 所有的条件都被忽略时这个条件也会被忽略
+func And(conds ...Cond) Cond {
+	return groupCond{conds: conds, joiner: " AND "}
+}
+
+// Or 用 OR 连接多个条件， 所有的条件都被忽略时这个条件也会被忽略
+func Or(conds ...Cond) Cond {
+	return groupCond{conds: conds, joiner: " OR "}
+}
+
+type exprCond struct {
+	expr string
+	args []interface{}
+}
+
+func (cond exprCond) writeTo(b *builder) (bool, error) {
+	b.writeString("(")
+	if err := b.writeExpr(cond.expr, cond.args); err != nil {
+		return false, err
+	}
+	b.writeString(")")
+	return true, nil
+}
+
+// Expr 是一个手写的条件， 它用 ? 作为占位符(?? 表示一个 ?)， 如 Expr("age > ? AND age < ?", 10, 20)，
+// 注意 expr 会原样输出， 不要将用户的输入拼接到 expr 中
+func Expr(expr string, args ...interface{}) Cond {
+	return exprCond{expr: expr, args: args}
+}
+
+// rawCond 是 gobatis 生成的条件， 如软删除的过滤条件， 它没有参数
+type rawCond string
+
+func (cond rawCond) writeTo(b *builder) (bool, error) {
+	b.writeString(string(cond))
+	return true, nil
+}
@@ -0,0 +1,257 @@
+// Package sqlb 是一个用代码构建 sql 的工具， 它和 gobatis 共用 Mapper 和 Dialect， 如
+//
+//	var users []User
+//	err := sqlb.Select("id", "name").
+//		From(User{}).
+//		Where(sqlb.Eq("status", 1), sqlb.Like("name", q)).
+//		OrderBy("-id").
+//		Limit(20).
+//		All(ctx, sess).
+//		ScanSlice(&users)
+//
+// From 的参数是一个结构体时， 表名由 gobatis.ReadTableName 读取， 列名可以是数据库的列名也可以是
+// 结构体的字段名， 它们会通过 Mapper.TypeMap 转换为数据库的列名， 找不到时返回错误； 结构体有
+// deleted 字段时会自动过滤已删除的记录， 见 WithDeleted()； 结构体有 tenant 字段时会和生成的
+// select 语句一样加上租户的条件， 租户由 Config.TenantResolver 读取， 见 Tenant()。
+//
+// 生成的 sql 使用 Dialect.Placeholder() 的占位符， 通过 gobatis.SqlRunner 执行， 执行结果就是
+// gobatis 的 Result 和 Results， 所以结果的扫描方式和 gobatis 中的一样。
+package sqlb
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	gobatis "github.com/runner-mei/GoBatis"
+)
+
+// SelectBuilder 是 SELECT 语句的构建器
+type SelectBuilder struct {
+	columns     []string
+	table       interface{}
+	conds       []Cond
+	sorts       []string
+	offset      int64
+	limit       int64
+	withDeleted bool
+	hasTenant   bool
+	tenant      interface{}
+}
+
+// Select 开始构建一个 SELECT 语句， 没有指定列时为 SELECT *
+func Select(columns ...string) *SelectBuilder {
+	return &SelectBuilder{columns: columns}
+}
+
+// From 指定要查询的表， table 可以是结构体(或它的指针和 reflect.Type)， 也可以是表名
+func (sb *SelectBuilder) From(table interface{}) *SelectBuilder {
+	sb.table = table
+	return sb
+}
+
+// Where 添加查询条件， 多次调用时所有的条件都用 AND 连接
+func (sb *SelectBuilder) Where(conds ...Cond) *SelectBuilder {
+	sb.conds = append(sb.conds, conds...)
+	return sb
+}
+
+// OrderBy 添加排序的列， 格式和 <order_by /> 的一样， 如 "-id" 和 "name asc nulls last"，
+// 列必须是表中的列， 所以可以直接使用 http 请求中的排序参数
+func (sb *SelectBuilder) OrderBy(sorts ...string) *SelectBuilder {
+	for _, s := range sorts {
+		if s = strings.TrimSpace(s); s != "" {
+			sb.sorts = append(sb.sorts, s)
+		}
+	}
+	return sb
+}
+
+// Offset 指定跳过的记录数， 小于等于 0 时表示不跳过
+func (sb *SelectBuilder) Offset(offset int64) *SelectBuilder {
+	sb.offset = offset
+	return sb
+}
+
+// Limit 指定返回的最大记录数， 小于等于 0 时表示不限制
+func (sb *SelectBuilder) Limit(limit int64) *SelectBuilder {
+	sb.limit = limit
+	return sb
+}
+
+// WithDeleted 表示查询时包含已删除的记录
+func (sb *SelectBuilder) WithDeleted() *SelectBuilder {
+	sb.withDeleted = true
+	return sb
+}
+
+// Tenant 指定租户的值， 不指定时 One, All 和 Count 会用 Config.TenantResolver 从 ctx 中读取，
+// 表没有 tenant 字段或 mapper 是 Mapper.WithoutTenant() 时忽略它
+func (sb *SelectBuilder) Tenant(value interface{}) *SelectBuilder {
+	sb.hasTenant = true
+	sb.tenant = value
+	return sb
+}
+
+// ToSQL 生成 sql 和它的参数， 表有 tenant 字段时必须先调用 Tenant()
+func (sb *SelectBuilder) ToSQL(dialect gobatis.Dialect, mapper *gobatis.Mapper) (string, []interface{}, error) {
+	return sb.build(dialect, mapper, nil, false)
+}
+
+// ToCountSQL 生成查询记录数的 sql 和它的参数， 它会忽略列， 排序和分页
+func (sb *SelectBuilder) ToCountSQL(dialect gobatis.Dialect, mapper *gobatis.Mapper) (string, []interface{}, error) {
+	return sb.build(dialect, mapper, nil, true)
+}
+
+func (sb *SelectBuilder) build(dialect gobatis.Dialect, mapper *gobatis.Mapper, tenant func() (interface{}, error), isCount bool) (string, []interface{}, error) {
+	b, err := newBuilder(dialect, mapper, sb.table)
+	if err != nil {
+		return "", nil, err
+	}
+
+	b.writeString("SELECT ")
+	if isCount {
+		b.writeString("count(*)")
+	} else if len(sb.columns) == 0 {
+		b.writeString("*")
+	} else {
+		for idx, name := range sb.columns {
+			column, err := b.column(name)
+			if err != nil {
+				return "", nil, err
+			}
+			if idx > 0 {
+				b.writeString(", ")
+			}
+			b.writeString(column)
+		}
+	}
+	b.writeString(" FROM ")
+	b.writeString(b.table())
+
+	if err := sb.writeWhere(b, tenant); err != nil {
+		return "", nil, err
+	}
+
+	if !isCount {
+		if len(sb.sorts) > 0 {
+			var allowed []string
+			if b.rType != nil {
+				allowed = gobatis.SortableColumns(b.dialect, b.mapper, b.rType)
+			}
+			orderBy, err := gobatis.ToOrderBy(b.dialect, strings.Join(sb.sorts, ","), allowed)
+			if err != nil {
+				return "", nil, err
+			}
+			b.writeString(" ORDER BY ")
+			b.writeString(orderBy)
+		}
+
+		if sb.offset > 0 || sb.limit > 0 {
+			s, args := b.dialect.GeneratePagination(sb.offset, sb.limit)
+			if err := b.writeExpr(strings.TrimRight(s, " "), args); err != nil {
+				return "", nil, err
+			}
+		}
+	}
+	return b.sql(), b.params, nil
+}
+
+func (sb *SelectBuilder) writeWhere(b *builder, tenant func() (interface{}, error)) error {
+	conds := sb.conds
+	if b.rType != nil {
+		if !sb.withDeleted {
+			if filter := gobatis.SoftDeleteFilter(b.dialect, b.mapper, b.rType); filter != "" {
+				conds = append([]Cond{rawCond(filter)}, conds...)
+			}
+		}
+		if field := gobatis.TenantField(b.mapper, b.rType); field != nil {
+			value, err := sb.tenantValue(b, tenant)
+			if err != nil {
+				return err
+			}
+			conds = append(conds, Eq(field.Name, value))
+		}
+	}
+	if len(conds) == 0 {
+		return nil
+	}
+
+	fragments, params, s := b.fragments, b.params, b.sb.String()
+	b.writeString(" WHERE ")
+	ok, err := b.writeConds(conds, " AND ")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		b.fragments, b.params = fragments, params
+		b.sb.Reset()
+		b.sb.WriteString(s)
+	}
+	return nil
+}
+
+func (sb *SelectBuilder) tenantValue(b *builder, tenant func() (interface{}, error)) (interface{}, error) {
+	value := sb.tenant
+	if !sb.hasTenant {
+		if tenant == nil {
+			return nil, errors.New("table '" + b.tableName + "' has tenant field, but tenant isnot set, please invoke Tenant()")
+		}
+		var err error
+		value, err = tenant()
+		if err != nil {
+			return nil, errors.New("table '" + b.tableName + "' read tenant fail, " + err.Error())
+		}
+	}
+	if value == nil {
+		return nil, errors.New("table '" + b.tableName + "' has tenant field, but tenant isnot found")
+	}
+	return value, nil
+}
+
+func (sb *SelectBuilder) toSQL(ctx context.Context, runner gobatis.SqlRunner, isCount bool) (string, []interface{}, error) {
+	return sb.build(runner.Dialect(), runner.Mapper(), func() (interface{}, error) {
+		return runner.Tenant(ctx)
+	}, isCount)
+}
+
+// One 执行查询并返回第一条记录
+func (sb *SelectBuilder) One(ctx context.Context, sess gobatis.SqlSession) gobatis.Result {
+	runner, ok := gobatis.ToSqlRunner(sess)
+	if !ok {
+		return gobatis.ErrorResult(errors.New("session isnot a gobatis.SqlRunner"))
+	}
+	sqlStr, params, err := sb.toSQL(ctx, runner, false)
+	if err != nil {
+		return gobatis.ErrorResult(err)
+	}
+	return runner.QueryRowSQL(ctx, "sqlb.select", sqlStr, params)
+}
+
+// All 执行查询并返回所有的记录
+func (sb *SelectBuilder) All(ctx context.Context, sess gobatis.SqlSession) *gobatis.Results {
+	runner, ok := gobatis.ToSqlRunner(sess)
+	if !ok {
+		return gobatis.ErrorResults(errors.New("session isnot a gobatis.SqlRunner"))
+	}
+	sqlStr, params, err := sb.toSQL(ctx, runner, false)
+	if err != nil {
+		return gobatis.ErrorResults(err)
+	}
+	return runner.QuerySQL(ctx, "sqlb.select", sqlStr, params)
+}
+
+// Count 执行查询并返回记录数
+func (sb *SelectBuilder) Count(ctx context.Context, sess gobatis.SqlSession) (int64, error) {
+	runner, ok := gobatis.ToSqlRunner(sess)
+	if !ok {
+		return 0, errors.New("session isnot a gobatis.SqlRunner")
+	}
+	sqlStr, params, err := sb.toSQL(ctx, runner, true)
+	if err != nil {
+		return 0, err
+	}
+	var count int64
+	err = runner.QueryRowSQL(ctx, "sqlb.count", sqlStr, params).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,324 @@
+package sqlb_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	gobatis "github.com/runner-mei/GoBatis"
+	"github.com/runner-mei/GoBatis/sqlb"
+	"github.com/runner-mei/GoBatis/tests"
+)
+
+type User struct {
+	TableName struct{}   `db:"sqlb_users"`
+	ID        int64      `db:"id,autoincr,pk"`
+	Name      string     `db:"name"`
+	Status    int        `db:"status"`
+	Group     string     `db:"group,quote"`
+	Secret    string     `db:"secret,encrypt"`
+	DeletedAt *time.Time `db:"deleted_at,deleted"`
+}
+
+var mapper = gobatis.CreateMapper("", nil, nil)
+
+func TestSelectToSQL(t *testing.T) {
+	for idx, test := range []struct {
+		dbType  gobatis.Dialect
+		builder *sqlb.SelectBuilder
+		sql     string
+		params  []interface{}
+		err     string
+	}{
+		{
+			builder: sqlb.Select("id", "Name").From(User{}).
+				Where(sqlb.Eq("status", 1), sqlb.Like("name", "ab")).
+				OrderBy("-id").
+				Limit(20),
+			sql:    `SELECT id, name FROM sqlb_users WHERE deleted_at IS NULL AND status = $1 AND name LIKE $2 ORDER BY id DESC LIMIT 20`,
+			params: []interface{}{1, "%ab%"},
+		},
+		{
+			dbType: gobatis.DbTypeMysql,
+			builder: sqlb.Select("id", "group").From(&User{}).
+				Where(sqlb.Eq("status", 1), sqlb.Like("name", "")).
+				OrderBy("name", "-id").
+				Offset(10).Limit(20),
+			sql:    "SELECT id, `group` FROM sqlb_users WHERE deleted_at IS NULL AND status = ? ORDER BY name, id DESC OFFSET 10 LIMIT 20",
+			params: []interface{}{1},
+		},
+		{
+			builder: sqlb.Select().From(reflect.TypeOf(User{})).WithDeleted().
+				Where(sqlb.Or(sqlb.StartsWith("name", "a"), sqlb.In("id", []int64{1, 2})),
+					sqlb.Or(sqlb.EndsWith("name", ""), sqlb.NotIn("id", []int64{})),
+					sqlb.Expr("status > ? AND status < ?", 1, 5)),
+			sql:    `SELECT * FROM sqlb_users WHERE (name LIKE $1 OR id IN ($2, $3)) AND (status > $4 AND status < $5)`,
+			params: []interface{}{"a%", int64(1), int64(2), 1, 5},
+		},
+		{
+			dbType: gobatis.DbTypeSqlite,
+			builder: sqlb.Select("id").From(User{}).WithDeleted().
+				Where(sqlb.StartsWith("name", "a%"), sqlb.EndsWith("name", "_b"), sqlb.Like("name", "a_b%")),
+			sql:    `SELECT id FROM sqlb_users WHERE name LIKE ? ESCAPE '\' AND name LIKE ? ESCAPE '\' AND name LIKE ?`,
+			params: []interface{}{`a\%%`, `%\_b`, "a_b%"},
+		},
+		{
+			builder: sqlb.Select("id").From(User{}).WithDeleted().
+				Where(sqlb.Eq("name", nil), sqlb.Ne("status", nil), sqlb.In("id", []int{}), sqlb.IsNotNull("deleted_at")),
+			sql: `SELECT id FROM sqlb_users WHERE name IS NULL AND status IS NOT NULL AND 1 <> 1 AND deleted_at IS NOT NULL`,
+		},
+		{
+			builder: sqlb.Select("id", "name").From("users").Where(sqlb.Gte("age", 10), sqlb.Lt("age", 20)).OrderBy("u.name asc"),
+			sql:     `SELECT id, name FROM users WHERE age >= $1 AND age < $2 ORDER BY u.name ASC`,
+			params:  []interface{}{10, 20},
+		},
+		{
+			builder: sqlb.Select("abc").From(User{}),
+			err:     "column 'abc' isnot found in 'sqlb_users'",
+		},
+		{
+			builder: sqlb.Select("id").From(User{}).Where(sqlb.Eq("secret", "a")),
+			err:     "column 'secret' is encrypted",
+		},
+		{
+			builder: sqlb.Select("id").From(User{}).OrderBy("secret"),
+			err:     "sort column 'secret' isnot allowed",
+		},
+		{
+			builder: sqlb.Select("id").From(User{}).OrderBy("id; drop table users"),
+			err:     "sort column 'id;' isnot allowed",
+		},
+		{
+			builder: sqlb.Select("id").From("users").Where(sqlb.In("id", 1)),
+			err:     "values of 'id' must is a slice",
+		},
+		{
+			builder: sqlb.Select("id").From("users").Where(sqlb.Expr("id = ?")),
+			err:     "has too many placeholders",
+		},
+		{
+			builder: sqlb.Select("id"),
+			err:     "table is missing",
+		},
+	} {
+		dbType := test.dbType
+		if dbType == nil {
+			dbType = gobatis.DbTypePostgres
+		}
+
+		sqlStr, params, err := test.builder.ToSQL(dbType, mapper)
+		if test.err != "" {
+			if err == nil {
+				t.Error("[", idx, "] excepted error got ok")
+			} else if !strings.Contains(err.Error(), test.err) {
+				t.Error("[", idx, "] excepted is", test.err)
+				t.Error("[", idx, "] actual   is", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+
+		if sqlStr != test.sql {
+			t.Error("[", idx, "] excepted is", test.sql)
+			t.Error("[", idx, "] actual   is", sqlStr)
+		}
+		if len(params) != 0 || len(test.params) != 0 {
+			if !reflect.DeepEqual(params, test.params) {
+				t.Error("[", idx, "] excepted is", test.params)
+				t.Error("[", idx, "] actual   is", params)
+			}
+		}
+	}
+
+	sqlStr, params, err := sqlb.Select("id").From(User{}).
+		Where(sqlb.Eq("status", 1)).OrderBy("-id").Limit(20).
+		ToCountSQL(gobatis.DbTypePostgres, mapper)
+	if err != nil {
+		t.Error(err)
+	} else if excepted := `SELECT count(*) FROM sqlb_users WHERE deleted_at IS NULL AND status = $1`; sqlStr != excepted {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", sqlStr)
+	} else if len(params) != 1 || params[0] != 1 {
+		t.Error("excepted is [1]")
+		t.Error("actual   is", params)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	factory, err := gobatis.New(&gobatis.Config{DriverName: tests.TestDrv,
+		DataSource: tests.TestConnURL,
+		Tracer:     gobatis.NullTracer{}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer factory.Close()
+
+	ctx := context.Background()
+	factory.DB().ExecContext(ctx, `DROP TABLE IF EXISTS sqlb_users`)
+	if _, err := factory.DB().ExecContext(ctx, `CREATE TABLE sqlb_users (id bigint, name varchar(50), status int, "group" varchar(50), secret varchar(50), deleted_at timestamp NULL)`); err != nil {
+		t.Error(err)
+		return
+	}
+	defer factory.DB().ExecContext(ctx, `DROP TABLE sqlb_users`)
+
+	for _, s := range []string{
+		`INSERT INTO sqlb_users(id, name, status) VALUES(1, 'abc', 1)`,
+		`INSERT INTO sqlb_users(id, name, status) VALUES(2, 'abd', 1)`,
+		`INSERT INTO sqlb_users(id, name, status) VALUES(3, 'xbc', 2)`,
+		`INSERT INTO sqlb_users(id, name, status, deleted_at) VALUES(4, 'abe', 1, CURRENT_TIMESTAMP)`,
+	} {
+		if _, err := factory.DB().ExecContext(ctx, s); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	sess := factory.SessionReference()
+
+	var users []User
+	err = sqlb.Select("id", "name").From(User{}).
+		Where(sqlb.Eq("status", 1), sqlb.Like("name", "ab")).
+		OrderBy("-id").
+		Limit(20).
+		All(ctx, sess).
+		ScanSlice(&users)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(users) != 2 || users[0].ID != 2 || users[1].ID != 1 || users[0].Name != "abd" {
+		t.Error("excepted is [2, 1]")
+		t.Error("actual   is", users)
+	}
+
+	var user User
+	err = sqlb.Select("id", "name", "status").From(User{}).Where(sqlb.EndsWith("name", "bc")).OrderBy("id").Offset(1).
+		One(ctx, gobatis.Reference{SqlSession: sess}).
+		Scan(&user)
+	if err != nil {
+		t.Error(err)
+	} else if user.ID != 3 {
+		t.Error("excepted is 3")
+		t.Error("actual   is", user.ID)
+	}
+
+	count, err := sqlb.Select().From(User{}).WithDeleted().Where(sqlb.StartsWith("name", "ab")).Count(ctx, sess)
+	if err != nil {
+		t.Error(err)
+	} else if count != 3 {
+		t.Error("excepted is 3")
+		t.Error("actual   is", count)
+	}
+
+	err = sqlb.Select().From(User{}).OrderBy("abc").All(ctx, sess).ScanSlice(&users)
+	if err == nil {
+		t.Error("excepted error got ok")
+	} else if !strings.Contains(err.Error(), "sort column 'abc' isnot allowed") {
+		t.Error(err)
+	}
+}
+
+type TenantUser struct {
+	TableName struct{} `db:"sqlb_tenant_users"`
+	ID        int64    `db:"id,autoincr,pk"`
+	Name      string   `db:"name"`
+	TenantID  int64    `db:"tenant_id,tenant"`
+}
+
+type tenantKey struct{}
+
+func TestSelectTenant(t *testing.T) {
+	sqlStr, params, err := sqlb.Select("id").From(TenantUser{}).
+		Where(sqlb.Eq("name", "abc")).
+		Tenant(int64(2)).
+		ToSQL(gobatis.DbTypePostgres, mapper)
+	if err != nil {
+		t.Error(err)
+	} else if excepted := `SELECT id FROM sqlb_tenant_users WHERE name = $1 AND tenant_id = $2`; sqlStr != excepted {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", sqlStr)
+	} else if !reflect.DeepEqual(params, []interface{}{"abc", int64(2)}) {
+		t.Error("excepted is [abc 2]")
+		t.Error("actual   is", params)
+	}
+
+	sqlStr, _, err = sqlb.Select("id").From(TenantUser{}).ToSQL(gobatis.DbTypePostgres, mapper.WithoutTenant())
+	if err != nil {
+		t.Error(err)
+	} else if excepted := `SELECT id FROM sqlb_tenant_users`; sqlStr != excepted {
+		t.Error("excepted is", excepted)
+		t.Error("actual   is", sqlStr)
+	}
+
+	_, _, err = sqlb.Select("id").From(TenantUser{}).ToSQL(gobatis.DbTypePostgres, mapper)
+	if err == nil {
+		t.Error("excepted error got ok")
+	} else if !strings.Contains(err.Error(), "tenant isnot set") {
+		t.Error(err)
+	}
+
+	factory, err := gobatis.New(&gobatis.Config{DriverName: tests.TestDrv,
+		DataSource: tests.TestConnURL,
+		Tracer:     gobatis.NullTracer{},
+		TenantResolver: func(ctx context.Context) (interface{}, error) {
+			return ctx.Value(tenantKey{}), nil
+		}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer factory.Close()
+
+	ctx := context.Background()
+	factory.DB().ExecContext(ctx, `DROP TABLE IF EXISTS sqlb_tenant_users`)
+	if _, err := factory.DB().ExecContext(ctx, `CREATE TABLE sqlb_tenant_users (id bigint, name varchar(50), tenant_id bigint)`); err != nil {
+		t.Error(err)
+		return
+	}
+	defer factory.DB().ExecContext(ctx, `DROP TABLE sqlb_tenant_users`)
+
+	for _, s := range []string{
+		`INSERT INTO sqlb_tenant_users(id, name, tenant_id) VALUES(1, 'abc', 1)`,
+		`INSERT INTO sqlb_tenant_users(id, name, tenant_id) VALUES(2, 'abd', 2)`,
+		`INSERT INTO sqlb_tenant_users(id, name, tenant_id) VALUES(3, 'abe', 2)`,
+	} {
+		if _, err := factory.DB().ExecContext(ctx, s); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	sess := factory.SessionReference()
+
+	var users []TenantUser
+	err = sqlb.Select("id", "name").From(TenantUser{}).OrderBy("id").
+		All(context.WithValue(ctx, tenantKey{}, int64(2)), sess).
+		ScanSlice(&users)
+	if err != nil {
+		t.Error(err)
+	} else if len(users) != 2 || users[0].ID != 2 || users[1].ID != 3 {
+		t.Error("excepted is [2, 3]")
+		t.Error("actual   is", users)
+	}
+
+	count, err := sqlb.Select().From(TenantUser{}).Count(context.WithValue(ctx, tenantKey{}, int64(1)), sess)
+	if err != nil {
+		t.Error(err)
+	} else if count != 1 {
+		t.Error("excepted is 1")
+		t.Error("actual   is", count)
+	}
+
+	_, err = sqlb.Select().From(TenantUser{}).Count(ctx, sess)
+	if err == nil {
+		t.Error("excepted error got ok")
+	} else if !strings.Contains(err.Error(), "tenant isnot found") {
+		t.Error(err)
+	}
+}
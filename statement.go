@@ -54,10 +54,17 @@ var (
 )
 
 type Param struct {
-	Name    string
-	Type    string
-	Null    sql.NullBool
-	NotNull sql.NullBool
+	Name       string
+	Type       string
+	Handler    string
+	Encoding   string
+	Encrypt    bool
+	BlindIndex bool
+	Version    bool
+	Tenant     bool
+	Operator   bool
+	Null       sql.NullBool
+	NotNull    sql.NullBool
 }
 
 type Params []Param
@@ -97,6 +104,24 @@ func parseParam(s string) (Param, error) {
 		switch strings.ToLower(strings.TrimSpace(key)) {
 		case "type":
 			param.Type = value
+		case "handler":
+			param.Handler = value
+		case "enum", "text", "binary":
+			encoding, err := toEncoding(strings.ToLower(strings.TrimSpace(key)), value)
+			if err != nil {
+				return Param{Name: s}, errors.New("param '" + s + "' is syntex error - " + err.Error())
+			}
+			param.Encoding = encoding
+		case "encrypt":
+			param.Encrypt = value != "false"
+		case "blindindex":
+			param.BlindIndex = value != "false"
+		case "version":
+			param.Version = value != "false"
+		case "tenant":
+			param.Tenant = value != "false"
+		case "creator", "updater":
+			param.Operator = value != "false"
 		case "null":
 			param.Null.Valid = true
 			param.Null.Bool = value == "true"
@@ -125,6 +150,31 @@ type MappedStatement struct {
 	result      ResultType
 	rawSQL      string
 	dynamicSQLs []DynamicSQL
+	audit       *auditStatement
+	recordTypes []reflect.Type
+}
+
+// AddRecordTypes 记录语句的参数和返回值中用到的记录类型， 初始化时的表结构检查(见
+// Config.SchemaCheck)会检查它们， 生成的代码会为每个语句调用它， 包括 xml 中定义的语句
+func (stmt *MappedStatement) AddRecordTypes(types ...reflect.Type) {
+	for _, rType := range types {
+		for rType.Kind() == reflect.Ptr || rType.Kind() == reflect.Slice || rType.Kind() == reflect.Array {
+			rType = rType.Elem()
+		}
+		if rType.Kind() != reflect.Struct {
+			continue
+		}
+		exists := false
+		for _, t := range stmt.recordTypes {
+			if t == rType {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			stmt.recordTypes = append(stmt.recordTypes, rType)
+		}
+	}
 }
 
 func (stmt *MappedStatement) SQLStrings() []string {
@@ -0,0 +1,89 @@
+package gobatis
+
+import (
+	"strings"
+)
+
+func isExceptedStatement(name string, prefixs, suffixs, fullnames []string) bool {
+	name = strings.ToLower(name)
+	for _, prefix := range prefixs {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+
+	for _, suffix := range suffixs {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	for _, fullname := range fullnames {
+		if name == fullname {
+			return true
+		}
+	}
+	return false
+}
+
+// IsInsertStatement 判断方法名是不是一个 insert 语句， 如 InsertXXX， AddXXX 和 CreateXXX
+func IsInsertStatement(name string) bool {
+	return isExceptedStatement(name, []string{
+		"insert",
+		"upsert",
+		"add",
+		"create",
+	}, nil, nil)
+}
+
+// IsUpdateStatement 判断方法名是不是一个 update 语句， 如 UpdateXXX， SetXXX 和 RestoreXXX
+func IsUpdateStatement(name string) bool {
+	return isExceptedStatement(name, []string{
+		"set",
+		"update",
+		"write",
+		"restore",
+	}, nil, nil)
+}
+
+// IsDeleteStatement 判断方法名是不是一个 delete 语句， 如 DeleteXXX 和 RemoveXXX
+func IsDeleteStatement(name string) bool {
+	return isExceptedStatement(name, []string{
+		"delete",
+		"remove",
+		"clear",
+	}, nil, nil)
+}
+
+// IsSelectStatement 判断方法名是不是一个 select 语句， 如 FindXXX， GetXXX， QueryXXX 和 ForEachXXX
+func IsSelectStatement(name string) bool {
+	return isExceptedStatement(name, []string{
+		"select",
+		"find",
+		"get",
+		"query",
+		"list",
+		"count",
+		"read",
+		"load",
+		"statby",
+		"statsby",
+		"foreach",
+	}, []string{"count", "foreach"}, []string{"id", "all", "names", "titles"})
+}
+
+// StatementTypeByName 按方法名推断语句的类型， 无法推断时返回 StatementTypeNone
+func StatementTypeByName(name string) StatementType {
+	if IsInsertStatement(name) {
+		return StatementTypeInsert
+	}
+	if IsUpdateStatement(name) {
+		return StatementTypeUpdate
+	}
+	if IsDeleteStatement(name) {
+		return StatementTypeDelete
+	}
+	if IsSelectStatement(name) {
+		return StatementTypeSelect
+	}
+	return StatementTypeNone
+}
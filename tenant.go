@@ -0,0 +1,93 @@
+package gobatis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// 多租户
+//
+// 字段上加上 tenant 标签(如 `db:"tenant_id,tenant"`)后， 生成的 insert 语句会用
+// #{tenant_id,tenant} 来填充这个字段， 生成的 select, count, update 和 delete 语句会
+// 自动加上 tenant_id=#{tenant_id,tenant} 条件， 带有 tenant 选项的参数的值是由
+// Config.TenantResolver 从 context 中读取的。
+//
+// 管理员用的方法可以在注释中用 "@option tenant ignore" 来忽略租户条件，
+// 生成的代码会用 Mapper.WithoutTenant() 来生成 sql
+
+// TenantResolver 从 context 中读取当前的租户
+type TenantResolver func(ctx context.Context) (interface{}, error)
+
+// WithoutTenant 返回一个忽略 tenant 标签的 Mapper， 用它生成的 sql 不会加上租户的条件
+func (m *Mapper) WithoutTenant() *Mapper {
+	copyed := m.clone()
+	copyed.ignoreTenant = true
+	return copyed
+}
+
+func isTenantField(mapper *Mapper, field *FieldInfo) bool {
+	if mapper.ignoreTenant || field == nil || field.Options == nil {
+		return false
+	}
+	_, ok := field.Options["tenant"]
+	return ok
+}
+
+func findTenantField(mapper *Mapper, rType reflect.Type) *FieldInfo {
+	if mapper.ignoreTenant {
+		return nil
+	}
+	structType := mapper.TypeMap(rType)
+	for idx := range structType.Index {
+		if isTenantField(mapper, structType.Index[idx]) {
+			return structType.Index[idx]
+		}
+	}
+	return nil
+}
+
+// TenantField 返回 rType 中带有 tenant 标签的字段， 没有这个字段或 mapper 忽略租户时返回 nil，
+// sqlb 等 sql 构建器用它来加上租户的条件
+func TenantField(mapper *Mapper, rType reflect.Type) *FieldInfo {
+	return findTenantField(mapper, rType)
+}
+
+func tenantParam(field *FieldInfo) string {
+	return "#{" + field.Name + ",tenant}"
+}
+
+func tenantCondition(dbType Dialect, field *FieldInfo) string {
+	return quoteColumn(dbType, field) + "=" + tenantParam(field)
+}
+
+// appendTenantFilter 将租户的条件追加到 where 的表达式中
+func appendTenantFilter(dbType Dialect, mapper *Mapper, rType reflect.Type, exprs []string) []string {
+	if field := findTenantField(mapper, rType); field != nil {
+		return append(exprs, tenantCondition(dbType, field))
+	}
+	return exprs
+}
+
+func writeTenantCondition(sb *strings.Builder, dbType Dialect, mapper *Mapper, rType reflect.Type) {
+	if field := findTenantField(mapper, rType); field != nil {
+		sb.WriteString(" AND ")
+		sb.WriteString(tenantCondition(dbType, field))
+	}
+}
+
+func (bc *Context) tenantValue(param *Param) (interface{}, error) {
+	if bc.tenant == nil {
+		return nil, errors.New("param '" + param.Name + "' is tenant, but tenant resolver isnot configured")
+	}
+	value, err := bc.tenant()
+	if err != nil {
+		return nil, fmt.Errorf("param '%s' read tenant fail, %s", param.Name, err)
+	}
+	if value == nil {
+		return nil, errors.New("param '" + param.Name + "' is tenant, but tenant isnot found in the context")
+	}
+	return toSQLType(bc.Dialect, param, value)
+}
@@ -0,0 +1,89 @@
+package gobatis_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	gobatis "github.com/runner-mei/GoBatis"
+)
+
+type tenantKey struct{}
+
+func TestTenant(t *testing.T) {
+	rType := reflect.TypeOf(&T22{})
+	factory, teardown := setupFixture(t, &gobatis.Config{
+		TenantResolver: func(ctx context.Context) (interface{}, error) {
+			return ctx.Value(tenantKey{}), nil
+		}}, rType, []fixtureStatement{
+		{id: "T22.insert", stmtType: gobatis.StatementTypeInsert, generate: func(ctx *gobatis.InitContext) (string, error) {
+			return gobatis.GenerateInsertSQL(ctx.Dialect, ctx.Mapper, rType, []string{"r"}, []reflect.Type{rType}, false)
+		}},
+		{id: "T22.count", stmtType: gobatis.StatementTypeSelect, generate: func(ctx *gobatis.InitContext) (string, error) {
+			return gobatis.GenerateCountSQL(ctx.Dialect, ctx.Mapper, rType, nil, nil, nil)
+		}},
+		{id: "T22.countAll", stmtType: gobatis.StatementTypeSelect, generate: func(ctx *gobatis.InitContext) (string, error) {
+			return gobatis.GenerateCountSQL(ctx.Dialect, ctx.Mapper.WithoutTenant(), rType, nil, nil, nil)
+		}},
+		{id: "T22.deleteAll", stmtType: gobatis.StatementTypeDelete, generate: func(ctx *gobatis.InitContext) (string, error) {
+			return gobatis.GenerateDeleteSQL(ctx.Dialect, ctx.Mapper, rType, nil, nil, nil)
+		}},
+	})
+	defer teardown()
+	if factory == nil {
+		return
+	}
+	ctx := context.Background()
+
+	ref := factory.SessionReference()
+	ctx1 := context.WithValue(ctx, tenantKey{}, int64(1))
+	ctx2 := context.WithValue(ctx, tenantKey{}, int64(2))
+
+	for _, c := range []context.Context{ctx1, ctx1, ctx2} {
+		record := &T22{Name: "a"}
+		if _, err := ref.Insert(c, "T22.insert", []string{"r"}, []interface{}{record}); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	_, err := ref.Insert(ctx, "T22.insert", []string{"r"}, []interface{}{&T22{Name: "a"}})
+	if err == nil {
+		t.Error("excepted error got ok")
+	} else if !strings.Contains(err.Error(), "tenant isnot found") {
+		t.Error(err)
+	}
+
+	for idx, test := range []struct {
+		ctx      context.Context
+		id       string
+		excepted int64
+	}{
+		{ctx: ctx1, id: "T22.count", excepted: 2},
+		{ctx: ctx2, id: "T22.count", excepted: 1},
+		{ctx: ctx, id: "T22.countAll", excepted: 3},
+	} {
+		var count int64
+		if err := ref.SelectOne(test.ctx, test.id, nil, nil).Scan(&count); err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		if count != test.excepted {
+			t.Error("[", idx, "] excepted is", test.excepted)
+			t.Error("[", idx, "] actual   is", count)
+		}
+	}
+
+	if _, err := ref.Delete(ctx2, "T22.deleteAll", nil, nil); err != nil {
+		t.Error(err)
+		return
+	}
+	var count int64
+	if err := ref.SelectOne(ctx, "T22.countAll", nil, nil).Scan(&count); err != nil {
+		t.Error(err)
+	} else if count != 2 {
+		t.Error("excepted is 2")
+		t.Error("actual   is", count)
+	}
+}
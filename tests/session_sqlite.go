@@ -0,0 +1,7 @@
+// +build cgo
+
+package tests
+
+import (
+	_ "github.com/mattn/go-sqlite3"
+)
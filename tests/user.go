@@ -199,6 +199,12 @@ type TestUserGroups interface {
 	//          GROUP BY groups.id
 	//         -- see JSON_OBJECTAGG and JSON_ARRAYAGG
 	//
+	// @sqlite SELECT groups.id, groups.name, json_group_array(u2g.user_id) as user_ids
+	//          FROM gobatis_usergroups as groups LEFT JOIN gobatis_user_and_groups as u2g
+	//               ON groups.id = u2g.group_id
+	//          WHERE groups.id = #{id}
+	//          GROUP BY groups.id
+	//
 	// @mssql SELECT groups.id, MIN(groups.name) as name, CONCAT('[', STUFF((
 	//             SELECT ',' + CAST(u2g.user_id AS varchar(100))
 	//             FROM gobatis_user_and_groups as u2g
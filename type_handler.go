@@ -1,5 +1,166 @@
 package gobatis
 
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// TypeHandler 负责 go 类型和数据库值之间的转换， 用于那些无法自已实现
+// sql.Scanner 和 driver.Valuer 的类型(如第三方库中的 decimal 和 money 等)
 type TypeHandler interface {
-	Convert()
+	// ToDB 将 value 转换为数据库驱动能接受的值， value 不会为 nil， 指针会被解引用
+	ToDB(dialect Dialect, value interface{}) (interface{}, error)
+
+	// FromDB 将数据库返回的值转换为 go 的值， src 不会为 nil
+	FromDB(dialect Dialect, src interface{}) (interface{}, error)
+}
+
+var (
+	typeHandlerLock   sync.RWMutex
+	typeHandlers      = map[reflect.Type]TypeHandler{}
+	namedTypeHandlers = map[string]TypeHandler{}
+)
+
+// RegisterTypeHandler 注册一个类型转换器， typ 为 nil 时只按名称注册，
+// names 用于在字段上用 `db:"price,handler=money"` 或在 sql 中用
+// #{price,handler=money} 来指定转换器， 名称不区分大小写。
+//
+// 注意转换器在创建映射时就被确定了， 所以应该在初始化时注册
+func RegisterTypeHandler(typ reflect.Type, handler TypeHandler, names ...string) {
+	if handler == nil {
+		panic(errors.New("type handler is nil"))
+	}
+
+	typeHandlerLock.Lock()
+	defer typeHandlerLock.Unlock()
+
+	if typ != nil {
+		typeHandlers[typ] = handler
+	}
+	for _, name := range names {
+		namedTypeHandlers[strings.ToLower(name)] = handler
+	}
+}
+
+func typeHandlerByName(name string) (TypeHandler, error) {
+	typeHandlerLock.RLock()
+	handler := namedTypeHandlers[strings.ToLower(name)]
+	typeHandlerLock.RUnlock()
+
+	if handler == nil {
+		return nil, errors.New("type handler '" + name + "' isnot found")
+	}
+	return handler, nil
+}
+
+// typeHandlerByType 查找 typ 的转换器， typ 为指针时也会查找它指向的类型
+func typeHandlerByType(typ reflect.Type) TypeHandler {
+	typeHandlerLock.RLock()
+	defer typeHandlerLock.RUnlock()
+
+	if len(typeHandlers) == 0 {
+		return nil
+	}
+	if handler := typeHandlers[typ]; handler != nil {
+		return handler
+	}
+	if typ.Kind() == reflect.Ptr {
+		return typeHandlers[typ.Elem()]
+	}
+	return nil
+}
+
+func (fi *FieldInfo) typeHandler() (TypeHandler, error) {
+	if name := fi.Options["handler"]; name != "" {
+		return typeHandlerByName(name)
+	}
+	name, err := fieldEncoding(fi)
+	if err != nil {
+		return nil, err
+	}
+	if name != "" {
+		return makeEncodingHandler(name, fi.Field.Type)
+	}
+	return typeHandlerByType(fi.Field.Type), nil
+}
+
+func typeHandlerToDB(dialect Dialect, handler TypeHandler, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if rv := reflect.ValueOf(value); rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		value = rv.Elem().Interface()
+	}
+	return handler.ToDB(dialect, value)
+}
+
+var _ sql.Scanner = &typeHandlerScanner{}
+
+type typeHandlerScanner struct {
+	dialect Dialect
+	name    string
+	handler TypeHandler
+	field   reflect.Value
+
+	Valid bool
+}
+
+func (s *typeHandlerScanner) Scan(src interface{}) error {
+	if src == nil {
+		s.field.Set(reflect.Zero(s.field.Type()))
+		return nil
+	}
+
+	value, err := s.handler.FromDB(s.dialect, src)
+	if err != nil {
+		return fmt.Errorf("column %s convert to '%s' fail, %s", s.name, s.field.Type(), err)
+	}
+	if value == nil {
+		s.field.Set(reflect.Zero(s.field.Type()))
+		return nil
+	}
+
+	rv := reflect.ValueOf(value)
+	typ := s.field.Type()
+	switch {
+	case rv.Type().AssignableTo(typ):
+		s.field.Set(rv)
+	case typ.Kind() == reflect.Ptr && rv.Type().AssignableTo(typ.Elem()):
+		ptr := reflect.New(typ.Elem())
+		ptr.Elem().Set(rv)
+		s.field.Set(ptr)
+	case rv.Kind() == reflect.Ptr && rv.Type().Elem().AssignableTo(typ):
+		if rv.IsNil() {
+			s.field.Set(reflect.Zero(typ))
+		} else {
+			s.field.Set(rv.Elem())
+		}
+	case rv.Kind() == typ.Kind() && rv.Type().ConvertibleTo(typ):
+		s.field.Set(rv.Convert(typ))
+	default:
+		return fmt.Errorf("column %s convert to '%s' fail, type handler return '%T'", s.name, typ, value)
+	}
+	s.Valid = true
+	return nil
+}
+
+// makeTypeHandlerScanner 当 dest 的类型注册了转换器时返回一个用该转换器的扫描器，
+// 否则原样返回 dest
+func makeTypeHandlerScanner(dialect Dialect, name string, dest interface{}) interface{} {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return dest
+	}
+	handler := typeHandlerByType(rv.Type().Elem())
+	if handler == nil {
+		return dest
+	}
+	return &typeHandlerScanner{dialect: dialect, name: name, handler: handler, field: rv.Elem()}
 }
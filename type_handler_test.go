@@ -0,0 +1,258 @@
+package gobatis_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	gobatis "github.com/runner-mei/GoBatis"
+)
+
+type money struct {
+	cents int64
+}
+
+type moneyHandler struct{}
+
+func (moneyHandler) ToDB(dialect gobatis.Dialect, value interface{}) (interface{}, error) {
+	m, ok := value.(money)
+	if !ok {
+		return nil, fmt.Errorf("want money got %T", value)
+	}
+	return fmt.Sprintf("%d.%02d", m.cents/100, m.cents%100), nil
+}
+
+func (moneyHandler) FromDB(dialect gobatis.Dialect, src interface{}) (interface{}, error) {
+	var s string
+	switch v := src.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return nil, fmt.Errorf("want string got %T", src)
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return nil, err
+	}
+	return money{cents: int64(f*100 + 0.5)}, nil
+}
+
+type upperHandler struct{}
+
+func (upperHandler) ToDB(dialect gobatis.Dialect, value interface{}) (interface{}, error) {
+	s, ok := value.(string)
+	if !ok {
+		return nil, errors.New("want string")
+	}
+	return strings.ToUpper(s), nil
+}
+
+func (upperHandler) FromDB(dialect gobatis.Dialect, src interface{}) (interface{}, error) {
+	bs, ok := src.([]byte)
+	if !ok {
+		return nil, errors.New("want []byte")
+	}
+	return strings.ToLower(string(bs)), nil
+}
+
+type dialectName string
+
+type dialectNameHandler struct{}
+
+func (dialectNameHandler) ToDB(dialect gobatis.Dialect, value interface{}) (interface{}, error) {
+	return value, nil
+}
+
+func (dialectNameHandler) FromDB(dialect gobatis.Dialect, src interface{}) (interface{}, error) {
+	if dialect == nil {
+		return nil, errors.New("dialect is nil")
+	}
+	return dialectName(dialect.Name()), nil
+}
+
+type typeHandlerRecord struct {
+	Price    money  `db:"price"`
+	PricePtr *money `db:"price_ptr"`
+	Code     string `db:"code,handler=upper"`
+	Bad      string `db:"bad,handler=notexists"`
+}
+
+type fakeRows struct {
+	columns []string
+	values  []interface{}
+}
+
+func (r *fakeRows) Columns() ([]string, error) { return r.columns, nil }
+func (r *fakeRows) Err() error                 { return nil }
+func (r *fakeRows) Scan(dest ...interface{}) error {
+	for idx := range dest {
+		if err := dest[idx].(interface{ Scan(interface{}) error }).Scan(r.values[idx]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestTypeHandler(t *testing.T) {
+	gobatis.RegisterTypeHandler(reflect.TypeOf(money{}), moneyHandler{})
+	gobatis.RegisterTypeHandler(nil, upperHandler{}, "Upper")
+
+	mapper := gobatis.CreateMapper("", nil, nil)
+	tm := mapper.TypeMap(reflect.TypeOf(typeHandlerRecord{}))
+
+	record := &typeHandlerRecord{Price: money{cents: 1234}, Code: "abc"}
+	rv := reflect.ValueOf(record).Elem()
+	for idx, test := range []struct {
+		column   string
+		excepted interface{}
+	}{
+		{column: "price", excepted: "12.34"},
+		{column: "price_ptr", excepted: nil},
+		{column: "code", excepted: "ABC"},
+	} {
+		value, err := tm.Names[test.column].RValue(gobatis.DbTypePostgres, &gobatis.Param{Name: test.column}, rv)
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		if value != test.excepted {
+			t.Error("[", idx, "] excepted is", test.excepted)
+			t.Error("[", idx, "] actual   is", value)
+		}
+	}
+
+	if _, err := tm.Names["bad"].RValue(gobatis.DbTypePostgres, &gobatis.Param{Name: "bad"}, rv); err == nil {
+		t.Error("excepted error got ok")
+	} else if !strings.Contains(err.Error(), "notexists") {
+		t.Error(err)
+	}
+
+	var scanned typeHandlerRecord
+	rv = reflect.ValueOf(&scanned).Elem()
+	for idx, test := range []struct {
+		column string
+		src    interface{}
+	}{
+		{column: "price", src: []byte("5.60")},
+		{column: "price_ptr", src: "7.01"},
+		{column: "code", src: []byte("XYZ")},
+	} {
+		scanner, err := tm.Names[test.column].LValue(gobatis.DbTypePostgres, test.column, rv)
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		if err := scanner.(interface{ Scan(interface{}) error }).Scan(test.src); err != nil {
+			t.Error("[", idx, "]", err)
+		}
+	}
+	if scanned.Price.cents != 560 {
+		t.Error("excepted is 560")
+		t.Error("actual   is", scanned.Price.cents)
+	}
+	if scanned.PricePtr == nil || scanned.PricePtr.cents != 701 {
+		t.Error("excepted is 701")
+		t.Error("actual   is", scanned.PricePtr)
+	}
+	if scanned.Code != "xyz" {
+		t.Error("excepted is xyz")
+		t.Error("actual   is", scanned.Code)
+	}
+
+	var m money
+	err := gobatis.ScanAny(gobatis.DbTypePostgres, mapper, &fakeRows{columns: []string{"price"}, values: []interface{}{"1.5"}}, &m, false, false)
+	if err != nil {
+		t.Error(err)
+	} else if m.cents != 150 {
+		t.Error("excepted is 150")
+		t.Error("actual   is", m.cents)
+	}
+
+	m = money{}
+	nullable := &gobatis.Nullable{Name: "price", Value: &m}
+	if err := nullable.Scan([]byte("0.07")); err != nil {
+		t.Error(err)
+	} else if m.cents != 7 {
+		t.Error("excepted is 7")
+		t.Error("actual   is", m.cents)
+	}
+
+	gobatis.RegisterTypeHandler(reflect.TypeOf(dialectName("")), dialectNameHandler{})
+	var name dialectName
+	nullable = &gobatis.Nullable{Dialect: gobatis.DbTypeMysql, Name: "name", Value: &name}
+	if err := nullable.Scan("abc"); err != nil {
+		t.Error(err)
+	} else if name != "mysql" {
+		t.Error("excepted is mysql")
+		t.Error("actual   is", name)
+	}
+
+	name = ""
+	nullable = &gobatis.Nullable{Value: &name}
+	err = gobatis.ScanAny(gobatis.DbTypePostgres, mapper, &fakeRows{columns: []string{"name"}, values: []interface{}{"abc"}}, nullable, false, false)
+	if err != nil {
+		t.Error(err)
+	} else if name != "postgres" {
+		t.Error("excepted is postgres")
+		t.Error("actual   is", name)
+	}
+
+	initCtx := &gobatis.InitContext{Config: &gobatis.Config{},
+		Dialect:    gobatis.DbTypePostgres,
+		Mapper:     mapper,
+		Statements: make(map[string]*gobatis.MappedStatement)}
+	for idx, test := range []struct {
+		sql         string
+		paramNames  []string
+		paramValues []interface{}
+		excepted    []interface{}
+	}{
+		{sql: `select * from t where code = #{code,handler=upper}`,
+			paramNames:  []string{"code"},
+			paramValues: []interface{}{"abc"},
+			excepted:    []interface{}{"ABC"}},
+		{sql: `select * from t where price = #{price}`,
+			paramNames:  []string{"price"},
+			paramValues: []interface{}{money{cents: 100}},
+			excepted:    []interface{}{"1.00"}},
+		{sql: `select * from t where price = #{r.price} and code = #{r.Price,handler=upper}`,
+			paramNames:  []string{"r"},
+			paramValues: []interface{}{&typeHandlerRecord{Price: money{cents: 1}}},
+			excepted:    nil},
+		{sql: `select * from t where price = #{r.price} and code = #{r.code}`,
+			paramNames:  []string{"r"},
+			paramValues: []interface{}{&typeHandlerRecord{Price: money{cents: 1}, Code: "a"}},
+			excepted:    []interface{}{"0.01", "A"}},
+	} {
+		stmt, err := gobatis.NewMapppedStatement(initCtx, "typehandler", gobatis.StatementTypeSelect, gobatis.ResultStruct, test.sql)
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		ctx, err := gobatis.NewContext(nil, gobatis.DbTypePostgres, mapper, test.paramNames, test.paramValues)
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		sqlParams, err := stmt.GenerateSQLs(ctx)
+		if test.excepted == nil {
+			if err == nil {
+				t.Error("[", idx, "] excepted error got ok")
+			}
+			continue
+		}
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		if !reflect.DeepEqual(sqlParams[0].Params, test.excepted) {
+			t.Error("[", idx, "] excepted is", test.excepted)
+			t.Error("[", idx, "] actual   is", sqlParams[0].Params)
+		}
+	}
+}
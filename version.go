@@ -0,0 +1,170 @@
+package gobatis
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+
+	"github.com/runner-mei/GoBatis/reflectx"
+)
+
+// 乐观锁
+//
+// 字段上加上 version 标签(如 `db:"version,version"`)后， 生成的 update 语句会变为
+//
+//   UPDATE xxx SET ..., version=version+1 WHERE id=#{x.id} AND version=#{x.version,version}
+//
+// 带有 version 选项的参数用于乐观锁的检查， 执行后没有记录被更新时返回 ErrStaleObject，
+// 成功时参数中的 version 字段会被加 1， 所以版本所在的参数必须是结构体的指针， 否则返回错误，
+// 而版本是一个普通的参数（如 #{version,version}）时不会回写。
+//
+// upsert 语句插入的版本和更新后的版本都是参数中的版本加 1， 只有版本一致时才更新。
+// 注意 mysql 中版本不一致时是靠影响的行数为 0 来判断的， 所以不能在连接参数中指定 clientFoundRows=true
+
+func isVersionField(field *FieldInfo) bool {
+	if field == nil || field.Options == nil {
+		return false
+	}
+	_, ok := field.Options["version"]
+	return ok
+}
+
+func findVersionField(mapper *Mapper, rType reflect.Type) *FieldInfo {
+	structType := mapper.TypeMap(rType)
+	for idx := range structType.Index {
+		if isVersionField(structType.Index[idx]) {
+			return structType.Index[idx]
+		}
+	}
+	return nil
+}
+
+// writeMysqlVersionIf 生成 IF(version=VALUES(version)-1, then, else)， 用于 mysql 的 upsert
+func writeMysqlVersionIf(sb *strings.Builder, dbType Dialect, field *FieldInfo, then, otherwise string) {
+	column := quoteColumn(dbType, field)
+	sb.WriteString("IF(")
+	sb.WriteString(column)
+	sb.WriteString("=VALUES(")
+	sb.WriteString(column)
+	sb.WriteString(")-1, ")
+	sb.WriteString(then)
+	sb.WriteString(", ")
+	sb.WriteString(otherwise)
+	sb.WriteString(")")
+}
+
+func writeVersionIncr(sb *strings.Builder, dbType Dialect, table string, field *FieldInfo) {
+	column := quoteColumn(dbType, field)
+	sb.WriteString(column)
+	sb.WriteString("=")
+	if table != "" {
+		sb.WriteString(table)
+		sb.WriteString(".")
+	}
+	sb.WriteString(column)
+	sb.WriteString("+1")
+}
+
+// writeWhereWithVersion 将版本检查的条件追加到 where 语句中
+func writeWhereWithVersion(sb *strings.Builder, dbType Dialect, where string, name string, field *FieldInfo) {
+	hasWhereTag := strings.HasSuffix(where, "</where>")
+	if hasWhereTag {
+		where = strings.TrimSuffix(where, "</where>")
+	}
+	sb.WriteString(where)
+	sb.WriteString(" AND ")
+	sb.WriteString(quoteColumn(dbType, field))
+	sb.WriteString("=#{")
+	sb.WriteString(name)
+	sb.WriteString(",version}")
+	if hasWhereTag {
+		sb.WriteString("</where>")
+	}
+}
+
+// versionParam 是乐观锁的版本参数， field 无效时表示版本是一个普通的参数， 无法回写
+type versionParam struct {
+	name  string
+	field reflect.Value
+}
+
+// addVersion 在生成 SQL 时记录版本参数， 它会在执行语句前检查版本能否回写，
+// 版本所在的参数不是结构体的指针时返回错误
+func (bc *Context) addVersion(name string) error {
+	for _, v := range bc.versions {
+		if v.name == name {
+			return nil
+		}
+	}
+	field, err := bc.lookupVersion(name)
+	if err != nil {
+		return err
+	}
+	bc.versions = append(bc.versions, versionParam{name: name, field: field})
+	return nil
+}
+
+// checkVersions 在执行带有乐观锁的语句后检查结果， 并将参数中的版本加 1
+func (bc *Context) checkVersions(rowsAffected int64) error {
+	if len(bc.versions) == 0 {
+		return nil
+	}
+	if rowsAffected == 0 {
+		return ErrStaleObject
+	}
+	for _, v := range bc.versions {
+		if !v.field.IsValid() {
+			continue
+		}
+		switch v.field.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			v.field.SetInt(v.field.Int() + 1)
+		default:
+			v.field.SetUint(v.field.Uint() + 1)
+		}
+	}
+	return nil
+}
+
+// lookupVersion 查找参数中的版本字段， 版本是一个普通的参数（如 #{version,version}）时无法回写，
+// 这时返回一个无效的 reflect.Value
+func (bc *Context) lookupVersion(name string) (reflect.Value, error) {
+	var value interface{}
+	if idx := strings.IndexByte(name, '.'); idx >= 0 {
+		found := false
+		for i := range bc.ParamNames {
+			if bc.ParamNames[i] == name[:idx] {
+				value = bc.ParamValues[i]
+				found = true
+				break
+			}
+		}
+		if !found {
+			return reflect.Value{}, errors.New("version '" + name + "' isnot found in the parameters")
+		}
+		name = name[idx+1:]
+	} else if len(bc.ParamNames) == 0 && len(bc.ParamValues) == 1 {
+		value = bc.ParamValues[0]
+	} else {
+		return reflect.Value{}, nil
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, errors.New("version '" + name + "' cannot be incremented, its parameter must be a pointer to struct, actual is '" + rv.Kind().String() + "'")
+	}
+	rv = rv.Elem()
+
+	fi, _, err := toFieldName(bc.Mapper.TypeMap(rv.Type()), name, nil)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	field := reflectx.FieldByIndexes(rv, fi.Index)
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return field, nil
+	default:
+		return reflect.Value{}, errors.New("field '" + fi.Field.Name + "' is version, it must is a integer")
+	}
+}
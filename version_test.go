@@ -0,0 +1,109 @@
+package gobatis_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	gobatis "github.com/runner-mei/GoBatis"
+)
+
+func TestOptimisticLock(t *testing.T) {
+	rType := reflect.TypeOf(&T21{})
+	factory, teardown := setupFixture(t, nil, rType, []fixtureStatement{
+		{id: "T21.insert", stmtType: gobatis.StatementTypeInsert, generate: func(ctx *gobatis.InitContext) (string, error) {
+			return gobatis.GenerateInsertSQL(ctx.Dialect, ctx.Mapper, rType, []string{"r"}, []reflect.Type{rType}, false)
+		}},
+		{id: "T21.update", stmtType: gobatis.StatementTypeUpdate, generate: func(ctx *gobatis.InitContext) (string, error) {
+			return gobatis.GenerateUpdateSQL(ctx.Dialect, ctx.Mapper, "r.", rType, nil, nil)
+		}},
+		{id: "T21.upsert", stmtType: gobatis.StatementTypeInsert, generate: func(ctx *gobatis.InitContext) (string, error) {
+			return gobatis.GenerateUpsertSQL(ctx.Dialect, ctx.Mapper, rType, nil, nil, nil, false)
+		}},
+	})
+	defer teardown()
+	if factory == nil {
+		return
+	}
+	ctx := context.Background()
+
+	ref := factory.SessionReference()
+	record := &T21{Name: "a", Version: 1}
+	var err error
+	record.ID, err = ref.Insert(ctx, "T21.insert", []string{"r"}, []interface{}{record})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	stale := *record
+
+	record.Name = "b"
+	if _, err := ref.Update(ctx, "T21.update", []string{"r"}, []interface{}{record}); err != nil {
+		t.Error(err)
+		return
+	}
+	if record.Version != 2 {
+		t.Error("excepted is 2")
+		t.Error("actual   is", record.Version)
+	}
+
+	stale.Name = "c"
+	if _, err := ref.Update(ctx, "T21.update", []string{"r"}, []interface{}{&stale}); err != gobatis.ErrStaleObject {
+		t.Error("excepted is", gobatis.ErrStaleObject)
+		t.Error("actual   is", err)
+	}
+	if stale.Version != 1 {
+		t.Error("excepted is 1")
+		t.Error("actual   is", stale.Version)
+	}
+
+	record.Name = "d"
+	if _, err := ref.Update(ctx, "T21.update", []string{"r"}, []interface{}{record}); err != nil {
+		t.Error(err)
+	} else if record.Version != 3 {
+		t.Error("excepted is 3")
+		t.Error("actual   is", record.Version)
+	}
+
+	// 版本所在的参数不是结构体的指针时无法回写版本， 语句不会被执行
+	if _, err := ref.Update(ctx, "T21.update", []string{"r"}, []interface{}{*record}); err == nil {
+		t.Error("excepted error got ok")
+	}
+
+	// upsert 插入和更新后的版本都是参数中的版本加 1
+	upserted := &T21{Name: "e", Version: 1}
+	if _, err := ref.Insert(ctx, "T21.upsert", nil, []interface{}{upserted}); err != nil {
+		t.Error(err)
+		return
+	}
+	if upserted.Version != 2 {
+		t.Error("excepted is 2")
+		t.Error("actual   is", upserted.Version)
+	}
+
+	staleUpserted := *upserted
+	if _, err := ref.Insert(ctx, "T21.upsert", nil, []interface{}{upserted}); err != nil {
+		t.Error(err)
+	} else if upserted.Version != 3 {
+		t.Error("excepted is 3")
+		t.Error("actual   is", upserted.Version)
+	}
+
+	if _, err := ref.Insert(ctx, "T21.upsert", nil, []interface{}{&staleUpserted}); err != gobatis.ErrStaleObject {
+		t.Error("excepted is", gobatis.ErrStaleObject)
+		t.Error("actual   is", err)
+	}
+	if staleUpserted.Version != 2 {
+		t.Error("excepted is 2")
+		t.Error("actual   is", staleUpserted.Version)
+	}
+
+	var version int
+	if err := factory.DB().QueryRowContext(ctx, `SELECT version FROM t21 WHERE name = 'e'`).Scan(&version); err != nil {
+		t.Error(err)
+	} else if version != 3 {
+		t.Error("excepted is 3")
+		t.Error("actual   is", version)
+	}
+}
@@ -0,0 +1,148 @@
+package gobatis
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+)
+
+// 参数名的后缀表示的比较操作， 如 FindUsers(ageGte int, nameStartsWith string) 会生成
+//
+//	age >= #{ageGte} AND name like <like value="nameStartsWith" match="prefix" />
+//
+// 注意 Lt 和 Lte 会生成 #{x} > col 和 #{x} >= col， 这是为了避免在 xml 中使用 '<'
+const (
+	whereOpEqual      = ""
+	whereOpLike       = "like"
+	whereOpNotLike    = "notlike"
+	whereOpStartsWith = "startswith"
+	whereOpEndsWith   = "endswith"
+	whereOpContains   = "contains"
+	whereOpGt         = "gt"
+	whereOpGte        = "gte"
+	whereOpLt         = "lt"
+	whereOpLte        = "lte"
+	whereOpNe         = "ne"
+	whereOpNotIn      = "notin"
+	whereOpIsNull     = "isnull"
+	whereOpIsNotNull  = "isnotnull"
+)
+
+// whereOpSuffixes 中较长的后缀必须在前面， 如 notlike 在 like 前面
+var whereOpSuffixes = []string{
+	whereOpIsNotNull,
+	whereOpStartsWith,
+	whereOpEndsWith,
+	whereOpContains,
+	whereOpNotLike,
+	whereOpIsNull,
+	whereOpNotIn,
+	whereOpLike,
+	whereOpGte,
+	whereOpLte,
+	whereOpGt,
+	whereOpLt,
+	whereOpNe,
+}
+
+func isLikeWhereOp(op string) bool {
+	switch op {
+	case whereOpLike, whereOpNotLike, whereOpStartsWith, whereOpEndsWith, whereOpContains:
+		return true
+	}
+	return false
+}
+
+// isOptionalWhereOp 表示参数为空时不生成这个条件
+func isOptionalWhereOp(op string) bool {
+	return isLikeWhereOp(op) || op == whereOpNotIn
+}
+
+// toWhereField 查找参数对应的字段和比较操作， 参数名和字段名不一致时才检查后缀
+func toWhereField(structType *StructMap, name string, argType reflect.Type) (*FieldInfo, bool, string, error) {
+	field, isSlice, err := toFieldName(structType, name, argType)
+	if err == nil {
+		return field, isSlice, whereOpEqual, nil
+	}
+
+	lower := strings.ToLower(name)
+	for _, op := range whereOpSuffixes {
+		if len(lower) <= len(op) || !strings.HasSuffix(lower, op) {
+			continue
+		}
+
+		fieldArgType := argType
+		if op == whereOpIsNull || op == whereOpIsNotNull {
+			fieldArgType = nil
+		}
+		fi, isArgSlice, e := toFieldName(structType, name[:len(name)-len(op)], fieldArgType)
+		if e != nil {
+			continue
+		}
+		if e := checkWhereOp(name, op, fi, isArgSlice, argType); e != nil {
+			return nil, false, "", e
+		}
+		return fi, isArgSlice, op, nil
+	}
+	return nil, false, "", err
+}
+
+func checkWhereOp(name, op string, field *FieldInfo, isArgSlice bool, argType reflect.Type) error {
+	if IsValueRange(argType) {
+		return errors.New("'" + name + "' is a range, operator '" + op + "' is unsupported")
+	}
+
+	switch op {
+	case whereOpNotIn:
+		if !isArgSlice {
+			return errors.New("'" + name + "' must is a slice")
+		}
+		return nil
+	case whereOpIsNull, whereOpIsNotNull:
+		if argType != nil && argType.Kind() != reflect.Bool {
+			return errors.New("'" + name + "' must is a bool")
+		}
+		return nil
+	}
+
+	if isArgSlice {
+		if isLikeWhereOp(op) {
+			return errors.New("'" + name + "' must cannot is a slice, like array is unsupported")
+		}
+		return errors.New("'" + name + "' must cannot is a slice")
+	}
+	if isLikeWhereOp(op) && field.Field.Type.Kind() != reflect.String {
+		return errors.New("'" + name + "' must cannot is a string, like array is unsupported")
+	}
+	return nil
+}
+
+// whereOpExpr 生成比较操作的表达式， 不包含 IsNull， IsNotNull 和 NotIn
+func whereOpExpr(dbType Dialect, field *FieldInfo, name, op string) string {
+	column := quoteColumn(dbType, field)
+	param := "#{" + name + encodingOption(field) + "}"
+	switch op {
+	case whereOpGt:
+		return column + " > " + param
+	case whereOpGte:
+		return column + " >= " + param
+	case whereOpLt:
+		return param + " > " + column
+	case whereOpLte:
+		return param + " >= " + column
+	case whereOpNe:
+		return "(" + column + " <> " + param + " OR " + column + " IS NULL)"
+	case whereOpStartsWith:
+		return column + ` like <like value="` + name + `" match="prefix" />`
+	case whereOpEndsWith:
+		return column + ` like <like value="` + name + `" match="suffix" />`
+	case whereOpContains:
+		return column + ` like <like value="` + name + `" match="any" />`
+	case whereOpNotLike:
+		return "(" + column + ` NOT LIKE <like value="` + name + `" /> OR ` + column + " IS NULL)"
+	case whereOpLike:
+		return column + ` like <like value="` + name + `" />`
+	default:
+		return column + "=" + param
+	}
+}
@@ -0,0 +1,119 @@
+package gobatis_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	gobatis "github.com/runner-mei/GoBatis"
+	"github.com/runner-mei/GoBatis/tests"
+)
+
+func TestWhereOperators(t *testing.T) {
+	callbacks := gobatis.ClearInit()
+	defer gobatis.SetInit(callbacks)
+
+	_intType := reflect.TypeOf(int(0))
+	_boolType := reflect.TypeOf(true)
+	queries := []struct {
+		id       string
+		names    []string
+		argTypes []reflect.Type
+	}{
+		{id: "T26.ageRange", names: []string{"ageGte", "ageLt"}, argTypes: []reflect.Type{_intType, _intType}},
+		{id: "T26.ageLte", names: []string{"ageLte", "nameStartsWith"}, argTypes: []reflect.Type{_intType, _stringType}},
+		{id: "T26.nameNe", names: []string{"nameNe"}, argTypes: []reflect.Type{_stringType}},
+		{id: "T26.nameStartsWith", names: []string{"nameStartsWith"}, argTypes: []reflect.Type{_stringType}},
+		{id: "T26.nameEndsWith", names: []string{"nameEndsWith"}, argTypes: []reflect.Type{_stringType}},
+		{id: "T26.nameContains", names: []string{"nameContains"}, argTypes: []reflect.Type{_stringType}},
+		{id: "T26.nameNotLike", names: []string{"nameNotLike"}, argTypes: []reflect.Type{_stringType}},
+		{id: "T26.idNotIn", names: []string{"idNotIn"}, argTypes: []reflect.Type{reflect.TypeOf([]int64{})}},
+		{id: "T26.nameIsNull", names: []string{"ageGt", "nameIsNull"}, argTypes: []reflect.Type{_intType, _boolType}},
+	}
+
+	gobatis.Init(func(ctx *gobatis.InitContext) error {
+		for _, query := range queries {
+			sqlStr, err := gobatis.GenerateCountSQL(ctx.Dialect, ctx.Mapper, reflect.TypeOf(&T26{}), query.names, query.argTypes, nil)
+			if err != nil {
+				return err
+			}
+			stmt, err := gobatis.NewMapppedStatement(ctx, query.id, gobatis.StatementTypeSelect, gobatis.ResultStruct, sqlStr)
+			if err != nil {
+				return err
+			}
+			ctx.Statements[query.id] = stmt
+		}
+		return nil
+	})
+
+	factory, err := gobatis.New(&gobatis.Config{DriverName: tests.TestDrv,
+		DataSource: tests.TestConnURL,
+		Tracer:     gobatis.NullTracer{}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer factory.Close()
+
+	ctx := context.Background()
+	factory.DB().ExecContext(ctx, `DROP TABLE IF EXISTS t26`)
+	if _, err := factory.DB().ExecContext(ctx, `CREATE TABLE t26 (id bigint, name varchar(50), age int, status int)`); err != nil {
+		t.Error(err)
+		return
+	}
+	defer factory.DB().ExecContext(ctx, `DROP TABLE t26`)
+
+	for _, s := range []string{
+		`INSERT INTO t26(id, name, age, status) VALUES(1, 'abc', 10, 1)`,
+		`INSERT INTO t26(id, name, age, status) VALUES(2, 'abd', 20, 1)`,
+		`INSERT INTO t26(id, name, age, status) VALUES(3, 'xbc', 30, 1)`,
+		`INSERT INTO t26(id, age, status) VALUES(4, 40, 1)`,
+		`INSERT INTO t26(id, name, age, status) VALUES(5, 'a%c', 50, 1)`,
+		`INSERT INTO t26(id, name, age, status) VALUES(6, 'a_d', 60, 1)`,
+	} {
+		if _, err := factory.DB().ExecContext(ctx, s); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	ref := factory.SessionReference()
+	for idx, test := range []struct {
+		id       string
+		values   []interface{}
+		excepted int64
+	}{
+		{id: "T26.ageRange", values: []interface{}{20, 40}, excepted: 2},
+		{id: "T26.ageLte", values: []interface{}{20, "ab"}, excepted: 2},
+		{id: "T26.ageLte", values: []interface{}{30, ""}, excepted: 3},
+		{id: "T26.nameNe", values: []interface{}{"abc"}, excepted: 5},
+		{id: "T26.nameEndsWith", values: []interface{}{"bc"}, excepted: 2},
+		{id: "T26.nameNotLike", values: []interface{}{"b"}, excepted: 3},
+		{id: "T26.idNotIn", values: []interface{}{[]int64{1, 2}}, excepted: 4},
+		{id: "T26.idNotIn", values: []interface{}{[]int64{}}, excepted: 6},
+		{id: "T26.nameIsNull", values: []interface{}{0, true}, excepted: 1},
+		{id: "T26.nameIsNull", values: []interface{}{10, false}, excepted: 4},
+		// StartsWith， EndsWith 和 Contains 的值中的 % 和 _ 是普通字符
+		{id: "T26.nameStartsWith", values: []interface{}{"a%"}, excepted: 1},
+		{id: "T26.nameStartsWith", values: []interface{}{"a_"}, excepted: 1},
+		{id: "T26.nameEndsWith", values: []interface{}{"%c"}, excepted: 1},
+		{id: "T26.nameContains", values: []interface{}{"_"}, excepted: 1},
+	} {
+		var names []string
+		for _, query := range queries {
+			if query.id == test.id {
+				names = query.names
+			}
+		}
+
+		var count int64
+		if err := ref.SelectOne(ctx, test.id, names, test.values).Scan(&count); err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		if count != test.excepted {
+			t.Error("[", idx, "] excepted is", test.excepted)
+			t.Error("[", idx, "] actual   is", count)
+		}
+	}
+}
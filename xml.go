@@ -253,9 +253,16 @@ func readElementForXML(decoder *xml.Decoder, tag string) ([]sqlExpression, error
 				if value == "" {
 					return nil, errors.New("element like must has a 'value' notempty attribute")
 				}
+				match := readElementAttrForXML(el.Attr, "match")
+				switch match {
+				case "", "any", "prefix", "suffix":
+				default:
+					return nil, errors.New("element like has a invalid 'match' attribute - " + match)
+				}
 				likeExpr := &likeExpression{
 					prefix: prefix,
-					value:  value}
+					value:  value,
+					match:  match}
 				lastPrint = &likeExpr.suffix
 				expressions = append(expressions, likeExpr)
 			case "pagination":
@@ -285,7 +292,8 @@ func readElementForXML(decoder *xml.Decoder, tag string) ([]sqlExpression, error
 					return nil, errors.New("element order_by must is empty element")
 				}
 				orderBy := &orderByExpression{
-					sort: readElementAttrForXML(el.Attr, "sort")}
+					sort:    readElementAttrForXML(el.Attr, "sort"),
+					allowed: splitAllowedColumns(readElementAttrForXML(el.Attr, "allowed"))}
 				if orderBy.sort == "" {
 					orderBy.sort = readElementAttrForXML(el.Attr, "by")
 				}
@@ -520,6 +528,16 @@ func (foreach *xmlForEachElement) String() string {
 	return sb.String()
 }
 
+func splitAllowedColumns(s string) []string {
+	var columns []string
+	for _, column := range strings.Split(s, ",") {
+		if column = strings.TrimSpace(column); column != "" {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
 func hasXMLTag(sqlStr string) bool {
 	for _, tag := range []string{"<where>", "<set>", "<chose>", "<if>", "<foreach>"} {
 		if strings.Contains(sqlStr, tag) {
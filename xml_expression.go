@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 	"unicode"
@@ -195,6 +196,27 @@ var expFunctions = map[string]govaluate.ExpressionFunction{
 
 	"isnotnull": isNotNull,
 	"isNotNull": isNotNull,
+
+	"isZero": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, errors.New("isZero() args isnot 1")
+		}
+		return isZero(args[0]), nil
+	},
+	"isNotZero": func(args ...interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, errors.New("isNotZero() args isnot 1")
+		}
+		return !isZero(args[0]), nil
+	},
+}
+
+func isZero(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	rv := reflect.ValueOf(value)
+	return reflect.DeepEqual(value, reflect.Zero(rv.Type()).Interface())
 }
 
 type sqlPrinter struct {
@@ -738,9 +760,35 @@ type likeExpression struct {
 	prefix string
 	suffix string
 	value  string
+	match  string // 为 prefix 时生成 'xxx%'，为 suffix 时生成 '%xxx'， 其它为 '%xxx%'， 不为空时会转义值中的通配符
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// mssql 中的 [ 也是通配符
+var mssqlLikeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`, `[`, `\[`)
+
+// escapeLike 转义值中的 like 通配符， 并返回需要的 ESCAPE 子句，
+// mysql 和 postgres 缺省用 \ 作为转义符， 不需要 ESCAPE 子句
+func escapeLike(dialect Dialect, s string) (string, string) {
+	if IsDialect(dialect, DbTypeMysql) || IsDialect(dialect, DbTypePostgres) {
+		return likeEscaper.Replace(s), ""
+	}
+	if IsDialect(dialect, DbTypeMSSql) {
+		return mssqlLikeEscaper.Replace(s), ` ESCAPE '\'`
+	}
+	return likeEscaper.Replace(s), ` ESCAPE '\'`
+}
+
+// EscapeLike 转义值中的 like 通配符， 返回转义后的值和需要的 ESCAPE 子句
+func EscapeLike(dbType Dialect, s string) (string, string) {
+	return escapeLike(dbType, s)
 }
 
 func (expr likeExpression) String() string {
+	if expr.match != "" {
+		return expr.prefix + `<like value="` + expr.value + `" match="` + expr.match + `" />` + expr.suffix
+	}
 	return expr.prefix + `<like value="` + expr.value + `" />` + expr.suffix
 }
 
@@ -765,7 +813,19 @@ func (expr likeExpression) writeTo(printer *sqlPrinter) {
 		}
 
 		printer.sb.WriteString(expr.prefix)
-		if strings.HasPrefix(s, "%") || strings.HasSuffix(s, "%") {
+		if expr.match != "" {
+			// 指定了 match 时值中的 % 和 _ 是普通字符， 需要转义
+			escaped, escapeClause := escapeLike(printer.ctx.Dialect, s)
+			switch expr.match {
+			case "prefix":
+				printer.addPlaceholderAndParam(escaped + "%")
+			case "suffix":
+				printer.addPlaceholderAndParam("%" + escaped)
+			default:
+				printer.addPlaceholderAndParam("%" + escaped + "%")
+			}
+			printer.sb.WriteString(escapeClause)
+		} else if strings.HasPrefix(s, "%") || strings.HasSuffix(s, "%") {
 			printer.addPlaceholderAndParam(s)
 		} else {
 			printer.addPlaceholderAndParam("%" + s + "%")
@@ -797,14 +857,25 @@ func (expr paginationExpression) writeTo(printer *sqlPrinter) {
 }
 
 type orderByExpression struct {
-	sort string
+	sort    string
+	allowed []string
 }
 
 func (expr orderByExpression) String() string {
-	if expr.sort == "" {
-		return "<order_by />"
-	}
-	return `<order_by sort="` + expr.sort + `"/>`
+	var sb strings.Builder
+	sb.WriteString("<order_by")
+	if expr.sort != "" {
+		sb.WriteString(` sort="`)
+		sb.WriteString(expr.sort)
+		sb.WriteString(`"`)
+	}
+	if len(expr.allowed) != 0 {
+		sb.WriteString(` allowed="`)
+		sb.WriteString(strings.Join(expr.allowed, ","))
+		sb.WriteString(`"`)
+	}
+	sb.WriteString(" />")
+	return sb.String()
 }
 
 func (expr orderByExpression) writeTo(printer *sqlPrinter) {
@@ -819,20 +890,122 @@ func (expr orderByExpression) writeTo(printer *sqlPrinter) {
 	}
 
 	s := fmt.Sprint(o)
-	if s == "" {
+	if strings.TrimSpace(s) == "" {
 		return
 	}
 
+	orderBy, err := toOrderBy(printer.ctx.Dialect, s, expr.allowed)
+	if err != nil {
+		printer.err = err
+		return
+	}
 	printer.sb.WriteString(" ORDER BY ")
-	if strings.HasPrefix(s, "+") {
-		printer.sb.WriteString(strings.TrimPrefix(s, "+"))
-		printer.sb.WriteString(" ASC")
-	} else if strings.HasPrefix(s, "-") {
-		printer.sb.WriteString(strings.TrimPrefix(s, "-"))
-		printer.sb.WriteString(" DESC")
-	} else {
-		printer.sb.WriteString(s)
+	printer.sb.WriteString(orderBy)
+}
+
+var sortColumnRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// ToOrderBy 将排序参数转换为 ORDER BY 子句(不含 ORDER BY)， 格式见 toOrderBy
+func ToOrderBy(dialect Dialect, sort string, allowed []string) (string, error) {
+	return toOrderBy(dialect, sort, allowed)
+}
+
+// toOrderBy 将排序参数转换为 ORDER BY 子句， 排序参数的格式为逗号分隔的多个字段，
+// 每个字段的格式为 [+|-]column [ASC|DESC] [NULLS FIRST|NULLS LAST]， allowed 不为空时
+// 字段必须在 allowed 中， 否则字段必须是一个合法的标识符， 不合法时返回错误， 以防止 sql 注入。
+// allowed 中的列名可以是已引用的(如 "order")， 没有引用的列名会按 IdentifierQuotePolicy 引用
+func toOrderBy(dialect Dialect, s string, allowed []string) (string, error) {
+	var sb strings.Builder
+	for idx, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+
+		direction := ""
+		if strings.HasPrefix(item, "+") {
+			direction = "ASC"
+			item = item[1:]
+		} else if strings.HasPrefix(item, "-") {
+			direction = "DESC"
+			item = item[1:]
+		}
+
+		fields := strings.Fields(item)
+		if len(fields) == 0 {
+			return "", errors.New("sort '" + s + "' is invalid")
+		}
+
+		column, ok := toSortColumn(dialect, fields[0], allowed)
+		if !ok {
+			return "", errors.New("sort column '" + fields[0] + "' isnot allowed")
+		}
+
+		fields = fields[1:]
+		if len(fields) > 0 {
+			switch upper := strings.ToUpper(fields[0]); upper {
+			case "ASC", "DESC":
+				if direction != "" {
+					return "", errors.New("sort '" + s + "' is invalid")
+				}
+				direction = upper
+				fields = fields[1:]
+			}
+		}
+
+		nulls := ""
+		if len(fields) == 2 && strings.ToUpper(fields[0]) == "NULLS" {
+			switch upper := strings.ToUpper(fields[1]); upper {
+			case "FIRST", "LAST":
+				nulls = "NULLS " + upper
+				fields = nil
+			}
+		}
+		if len(fields) != 0 {
+			return "", errors.New("sort '" + s + "' is invalid")
+		}
+		if nulls != "" && !isNullsOrderSupported(dialect) {
+			return "", errors.New("sort '" + s + "' is invalid, " + nulls + " is unsupported for " + dialect.Name())
+		}
+
+		if idx > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(column)
+		if direction != "" {
+			sb.WriteString(" ")
+			sb.WriteString(direction)
+		}
+		if nulls != "" {
+			sb.WriteString(" ")
+			sb.WriteString(nulls)
+		}
 	}
+	return sb.String(), nil
+}
+
+func toSortColumn(dialect Dialect, column string, allowed []string) (string, bool) {
+	if len(allowed) == 0 {
+		if !sortColumnRegexp.MatchString(column) {
+			return "", false
+		}
+		return quoteTableName(dialect, column), true
+	}
+	for _, a := range allowed {
+		if isQuoted(a) {
+			if strings.EqualFold(a[1:len(a)-1], column) {
+				return a, true
+			}
+		} else if strings.EqualFold(a, column) {
+			return quoteIdentifier(dialect, a, false), true
+		}
+	}
+	return "", false
+}
+
+func isNullsOrderSupported(dialect Dialect) bool {
+	switch rootDialect(dialect) {
+	case DbTypePostgres, DbTypeOracle, DbTypeSqlite:
+		return true
+	}
+	return false
 }
 
 type trimExpression struct {
@@ -910,6 +1083,23 @@ func (expr trimExpression) hasSuffix(s string) (bool, string) {
 	return false, ""
 }
 
+// trimPrefix 忽略开头的空白字符， prefix 是小写的， 比较时不区分大小写， 所以这里按长度删除
+func (expr trimExpression) trimPrefix(s string) string {
+	trimed := strings.TrimLeftFunc(s, unicode.IsSpace)
+	if ok, prefix := expr.hasPrefix(trimed); ok {
+		return trimed[len(prefix):]
+	}
+	return s
+}
+
+func (expr trimExpression) trimSuffix(s string) string {
+	trimed := strings.TrimRightFunc(s, unicode.IsSpace)
+	if ok, suffix := expr.hasSuffix(trimed); ok {
+		return trimed[:len(trimed)-len(suffix)]
+	}
+	return s
+}
+
 func (expr trimExpression) writeTo(printer *sqlPrinter) {
 	newPrinter := &sqlPrinter{
 		ctx:    printer.ctx,
@@ -927,14 +1117,8 @@ func (expr trimExpression) writeTo(printer *sqlPrinter) {
 		return
 	}
 
-	hasPrefix, prefix := expr.hasPrefix(s)
-	hasSuffix, suffix := expr.hasSuffix(s)
-	if hasPrefix {
-		s = strings.TrimPrefix(s, prefix)
-	}
-	if hasSuffix {
-		s = strings.TrimSuffix(s, suffix)
-	}
+	s = expr.trimPrefix(s)
+	s = expr.trimSuffix(s)
 	if strings.TrimSpace(s) == "" {
 		return
 	}
@@ -958,12 +1142,8 @@ func (expr trimExpression) writeTo(printer *sqlPrinter) {
 
 		s = newPrinter.sb.String()
 
-		if hasPrefix, prefix = expr.hasPrefix(s); hasPrefix {
-			s = strings.TrimPrefix(s, prefix)
-		}
-		if hasSuffix, suffix = expr.hasSuffix(s); hasSuffix {
-			s = strings.TrimSuffix(s, suffix)
-		}
+		s = expr.trimPrefix(s)
+		s = expr.trimSuffix(s)
 	}
 
 	printer.sb.WriteString(s)
@@ -552,6 +552,22 @@ func TestXmlOk(t *testing.T) {
 			exceptedSQL:     "aa $1",
 			execeptedParams: []interface{}{"%2%"},
 		},
+		{
+			name:            "like_match_prefix",
+			sql:             `aa <like value="b" match="prefix" />`,
+			paramNames:      []string{"a", "b"},
+			paramValues:     []interface{}{[]int{}, "abc"},
+			exceptedSQL:     "aa $1",
+			execeptedParams: []interface{}{"abc%"},
+		},
+		{
+			name:            "like_match_suffix",
+			sql:             `aa <like value="b" match="suffix" />`,
+			paramNames:      []string{"a", "b"},
+			paramValues:     []interface{}{[]int{}, "abc"},
+			exceptedSQL:     "aa $1",
+			execeptedParams: []interface{}{"%abc"},
+		},
 		{
 			name:            "like_with_field.field.field",
 			sql:             `aa <like value="a.Field.Field.Field"/>`,
@@ -640,6 +656,22 @@ func TestXmlOk(t *testing.T) {
 			exceptedSQL:     "aa  ORDER BY abc DESC",
 			execeptedParams: []interface{}{},
 		},
+		{
+			name:            "order by multiple",
+			sql:             `aa <order_by />`,
+			paramNames:      []string{"sort"},
+			paramValues:     []interface{}{"-abc, name asc nulls last,id DESC NULLS FIRST"},
+			exceptedSQL:     "aa  ORDER BY abc DESC, name ASC NULLS LAST, id DESC NULLS FIRST",
+			execeptedParams: []interface{}{},
+		},
+		{
+			name:            "order by allowed",
+			sql:             `aa <order_by sort="aa" allowed="id, Name"/>`,
+			paramNames:      []string{"aa"},
+			paramValues:     []interface{}{"-name,+ID"},
+			exceptedSQL:     "aa  ORDER BY Name DESC, id ASC",
+			execeptedParams: []interface{}{},
+		},
 		{
 			name:            "order by 4",
 			sql:             `aa <order_by sort="aa"/>`,
@@ -673,6 +705,14 @@ func TestXmlOk(t *testing.T) {
 			exceptedSQL:     "aa  a",
 			execeptedParams: []interface{}{},
 		},
+		{
+			name:            "trim prefix ignore case and space",
+			sql:             `aa <trim prefixOverrides="or " prefix="AND (" suffix=")"> OR a OR b</trim>`,
+			paramNames:      []string{"aa"},
+			paramValues:     []interface{}{},
+			exceptedSQL:     "aa AND ( a OR b)",
+			execeptedParams: []interface{}{},
+		},
 		{
 			name:            "trim suffix 2",
 			sql:             `aa <trim suffixOverrides=",">a </trim>`,
@@ -918,6 +958,28 @@ func TestXmlFail(t *testing.T) {
 			err:         "#{",
 		},
 
+		{
+			name:        "order by injection",
+			sql:         `aa <order_by />`,
+			paramNames:  []string{"sort"},
+			paramValues: []interface{}{"id; DROP TABLE users"},
+			err:         "isnot allowed",
+		},
+		{
+			name:        "order by invalid direction",
+			sql:         `aa <order_by />`,
+			paramNames:  []string{"sort"},
+			paramValues: []interface{}{"id desc, name up"},
+			err:         "is invalid",
+		},
+		{
+			name:        "order by not allowed",
+			sql:         `aa <order_by allowed="id,name" />`,
+			paramNames:  []string{"sort"},
+			paramValues: []interface{}{"password"},
+			err:         "sort column 'password' isnot allowed",
+		},
+
 		{
 			name:        "like empty value",
 			sql:         `aa <like value="a" />`,
@@ -926,6 +988,14 @@ func TestXmlFail(t *testing.T) {
 			err:         "empty",
 		},
 
+		{
+			name:        "like match error",
+			sql:         `aa <like value="a" match="abc" />`,
+			paramNames:  []string{"a"},
+			paramValues: []interface{}{"a"},
+			err:         "invalid 'match' attribute",
+		},
+
 		{
 			name:        "like xml error",
 			sql:         `aa <like value="" />`,
@@ -1236,3 +1306,105 @@ func TestXmlExpressionFail(t *testing.T) {
 		t.Error("got   ok")
 	}
 }
+
+func TestXmlOrderByNulls(t *testing.T) {
+	for idx, test := range []struct {
+		dialect     gobatis.Dialect
+		exceptedSQL string
+		err         string
+	}{
+		{dialect: gobatis.DbTypeSqlite, exceptedSQL: "aa  ORDER BY id DESC NULLS LAST"},
+		{dialect: gobatis.DbTypeKingbase, exceptedSQL: "aa  ORDER BY id DESC NULLS LAST"},
+		{dialect: gobatis.DbTypeMysql, err: "NULLS LAST is unsupported for mysql"},
+		{dialect: gobatis.DbTypeMSSql, err: "NULLS LAST is unsupported for mssql"},
+	} {
+		initCtx := &gobatis.InitContext{Config: &gobatis.Config{},
+			Dialect:    test.dialect,
+			Mapper:     gobatis.CreateMapper("", nil, nil),
+			Statements: make(map[string]*gobatis.MappedStatement)}
+		stmt, err := gobatis.NewMapppedStatement(initCtx, "ddd", gobatis.StatementTypeSelect, gobatis.ResultStruct, `aa <order_by />`)
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+
+		ctx, err := gobatis.NewContext(nil, test.dialect, initCtx.Mapper, []string{"sort"}, []interface{}{"-id nulls last"})
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+
+		sqlParams, err := stmt.GenerateSQLs(ctx)
+		if test.err != "" {
+			if err == nil {
+				t.Error("[", idx, "] except return a error")
+			} else if !strings.Contains(err.Error(), test.err) {
+				t.Error("[", idx, "] excepted is", test.err)
+				t.Error("[", idx, "] actual   is", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		if sqlParams[0].SQL != test.exceptedSQL {
+			t.Error("[", idx, "] excepted is", test.exceptedSQL)
+			t.Error("[", idx, "] actual   is", sqlParams[0].SQL)
+		}
+	}
+}
+
+func TestXmlLikeEscape(t *testing.T) {
+	for idx, test := range []struct {
+		dialect         gobatis.Dialect
+		sql             string
+		value           string
+		exceptedSQL     string
+		execeptedParams []interface{}
+	}{
+		{dialect: gobatis.DbTypePostgres, sql: `aa <like value="a" match="prefix" />`, value: `a%b_c\`,
+			exceptedSQL: "aa $1", execeptedParams: []interface{}{`a\%b\_c\\%`}},
+		{dialect: gobatis.DbTypeMysql, sql: `aa <like value="a" match="suffix" />`, value: "%ab",
+			exceptedSQL: "aa ?", execeptedParams: []interface{}{`%\%ab`}},
+		{dialect: gobatis.DbTypeSqlite, sql: `aa <like value="a" match="any" /> bb`, value: "a_b",
+			exceptedSQL: `aa ? ESCAPE '\' bb`, execeptedParams: []interface{}{`%a\_b%`}},
+		{dialect: gobatis.DbTypeMSSql, sql: `aa <like value="a" match="prefix" />`, value: "[a]%",
+			exceptedSQL: `aa ? ESCAPE '\'`, execeptedParams: []interface{}{`\[a]\%%`}},
+		{dialect: gobatis.DbTypeOracle, sql: `aa <like value="a" match="suffix" />`, value: "a_",
+			exceptedSQL: `aa ? ESCAPE '\'`, execeptedParams: []interface{}{`%a\_`}},
+		// 没有 match 属性时保留值中的通配符
+		{dialect: gobatis.DbTypeSqlite, sql: `aa <like value="a" />`, value: "a_b%",
+			exceptedSQL: "aa ?", execeptedParams: []interface{}{"a_b%"}},
+	} {
+		initCtx := &gobatis.InitContext{Config: &gobatis.Config{},
+			Dialect:    test.dialect,
+			Mapper:     gobatis.CreateMapper("", nil, nil),
+			Statements: make(map[string]*gobatis.MappedStatement)}
+		stmt, err := gobatis.NewMapppedStatement(initCtx, "ddd", gobatis.StatementTypeSelect, gobatis.ResultStruct, test.sql)
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+
+		ctx, err := gobatis.NewContext(nil, test.dialect, initCtx.Mapper, []string{"a"}, []interface{}{test.value})
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+
+		sqlParams, err := stmt.GenerateSQLs(ctx)
+		if err != nil {
+			t.Error("[", idx, "]", err)
+			continue
+		}
+		if sqlParams[0].SQL != test.exceptedSQL {
+			t.Error("[", idx, "] excepted is", test.exceptedSQL)
+			t.Error("[", idx, "] actual   is", sqlParams[0].SQL)
+		}
+		if !reflect.DeepEqual(sqlParams[0].Params, test.execeptedParams) {
+			t.Error("[", idx, "] excepted is", test.execeptedParams)
+			t.Error("[", idx, "] actual   is", sqlParams[0].Params)
+		}
+	}
+}